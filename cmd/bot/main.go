@@ -10,8 +10,10 @@ import (
 	"time"
 
 	"github.com/oklog/run"
+	tele "gopkg.in/telebot.v3"
 
 	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/http"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/listener"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
@@ -21,6 +23,9 @@ import (
 	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
 )
 
+// commit is set by -ldflags "-X main.commit=<sha>"
+var commit string
+
 func main() {
 	config, err := config.New()
 	if err != nil {
@@ -42,31 +47,81 @@ func main() {
 		log.Fatal("failed to connect to db: ", err)
 	}
 
+	if config.DB.SchemaCheck {
+		if err := db.CheckSchemaVersion(ctx, sqlDB); err != nil {
+			log.Fatal("failed to check schema version: ", err)
+		}
+	}
+
 	repo := db.New(sqlDB)
-	telegramClient := telegram.NewClient()
+
+	// bot api client sends messages to chats by id, e.g. alerts of results from the mini app
+	sender, err := tele.NewBot(tele.Settings{Token: config.Token, Offline: true})
+	if err != nil {
+		log.Fatal("failed to create telegram sender: ", err)
+	}
+	telegramClient := telegram.NewClient(sender)
 	processor := resultsprocessor.New()
-	svc := service.New(telegramClient, repo, processor, logger)
 
-	var g run.Group
-	{
-		logger := logger.WithPrefix("task-name", "message-listener")
-		listener, err := listener.New(logger, config.Token, config.AdminUserIDs, config.PollInterval, svc)
+	eventBus := service.NewEventBus()
+	opts := []service.Option{service.WithEvents(eventBus, config.EventsAnswersThrottle)}
+	if config.AlertRulesFile != "" {
+		data, err := os.ReadFile(config.AlertRulesFile)
+		if err != nil {
+			log.Fatal("failed to read alert rules: ", err)
+		}
+
+		rules, err := entity.ParseAlertRules(data)
 		if err != nil {
-			log.Fatal("failed to create listener: ", err)
+			log.Fatal("failed to parse alert rules: ", err)
 		}
 
+		opts = append(opts, service.WithAlerts(rules, config.AlertChatID))
+	}
+
+	svc := service.New(telegramClient, repo, processor, logger, opts...)
+
+	listenerLogger := logger.WithPrefix("task-name", "message-listener")
+	botListener, err := listener.New(listenerLogger, config.Token, config.AdminUserIDs, config.PollInterval, svc)
+	if err != nil {
+		log.Fatal("failed to create listener: ", err)
+	}
+
+	// Telegram outage doesn't make the API of the mini app unready, so polling is only reported by the liveness probe
+	readinessChecks := []http.ReadinessCheck{
+		{Name: "db", Check: sqlDB.PingContext},
+	}
+	healthChecks := []http.ReadinessCheck{
+		{Name: "listener", Check: botListener.CheckPolling},
+	}
+	if config.DB.SchemaCheck {
+		// the schema migrated by a newer release doesn't make old pods unready during the rollout
+		readinessChecks = append(readinessChecks, http.ReadinessCheck{Name: "migrations", Check: func(ctx context.Context) error {
+			return db.CheckSchemaNotBehind(ctx, sqlDB)
+		}})
+	}
+
+	metricsServer := http.NewMetricsServer(
+		config.MetricsPort,
+		readinessChecks,
+		healthChecks,
+		http.NewBuildInfo(config.ReleaseVersion, commit),
+		logger.WithPrefix("task-name", "metrics-server"),
+	)
+
+	var g run.Group
+	{
 		g.Add(func() error {
-			logger.Infof(ctx, "started")
-			listener.Start()
+			listenerLogger.Infof(ctx, "started")
+			botListener.Start()
 			return nil
 		}, func(err error) {
-			logger.Infof(ctx, "stopped")
-			listener.Stop()
+			listenerLogger.Infof(ctx, "stopped")
+			botListener.Stop()
 		})
 	}
 	{
 		logger := logger.WithPrefix("task-name", "metrics-server")
-		metricsServer := http.NewMetricsServer(config.MetricsPort, logger)
 		g.Add(func() error {
 			logger.Infof(ctx, "started")
 			metricsServer.Start()
@@ -78,7 +133,7 @@ func main() {
 	}
 	{
 		logger := logger.WithPrefix("task-name", "http-server")
-		httpServer := http.NewAPIServer(
+		httpServer, err := http.NewAPIServer(
 			config.APIPort,
 			config.Token,
 			config.AllowedOrigins,
@@ -86,6 +141,10 @@ func main() {
 			svc,
 			logger,
 		)
+		if err != nil {
+			log.Fatal("failed to create http server: ", err)
+		}
+
 		g.Add(func() error {
 			logger.Infof(ctx, "started")
 			return httpServer.Start()
@@ -97,6 +156,42 @@ func main() {
 			}
 		})
 	}
+	{
+		logger := logger.WithPrefix("task-name", "events-listener")
+		listenerCtx, cancel := context.WithCancel(ctx)
+
+		g.Add(func() error {
+			logger.Infof(ctx, "started")
+			return db.ListenEvents(listenerCtx, config.DB.ConnectionString(), logger, eventBus.Publish)
+		}, func(err error) {
+			cancel()
+			logger.Infof(ctx, "stopped")
+		})
+	}
+	{
+		logger := logger.WithPrefix("task-name", "norms-refresher")
+		ticker := time.NewTicker(config.NormsRefreshInterval)
+		stop := make(chan struct{})
+
+		g.Add(func() error {
+			logger.Infof(ctx, "started")
+			for {
+				if err := svc.RefreshNorms(ctx); err != nil {
+					logger.Errorf(ctx, "failed to refresh norms: %v", err)
+				}
+
+				select {
+				case <-ticker.C:
+				case <-stop:
+					return nil
+				}
+			}
+		}, func(err error) {
+			ticker.Stop()
+			close(stop)
+			logger.Infof(ctx, "stopped")
+		})
+	}
 	{
 		logger := logger.WithPrefix("task-name", "sig-listener")
 		c := make(chan os.Signal, 1)
@@ -106,6 +201,15 @@ func main() {
 			logger.Infof(ctx, "started")
 			s := <-c
 			logger.Warnf(ctx, "program terminated with signal: %v", s)
+			metricsServer.SetShuttingDown()
+
+			// readiness probes see the bot is not ready before the servers are stopped
+			logger.Infof(ctx, "draining for %s", config.ShutdownDelay)
+			select {
+			case <-time.After(config.ShutdownDelay):
+			case <-c:
+			}
+
 			return fmt.Errorf("interrupted with sig %q", s)
 		}, func(err error) {
 			logger.Infof(ctx, "stopped")
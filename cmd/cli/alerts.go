@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"github.com/google/subcommands"
+)
+
+type AlertsListCmd struct {
+	all bool
+}
+
+func (*AlertsListCmd) Name() string     { return "alerts-list" }
+func (*AlertsListCmd) Synopsis() string { return "list alerts of high-risk results" }
+func (*AlertsListCmd) Usage() string {
+	return `alerts-list [-all]:
+	List not acknowledged alerts of high-risk results, all alerts with -all
+  `
+}
+
+func (p *AlertsListCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.all, "all", false, "include acknowledged alerts")
+}
+
+func (p *AlertsListCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	alerts, err := svc.GetAlerts(ctx, !p.all)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get alerts: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GUID\tCREATED AT\tUSER\tSURVEY\tSCALE\tSCORE\tLEVEL\tACKNOWLEDGED BY")
+	for _, alert := range alerts {
+		acknowledgedBy := "-"
+		if alert.AcknowledgedBy != nil {
+			acknowledgedBy = fmt.Sprint(*alert.AcknowledgedBy)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%g\t%s\t%s\n",
+			alert.GUID,
+			alert.CreatedAt.Format(time.DateTime),
+			alert.UserGUID,
+			alert.SurveyGUID,
+			alert.ScaleName,
+			alert.Score,
+			alert.Level,
+			acknowledgedBy,
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		logger.Errorf(ctx, "failed to write alerts: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"github.com/google/subcommands"
+	"github.com/google/uuid"
+)
+
+type APIKeyCreateCmd struct {
+	name   string
+	scopes string
+}
+
+func (*APIKeyCreateCmd) Name() string     { return "api-key-create" }
+func (*APIKeyCreateCmd) Synopsis() string { return "create API key for the admin API" }
+func (*APIKeyCreateCmd) Usage() string {
+	return `api-key-create -name <name> -scopes <scope,...>:
+	Create API key, it is printed only once. Scopes: results:read, surveys:manage
+  `
+}
+
+func (p *APIKeyCreateCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.name, "name", "", "name of the client using the key")
+	f.StringVar(&p.scopes, "scopes", "", "comma separated scopes")
+}
+
+func (p *APIKeyCreateCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if p.name == "" {
+		log.Print("name is required")
+		return subcommands.ExitUsageError
+	}
+
+	scopes, err := entity.ParseAPIKeyScopes(p.scopes)
+	if err != nil {
+		log.Print("failed to parse scopes: ", err)
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	key, secret, err := svc.CreateAPIKey(ctx, p.name, scopes)
+	if err != nil {
+		logger.Errorf(ctx, "failed to create api key: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	logger.Infof(ctx, "api key %s created", key.GUID)
+	fmt.Println(secret)
+
+	return subcommands.ExitSuccess
+}
+
+type APIKeyListCmd struct {
+}
+
+func (*APIKeyListCmd) Name() string     { return "api-key-list" }
+func (*APIKeyListCmd) Synopsis() string { return "list API keys" }
+func (*APIKeyListCmd) Usage() string {
+	return `api-key-list:
+	List API keys including revoked ones
+  `
+}
+
+func (p *APIKeyListCmd) SetFlags(f *flag.FlagSet) {
+}
+
+func (p *APIKeyListCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	keys, err := svc.GetAPIKeys(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get api keys: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+
+		return t.Format(time.DateTime)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GUID\tNAME\tSCOPES\tCREATED AT\tLAST USED AT\tREVOKED AT")
+	for _, key := range keys {
+		scopes := make([]string, 0, len(key.Scopes))
+		for _, scope := range key.Scopes {
+			scopes = append(scopes, string(scope))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			key.GUID,
+			key.Name,
+			strings.Join(scopes, ","),
+			key.CreatedAt.Format(time.DateTime),
+			formatTime(key.LastUsedAt),
+			formatTime(key.RevokedAt),
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		logger.Errorf(ctx, "failed to write api keys: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
+
+type APIKeyRevokeCmd struct {
+}
+
+func (*APIKeyRevokeCmd) Name() string     { return "api-key-revoke" }
+func (*APIKeyRevokeCmd) Synopsis() string { return "revoke API key" }
+func (*APIKeyRevokeCmd) Usage() string {
+	return `api-key-revoke <guid>:
+	Revoke API key, requests with it are rejected
+  `
+}
+
+func (p *APIKeyRevokeCmd) SetFlags(f *flag.FlagSet) {
+}
+
+func (p *APIKeyRevokeCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	keyGUID, err := uuid.Parse(f.Arg(0))
+	if err != nil {
+		log.Print("failed to parse api key guid: ", err)
+		return subcommands.ExitFailure
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	if err := svc.RevokeAPIKey(ctx, keyGUID); err != nil {
+		logger.Errorf(ctx, "failed to revoke api key: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	logger.Infof(ctx, "api key %s revoked", keyGUID)
+
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"github.com/google/subcommands"
+)
+
+type BackupCmd struct {
+}
+
+func (*BackupCmd) Name() string     { return "backup" }
+func (*BackupCmd) Synopsis() string { return "save surveys, users and survey states to archive" }
+func (*BackupCmd) Usage() string {
+	return `backup <file_path>:
+	Save surveys, users and survey states to zip archive of JSON lines files with manifest and checksums.
+	All records are read from the same snapshot of data, the bot could keep working during backup.
+  `
+}
+
+func (p *BackupCmd) SetFlags(f *flag.FlagSet) {
+}
+
+func (p *BackupCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	filePath := f.Arg(0)
+	if filePath == "" {
+		log.Print("empty file path")
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	if err := db.CheckSchemaVersion(ctx, sqlDB); err != nil {
+		logger.Errorf(ctx, "failed to check schema version: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	schemaVersion, _, err := db.SchemaVersion(ctx, sqlDB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get schema version: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		logger.Errorf(ctx, "failed to create file: %s", err)
+		return subcommands.ExitFailure
+	}
+	defer func() { _ = file.Close() }()
+
+	manifest, err := svc.Backup(ctx, file, schemaVersion)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		logger.Errorf(ctx, "failed to backup: %s", err)
+		_ = os.Remove(filePath)
+		return subcommands.ExitFailure
+	}
+
+	for _, file := range manifest.Files {
+		fmt.Printf("%s: %d records\n", file.Name, file.Records)
+	}
+	fmt.Printf("Saved backup of schema version %d to %s\n", manifest.SchemaVersion, filePath)
+
+	return subcommands.ExitSuccess
+}
+
+type RestoreCmd struct {
+	policy string
+	check  bool
+}
+
+func (*RestoreCmd) Name() string     { return "restore" }
+func (*RestoreCmd) Synopsis() string { return "restore surveys, users and survey states from archive" }
+func (*RestoreCmd) Usage() string {
+	return `restore [-policy fail|skip|overwrite] [-check] <file_path>:
+	Check the archive made by backup and import it in one transaction. Records already stored in the database
+	fail the restore by default, they are kept with -policy skip and replaced with -policy overwrite.
+  `
+}
+
+func (p *RestoreCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.policy, "policy", string(service.RestoreFail), "what to do with stored records: fail, skip or overwrite")
+	f.BoolVar(&p.check, "check", false, "only check the archive, the database is not used")
+}
+
+func (p *RestoreCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	filePath := f.Arg(0)
+	if filePath == "" {
+		log.Print("empty file path")
+		return subcommands.ExitUsageError
+	}
+
+	policy := service.RestorePolicy(p.policy)
+	switch policy {
+	case service.RestoreFail, service.RestoreSkip, service.RestoreOverwrite:
+	default:
+		log.Print("unknown policy: ", p.policy)
+		return subcommands.ExitUsageError
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Print("failed to open file: ", err)
+		return subcommands.ExitFailure
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		log.Print("failed to stat file: ", err)
+		return subcommands.ExitFailure
+	}
+
+	manifest, err := service.CheckBackup(file, info.Size())
+	if err != nil {
+		log.Print("failed to check backup: ", err)
+		return subcommands.ExitFailure
+	}
+
+	fmt.Printf("Backup of schema version %d created at %s\n", manifest.SchemaVersion, manifest.CreatedAt.Format(time.DateTime))
+	for _, file := range manifest.Files {
+		fmt.Printf("%s: %d records\n", file.Name, file.Records)
+	}
+
+	if p.check {
+		return subcommands.ExitSuccess
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	// records of older backups are restored to the latest schema, so it should be migrated first
+	if err := db.CheckSchemaVersion(ctx, sqlDB); err != nil {
+		logger.Errorf(ctx, "failed to check schema version: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	_, stats, err := svc.Restore(ctx, file, info.Size(), policy)
+	if err != nil {
+		logger.Errorf(ctx, "failed to restore: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	fmt.Printf("Restored surveys: %d, skipped: %d\n", stats.Surveys.Restored, stats.Surveys.Skipped)
+	fmt.Printf("Restored users: %d, skipped: %d\n", stats.Users.Restored, stats.Users.Skipped)
+	fmt.Printf("Restored survey states: %d, skipped: %d\n", stats.SurveyStates.Restored, stats.SurveyStates.Skipped)
+
+	return subcommands.ExitSuccess
+}
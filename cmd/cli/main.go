@@ -14,8 +14,31 @@ func main() {
 	subcommands.Register(subcommands.CommandsCommand(), "")
 	subcommands.Register(&CreateSurveyCmd{}, "")
 	subcommands.Register(&UpdateSurveyCmd{}, "")
+	subcommands.Register(&SurveyDiffCmd{}, "")
+	subcommands.Register(&SurveyListCmd{}, "")
+	subcommands.Register(&SurveyShowCmd{}, "")
+	subcommands.Register(&SurveyExportCmd{}, "")
+	subcommands.Register(&SurveyDeleteCmd{}, "")
+	subcommands.Register(&SurveyValidateCmd{}, "")
+	subcommands.Register(&SurveySyncCmd{}, "")
+	subcommands.Register(&SurveyRunCmd{}, "")
 	subcommands.Register(&DeleteUserInfoCmd{}, "")
+	subcommands.Register(&UserListCmd{}, "")
+	subcommands.Register(&UserShowCmd{}, "")
+	subcommands.Register(&UserResetCmd{}, "")
+	subcommands.Register(&UserForgetCmd{}, "")
 	subcommands.Register(&GetResultsCmd{}, "")
+	subcommands.Register(&RefreshNormsCmd{}, "")
+	subcommands.Register(&ImportNormsCmd{}, "")
+	subcommands.Register(&SurveyReportCmd{}, "")
+	subcommands.Register(&AlertsListCmd{}, "")
+	subcommands.Register(&APIKeyCreateCmd{}, "")
+	subcommands.Register(&APIKeyListCmd{}, "")
+	subcommands.Register(&APIKeyRevokeCmd{}, "")
+	subcommands.Register(&BackupCmd{}, "")
+	subcommands.Register(&RestoreCmd{}, "")
+	subcommands.Register(&MigrateCmd{}, "")
+	subcommands.Register(&SimulateCmd{}, "")
 
 	flag.Parse()
 	ctx := context.Background()
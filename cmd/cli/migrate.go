@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
+	"github.com/google/subcommands"
+)
+
+type MigrateCmd struct {
+	yes bool
+}
+
+func (*MigrateCmd) Name() string     { return "migrate" }
+func (*MigrateCmd) Synopsis() string { return "show and change version of database schema" }
+func (*MigrateCmd) Usage() string {
+	return `migrate [-yes] status|up [n]|down [n]|force <version>:
+	status prints applied and pending migrations embedded into the binary.
+	up applies n pending migrations, all of them by default.
+	down rolls back n applied migrations, 1 by default. Data of rolled back tables and columns is lost.
+	force sets the version without running migrations and clears the dirty flag after the failed migration
+	is fixed by hand, version 0 means no migration is applied.
+  `
+}
+
+func (p *MigrateCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.yes, "yes", false, "don't ask for confirmation of down")
+}
+
+func (p *MigrateCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	action := f.Arg(0)
+
+	var n int
+	var forced uint64
+	switch action {
+	case "status":
+		if f.NArg() > 1 {
+			log.Print("status has no arguments")
+			return subcommands.ExitUsageError
+		}
+	case "up", "down":
+		// up applies all pending migrations by default, while down rolls back only the last one
+		if action == "down" {
+			n = 1
+		}
+
+		if f.NArg() > 1 {
+			var err error
+			if n, err = strconv.Atoi(f.Arg(1)); err != nil || n <= 0 {
+				log.Print("invalid number of migrations: ", f.Arg(1))
+				return subcommands.ExitUsageError
+			}
+		}
+	case "force":
+		if f.NArg() != 2 {
+			log.Print("force requires version")
+			return subcommands.ExitUsageError
+		}
+
+		var err error
+		if forced, err = strconv.ParseUint(f.Arg(1), 10, 0); err != nil {
+			log.Print("invalid version: ", f.Arg(1))
+			return subcommands.ExitUsageError
+		}
+	default:
+		log.Print("unknown action: ", action)
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	migrations, err := db.Migrations()
+	if err != nil {
+		log.Print("failed to read migrations: ", err)
+		return subcommands.ExitFailure
+	}
+
+	m, err := db.NewMigrator(config.DB.ConnectionString(), func(format string, args ...interface{}) {
+		fmt.Printf(format, args...)
+	})
+	if err != nil {
+		log.Print("failed to connect to db: ", err)
+		return subcommands.ExitFailure
+	}
+	defer func() { _ = m.Close() }()
+
+	switch action {
+	case "up":
+		err = m.Up(n)
+	case "down":
+		current, _, versionErr := m.Version()
+		if versionErr != nil {
+			log.Print(versionErr)
+			return subcommands.ExitFailure
+		}
+
+		var rolledBack []string
+		for i := len(migrations) - 1; i >= 0 && len(rolledBack) < n; i-- {
+			if migrations[i].Version <= current {
+				rolledBack = append(rolledBack, fmt.Sprintf("%d_%s", migrations[i].Version, migrations[i].Name))
+			}
+		}
+
+		if len(rolledBack) == 0 {
+			fmt.Println("No migrations to roll back")
+			return subcommands.ExitSuccess
+		}
+
+		fmt.Println("Migrations to roll back:")
+		for _, name := range rolledBack {
+			fmt.Printf("  %s\n", name)
+		}
+
+		if !p.yes && !confirm("Roll back migrations? Data of their tables and columns is lost") {
+			return subcommands.ExitSuccess
+		}
+
+		err = m.Down(n)
+	case "force":
+		err = m.Force(uint(forced))
+	}
+	if err != nil {
+		log.Print(err)
+		return subcommands.ExitFailure
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil {
+		log.Print(err)
+		return subcommands.ExitFailure
+	}
+
+	if action != "status" {
+		fmt.Println()
+	}
+
+	state := ""
+	if dirty {
+		state = " (dirty)"
+	}
+	fmt.Printf("Schema version: %d%s\n", version, state)
+	fmt.Printf("Latest migration: %d\n\n", migrations[len(migrations)-1].Version)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE")
+	for _, migration := range migrations {
+		state := "pending"
+		switch {
+		case migration.Version == version && dirty:
+			state = "dirty"
+		case migration.Version <= version:
+			state = "applied"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	if err := w.Flush(); err != nil {
+		log.Print("failed to print migrations: ", err)
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"github.com/google/subcommands"
+	"github.com/google/uuid"
+)
+
+type RefreshNormsCmd struct {
+}
+
+func (*RefreshNormsCmd) Name() string { return "norms-refresh" }
+func (*RefreshNormsCmd) Synopsis() string {
+	return "recalculate population norms from finished surveys"
+}
+func (*RefreshNormsCmd) Usage() string {
+	return `norms-refresh:
+	Recalculate population norms of all surveys from finished surveys
+  `
+}
+
+func (p *RefreshNormsCmd) SetFlags(f *flag.FlagSet) {
+}
+
+func (p *RefreshNormsCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	if err := svc.RefreshNorms(ctx); err != nil {
+		logger.Errorf(ctx, "failed to refresh norms: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
+
+type ImportNormsCmd struct {
+}
+
+func (*ImportNormsCmd) Name() string     { return "norms-import" }
+func (*ImportNormsCmd) Synopsis() string { return "import norm table of survey from CSV file" }
+func (*ImportNormsCmd) Usage() string {
+	return `norms-import <survey_guid> <file_path>:
+	Import norm table of survey from CSV file with header "scale,cohort,mean,std_dev,sample_size".
+	Empty cohort means whole population. Imported norms are preferred to population ones.
+  `
+}
+
+func (p *ImportNormsCmd) SetFlags(f *flag.FlagSet) {
+}
+
+func (p *ImportNormsCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	surveyGUID, err := uuid.Parse(f.Arg(0))
+	if err != nil {
+		log.Print("failed to parse survey guid: ", err)
+		return subcommands.ExitFailure
+	}
+
+	norms, err := readNormsFromFile(surveyGUID, f.Arg(1))
+	if err != nil {
+		log.Print("failed to read norms from file: ", err)
+		return subcommands.ExitFailure
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	if err := svc.ImportNorms(ctx, norms); err != nil {
+		logger.Errorf(ctx, "failed to import norms: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	logger.Infof(ctx, "imported %d norms for survey %s", len(norms), surveyGUID)
+
+	return subcommands.ExitSuccess
+}
+
+func readNormsFromFile(surveyGUID uuid.UUID, filename string) ([]entity.Norm, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+
+	if len(records) < 2 {
+		return nil, fmt.Errorf("no norms in file")
+	}
+
+	var norms []entity.Norm
+	for i, record := range records[1:] {
+		if len(record) != 5 {
+			return nil, fmt.Errorf("line %d: expected 5 columns, got %d", i+2, len(record))
+		}
+
+		mean, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: failed to parse mean: %w", i+2, err)
+		}
+
+		stdDev, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: failed to parse std_dev: %w", i+2, err)
+		}
+
+		sampleSize, err := strconv.Atoi(record[4])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: failed to parse sample_size: %w", i+2, err)
+		}
+
+		norms = append(norms, entity.Norm{
+			SurveyGUID: surveyGUID,
+			Scale:      record[0],
+			Cohort:     record[1],
+			Source:     entity.NormSourceImported,
+			Mean:       mean,
+			StdDev:     stdDev,
+			SampleSize: sampleSize,
+		})
+	}
+
+	return norms, nil
+}
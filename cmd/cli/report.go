@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"github.com/google/subcommands"
+	"github.com/google/uuid"
+)
+
+type SurveyReportCmd struct {
+	answers bool
+}
+
+func (*SurveyReportCmd) Name() string     { return "survey-report" }
+func (*SurveyReportCmd) Synopsis() string { return "save PDF report of the finished survey" }
+func (*SurveyReportCmd) Usage() string {
+	return `survey-report [-answers] <user_guid> <survey_guid> <file_path>:
+	Save PDF report of the latest finished attempt of the survey by the user
+  `
+}
+
+func (p *SurveyReportCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.answers, "answers", false, "include answers to the report")
+}
+
+func (p *SurveyReportCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	userGUID, err := uuid.Parse(f.Arg(0))
+	if err != nil {
+		log.Print("failed to parse user guid: ", err)
+		return subcommands.ExitFailure
+	}
+
+	surveyGUID, err := uuid.Parse(f.Arg(1))
+	if err != nil {
+		log.Print("failed to parse survey guid: ", err)
+		return subcommands.ExitFailure
+	}
+
+	filePath := f.Arg(2)
+	if filePath == "" {
+		log.Print("empty file path")
+		return subcommands.ExitFailure
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	report, err := svc.GetUserSurveyReport(ctx, userGUID, surveyGUID, p.answers)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get report: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	if err := os.WriteFile(filePath, report, 0644); err != nil {
+		logger.Errorf(ctx, "failed to write report: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	logger.Infof(ctx, "saved report to %s", filePath)
+
+	return subcommands.ExitSuccess
+}
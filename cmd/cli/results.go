@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"time"
@@ -16,27 +18,60 @@ import (
 )
 
 type GetResultsCmd struct {
+	from   string
+	to     string
+	survey string
+	user   string
+	scale  string
+	level  string
+	format string
+	out    string
 }
 
 func (*GetResultsCmd) Name() string     { return "survey-get-results" }
-func (*GetResultsCmd) Synopsis() string { return "get results and print it to output in CSV format" }
+func (*GetResultsCmd) Synopsis() string { return "export results of finished surveys" }
 func (*GetResultsCmd) Usage() string {
-	return `survey-get-results:
-	Get results and print it to output in CSV format
+	return `survey-get-results [-from <date>] [-to <date>] [-survey <survey_guid>] [-user <user_guid>] [-scale <key> [-level <level>]] [-format csv|jsonl|xlsx] [-out <file_path>]:
+	Export results of finished surveys to stdout or to the file, CSV by default. Dates are in 2006-01-02 or RFC3339 format,
+	surveys finished at -from are included, at -to are not. Filters are the same as in the admin API. Progress is printed
+	to stderr.
   `
 }
 
 func (p *GetResultsCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.from, "from", "", "export surveys finished since the date")
+	f.StringVar(&p.to, "to", "", "export surveys finished before the date")
+	f.StringVar(&p.survey, "survey", "", "export results of the survey")
+	f.StringVar(&p.user, "user", "", "export results of the user")
+	f.StringVar(&p.scale, "scale", "", "export results having the scale")
+	f.StringVar(&p.level, "level", "", "export results with the level on the scale, requires -scale")
+	f.StringVar(&p.format, "format", string(service.ResultsFormatCSV), "format of results: csv, jsonl or xlsx")
+	f.StringVar(&p.out, "out", "", "write results to the file instead of stdout")
 }
 
 func (p *GetResultsCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	filter, err := p.filter()
+	if err != nil {
+		log.Print("invalid filter: ", err)
+		return subcommands.ExitUsageError
+	}
+
+	format := service.ResultsFormat(p.format)
+	switch format {
+	case service.ResultsFormatCSV, service.ResultsFormatJSONL, service.ResultsFormatXLSX:
+	default:
+		log.Print("unknown format: ", p.format)
+		return subcommands.ExitUsageError
+	}
+
 	config, err := config.New()
 	if err != nil {
 		log.Print("failed to read config: ", err)
 		return subcommands.ExitFailure
 	}
 
-	logger := logger.New(config.Level, config.Env, config.ReleaseVersion, os.Stdout)
+	// stdout is kept for results
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stderr)
 
 	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
 	if err != nil {
@@ -48,10 +83,32 @@ func (p *GetResultsCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...inter
 	processor := resultsprocessor.New()
 	svc := service.New(nil, repo, processor, logger)
 
-	//todo: use transaction here
-	total, err := svc.SaveFinishedSurveys(ctx, nil, os.Stdout, service.ResultsFilter{}, 128)
+	var w io.Writer = os.Stdout
+	if p.out != "" {
+		file, err := os.Create(p.out)
+		if err != nil {
+			logger.Errorf(ctx, "failed to create file: %s", err)
+			return subcommands.ExitFailure
+		}
+		defer func() { _ = file.Close() }()
+
+		w = file
+	}
+
+	total, err := svc.ExportResults(ctx, w, service.ResultsExport{
+		Filter: filter,
+		Format: format,
+		Progress: func(exported int) {
+			fmt.Fprintf(os.Stderr, "exported %d results\n", exported)
+		},
+	})
 	if err != nil {
-		logger.Errorf(ctx, "failed to save finished surveys: %s", err)
+		logger.Errorf(ctx, "failed to export results: %s", err)
+
+		if p.out != "" {
+			_ = os.Remove(p.out)
+		}
+
 		return subcommands.ExitFailure
 	}
 
@@ -59,3 +116,14 @@ func (p *GetResultsCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...inter
 
 	return subcommands.ExitSuccess
 }
+
+func (p *GetResultsCmd) filter() (service.ResultsFilter, error) {
+	return service.ParseResultsFilter(service.ResultsFilterParams{
+		From:   p.from,
+		To:     p.to,
+		Survey: p.survey,
+		User:   p.user,
+		Scale:  p.scale,
+		Level:  p.level,
+	})
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	stdcontext "context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/telegram"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"github.com/google/subcommands"
+	tele "gopkg.in/telebot.v3"
+)
+
+type SurveyRunCmd struct {
+	answers string
+	chart   string
+}
+
+func (*SurveyRunCmd) Name() string     { return "survey-run" }
+func (*SurveyRunCmd) Synopsis() string { return "take survey from file in terminal" }
+func (*SurveyRunCmd) Usage() string {
+	return `survey-run [-answers <file>] [-chart <file>] <file_path>:
+	Take the survey from the file in the terminal without database and Telegram. Questions, errors and results are
+	rendered as the bot sends them, buttons are printed in brackets. Norms and changes relatively to previous
+	attempts are not applied.
+	With -answers answers are read from the file, one per line, the run fails on the invalid answer.
+  `
+}
+
+func (p *SurveyRunCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.answers, "answers", "", "file with answers, one per line")
+	f.StringVar(&p.chart, "chart", "", "file to save chart of results to")
+}
+
+func (p *SurveyRunCmd) Execute(ctx stdcontext.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	filename := f.Arg(0)
+	if filename == "" {
+		log.Print("empty file path")
+		return subcommands.ExitUsageError
+	}
+
+	survey, err := service.ReadSurveyFromFile(filename)
+	if err != nil {
+		log.Print("failed to read survey from file: ", err)
+		return subcommands.ExitFailure
+	}
+
+	processor := resultsprocessor.New()
+	if err := processor.Validate(survey); err != nil {
+		log.Print("failed to validate survey: ", err)
+		return subcommands.ExitFailure
+	}
+
+	input := io.Reader(os.Stdin)
+	scripted := p.answers != ""
+	if scripted {
+		file, err := os.Open(p.answers)
+		if err != nil {
+			log.Print("failed to open answers: ", err)
+			return subcommands.ExitFailure
+		}
+		defer func() { _ = file.Close() }()
+
+		input = file
+	}
+
+	scanner := bufio.NewScanner(input)
+	terminal := &terminalContext{Context: ctx, w: os.Stdout, chart: p.chart}
+	client := telegram.NewClient(nil)
+
+	var answers []entity.Answer
+	asked := -1
+	for len(answers) < len(survey.Questions) {
+		question := survey.Questions[len(answers)]
+
+		// the bot doesn't repeat the question after the invalid answer
+		if asked != len(answers) {
+			if err := client.SendSurveyQuestion(terminal, question); err != nil {
+				log.Print("failed to render question: ", err)
+				return subcommands.ExitFailure
+			}
+			asked = len(answers)
+		}
+
+		if !scripted {
+			fmt.Print("> ")
+		}
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				log.Print("failed to read answer: ", err)
+			} else {
+				log.Printf("no answer to question %d, survey is not finished", len(answers)+1)
+			}
+			return subcommands.ExitFailure
+		}
+
+		msg := strings.TrimSpace(scanner.Text())
+		if scripted {
+			fmt.Printf("> %s\n", msg)
+		}
+		fmt.Println()
+
+		answer, err := question.GetAnswer(msg)
+		if err != nil {
+			// the same message as the bot replies with
+			if err := terminal.Send(service.AnswerErrorText(err)); err != nil {
+				log.Print("failed to render error: ", err)
+				return subcommands.ExitFailure
+			}
+
+			if scripted {
+				log.Printf("invalid answer to question %d: %s", len(answers)+1, err)
+				return subcommands.ExitFailure
+			}
+
+			continue
+		}
+
+		answers = append(answers, answer)
+	}
+
+	if scripted && scanner.Scan() {
+		log.Printf("survey is finished, answer %q is left", scanner.Text())
+		return subcommands.ExitFailure
+	}
+
+	results, err := processor.GetResults(survey, answers)
+	if err != nil {
+		log.Print("failed to get results: ", err)
+		return subcommands.ExitFailure
+	}
+
+	if err := client.SendResults(terminal, survey, results); err != nil {
+		log.Print("failed to render results: ", err)
+		return subcommands.ExitFailure
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		log.Print("failed to print results: ", err)
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
+
+// terminalContext prints messages sent by the telegram client instead of sending them to the chat
+type terminalContext struct {
+	stdcontext.Context
+
+	w io.Writer
+	// chart is a file photos are saved to, they are skipped if it is empty
+	chart string
+}
+
+var _ context.Context = (*terminalContext)(nil)
+
+func (c *terminalContext) Send(msg interface{}, options ...interface{}) error {
+	switch msg := msg.(type) {
+	case string:
+		fmt.Fprintln(c.w, msg)
+	case *tele.Photo:
+		if c.chart == "" {
+			fmt.Fprintln(c.w, "[chart]")
+			break
+		}
+
+		data, err := io.ReadAll(msg.FileReader)
+		if err != nil {
+			return fmt.Errorf("failed to read chart: %w", err)
+		}
+
+		if err := os.WriteFile(c.chart, data, 0o644); err != nil {
+			return fmt.Errorf("failed to save chart: %w", err)
+		}
+
+		fmt.Fprintf(c.w, "[chart is saved to %s]\n", c.chart)
+	default:
+		return fmt.Errorf("unknown message type: %T", msg)
+	}
+
+	for _, option := range options {
+		markup, ok := option.(*tele.ReplyMarkup)
+		if !ok {
+			continue
+		}
+
+		for _, row := range markup.InlineKeyboard {
+			buttons := make([]string, 0, len(row))
+			for _, button := range row {
+				buttons = append(buttons, "["+button.Text+"]")
+			}
+
+			fmt.Fprintln(c.w, strings.Join(buttons, " "))
+		}
+	}
+
+	fmt.Fprintln(c.w)
+
+	return nil
+}
+
+func (c *terminalContext) UserID() int64 {
+	return 0
+}
+
+func (c *terminalContext) ChatID() int64 {
+	return 0
+}
+
+func (c *terminalContext) Nickname() string {
+	return "terminal"
+}
+
+func (c *terminalContext) SetStdContext(ctx stdcontext.Context) {
+	c.Context = ctx
+}
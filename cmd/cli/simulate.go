@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/simulator"
+	"github.com/google/subcommands"
+)
+
+// simulatorFirstUserID is above telegram ids, so virtual users don't clash with real ones
+const simulatorFirstUserID = 1 << 53
+
+type SimulateCmd struct {
+	users    int
+	duration time.Duration
+	think    time.Duration
+	invalid  float64
+	seed     int64
+	maxConns int
+	out      string
+	cleanup  bool
+	yes      bool
+}
+
+func (*SimulateCmd) Name() string     { return "simulate" }
+func (*SimulateCmd) Synopsis() string { return "run load test with virtual users" }
+func (*SimulateCmd) Usage() string {
+	return `simulate [-users n] [-duration d] [-think d] [-invalid share] [-out file] [-cleanup] [-yes]:
+	Drive virtual users through start, list, survey and answer handlers of the bot against the database, messages
+	are counted instead of sending them. Each user passes all surveys once, or users are replaced with new ones
+	until -duration elapses. Throughput, latency percentiles, transaction conflicts and errors of operations are
+	written as JSON. Virtual users and their answers are stored in the database, use the one of the test
+	environment or -cleanup.
+  `
+}
+
+func (p *SimulateCmd) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&p.users, "users", 10, "number of concurrent virtual users")
+	f.DurationVar(&p.duration, "duration", 0, "duration of soak test, each user passes all surveys once if 0")
+	f.DurationVar(&p.think, "think", 0, "average pause of users between operations")
+	f.Float64Var(&p.invalid, "invalid", 0.1, "share of questions answered with invalid answer first")
+	f.Int64Var(&p.seed, "seed", 1, "seed of random answers")
+	f.IntVar(&p.maxConns, "max-conns", 0, "maximum number of open connections to db, unlimited if 0")
+	f.StringVar(&p.out, "out", "", "file to write report to, stdout by default")
+	f.BoolVar(&p.cleanup, "cleanup", false, "delete virtual users with their answers after the run")
+	f.BoolVar(&p.yes, "yes", false, "don't ask for confirmation")
+}
+
+func (p *SimulateCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if p.users <= 0 {
+		log.Print("invalid number of users: ", p.users)
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stderr)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+	sqlDB.SetMaxOpenConns(p.maxConns)
+
+	tg := simulator.NewTelegram()
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(tg, repo, processor, logger)
+
+	surveys, err := svc.GetSurveys(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get surveys: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	question := fmt.Sprintf("%d virtual users will pass %d surveys in database %s on %s. Continue?",
+		p.users, len(surveys), config.DB.Name, config.DB.Host)
+	if !p.yes && !confirm(question) {
+		return subcommands.ExitSuccess
+	}
+
+	// the report is written for the finished operations if the run is interrupted
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	started := time.Now()
+	report, err := simulator.Run(ctx, svc, tg, surveys, simulator.Config{
+		Users:          p.users,
+		Duration:       p.duration,
+		Think:          p.think,
+		InvalidAnswers: p.invalid,
+		FirstUserID:    simulatorFirstUserID,
+		Seed:           p.seed,
+		Progress: func(operations, errors int) {
+			fmt.Fprintf(os.Stderr, "%s: %d operations, %d errors\n", time.Since(started).Round(time.Second), operations, errors)
+		},
+		ProgressInterval: 10 * time.Second,
+	})
+	if err != nil {
+		log.Print("failed to run simulation: ", err)
+		return subcommands.ExitUsageError
+	}
+	report.Release = config.ReleaseVersion
+
+	printReport(os.Stderr, report)
+
+	status := subcommands.ExitSuccess
+	if err := writeReport(p.out, report); err != nil {
+		log.Print("failed to write report: ", err)
+		status = subcommands.ExitFailure
+	}
+
+	if p.cleanup {
+		// users are deleted even if the run is interrupted
+		deleted, err := deleteVirtualUsers(context.WithoutCancel(ctx), svc, report.UsersStarted)
+		if err != nil {
+			log.Print("failed to delete virtual users: ", err)
+			status = subcommands.ExitFailure
+		}
+		fmt.Fprintf(os.Stderr, "Deleted %d virtual users\n", deleted)
+	}
+
+	return status
+}
+
+func printReport(w io.Writer, report simulator.Report) {
+	fmt.Fprintf(w, "%d users, %d surveys finished in %.1fs\n", report.UsersStarted, report.SurveysFinished, report.Duration)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tCOUNT\tERRORS\tCONFLICTS\tOPS/S\tP50 MS\tP95 MS\tP99 MS\tMAX MS")
+
+	ops := make([]string, 0, len(report.Operations))
+	for op := range report.Operations {
+		ops = append(ops, op)
+	}
+	slices.Sort(ops)
+
+	row := func(op string, stats simulator.OperationStats) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\n", op, stats.Count, stats.Errors, stats.Conflicts,
+			stats.Throughput, stats.Latency.P50, stats.Latency.P95, stats.Latency.P99, stats.Latency.Max)
+	}
+	for _, op := range ops {
+		row(op, report.Operations[op])
+	}
+	row("total", report.Total)
+
+	_ = tw.Flush()
+}
+
+func writeReport(path string, report simulator.Report) error {
+	w := io.Writer(os.Stdout)
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	return nil
+}
+
+// deleteVirtualUsers deletes users started by the simulator with all their survey states
+func deleteVirtualUsers(ctx context.Context, svc service.Service, users int) (int, error) {
+	deleted := 0
+	for i := 0; i < users; i++ {
+		user, err := svc.FindUser(ctx, strconv.FormatInt(simulatorFirstUserID+int64(i), 10))
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			continue
+		case err != nil:
+			return deleted, err
+		}
+
+		if err := svc.DeleteUser(ctx, user.GUID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"text/tabwriter"
 	"time"
 
 	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
@@ -39,7 +45,7 @@ func (p *CreateSurveyCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...int
 		return subcommands.ExitFailure
 	}
 
-	logger := logger.New(config.Level, config.Env, config.ReleaseVersion, os.Stdout)
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
 
 	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
 	if err != nil {
@@ -69,16 +75,20 @@ func (p *CreateSurveyCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...int
 }
 
 type UpdateSurveyCmd struct {
+	dryRun bool
 }
 
-func (*UpdateSurveyCmd) Name() string               { return "survey-update" }
-func (p *UpdateSurveyCmd) SetFlags(f *flag.FlagSet) {}
+func (*UpdateSurveyCmd) Name() string { return "survey-update" }
+func (p *UpdateSurveyCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.dryRun, "dry-run", false, "print changes of the survey without updating it")
+}
 func (*UpdateSurveyCmd) Synopsis() string {
 	return "Update survey from file"
 }
 func (*UpdateSurveyCmd) Usage() string {
-	return `survey-update <survey_guid> <file_path>:
+	return `survey-update [-dry-run] <survey_guid> <file_path>:
 	Updates survey from file by guid. Updates "name", "description", "questions" and "calculations_type" fields.
+	Changes are printed before the update, with -dry-run the survey is not updated
   `
 }
 
@@ -89,7 +99,7 @@ func (p *UpdateSurveyCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...int
 		return subcommands.ExitFailure
 	}
 
-	logger := logger.New(config.Level, config.Env, config.ReleaseVersion, os.Stdout)
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
 
 	surveyGUIDRaw := f.Arg(0)
 	filename := f.Arg(1)
@@ -118,6 +128,22 @@ func (p *UpdateSurveyCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...int
 
 	new.GUID = surveyGUID
 
+	diff, err := svc.DiffSurvey(ctx, new)
+	if err != nil {
+		logger.Errorf(ctx, "failed to diff survey: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	printSurveyDiff(os.Stdout, diff)
+
+	if p.dryRun {
+		if diff.Refused != nil {
+			return subcommands.ExitFailure
+		}
+
+		return subcommands.ExitSuccess
+	}
+
 	if err := svc.UpdateSurvey(ctx, new); err != nil {
 		logger.Errorf(ctx, "failed to update survey: %s", err)
 		return subcommands.ExitFailure
@@ -127,3 +153,448 @@ func (p *UpdateSurveyCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...int
 
 	return subcommands.ExitSuccess
 }
+
+type SurveyDiffCmd struct {
+}
+
+func (*SurveyDiffCmd) Name() string     { return "survey-diff" }
+func (*SurveyDiffCmd) Synopsis() string { return "print changes of survey made by file" }
+func (*SurveyDiffCmd) Usage() string {
+	return `survey-diff <survey_guid> <file_path>:
+	Print changes survey-update makes to the survey by questions. Changes altering the meaning
+	of answers already stored, e.g. re-numbered possible answers, are marked as breaking
+  `
+}
+
+func (p *SurveyDiffCmd) SetFlags(f *flag.FlagSet) {
+}
+
+func (p *SurveyDiffCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	surveyGUID, err := uuid.Parse(f.Arg(0))
+	if err != nil {
+		log.Print("failed to parse survey guid: ", err)
+		return subcommands.ExitUsageError
+	}
+
+	new, err := service.ReadSurveyFromFile(f.Arg(1))
+	if err != nil {
+		log.Print("failed to read survey from file: ", err)
+		return subcommands.ExitFailure
+	}
+
+	new.GUID = surveyGUID
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	diff, err := svc.DiffSurvey(ctx, new)
+	if err != nil {
+		logger.Errorf(ctx, "failed to diff survey: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	printSurveyDiff(os.Stdout, diff)
+
+	return subcommands.ExitSuccess
+}
+
+// printSurveyDiff prints changes grouped by questions, breaking changes are followed by the reason
+func printSurveyDiff(w io.Writer, diff service.SurveyDiff) {
+	if len(diff.Changes) == 0 {
+		fmt.Fprintln(w, "No changes")
+	}
+
+	question := -1
+	for _, change := range diff.Changes {
+		indent := ""
+		if change.Question >= 0 {
+			if change.Question != question {
+				fmt.Fprintf(w, "questions[%d]:\n", change.Question)
+				question = change.Question
+			}
+
+			indent = "  "
+		}
+
+		fmt.Fprintf(w, "%s%s\n", indent, change)
+		if change.Breaking != "" {
+			fmt.Fprintf(w, "%s  BREAKING: %s\n", indent, change.Breaking)
+		}
+	}
+
+	if len(diff.Changes) > 0 {
+		fmt.Fprintf(w, "%d changes, %d breaking\n", len(diff.Changes), len(diff.Breaking()))
+	}
+
+	if diff.Refused != nil {
+		fmt.Fprintf(w, "Update is refused: %s\n", diff.Refused)
+	}
+}
+
+type SurveyListCmd struct {
+	json bool
+}
+
+func (*SurveyListCmd) Name() string     { return "survey-list" }
+func (*SurveyListCmd) Synopsis() string { return "list surveys" }
+func (*SurveyListCmd) Usage() string {
+	return `survey-list [-json]:
+	List not deleted surveys with numbers of questions, active and finished states
+  `
+}
+
+func (p *SurveyListCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.json, "json", false, "print JSON instead of the table")
+}
+
+func (p *SurveyListCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	summaries, err := svc.GetSurveySummaries(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get surveys: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	if p.json {
+		type surveySummary struct {
+			ID               int64     `json:"id"`
+			GUID             uuid.UUID `json:"guid"`
+			Name             string    `json:"name"`
+			CalculationsType string    `json:"calculations_type"`
+			Questions        int       `json:"questions"`
+			Active           int       `json:"active"`
+			Completions      int       `json:"completions"`
+		}
+
+		result := make([]surveySummary, 0, len(summaries))
+		for _, summary := range summaries {
+			result = append(result, surveySummary{
+				ID:               summary.Survey.ID,
+				GUID:             summary.Survey.GUID,
+				Name:             summary.Survey.Name,
+				CalculationsType: summary.Survey.CalculationsType,
+				Questions:        len(summary.Survey.Questions),
+				Active:           summary.Active,
+				Completions:      summary.Finished,
+			})
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			logger.Errorf(ctx, "failed to write surveys: %s", err)
+			return subcommands.ExitFailure
+		}
+
+		return subcommands.ExitSuccess
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tGUID\tNAME\tTYPE\tQUESTIONS\tACTIVE\tCOMPLETIONS")
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%d\n",
+			summary.Survey.ID,
+			summary.Survey.GUID,
+			summary.Survey.Name,
+			summary.Survey.CalculationsType,
+			len(summary.Survey.Questions),
+			summary.Active,
+			summary.Finished,
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		logger.Errorf(ctx, "failed to write surveys: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
+
+type SurveyShowCmd struct {
+}
+
+func (*SurveyShowCmd) Name() string     { return "survey-show" }
+func (*SurveyShowCmd) Synopsis() string { return "print survey" }
+func (*SurveyShowCmd) Usage() string {
+	return `survey-show <survey_guid>:
+	Print survey with questions and possible answers
+  `
+}
+
+func (p *SurveyShowCmd) SetFlags(f *flag.FlagSet) {
+}
+
+func (p *SurveyShowCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	surveyGUID, err := uuid.Parse(f.Arg(0))
+	if err != nil {
+		log.Print("failed to parse survey guid: ", err)
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	survey, err := svc.GetSurvey(ctx, surveyGUID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get survey: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	fmt.Printf("ID: %d\nGUID: %s\nName: %s\nType: %s\n", survey.ID, survey.GUID, survey.Name, survey.CalculationsType)
+	fmt.Printf("Description: %s\n", survey.Description)
+
+	for i, question := range survey.Questions {
+		fmt.Printf("\n%d. %s\n", i+1, question.Text)
+
+		switch question.AnswerType {
+		case entity.AnswerTypeSegment:
+			fmt.Printf("   %s from %d to %d\n", question.AnswerType, question.PossibleAnswers[0], question.PossibleAnswers[1])
+		default:
+			fmt.Printf("   %s\n", question.AnswerType)
+			for j, answer := range question.PossibleAnswers {
+				text := ""
+				if j < len(question.AnswersText) {
+					text = question.AnswersText[j]
+				}
+
+				fmt.Printf("   %d) %s\n", answer, text)
+			}
+		}
+	}
+
+	return subcommands.ExitSuccess
+}
+
+type SurveyExportCmd struct {
+	out string
+}
+
+func (*SurveyExportCmd) Name() string     { return "survey-export" }
+func (*SurveyExportCmd) Synopsis() string { return "export survey to file" }
+func (*SurveyExportCmd) Usage() string {
+	return `survey-export [-out <file_path>] <survey_guid>:
+	Write survey in the format of survey-create and survey-update, to stdout if -out is not set
+  `
+}
+
+func (p *SurveyExportCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.out, "out", "", "file to write the survey to")
+}
+
+func (p *SurveyExportCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	surveyGUID, err := uuid.Parse(f.Arg(0))
+	if err != nil {
+		log.Print("failed to parse survey guid: ", err)
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	// the survey is written to stdout, so logs are written to stderr
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stderr)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	survey, err := svc.GetSurvey(ctx, surveyGUID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get survey: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	var out io.Writer = os.Stdout
+	if p.out != "" {
+		file, err := os.Create(p.out)
+		if err != nil {
+			logger.Errorf(ctx, "failed to create file: %s", err)
+			return subcommands.ExitFailure
+		}
+		defer file.Close()
+
+		out = file
+	}
+
+	if err := service.WriteSurveyFile(out, survey); err != nil {
+		logger.Errorf(ctx, "failed to write survey: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
+
+type SurveyDeleteCmd struct {
+	force bool
+}
+
+func (*SurveyDeleteCmd) Name() string     { return "survey-delete" }
+func (*SurveyDeleteCmd) Synopsis() string { return "delete survey" }
+func (*SurveyDeleteCmd) Usage() string {
+	return `survey-delete [-force] <survey_guid>:
+	Soft delete survey, it is hidden from users but results are kept.
+	Surveys with active states of users are not deleted without -force
+  `
+}
+
+func (p *SurveyDeleteCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.force, "force", false, "delete survey even if users have active states of it")
+}
+
+func (p *SurveyDeleteCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	surveyGUID, err := uuid.Parse(f.Arg(0))
+	if err != nil {
+		log.Print("failed to parse survey guid: ", err)
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	err = svc.DeleteSurvey(ctx, surveyGUID, p.force)
+	switch {
+	case errors.Is(err, service.ErrSurveyInUse):
+		logger.Errorf(ctx, "survey is not deleted, use -force to delete it anyway: %s", err)
+		return subcommands.ExitFailure
+	case err != nil:
+		logger.Errorf(ctx, "failed to delete survey: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	logger.Infof(ctx, "survey deleted with guid %s", surveyGUID)
+
+	return subcommands.ExitSuccess
+}
+
+type SurveyValidateCmd struct {
+	strict bool
+}
+
+func (*SurveyValidateCmd) Name() string     { return "survey-validate" }
+func (*SurveyValidateCmd) Synopsis() string { return "validate survey files without database" }
+func (*SurveyValidateCmd) Usage() string {
+	return `survey-validate [-strict] <file_path...>:
+	Check survey files before survey-create and survey-update, all problems are printed as
+	file:line: path: message. Exits with non-zero code if any file has errors, or warnings with -strict
+  `
+}
+
+func (p *SurveyValidateCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.strict, "strict", false, "fail on warnings too")
+}
+
+func (p *SurveyValidateCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() == 0 {
+		log.Print("no survey files")
+		return subcommands.ExitUsageError
+	}
+
+	processor := resultsprocessor.New()
+
+	var errorsCount, warningsCount int
+	for _, filename := range f.Args() {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Printf("%s: failed to read file: %s\n", filename, err)
+			errorsCount++
+
+			continue
+		}
+
+		for _, fileErr := range service.CheckSurveyFile(data, processor) {
+			message := fileErr.Message
+			if fileErr.Path != "" {
+				message = fileErr.Path + ": " + message
+			}
+
+			if fileErr.Lint {
+				message = "warning: " + message
+				warningsCount++
+			} else {
+				errorsCount++
+			}
+
+			fmt.Printf("%s:%d: %s\n", filename, fileErr.Line, message)
+		}
+	}
+
+	fmt.Printf("%d files, %d errors, %d warnings\n", f.NArg(), errorsCount, warningsCount)
+
+	if errorsCount > 0 || (p.strict && warningsCount > 0) {
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
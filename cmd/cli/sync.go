@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"github.com/google/subcommands"
+)
+
+type SurveySyncCmd struct {
+	yes bool
+}
+
+func (*SurveySyncCmd) Name() string     { return "survey-sync" }
+func (*SurveySyncCmd) Synopsis() string { return "sync survey files of directory into database" }
+func (*SurveySyncCmd) Usage() string {
+	return `survey-sync [-yes] <dir>:
+	Create and update surveys from *.json files of the directory, surveys are matched by "slug" of the files.
+	Prints the plan and applies it in one transaction after confirmation. Stored surveys missing in the
+	directory are reported as orphaned and kept. Updates changing answers of surveys users are passing are refused
+  `
+}
+
+func (p *SurveySyncCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.yes, "yes", false, "apply the plan without confirmation")
+}
+
+func (p *SurveySyncCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	dir := f.Arg(0)
+	if dir == "" {
+		log.Print("no directory")
+		return subcommands.ExitUsageError
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		log.Print("failed to list files: ", err)
+		return subcommands.ExitFailure
+	}
+
+	var surveys []entity.Survey
+	for _, file := range files {
+		survey, err := service.ReadSurveyFromFile(file)
+		if err != nil {
+			log.Printf("failed to read survey from file %s: %s", file, err)
+			return subcommands.ExitFailure
+		}
+
+		if survey.Slug == "" {
+			log.Printf("empty slug in file %s", file)
+			return subcommands.ExitFailure
+		}
+
+		surveys = append(surveys, survey)
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	plan, err := svc.PlanSurveySync(ctx, surveys)
+	if err != nil {
+		logger.Errorf(ctx, "failed to plan sync: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	var changes, refused int
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tSLUG\tID\tGUID\tNAME\tDETAILS")
+	for _, item := range plan {
+		id, guid := "-", "-"
+		if item.Current != nil {
+			id, guid = strconv.FormatInt(item.Current.ID, 10), item.Current.GUID.String()
+		}
+
+		var details []string
+		if item.Adopted {
+			details = append(details, "matched by name")
+		}
+		if len(item.Changes) > 0 {
+			details = append(details, "changed "+strings.Join(item.Changes, ", "))
+		}
+		if item.Active > 0 {
+			details = append(details, fmt.Sprintf("%d active states", item.Active))
+		}
+		if item.Refused != nil {
+			details = append(details, "refused: "+item.Refused.Error())
+			refused++
+		}
+
+		if item.Action == service.SurveySyncCreate || item.Action == service.SurveySyncUpdate {
+			changes++
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Action, item.Slug, id, guid, item.Survey.Name, strings.Join(details, "; "))
+	}
+	w.Flush()
+
+	switch {
+	case refused > 0:
+		log.Printf("%d updates are refused, nothing is synced", refused)
+		return subcommands.ExitFailure
+	case changes == 0:
+		fmt.Println("Nothing to sync")
+		return subcommands.ExitSuccess
+	}
+
+	if !p.yes && !confirm(fmt.Sprintf("Apply %d changes?", changes)) {
+		fmt.Println("Cancelled")
+		return subcommands.ExitFailure
+	}
+
+	if err := svc.SyncSurveys(ctx, plan); err != nil {
+		logger.Errorf(ctx, "failed to sync surveys: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	fmt.Printf("Synced %d surveys\n", changes)
+
+	return subcommands.ExitSuccess
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/repository/db"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/resultsprocessor"
@@ -51,7 +57,7 @@ func (p *DeleteUserInfoCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...i
 		return subcommands.ExitFailure
 	}
 
-	logger := logger.New(config.Level, config.Env, config.ReleaseVersion, os.Stdout)
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
 
 	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
 	if err != nil {
@@ -88,3 +94,372 @@ func (p *DeleteUserInfoCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...i
 
 	return subcommands.ExitSuccess
 }
+
+type UserListCmd struct {
+	search string
+	limit  int
+	offset int
+	json   bool
+}
+
+func (*UserListCmd) Name() string     { return "user-list" }
+func (*UserListCmd) Synopsis() string { return "list users" }
+func (*UserListCmd) Usage() string {
+	return `user-list [-search <text>] [-limit <n>] [-offset <n>] [-json]:
+	List users by last activity, the search matches part of the nickname or the whole telegram id
+  `
+}
+
+func (p *UserListCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.search, "search", "", "search users by nickname or telegram id")
+	f.IntVar(&p.limit, "limit", 20, "maximum number of users")
+	f.IntVar(&p.offset, "offset", 0, "number of users to skip")
+	f.BoolVar(&p.json, "json", false, "print JSON instead of the table")
+}
+
+func (p *UserListCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if p.limit <= 0 || p.offset < 0 {
+		log.Print("limit should be positive and offset should not be negative")
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	list, err := svc.SearchUsers(ctx, p.limit, p.offset, strings.TrimPrefix(p.search, "@"))
+	if err != nil {
+		logger.Errorf(ctx, "failed to get users: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	if p.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(list); err != nil {
+			logger.Errorf(ctx, "failed to write users: %s", err)
+			return subcommands.ExitFailure
+		}
+
+		return subcommands.ExitSuccess
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GUID\tTELEGRAM_ID\tNICKNAME\tCOMPLETED\tANSWERED\tREGISTERED\tLAST_ACTIVITY")
+	for _, user := range list.Users {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\t%s\n",
+			user.GUID,
+			user.UserID,
+			user.NickName,
+			user.CompletedTests,
+			user.AnsweredQuestions,
+			user.RegisteredAt.Format(time.DateTime),
+			user.LastActivity.Format(time.DateTime),
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		logger.Errorf(ctx, "failed to write users: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	fmt.Printf("Shown %d of %d users\n", len(list.Users), list.Total)
+
+	return subcommands.ExitSuccess
+}
+
+type UserShowCmd struct {
+	answers bool
+}
+
+func (*UserShowCmd) Name() string     { return "user-show" }
+func (*UserShowCmd) Synopsis() string { return "print user with survey states" }
+func (*UserShowCmd) Usage() string {
+	return `user-show [-answers] <user_guid|telegram_id|nickname>:
+	Print user with all survey states and their progress
+  `
+}
+
+func (p *UserShowCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.answers, "answers", false, "print answers of every survey state")
+}
+
+func (p *UserShowCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		log.Print("expected user")
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	user, err := svc.FindUser(ctx, f.Arg(0))
+	if err != nil {
+		logger.Errorf(ctx, "failed to find user: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	details, err := svc.GetUserDetails(ctx, user.GUID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get user details: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	currentSurvey := "-"
+	if user.CurrentSurvey != nil {
+		currentSurvey = user.CurrentSurvey.String()
+	}
+
+	fmt.Printf("GUID: %s\nTelegram ID: %d\nNickname: %s\n", user.GUID, user.UserID, user.Nickname)
+	fmt.Printf("Cohort: %s\nCurrent survey: %s\nLast activity: %s\n", user.Cohort, currentSurvey, user.LastActivity.Format(time.DateTime))
+
+	if len(details.Surveys) == 0 {
+		fmt.Println("\nNo survey states")
+		return subcommands.ExitSuccess
+	}
+
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SURVEY\tGUID\tSTATE\tPROGRESS\tSTARTED\tUPDATED")
+	for _, survey := range details.Surveys {
+		progress := fmt.Sprintf("%d/?", len(survey.Report.Answers))
+		if survey.Questions > 0 {
+			progress = fmt.Sprintf("%d/%d", len(survey.Report.Answers), survey.Questions)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			survey.Report.SurveyName,
+			survey.Report.SurveyGUID,
+			survey.Report.State,
+			progress,
+			survey.Report.StartedAt.Format(time.DateTime),
+			survey.Report.FinishedAt.Format(time.DateTime),
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		logger.Errorf(ctx, "failed to write user: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	if p.answers {
+		for _, survey := range details.Surveys {
+			fmt.Printf("\n%s (%s, started %s)\n", survey.Report.SurveyName, survey.Report.State, survey.Report.StartedAt.Format(time.DateTime))
+
+			for i, answer := range survey.Answers {
+				fmt.Printf("%d. %s\n   %s\n", i+1, answer.Question, answer.Answer)
+			}
+		}
+	}
+
+	return subcommands.ExitSuccess
+}
+
+type UserResetCmd struct {
+	finished bool
+	yes      bool
+}
+
+func (*UserResetCmd) Name() string     { return "user-reset" }
+func (*UserResetCmd) Synopsis() string { return "reset survey of user" }
+func (*UserResetCmd) Usage() string {
+	return `user-reset [-finished] [-yes] <user_guid|telegram_id|nickname> <survey_guid|slug>:
+	Delete the active state of the survey, so the user starts it from the first question.
+	Finished attempts are deleted too with -finished.
+  `
+}
+
+func (p *UserResetCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.finished, "finished", false, "delete finished attempts of the survey too")
+	f.BoolVar(&p.yes, "yes", false, "reset without confirmation")
+}
+
+func (p *UserResetCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		log.Print("expected user and survey")
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	user, err := svc.FindUser(ctx, f.Arg(0))
+	if err != nil {
+		logger.Errorf(ctx, "failed to find user: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	survey, err := findSurvey(ctx, svc, f.Arg(1))
+	if err != nil {
+		logger.Errorf(ctx, "failed to find survey: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	states := "the active state"
+	if p.finished {
+		states = "all states"
+	}
+
+	if !p.yes && !confirm(fmt.Sprintf("Delete %s of survey %q by user %s (%d)?", states, survey.Name, user.Nickname, user.UserID)) {
+		fmt.Println("Cancelled")
+		return subcommands.ExitFailure
+	}
+
+	deleted, err := svc.ResetUserSurvey(ctx, user.GUID, survey.GUID, p.finished)
+	if err != nil {
+		logger.Errorf(ctx, "failed to reset survey: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	fmt.Printf("Deleted %d survey states\n", deleted)
+
+	return subcommands.ExitSuccess
+}
+
+type UserForgetCmd struct {
+	delete bool
+	yes    bool
+}
+
+func (*UserForgetCmd) Name() string     { return "user-forget" }
+func (*UserForgetCmd) Synopsis() string { return "anonymize or delete user" }
+func (*UserForgetCmd) Usage() string {
+	return `user-forget [-delete] [-yes] <user_guid|telegram_id|nickname>:
+	Anonymize the user: telegram id and nickname are replaced and alerts are deleted, survey states are kept
+	for statistics. The user with all survey states is deleted with -delete.
+  `
+}
+
+func (p *UserForgetCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.delete, "delete", false, "delete the user with all survey states instead of anonymizing")
+	f.BoolVar(&p.yes, "yes", false, "forget without confirmation")
+}
+
+func (p *UserForgetCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		log.Print("expected user")
+		return subcommands.ExitUsageError
+	}
+
+	config, err := config.New()
+	if err != nil {
+		log.Print("failed to read config: ", err)
+		return subcommands.ExitFailure
+	}
+
+	logger := logger.New(config.Env, config.Level, config.ReleaseVersion, os.Stdout)
+
+	sqlDB, err := db.ConnectWithTimeout(time.Minute, config.DB)
+	if err != nil {
+		logger.Errorf(ctx, "failed to connect to db: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	repo := db.New(sqlDB)
+	processor := resultsprocessor.New()
+	svc := service.New(nil, repo, processor, logger)
+
+	user, err := svc.FindUser(ctx, f.Arg(0))
+	if err != nil {
+		logger.Errorf(ctx, "failed to find user: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	action, done := "Anonymize", "Anonymized"
+	if p.delete {
+		action, done = "Delete", "Deleted"
+	}
+
+	if !p.yes && !confirm(fmt.Sprintf("%s user %s (%d, %s)?", action, user.Nickname, user.UserID, user.GUID)) {
+		fmt.Println("Cancelled")
+		return subcommands.ExitFailure
+	}
+
+	if p.delete {
+		err = svc.DeleteUser(ctx, user.GUID)
+	} else {
+		err = svc.AnonymizeUser(ctx, user.GUID)
+	}
+	if err != nil {
+		logger.Errorf(ctx, "failed to forget user: %s", err)
+		return subcommands.ExitFailure
+	}
+
+	fmt.Printf("%s user %s\n", done, user.GUID)
+
+	return subcommands.ExitSuccess
+}
+
+// findSurvey finds not deleted survey by guid or slug
+func findSurvey(ctx context.Context, svc service.Service, query string) (entity.Survey, error) {
+	if surveyGUID, err := uuid.Parse(query); err == nil {
+		return svc.GetSurvey(ctx, surveyGUID)
+	}
+
+	surveys, err := svc.GetSurveys(ctx)
+	if err != nil {
+		return entity.Survey{}, fmt.Errorf("failed to get surveys: %w", err)
+	}
+
+	for _, survey := range surveys {
+		if survey.Slug == query {
+			return survey, nil
+		}
+	}
+
+	return entity.Survey{}, fmt.Errorf("survey %s: %w", query, service.ErrNotFound)
+}
+
+// confirm asks the question and waits for "y" from stdin
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	return err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+}
@@ -0,0 +1,402 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+const (
+	KindBar   Kind = "bar"
+	KindRadar Kind = "radar"
+
+	// minRadarScales is a minimal number of scales when radar chart is used by default
+	minRadarScales = 5
+
+	width        = 800
+	padding      = 24
+	titleHeight  = 48
+	fontSize     = 18
+	titleSize    = 22
+	barRowHeight = 44
+	barHeight    = 24
+	barLabelArea = 280
+	barValueArea = 64
+	gridLines    = 4
+	radarWidth   = 1000
+	radarRadius  = 220
+)
+
+var (
+	ErrNoScales    = errors.New("no scales to draw")
+	ErrUnknownKind = errors.New("unknown chart kind")
+
+	backgroundColor = color.RGBA{255, 255, 255, 255}
+	textColor       = color.RGBA{33, 33, 33, 255}
+	gridColor       = color.RGBA{210, 210, 210, 255}
+	barColor        = color.RGBA{66, 133, 244, 255}
+	fillColor       = color.NRGBA{66, 133, 244, 96}
+
+	fontOnce sync.Once
+	fontErr  error
+	goFont   *opentype.Font
+)
+
+type (
+	Kind string
+
+	point struct {
+		x, y float64
+	}
+
+	// values are the normalized scales to draw
+	values struct {
+		names  []string
+		labels []string
+		values []float64
+		max    float64
+	}
+)
+
+// DefaultKind returns chart kind suitable for the scales:
+// profiles with many scales are drawn as radar, others as bars.
+func DefaultKind(scales []entity.ResultsScale) Kind {
+	if len(scales) >= minRadarScales {
+		return KindRadar
+	}
+
+	return KindBar
+}
+
+// ParseKind parses kind of the chart, empty string means kind by default
+func ParseKind(s string) (Kind, error) {
+	switch Kind(s) {
+	case "", KindBar, KindRadar:
+		return Kind(s), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownKind, s)
+	}
+}
+
+// Render draws scales of the results as PNG image.
+// Percentiles are drawn if all scales have norms, otherwise raw scores.
+func Render(kind Kind, title string, scales []entity.ResultsScale) ([]byte, error) {
+	if len(scales) == 0 {
+		return nil, ErrNoScales
+	}
+
+	if kind == "" {
+		kind = DefaultKind(scales)
+	}
+
+	var (
+		img *image.RGBA
+		err error
+	)
+	switch kind {
+	case KindBar:
+		img, err = renderBar(title, newValues(scales))
+	case KindRadar:
+		img, err = renderRadar(title, newValues(scales))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newValues(scales []entity.ResultsScale) values {
+	percentiles := true
+	for _, scale := range scales {
+		if scale.Norm == nil {
+			percentiles = false
+			break
+		}
+	}
+
+	v := values{max: 100}
+	if !percentiles {
+		v.max = 0
+	}
+
+	for _, scale := range scales {
+		v.names = append(v.names, scale.Name)
+
+		if percentiles {
+			v.values = append(v.values, scale.Norm.Percentile)
+			v.labels = append(v.labels, fmt.Sprintf("%.0f%%", scale.Norm.Percentile))
+			continue
+		}
+
+		v.values = append(v.values, math.Max(scale.Score, 0))
+		v.labels = append(v.labels, formatScore(scale.Score))
+		v.max = math.Max(v.max, scale.Score)
+	}
+
+	if v.max <= 0 {
+		v.max = 1
+	}
+
+	// round maximum up so grid lines get integer values
+	if !percentiles && v.max >= gridLines {
+		v.max = math.Ceil(v.max/gridLines) * gridLines
+	}
+
+	return v
+}
+
+func renderBar(title string, v values) (*image.RGBA, error) {
+	face, err := newFace(fontSize)
+	if err != nil {
+		return nil, err
+	}
+
+	top := padding
+	if title != "" {
+		top += titleHeight
+	}
+	height := top + len(v.values)*barRowHeight + padding + barRowHeight/2
+
+	img := newImage(width, height)
+	if err := drawTitle(img, title); err != nil {
+		return nil, err
+	}
+
+	left := float64(padding + barLabelArea)
+	right := float64(width - padding - barValueArea)
+	bottom := float64(top + len(v.values)*barRowHeight)
+
+	for i := 0; i <= gridLines; i++ {
+		x := left + (right-left)*float64(i)/gridLines
+		drawLine(img, point{x, float64(top)}, point{x, bottom}, 1, gridColor)
+		drawText(img, face, formatScore(v.max*float64(i)/gridLines), point{x, bottom + fontSize + 4}, 0.5, textColor)
+	}
+
+	for i, value := range v.values {
+		y := float64(top + i*barRowHeight)
+		center := y + barRowHeight/2
+		barWidth := (right - left) * math.Min(value/v.max, 1)
+
+		drawText(img, face, truncate(face, v.names[i], barLabelArea-12), point{padding, center + fontSize/3}, 0, textColor)
+		fillPolygon(img, []point{
+			{left, center - barHeight/2},
+			{left + barWidth, center - barHeight/2},
+			{left + barWidth, center + barHeight/2},
+			{left, center + barHeight/2},
+		}, barColor)
+		drawText(img, face, v.labels[i], point{left + barWidth + 8, center + fontSize/3}, 0, textColor)
+	}
+
+	return img, nil
+}
+
+func renderRadar(title string, v values) (*image.RGBA, error) {
+	face, err := newFace(fontSize)
+	if err != nil {
+		return nil, err
+	}
+
+	top := padding
+	if title != "" {
+		top += titleHeight
+	}
+	height := top + 2*radarRadius + 2*(padding+2*fontSize)
+
+	img := newImage(radarWidth, height)
+	if err := drawTitle(img, title); err != nil {
+		return nil, err
+	}
+
+	center := point{radarWidth / 2, float64(top + padding + 2*fontSize + radarRadius)}
+	vertex := func(i int, ratio float64) point {
+		angle := -math.Pi/2 + 2*math.Pi*float64(i)/float64(len(v.values))
+		return point{
+			center.x + radarRadius*ratio*math.Cos(angle),
+			center.y + radarRadius*ratio*math.Sin(angle),
+		}
+	}
+
+	for level := 1; level <= gridLines; level++ {
+		ratio := float64(level) / gridLines
+		for i := range v.values {
+			drawLine(img, vertex(i, ratio), vertex((i+1)%len(v.values), ratio), 1, gridColor)
+		}
+	}
+
+	polygon := make([]point, 0, len(v.values))
+	for i, value := range v.values {
+		drawLine(img, center, vertex(i, 1), 1, gridColor)
+		polygon = append(polygon, vertex(i, math.Min(value/v.max, 1)))
+	}
+
+	fillPolygon(img, polygon, fillColor)
+	for i := range polygon {
+		drawLine(img, polygon[i], polygon[(i+1)%len(polygon)], 3, barColor)
+	}
+
+	for i := range v.values {
+		p := vertex(i, 1.08)
+		cos := (p.x - center.x) / radarRadius
+
+		align := 0.5
+		switch {
+		case cos > 0.3:
+			align = 0
+		case cos < -0.3:
+			align = 1
+		}
+
+		available := float64(radarWidth - 2*padding)
+		switch align {
+		case 0:
+			available = radarWidth - padding - p.x
+		case 1:
+			available = p.x - padding
+		}
+
+		suffix := " (" + v.labels[i] + ")"
+		label := truncate(face, v.names[i], int(available)-font.MeasureString(face, suffix).Ceil()) + suffix
+		drawText(img, face, label, point{p.x, p.y + fontSize/3}, align, textColor)
+	}
+
+	return img, nil
+}
+
+func drawTitle(img *image.RGBA, title string) error {
+	if title == "" {
+		return nil
+	}
+
+	face, err := newFace(titleSize)
+	if err != nil {
+		return err
+	}
+
+	w := img.Bounds().Dx()
+	drawText(img, face, truncate(face, title, w-2*padding), point{float64(w) / 2, padding + titleSize}, 0.5, textColor)
+
+	return nil
+}
+
+func newImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(backgroundColor), image.Point{}, draw.Src)
+
+	return img
+}
+
+func newFace(size float64) (font.Face, error) {
+	fontOnce.Do(func() {
+		goFont, fontErr = opentype.Parse(goregular.TTF)
+	})
+	if fontErr != nil {
+		return nil, fmt.Errorf("failed to parse font: %w", fontErr)
+	}
+
+	face, err := opentype.NewFace(goFont, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font face: %w", err)
+	}
+
+	return face, nil
+}
+
+// drawText draws text with baseline at p, align is a share of text width to the left of p
+func drawText(img *image.RGBA, face font.Face, text string, p point, align float64, c color.Color) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+	}
+
+	textWidth := float64(d.MeasureString(text)) / 64
+	d.Dot = fixed.Point26_6{
+		X: fixed.Int26_6((p.x - textWidth*align) * 64),
+		Y: fixed.Int26_6(p.y * 64),
+	}
+	d.DrawString(text)
+}
+
+// truncate shortens text to fit into maxWidth pixels
+func truncate(face font.Face, text string, maxWidth int) string {
+	if font.MeasureString(face, text).Ceil() <= maxWidth {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if font.MeasureString(face, string(runes)+"…").Ceil() <= maxWidth {
+			break
+		}
+	}
+
+	return string(runes) + "…"
+}
+
+func fillPolygon(img *image.RGBA, points []point, c color.Color) {
+	if len(points) < 3 {
+		return
+	}
+
+	bounds := img.Bounds()
+	r := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	r.MoveTo(float32(points[0].x), float32(points[0].y))
+	for _, p := range points[1:] {
+		r.LineTo(float32(p.x), float32(p.y))
+	}
+	r.ClosePath()
+	r.Draw(img, bounds, image.NewUniform(c), image.Point{})
+}
+
+func drawLine(img *image.RGBA, from, to point, thickness float64, c color.Color) {
+	length := math.Hypot(to.x-from.x, to.y-from.y)
+	if length == 0 {
+		return
+	}
+
+	// normal vector of half thickness
+	nx := -(to.y - from.y) / length * thickness / 2
+	ny := (to.x - from.x) / length * thickness / 2
+
+	fillPolygon(img, []point{
+		{from.x + nx, from.y + ny},
+		{to.x + nx, to.y + ny},
+		{to.x - nx, to.y - ny},
+		{from.x - nx, from.y - ny},
+	}, c)
+}
+
+func formatScore(score float64) string {
+	if score == math.Trunc(score) {
+		return fmt.Sprintf("%.0f", score)
+	}
+
+	return fmt.Sprintf("%.2f", score)
+}
@@ -0,0 +1,86 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+func TestRender(t *testing.T) {
+	scales := []entity.ResultsScale{
+		{Key: "s1", Name: "Эмоциональное истощение", Score: 28},
+		{Key: "s2", Name: "Деперсонализация", Score: 12},
+		{Key: "s3", Name: "Редукция профессионализма", Score: 33},
+	}
+
+	tests := []struct {
+		name      string
+		kind      Kind
+		wantWidth int
+	}{
+		{name: "default", kind: "", wantWidth: width},
+		{name: "bar", kind: KindBar, wantWidth: width},
+		{name: "radar", kind: KindRadar, wantWidth: radarWidth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.kind, "Опросник", scales)
+			require.NoError(t, err)
+
+			img, err := png.Decode(bytes.NewReader(got))
+			require.NoError(t, err)
+			require.Equal(t, tt.wantWidth, img.Bounds().Dx())
+		})
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	_, err := Render(KindBar, "", nil)
+	require.ErrorIs(t, err, ErrNoScales)
+
+	_, err = Render("pie", "", []entity.ResultsScale{{Key: "s", Score: 1}})
+	require.ErrorIs(t, err, ErrUnknownKind)
+}
+
+func TestDefaultKind(t *testing.T) {
+	require.Equal(t, KindBar, DefaultKind(make([]entity.ResultsScale, 3)))
+	require.Equal(t, KindRadar, DefaultKind(make([]entity.ResultsScale, 6)))
+}
+
+func TestParseKind(t *testing.T) {
+	got, err := ParseKind("radar")
+	require.NoError(t, err)
+	require.Equal(t, KindRadar, got)
+
+	_, err = ParseKind("pie")
+	require.ErrorIs(t, err, ErrUnknownKind)
+}
+
+func TestNewValues(t *testing.T) {
+	got := newValues([]entity.ResultsScale{
+		{Name: "a", Score: -1},
+		{Name: "b", Score: 7.5},
+	})
+	require.Equal(t, values{
+		names:  []string{"a", "b"},
+		labels: []string{"-1", "7.50"},
+		values: []float64{0, 7.5},
+		max:    8,
+	}, got)
+
+	got = newValues([]entity.ResultsScale{
+		{Name: "a", Score: 10, Norm: &entity.ScaleNorm{Percentile: 25}},
+		{Name: "b", Score: 20, Norm: &entity.ScaleNorm{Percentile: 90}},
+	})
+	require.Equal(t, values{
+		names:  []string{"a", "b"},
+		labels: []string{"25%", "90%"},
+		values: []float64{25, 90},
+		max:    100,
+	}, got)
+}
@@ -17,6 +17,9 @@ type (
 		DB           DatabaseConfig `env:"-"`
 		PollInterval time.Duration  `env:"POLL_DURATION" envDefault:"1m"`
 
+		// ShutdownDelay is time between failing the readiness probe and stopping the servers on SIGTERM
+		ShutdownDelay time.Duration `env:"SHUTDOWN_DELAY" envDefault:"30s"`
+
 		SentryDSN     string        `env:"SENTRY_DSN"`
 		SentryTimeout time.Duration `env:"SENTRY_TIMEOUT" envDefault:"5s"`
 
@@ -25,7 +28,15 @@ type (
 		MetricsPort int `env:"METRICS_PORT" envDefault:"7777"`
 		APIPort     int `env:"API_PORT" envDefault:"8080"`
 
-		AllowedOrigins string `env:"ALLOWED_ORIGINS" envDefault:""`
+		AllowedOrigins []string `env:"ALLOWED_ORIGINS" envSeparator:","`
+
+		NormsRefreshInterval time.Duration `env:"NORMS_REFRESH_INTERVAL" envDefault:"24h"`
+
+		// EventsAnswersThrottle limits events of answers of the user to the survey in the live feed of admins
+		EventsAnswersThrottle time.Duration `env:"EVENTS_ANSWERS_THROTTLE" envDefault:"30s"`
+
+		AlertRulesFile string `env:"ALERT_RULES_FILE"`
+		AlertChatID    int64  `env:"ALERT_CHAT_ID"`
 	}
 
 	DatabaseConfig struct {
@@ -36,6 +47,8 @@ type (
 		Pwd          string `env:"DB_PWD,notEmpty"`
 		SslMode      string `env:"DB_SSL_MODE" envDefault:"disable"`
 		MigrationsUp bool   `env:"DB_MIGRATIONS_UP" envDefault:"true"`
+		// SchemaCheck refuses to start if the schema is not migrated to the latest embedded migration
+		SchemaCheck bool `env:"DB_SCHEMA_CHECK" envDefault:"true"`
 	}
 )
 
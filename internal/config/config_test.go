@@ -28,13 +28,18 @@ func TestNew(t *testing.T) {
 					Pwd:          "pwd",
 					SslMode:      "enable",
 					MigrationsUp: false,
+					SchemaCheck:  false,
 				},
 				ReleaseVersion: "1.0.0",
 				PollInterval:   10 * time.Minute,
 				SentryTimeout:  5 * time.Second,
+				ShutdownDelay:  30 * time.Second,
 				AdminUserIDs:   []int64{-1},
 				MetricsPort:    7777,
 				APIPort:        8080,
+
+				NormsRefreshInterval:  24 * time.Hour,
+				EventsAnswersThrottle: 30 * time.Second,
 			},
 			wantErr: false,
 			envs: map[string]string{
@@ -50,6 +55,7 @@ func TestNew(t *testing.T) {
 				"DB_SCHEMA":          "public2",
 				"DB_MIGRATIONS_UP":   "false",
 				"DB_MIGRATIONS_DOWN": "true",
+				"DB_SCHEMA_CHECK":    "false",
 				"POLL_DURATION":      "10m",
 				"RELEASE_VERSION":    "1.0.0",
 			},
@@ -107,12 +113,18 @@ func TestNew(t *testing.T) {
 					Pwd:          "pwd",
 					SslMode:      "enable",
 					MigrationsUp: true,
+					SchemaCheck:  true,
 				},
 				ReleaseVersion: "1.0.0",
 				PollInterval:   10 * time.Minute,
 				SentryTimeout:  5 * time.Second,
+				ShutdownDelay:  30 * time.Second,
 				MetricsPort:    7777,
 				APIPort:        8080,
+				AllowedOrigins: []string{"https://sv.ykonkov.com", "https://*.ykonkov.com"},
+
+				NormsRefreshInterval:  24 * time.Hour,
+				EventsAnswersThrottle: 30 * time.Second,
 			},
 			wantErr: false,
 			envs: map[string]string{
@@ -127,6 +139,7 @@ func TestNew(t *testing.T) {
 				"DB_SSL_MODE":        "enable",
 				"DB_SCHEMA":          "public2",
 				"DB_MIGRATIONS_UP":   "true",
+				"ALLOWED_ORIGINS":    "https://sv.ykonkov.com,https://*.ykonkov.com",
 				"DB_MIGRATIONS_DOWN": "true",
 				"POLL_DURATION":      "10m",
 				"RELEASE_VERSION":    "1.0.0",
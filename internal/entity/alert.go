@@ -0,0 +1,125 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type (
+	// AlertRule matches high-risk results on the scale of the survey.
+	// Scale matches if its level is one of levels or its score is in [min_score, max_score],
+	// at least one of the conditions should be set.
+	AlertRule struct {
+		CalculationsType string   `json:"calculations_type"`
+		Scale            string   `json:"scale"`
+		Levels           []string `json:"levels,omitempty"`
+		MinScore         *float64 `json:"min_score,omitempty"`
+		MaxScore         *float64 `json:"max_score,omitempty"`
+
+		// Help is appended to the user's results, default help is used if empty
+		Help string `json:"help,omitempty"`
+	}
+
+	AlertMatch struct {
+		Rule  AlertRule
+		Scale ResultsScale
+	}
+
+	// Alert is a record of the high-risk result
+	Alert struct {
+		GUID       uuid.UUID
+		UserGUID   uuid.UUID
+		SurveyGUID uuid.UUID
+		Scale      string
+		ScaleName  string
+		Score      float64
+		Level      string
+		CreatedAt  time.Time
+
+		// nil if alert is not acknowledged yet
+		AcknowledgedAt *time.Time
+		// telegram id of the admin acknowledged the alert
+		AcknowledgedBy *int64
+	}
+)
+
+// ParseAlertRules parses JSON array of alert rules
+func ParseAlertRules(data []byte) ([]AlertRule, error) {
+	var rules []AlertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alert rules: %w", err)
+	}
+
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("failed to validate alert rule %d, %w", i, err)
+		}
+	}
+
+	return rules, nil
+}
+
+func (r AlertRule) Validate() error {
+	if r.CalculationsType == "" {
+		return errors.New("empty calculations type")
+	}
+
+	if r.Scale == "" {
+		return errors.New("empty scale")
+	}
+
+	if len(r.Levels) == 0 && r.MinScore == nil && r.MaxScore == nil {
+		return errors.New("empty levels and score range")
+	}
+
+	if r.MinScore != nil && r.MaxScore != nil && *r.MinScore > *r.MaxScore {
+		return errors.New("min score is greater than max score")
+	}
+
+	return nil
+}
+
+// Match reports whether the scale of the survey results matches the rule
+func (r AlertRule) Match(calculationsType string, scale ResultsScale) bool {
+	if r.CalculationsType != calculationsType || r.Scale != scale.Key {
+		return false
+	}
+
+	if scale.Level != "" && slices.Contains(r.Levels, scale.Level) {
+		return true
+	}
+
+	if r.MinScore == nil && r.MaxScore == nil {
+		return false
+	}
+
+	if r.MinScore != nil && scale.Score < *r.MinScore {
+		return false
+	}
+
+	if r.MaxScore != nil && scale.Score > *r.MaxScore {
+		return false
+	}
+
+	return true
+}
+
+// FindAlerts returns scales of the results matching the rules, every scale is matched once by the first rule
+func FindAlerts(rules []AlertRule, calculationsType string, results Results) []AlertMatch {
+	var matches []AlertMatch
+	for _, scale := range results.Scales {
+		for _, rule := range rules {
+			if rule.Match(calculationsType, scale) {
+				matches = append(matches, AlertMatch{Rule: rule, Scale: scale})
+				break
+			}
+		}
+	}
+
+	return matches
+}
@@ -0,0 +1,99 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlertRules(t *testing.T) {
+	rules, err := ParseAlertRules([]byte(`[
+		{"calculations_type": "test_4", "scale": "s", "levels": ["тяжелая депрессия"], "help": "help"},
+		{"calculations_type": "test_1", "scale": "s1", "min_score": 30}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, []string{"тяжелая депрессия"}, rules[0].Levels)
+	require.Equal(t, 30.0, *rules[1].MinScore)
+
+	_, err = ParseAlertRules([]byte(`[{"calculations_type": "test_4", "scale": "s"}]`))
+	require.ErrorContains(t, err, "empty levels and score range")
+
+	_, err = ParseAlertRules([]byte(`[{"calculations_type": "test_4", "scale": "s", "min_score": 5, "max_score": 1}]`))
+	require.ErrorContains(t, err, "min score is greater than max score")
+
+	_, err = ParseAlertRules([]byte(`{}`))
+	require.Error(t, err)
+}
+
+func TestAlertRule_Match(t *testing.T) {
+	minScore, maxScore := 30.0, 40.0
+
+	tests := []struct {
+		name  string
+		rule  AlertRule
+		scale ResultsScale
+		want  bool
+	}{
+		{
+			name:  "level",
+			rule:  AlertRule{CalculationsType: "test_1", Scale: "s1", Levels: []string{"высокий уровень"}},
+			scale: ResultsScale{Key: "s1", Level: "высокий уровень"},
+			want:  true,
+		},
+		{
+			name:  "other level",
+			rule:  AlertRule{CalculationsType: "test_1", Scale: "s1", Levels: []string{"высокий уровень"}},
+			scale: ResultsScale{Key: "s1", Level: "средний уровень"},
+			want:  false,
+		},
+		{
+			name:  "min score",
+			rule:  AlertRule{CalculationsType: "test_1", Scale: "s1", MinScore: &minScore},
+			scale: ResultsScale{Key: "s1", Score: 30},
+			want:  true,
+		},
+		{
+			name:  "below min score",
+			rule:  AlertRule{CalculationsType: "test_1", Scale: "s1", MinScore: &minScore},
+			scale: ResultsScale{Key: "s1", Score: 29},
+			want:  false,
+		},
+		{
+			name:  "above max score",
+			rule:  AlertRule{CalculationsType: "test_1", Scale: "s1", MinScore: &minScore, MaxScore: &maxScore},
+			scale: ResultsScale{Key: "s1", Score: 41},
+			want:  false,
+		},
+		{
+			name:  "other scale",
+			rule:  AlertRule{CalculationsType: "test_1", Scale: "s1", MinScore: &minScore},
+			scale: ResultsScale{Key: "s2", Score: 35},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.rule.Match("test_1", tt.scale))
+		})
+	}
+}
+
+func TestFindAlerts(t *testing.T) {
+	minScore := 10.0
+	rules := []AlertRule{
+		{CalculationsType: "test_1", Scale: "s1", Levels: []string{"высокий уровень"}, Help: "first"},
+		{CalculationsType: "test_1", Scale: "s1", MinScore: &minScore, Help: "second"},
+		{CalculationsType: "test_2", Scale: "s2", MinScore: &minScore},
+	}
+
+	got := FindAlerts(rules, "test_1", Results{Scales: []ResultsScale{
+		{Key: "s1", Score: 40, Level: "высокий уровень"},
+		{Key: "s2", Score: 40},
+	}})
+
+	require.Len(t, got, 1)
+	require.Equal(t, "first", got[0].Rule.Help)
+	require.Equal(t, "s1", got[0].Scale.Key)
+}
@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// APIKeyScopeResultsRead allows to read results and analytics of surveys
+	APIKeyScopeResultsRead APIKeyScope = "results:read"
+	// APIKeyScopeSurveysManage allows to list, create, update and delete surveys
+	APIKeyScopeSurveysManage APIKeyScope = "surveys:manage"
+)
+
+var APIKeyScopes = []APIKeyScope{APIKeyScopeResultsRead, APIKeyScopeSurveysManage}
+
+type (
+	APIKeyScope string
+
+	// APIKey authenticates machine clients of the admin API, only hash of the key is stored
+	APIKey struct {
+		GUID      uuid.UUID
+		Name      string
+		Scopes    []APIKeyScope
+		CreatedAt time.Time
+
+		// nil if the key is never used
+		LastUsedAt *time.Time
+		// nil if the key is not revoked
+		RevokedAt *time.Time
+	}
+)
+
+// ParseAPIKeyScopes parses comma separated list of scopes
+func ParseAPIKeyScopes(s string) ([]APIKeyScope, error) {
+	var scopes []APIKeyScope
+	for _, item := range strings.Split(s, ",") {
+		scope := APIKeyScope(strings.TrimSpace(item))
+		if !slices.Contains(APIKeyScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes, nil
+}
+
+func (k APIKey) HasScope(scope APIKeyScope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAPIKeyScopes(t *testing.T) {
+	scopes, err := ParseAPIKeyScopes("results:read, surveys:manage,results:read")
+	require.NoError(t, err)
+	require.Equal(t, []APIKeyScope{APIKeyScopeResultsRead, APIKeyScopeSurveysManage}, scopes)
+
+	key := APIKey{Scopes: []APIKeyScope{APIKeyScopeResultsRead}}
+	require.True(t, key.HasScope(APIKeyScopeResultsRead))
+	require.False(t, key.HasScope(APIKeyScopeSurveysManage))
+
+	_, err = ParseAPIKeyScopes("results:write")
+	require.ErrorContains(t, err, `unknown scope "results:write"`)
+
+	_, err = ParseAPIKeyScopes("")
+	require.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -30,6 +31,8 @@ var (
 	ErrParseAnswerTypeMultiSelect = errors.New("can't parse answer to multiselect")
 	ErrAnswerOutOfRange           = errors.New("answer is out of range")
 	ErrAnswerNotFound             = errors.New("answer not found")
+
+	slugRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
 )
 
 type (
@@ -43,6 +46,9 @@ type (
 		Nickname      string
 		CurrentSurvey *uuid.UUID
 		LastActivity  time.Time
+
+		// Cohort is a group of users (e.g. a campaign from /start deep link), empty if unknown
+		Cohort string
 	}
 
 	Survey struct {
@@ -52,6 +58,9 @@ type (
 		Name             string
 		Description      string
 		Questions        []Question
+
+		// Slug is the stable key of the survey in survey files, empty for surveys created without it
+		Slug string
 	}
 
 	SurveyState struct {
@@ -74,6 +83,9 @@ type (
 		UserID      int64
 		Answers     []Answer
 		Results     *Results
+
+		// empty if the report is built only of finished states
+		State State
 	}
 
 	Question struct {
@@ -101,14 +113,44 @@ type (
 		Raw map[string]interface{}
 	}
 
+	ResultsScale struct {
+		// Key equals to the key of the scale in ResultsMetadata.Raw
+		Key   string
+		Name  string
+		Score float64
+
+		// empty if scale has no interpretation levels
+		Level string
+
+		// not nil if norms for the scale are available
+		Norm *ScaleNorm `json:",omitempty"`
+	}
+
 	Results struct {
 		Text     string
 		Metadata ResultsMetadata
+		Scales   []ResultsScale `json:",omitempty"`
+
+		// Changes relatively to the previous attempts of the survey, empty for the first attempt
+		Changes []ScaleChange `json:",omitempty"`
+	}
+
+	// ValidationError is a problem of the survey at the path of the field in the survey file,
+	// e.g. questions[2].answers_text[0]
+	ValidationError struct {
+		Path    string
+		Message string
+
+		// Lint is set for style warnings, the survey is valid despite of them
+		Lint bool
 	}
 
 	ResultsProcessor interface {
 		GetResults(survey Survey, answers []Answer) (Results, error)
 		Validate(survey Survey) error
+
+		// ValidationErrors returns all problems of the survey, including the ones of its calculations type
+		ValidationErrors(survey Survey) []ValidationError
 	}
 )
 
@@ -180,76 +222,203 @@ func (q Question) getAnswerSegment(answerRaw string) (Answer, error) {
 	}, nil
 }
 
-func (q Question) Validate() error {
-	text := utf8string.NewString(q.Text)
+// AnswerText returns human readable answer: texts of the chosen answers or the value of the segment
+func (q Question) AnswerText(a Answer) string {
+	if a.Type == AnswerTypeSegment {
+		return strings.Join(toStringSlice(a.Data), ", ")
+	}
 
-	if q.Text == "" {
-		return errors.New("empty question text")
+	texts := make([]string, 0, len(a.Data))
+	for _, value := range a.Data {
+		i := slices.Index(q.PossibleAnswers, value)
+		if i < 0 || i >= len(q.AnswersText) {
+			texts = append(texts, strconv.Itoa(value))
+			continue
+		}
+
+		texts = append(texts, q.AnswersText[i])
+	}
+
+	return strings.Join(texts, ", ")
+}
+
+func (q Question) Validate() error {
+	if errs := q.validationErrors(""); len(errs) > 0 {
+		return errors.New(errs[0].Message)
 	}
-	if !unicode.IsUpper(text.At(0)) {
-		return errors.New("question text should start with uppercase letter")
+
+	return nil
+}
+
+// validationErrors returns all problems of the question, paths of them are prefixed with prefix
+func (q Question) validationErrors(prefix string) []ValidationError {
+	var errs []ValidationError
+
+	add := func(path, message string) {
+		errs = append(errs, ValidationError{Path: prefix + path, Message: message})
 	}
 
-	lastSymbol := text.At(text.RuneCount() - 1)
-	if lastSymbol != '?' && lastSymbol != '.' {
-		return errors.New("question text should end with '?' or '.'")
+	if q.Text == "" {
+		add("text", "empty question text")
+	} else {
+		text := utf8string.NewString(q.Text)
+
+		if !unicode.IsUpper(text.At(0)) {
+			add("text", "question text should start with uppercase letter")
+		}
+
+		lastSymbol := text.At(text.RuneCount() - 1)
+		if lastSymbol != '?' && lastSymbol != '.' {
+			add("text", "question text should end with '?' or '.'")
+		}
 	}
 
-	for _, answerText := range q.AnswersText {
+	for i, answerText := range q.AnswersText {
 		if answerText == "" {
-			return errors.New("empty answer text")
+			add(fmt.Sprintf("answers_text[%d]", i), "empty answer text")
 		}
 	}
 
 	switch q.AnswerType {
 	case AnswerTypeSegment:
 		if len(q.PossibleAnswers) != 2 {
-			return errors.New("possible answers length should be 2")
+			add("possible_answers", "possible answers length should be 2")
 		}
 	case AnswerTypeSelect, AnswerTypeMultiSelect:
 		if len(q.PossibleAnswers) == 0 {
-			return errors.New("empty possible answers")
+			add("possible_answers", "empty possible answers")
+			break
 		}
 		if len(q.PossibleAnswers) != len(q.AnswersText) {
-			return errors.New("possible answers and answers text length mismatch")
+			add("possible_answers", "possible answers and answers text length mismatch")
 		}
 
-		for _, possibleAnswer := range q.PossibleAnswers {
+		for i, possibleAnswer := range q.PossibleAnswers {
 			if possibleAnswer < 0 || (len(q.AnswersText) < possibleAnswer) {
-				return errors.New("possible answer is out of range")
+				add(fmt.Sprintf("possible_answers[%d]", i), "possible answer is out of range")
 			}
 		}
 	default:
-		return errors.New("unknown answer type")
+		add("answer_type", "unknown answer type")
 	}
 
-	return nil
+	return errs
+}
+
+// lint returns style warnings of the question, they don't prevent the question from being used
+func (q Question) lint(prefix string) []ValidationError {
+	errs := lintText(prefix+"text", q.Text)
+
+	for i, answerText := range q.AnswersText {
+		path := fmt.Sprintf("%sanswers_text[%d]", prefix, i)
+
+		errs = append(errs, lintText(path, answerText)...)
+		if startsWithLower(answerText) {
+			errs = append(errs, ValidationError{Path: path, Message: "answer text should start with uppercase letter", Lint: true})
+		}
+	}
+
+	return errs
 }
 
 func (s Survey) Validate() error {
+	if errs := s.fieldErrors(); len(errs) > 0 {
+		return errors.New(errs[0].Message)
+	}
+
+	for i, question := range s.Questions {
+		if err := question.Validate(); err != nil {
+			return fmt.Errorf("failed to validate question %d, %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidationErrors returns all problems of the survey, unlike Validate it doesn't stop at the first one
+func (s Survey) ValidationErrors() []ValidationError {
+	errs := s.fieldErrors()
+
+	for i, question := range s.Questions {
+		errs = append(errs, question.validationErrors(fmt.Sprintf("questions[%d].", i))...)
+	}
+
+	return errs
+}
+
+// Lint returns style warnings of the survey: stray whitespaces, capitalization and punctuation of the texts
+func (s Survey) Lint() []ValidationError {
+	errs := lintText("name", s.Name)
+	if startsWithLower(s.Name) {
+		errs = append(errs, ValidationError{Path: "name", Message: "survey name should start with uppercase letter", Lint: true})
+	}
+
+	errs = append(errs, lintText("description", s.Description)...)
+	if startsWithLower(s.Description) {
+		errs = append(errs, ValidationError{Path: "description", Message: "survey description should start with uppercase letter", Lint: true})
+	}
+
+	if description := utf8string.NewString(strings.TrimSpace(s.Description)); description.RuneCount() > 0 {
+		if lastSymbol := description.At(description.RuneCount() - 1); !strings.ContainsRune(".!?", lastSymbol) {
+			errs = append(errs, ValidationError{Path: "description", Message: "survey description should end with '.', '!' or '?'", Lint: true})
+		}
+	}
+
+	for i, question := range s.Questions {
+		errs = append(errs, question.lint(fmt.Sprintf("questions[%d].", i))...)
+	}
+
+	return errs
+}
+
+func (s Survey) fieldErrors() []ValidationError {
+	var errs []ValidationError
+
 	if s.Name == "" {
-		return errors.New("empty survey name")
+		errs = append(errs, ValidationError{Path: "name", Message: "empty survey name"})
 	}
 
 	if s.Description == "" {
-		return errors.New("empty survey description")
+		errs = append(errs, ValidationError{Path: "description", Message: "empty survey description"})
 	}
 
 	if s.CalculationsType == "" {
-		return errors.New("empty calculations type")
+		errs = append(errs, ValidationError{Path: "calculations_type", Message: "empty calculations type"})
 	}
 
 	if len(s.Questions) == 0 {
-		return errors.New("empty questions")
+		errs = append(errs, ValidationError{Path: "questions", Message: "empty questions"})
 	}
 
-	for i, question := range s.Questions {
-		if err := question.Validate(); err != nil {
-			return fmt.Errorf("failed to validate question %d, %w", i, err)
-		}
+	if s.Slug != "" && !slugRegexp.MatchString(s.Slug) {
+		errs = append(errs, ValidationError{Path: "slug", Message: "slug should contain only lowercase latin letters, digits and hyphens"})
 	}
 
-	return nil
+	return errs
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+func lintText(path, text string) []ValidationError {
+	var errs []ValidationError
+
+	if text != strings.TrimSpace(text) {
+		errs = append(errs, ValidationError{Path: path, Message: "leading or trailing whitespace", Lint: true})
+	}
+
+	if strings.Contains(text, "  ") {
+		errs = append(errs, ValidationError{Path: path, Message: "double space", Lint: true})
+	}
+
+	return errs
+}
+
+func startsWithLower(text string) bool {
+	text = strings.TrimSpace(text)
+
+	return text != "" && unicode.IsLower(utf8string.NewString(text).At(0))
 }
 
 func (ss SurveyStateReport) ToCSV() ([]string, error) {
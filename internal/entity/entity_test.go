@@ -321,3 +321,153 @@ func TestQuestion_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestQuestion_AnswerText(t *testing.T) {
+	question := entity.Question{
+		AnswerType:      entity.AnswerTypeMultiSelect,
+		PossibleAnswers: []int{1, 2, 3},
+		AnswersText:     []string{"Никогда", "Иногда", "Часто"},
+	}
+
+	tests := []struct {
+		name     string
+		question entity.Question
+		answer   entity.Answer
+		want     string
+	}{
+		{
+			name:     "select",
+			question: question,
+			answer:   entity.Answer{Type: entity.AnswerTypeSelect, Data: []int{2}},
+			want:     "Иногда",
+		},
+		{
+			name:     "multiselect",
+			question: question,
+			answer:   entity.Answer{Type: entity.AnswerTypeMultiSelect, Data: []int{1, 3}},
+			want:     "Никогда, Часто",
+		},
+		{
+			name:     "unknown answer",
+			question: question,
+			answer:   entity.Answer{Type: entity.AnswerTypeSelect, Data: []int{7}},
+			want:     "7",
+		},
+		{
+			name:     "segment",
+			question: entity.Question{AnswerType: entity.AnswerTypeSegment, PossibleAnswers: []int{1, 10}},
+			answer:   entity.Answer{Type: entity.AnswerTypeSegment, Data: []int{4}},
+			want:     "4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.question.AnswerText(tt.answer); got != tt.want {
+				t.Errorf("Question.AnswerText() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSurvey_ValidationErrors(t *testing.T) {
+	survey := entity.Survey{
+		Name:             "Survey",
+		CalculationsType: "test_1",
+		Slug:             "Survey 1",
+		Questions: []entity.Question{
+			{
+				Text:            "Question?",
+				AnswerType:      entity.AnswerTypeSelect,
+				PossibleAnswers: []int{1, 2},
+				AnswersText:     []string{"Yes", "No"},
+			},
+			{
+				Text:            "question",
+				AnswerType:      entity.AnswerTypeSelect,
+				PossibleAnswers: []int{1, 5},
+				AnswersText:     []string{"Yes", ""},
+			},
+		},
+	}
+
+	want := []entity.ValidationError{
+		{Path: "description", Message: "empty survey description"},
+		{Path: "slug", Message: "slug should contain only lowercase latin letters, digits and hyphens"},
+		{Path: "questions[1].text", Message: "question text should start with uppercase letter"},
+		{Path: "questions[1].text", Message: "question text should end with '?' or '.'"},
+		{Path: "questions[1].answers_text[1]", Message: "empty answer text"},
+		{Path: "questions[1].possible_answers[1]", Message: "possible answer is out of range"},
+	}
+	if got := survey.ValidationErrors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Survey.ValidationErrors() = %v, want %v", got, want)
+	}
+
+	// Validate returns the first of them
+	if err := survey.Validate(); err == nil || err.Error() != "empty survey description" {
+		t.Errorf("Survey.Validate() error = %v", err)
+	}
+
+	survey.Description = "Description."
+	survey.Slug = "survey-1"
+	if err := survey.Validate(); err == nil || err.Error() != "failed to validate question 1, question text should start with uppercase letter" {
+		t.Errorf("Survey.Validate() error = %v", err)
+	}
+}
+
+func TestSurvey_Lint(t *testing.T) {
+	tests := []struct {
+		name   string
+		survey entity.Survey
+		want   []entity.ValidationError
+	}{
+		{
+			name: "ok",
+			survey: entity.Survey{
+				Name:        "Survey",
+				Description: "Description!",
+				Questions: []entity.Question{
+					{Text: "Question?", AnswersText: []string{"Yes", "10%"}},
+				},
+			},
+		},
+		{
+			name: "whitespaces",
+			survey: entity.Survey{
+				Name:        " Survey",
+				Description: "Description  of survey.",
+				Questions: []entity.Question{
+					{Text: "Question? ", AnswersText: []string{"Yes "}},
+				},
+			},
+			want: []entity.ValidationError{
+				{Path: "name", Message: "leading or trailing whitespace", Lint: true},
+				{Path: "description", Message: "double space", Lint: true},
+				{Path: "questions[0].text", Message: "leading or trailing whitespace", Lint: true},
+				{Path: "questions[0].answers_text[0]", Message: "leading or trailing whitespace", Lint: true},
+			},
+		},
+		{
+			name: "capitalization and punctuation",
+			survey: entity.Survey{
+				Name:        "survey",
+				Description: "description",
+				Questions: []entity.Question{
+					{Text: "Question?", AnswersText: []string{"Yes", "нет"}},
+				},
+			},
+			want: []entity.ValidationError{
+				{Path: "name", Message: "survey name should start with uppercase letter", Lint: true},
+				{Path: "description", Message: "survey description should start with uppercase letter", Lint: true},
+				{Path: "description", Message: "survey description should end with '.', '!' or '?'", Lint: true},
+				{Path: "questions[0].answers_text[1]", Message: "answer text should start with uppercase letter", Lint: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.survey.Lint(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Survey.Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
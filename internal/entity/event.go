@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventUserRegistered EventType = "user_registered"
+	EventSurveyStarted  EventType = "survey_started"
+	EventSurveyAnswered EventType = "survey_answered"
+	EventSurveyFinished EventType = "survey_finished"
+)
+
+type (
+	EventType string
+
+	// Event is an activity of the user shown in the live feed of admins
+	Event struct {
+		Type     EventType `json:"type"`
+		UserGUID uuid.UUID `json:"user_guid"`
+		Nickname string    `json:"nickname"`
+
+		// empty for EventUserRegistered
+		SurveyGUID *uuid.UUID `json:"survey_guid,omitempty"`
+		SurveyName string     `json:"survey_name,omitempty"`
+
+		// number of answered questions of the survey
+		Answered  int `json:"answered,omitempty"`
+		Questions int `json:"questions,omitempty"`
+
+		CreatedAt time.Time `json:"created_at"`
+	}
+)
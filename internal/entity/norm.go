@@ -0,0 +1,188 @@
+package entity
+
+import (
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+const (
+	NormSourcePopulation NormSource = "population"
+	NormSourceImported   NormSource = "imported"
+)
+
+type (
+	NormSource string
+
+	// Norm describes distribution of scores of one scale of the survey
+	Norm struct {
+		SurveyGUID uuid.UUID
+		Scale      string
+
+		// empty cohort means whole population
+		Cohort string
+		Source NormSource
+
+		Mean       float64
+		StdDev     float64
+		SampleSize int
+
+		// Distribution is sorted by score, empty for imported norms with mean and std dev only
+		Distribution []NormPoint
+	}
+
+	NormPoint struct {
+		Score float64 `json:"score"`
+		Count int     `json:"count"`
+	}
+
+	// ScaleNorm is a position of the score relatively to the norm
+	ScaleNorm struct {
+		// Percentile is a share of respondents (0-100) with lower score
+		Percentile float64
+		ZScore     float64
+		SampleSize int
+		Cohort     string
+		Source     NormSource
+	}
+)
+
+// NewPopulationNorm builds norm from the histogram of scores
+func NewPopulationNorm(surveyGUID uuid.UUID, scale, cohort string, points []NormPoint) Norm {
+	distribution := make([]NormPoint, 0, len(points))
+	byScore := make(map[float64]int)
+	for _, point := range points {
+		if _, ok := byScore[point.Score]; !ok {
+			distribution = append(distribution, NormPoint{Score: point.Score})
+		}
+		byScore[point.Score] += point.Count
+	}
+
+	for i := range distribution {
+		distribution[i].Count = byScore[distribution[i].Score]
+	}
+
+	sort.Slice(distribution, func(i, j int) bool {
+		return distribution[i].Score < distribution[j].Score
+	})
+
+	var (
+		total int
+		sum   float64
+	)
+	for _, point := range distribution {
+		total += point.Count
+		sum += point.Score * float64(point.Count)
+	}
+
+	norm := Norm{
+		SurveyGUID:   surveyGUID,
+		Scale:        scale,
+		Cohort:       cohort,
+		Source:       NormSourcePopulation,
+		SampleSize:   total,
+		Distribution: distribution,
+	}
+
+	if total == 0 {
+		return norm
+	}
+
+	norm.Mean = sum / float64(total)
+
+	var squares float64
+	for _, point := range distribution {
+		squares += (point.Score - norm.Mean) * (point.Score - norm.Mean) * float64(point.Count)
+	}
+	norm.StdDev = math.Sqrt(squares / float64(total))
+
+	return norm
+}
+
+// Rank returns position of the score relatively to the norm.
+// Percentile is calculated by distribution if it is present, otherwise normal distribution is assumed.
+func (n Norm) Rank(score float64) ScaleNorm {
+	result := ScaleNorm{
+		SampleSize: n.SampleSize,
+		Cohort:     n.Cohort,
+		Source:     n.Source,
+	}
+
+	if n.StdDev > 0 {
+		result.ZScore = (score - n.Mean) / n.StdDev
+	}
+
+	if len(n.Distribution) == 0 {
+		result.Percentile = 50 * (1 + math.Erf(result.ZScore/math.Sqrt2))
+		return result
+	}
+
+	var total, below int
+	for _, point := range n.Distribution {
+		total += point.Count
+		if point.Score < score {
+			below += point.Count
+		}
+	}
+
+	if total > 0 {
+		result.Percentile = 100 * float64(below) / float64(total)
+	}
+
+	return result
+}
+
+// FindNorm returns the most specific norm for the scale.
+// Imported norms are preferred to population ones, norms of the cohort are preferred to the whole population.
+func FindNorm(norms []Norm, scale, cohort string, minSampleSize int) (Norm, bool) {
+	var candidates []Norm
+	for _, norm := range norms {
+		if norm.Scale != scale {
+			continue
+		}
+		if norm.Cohort != "" && norm.Cohort != cohort {
+			continue
+		}
+		if norm.Source == NormSourcePopulation && norm.SampleSize < minSampleSize {
+			continue
+		}
+
+		candidates = append(candidates, norm)
+	}
+
+	if len(candidates) == 0 {
+		return Norm{}, false
+	}
+
+	priority := func(n Norm) int {
+		p := 0
+		if n.Cohort != "" {
+			p += 2
+		}
+		if n.Source == NormSourceImported {
+			p++
+		}
+
+		return p
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return priority(candidates[i]) > priority(candidates[j])
+	})
+
+	return candidates[0], true
+}
+
+// ApplyNorms sets norm of each scale of the results if the norm is found
+func (r *Results) ApplyNorms(norms []Norm, cohort string, minSampleSize int) {
+	for i, scale := range r.Scales {
+		norm, ok := FindNorm(norms, scale.Key, cohort, minSampleSize)
+		if !ok {
+			continue
+		}
+
+		rank := norm.Rank(scale.Score)
+		r.Scales[i].Norm = &rank
+	}
+}
@@ -0,0 +1,152 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPopulationNorm(t *testing.T) {
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+
+	got := NewPopulationNorm(surveyGUID, "s1", "", []NormPoint{
+		{Score: 4, Count: 1},
+		{Score: 2, Count: 2},
+		{Score: 4, Count: 1},
+	})
+
+	require.Equal(t, surveyGUID, got.SurveyGUID)
+	require.Equal(t, NormSourcePopulation, got.Source)
+	require.Equal(t, 4, got.SampleSize)
+	require.Equal(t, []NormPoint{{Score: 2, Count: 2}, {Score: 4, Count: 2}}, got.Distribution)
+	require.InDelta(t, 3, got.Mean, 0.0001)
+	require.InDelta(t, 1, got.StdDev, 0.0001)
+}
+
+func TestNewPopulationNorm_Empty(t *testing.T) {
+	got := NewPopulationNorm(uuid.Nil, "s1", "", nil)
+
+	require.Equal(t, 0, got.SampleSize)
+	require.Equal(t, 0.0, got.Mean)
+	require.Equal(t, 0.0, got.StdDev)
+}
+
+func TestNorm_Rank(t *testing.T) {
+	tests := []struct {
+		name           string
+		norm           Norm
+		score          float64
+		wantPercentile float64
+		wantZScore     float64
+	}{
+		{
+			name: "distribution",
+			norm: Norm{
+				Mean:       3,
+				StdDev:     1,
+				SampleSize: 4,
+				Distribution: []NormPoint{
+					{Score: 1, Count: 1},
+					{Score: 3, Count: 2},
+					{Score: 5, Count: 1},
+				},
+			},
+			score:          4,
+			wantPercentile: 75,
+			wantZScore:     1,
+		},
+		{
+			name: "distribution, lowest score",
+			norm: Norm{
+				Mean:         3,
+				StdDev:       1,
+				SampleSize:   2,
+				Distribution: []NormPoint{{Score: 1, Count: 1}, {Score: 5, Count: 1}},
+			},
+			score:          1,
+			wantPercentile: 0,
+			wantZScore:     -2,
+		},
+		{
+			name:           "normal distribution, mean",
+			norm:           Norm{Mean: 10, StdDev: 2, SampleSize: 100},
+			score:          10,
+			wantPercentile: 50,
+			wantZScore:     0,
+		},
+		{
+			name:           "normal distribution, one std dev",
+			norm:           Norm{Mean: 10, StdDev: 2, SampleSize: 100},
+			score:          12,
+			wantPercentile: 84.13,
+			wantZScore:     1,
+		},
+		{
+			name:           "zero std dev",
+			norm:           Norm{Mean: 10, SampleSize: 100},
+			score:          12,
+			wantPercentile: 50,
+			wantZScore:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.norm.Rank(tt.score)
+
+			require.InDelta(t, tt.wantPercentile, got.Percentile, 0.01)
+			require.InDelta(t, tt.wantZScore, got.ZScore, 0.0001)
+			require.Equal(t, tt.norm.SampleSize, got.SampleSize)
+		})
+	}
+}
+
+func TestFindNorm(t *testing.T) {
+	norms := []Norm{
+		{Scale: "s1", Source: NormSourcePopulation, SampleSize: 100, Mean: 1},
+		{Scale: "s1", Source: NormSourceImported, SampleSize: 10, Mean: 2},
+		{Scale: "s1", Cohort: "campaign", Source: NormSourcePopulation, SampleSize: 50, Mean: 3},
+		{Scale: "s1", Cohort: "small", Source: NormSourcePopulation, SampleSize: 5, Mean: 4},
+		{Scale: "s2", Source: NormSourcePopulation, SampleSize: 5, Mean: 5},
+	}
+
+	tests := []struct {
+		name     string
+		scale    string
+		cohort   string
+		wantMean float64
+		wantOK   bool
+	}{
+		{name: "cohort norm is preferred", scale: "s1", cohort: "campaign", wantMean: 3, wantOK: true},
+		{name: "imported norm is preferred", scale: "s1", cohort: "", wantMean: 2, wantOK: true},
+		{name: "small cohort is skipped", scale: "s1", cohort: "small", wantMean: 2, wantOK: true},
+		{name: "unknown cohort", scale: "s1", cohort: "other", wantMean: 2, wantOK: true},
+		{name: "small population is skipped", scale: "s2", cohort: "", wantOK: false},
+		{name: "unknown scale", scale: "s3", cohort: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FindNorm(norms, tt.scale, tt.cohort, 30)
+
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.wantMean, got.Mean)
+		})
+	}
+}
+
+func TestResults_ApplyNorms(t *testing.T) {
+	results := Results{
+		Scales: []ResultsScale{
+			{Key: "s1", Score: 12},
+			{Key: "s2", Score: 1},
+		},
+	}
+
+	results.ApplyNorms([]Norm{{Scale: "s1", Source: NormSourceImported, Mean: 10, StdDev: 2, SampleSize: 100}}, "", 30)
+
+	require.NotNil(t, results.Scales[0].Norm)
+	require.InDelta(t, 1, results.Scales[0].Norm.ZScore, 0.0001)
+	require.Nil(t, results.Scales[1].Norm)
+}
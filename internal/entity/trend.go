@@ -0,0 +1,106 @@
+package entity
+
+const (
+	TrendUp     Trend = "up"
+	TrendDown   Trend = "down"
+	TrendStable Trend = "stable"
+
+	// slopes less than epsilon are considered as stable
+	trendEpsilon = 1e-9
+)
+
+type (
+	Trend string
+
+	// ScaleChange is a change of the score on the scale relatively to the previous attempts of the survey
+	ScaleChange struct {
+		Key      string
+		Name     string
+		Previous float64
+		Current  float64
+
+		// Delta is a difference with the previous attempt
+		Delta float64
+
+		// Trend is a direction of the scores across all attempts
+		Trend    Trend
+		Attempts int
+	}
+)
+
+// CompareAttempts compares the last results with the previous ones.
+// Attempts are ordered from the oldest to the latest, scales missing in the previous attempt are skipped.
+func CompareAttempts(attempts []Results) []ScaleChange {
+	if len(attempts) < 2 {
+		return nil
+	}
+
+	current := attempts[len(attempts)-1]
+	previous := attempts[len(attempts)-2]
+
+	var changes []ScaleChange
+	for _, scale := range current.Scales {
+		prev, ok := previous.scale(scale.Key)
+		if !ok {
+			continue
+		}
+
+		var history []float64
+		for _, attempt := range attempts {
+			if s, ok := attempt.scale(scale.Key); ok {
+				history = append(history, s.Score)
+			}
+		}
+
+		changes = append(changes, ScaleChange{
+			Key:      scale.Key,
+			Name:     scale.Name,
+			Previous: prev.Score,
+			Current:  scale.Score,
+			Delta:    scale.Score - prev.Score,
+			Trend:    trend(history),
+			Attempts: len(history),
+		})
+	}
+
+	return changes
+}
+
+func (r Results) scale(key string) (ResultsScale, bool) {
+	for _, scale := range r.Scales {
+		if scale.Key == key {
+			return scale, true
+		}
+	}
+
+	return ResultsScale{}, false
+}
+
+// trend returns sign of the least squares slope of the scores
+func trend(scores []float64) Trend {
+	n := float64(len(scores))
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i, score := range scores {
+		x := float64(i)
+		sumX += x
+		sumY += score
+		sumXY += x * score
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return TrendStable
+	}
+
+	slope := (n*sumXY - sumX*sumY) / denominator
+	switch {
+	case slope > trendEpsilon:
+		return TrendUp
+	case slope < -trendEpsilon:
+		return TrendDown
+	default:
+		return TrendStable
+	}
+}
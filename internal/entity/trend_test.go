@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareAttempts(t *testing.T) {
+	attempt := func(scores ...float64) Results {
+		var results Results
+		for i, score := range scores {
+			key := []string{"s1", "s2"}[i]
+			results.Scales = append(results.Scales, ResultsScale{Key: key, Name: "Шкала " + key, Score: score})
+		}
+
+		return results
+	}
+
+	tests := []struct {
+		name     string
+		attempts []Results
+		want     []ScaleChange
+	}{
+		{
+			name:     "first attempt",
+			attempts: []Results{attempt(28)},
+			want:     nil,
+		},
+		{
+			name:     "two attempts",
+			attempts: []Results{attempt(28, 5), attempt(19, 5)},
+			want: []ScaleChange{
+				{Key: "s1", Name: "Шкала s1", Previous: 28, Current: 19, Delta: -9, Trend: TrendDown, Attempts: 2},
+				{Key: "s2", Name: "Шкала s2", Previous: 5, Current: 5, Delta: 0, Trend: TrendStable, Attempts: 2},
+			},
+		},
+		{
+			name:     "trend across all attempts",
+			attempts: []Results{attempt(10), attempt(20), attempt(18)},
+			want: []ScaleChange{
+				{Key: "s1", Name: "Шкала s1", Previous: 20, Current: 18, Delta: -2, Trend: TrendUp, Attempts: 3},
+			},
+		},
+		{
+			name:     "scale missing in previous attempt",
+			attempts: []Results{attempt(10), attempt(20, 3)},
+			want: []ScaleChange{
+				{Key: "s1", Name: "Шкала s1", Previous: 10, Current: 20, Delta: 10, Trend: TrendUp, Attempts: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, CompareAttempts(tt.attempts))
+		})
+	}
+}
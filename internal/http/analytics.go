@@ -0,0 +1,134 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+// defaultAbandonedAfterDays is a number of days without answers after which the active survey is abandoned
+const defaultAbandonedAfterDays = 7
+
+type SurveyAnalytics struct {
+	SurveyGUID     string  `json:"survey_guid"`
+	Name           string  `json:"name"`
+	Started        int     `json:"started"`
+	Finished       int     `json:"finished"`
+	Abandoned      int     `json:"abandoned"`
+	CompletionRate float64 `json:"completion_rate"`
+
+	// null if nobody finished the survey
+	MedianDurationSeconds *float64 `json:"median_duration_seconds"`
+
+	// number of active states by the number of answered questions
+	Funnel    []FunnelStep        `json:"funnel"`
+	Questions []QuestionAnalytics `json:"questions"`
+}
+
+type FunnelStep struct {
+	Answered int `json:"answered"`
+	Count    int `json:"count"`
+}
+
+type QuestionAnalytics struct {
+	Number     int               `json:"number"`
+	Text       string            `json:"text"`
+	AnswerType entity.AnswerType `json:"answer_type"`
+	Answers    []AnswerStats     `json:"answers"`
+}
+
+type AnswerStats struct {
+	Value int    `json:"value"`
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// handleAdminSurveyAnalytics returns completion funnel and answers distribution of the survey
+func (s *apiServer) handleAdminSurveyAnalytics(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleAdminSurveyAnalytics")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	surveyGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse survey guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	abandonedAfterDays := defaultAbandonedAfterDays
+	if value := r.URL.Query().Get("abandoned_after"); value != "" {
+		abandonedAfterDays, err = strconv.Atoi(value)
+		if err != nil || abandonedAfterDays < 0 {
+			s.log.Errorf(r.Context(), "failed to parse abandoned_after: %s", value)
+			s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+			return
+		}
+	}
+
+	analytics, err := s.svc.GetSurveyAnalytics(r.Context(), surveyGUID, time.Duration(abandonedAfterDays)*24*time.Hour)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s not found", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to get survey analytics: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	response := SurveyAnalytics{
+		SurveyGUID:     analytics.Survey.GUID.String(),
+		Name:           analytics.Survey.Name,
+		Started:        analytics.Started,
+		Finished:       analytics.Finished,
+		Abandoned:      analytics.Abandoned,
+		CompletionRate: analytics.CompletionRate,
+		Funnel:         []FunnelStep{},
+		Questions:      []QuestionAnalytics{},
+	}
+
+	if analytics.MedianDuration != nil {
+		seconds := analytics.MedianDuration.Seconds()
+		response.MedianDurationSeconds = &seconds
+	}
+
+	for _, step := range analytics.Funnel {
+		response.Funnel = append(response.Funnel, FunnelStep{Answered: step.Answered, Count: step.Count})
+	}
+
+	for i, question := range analytics.Questions {
+		item := QuestionAnalytics{
+			Number:     i + 1,
+			Text:       question.Question.Text,
+			AnswerType: question.Question.AnswerType,
+			Answers:    []AnswerStats{},
+		}
+
+		for _, answer := range question.Answers {
+			item.Answers = append(item.Answers, AnswerStats{Value: answer.Value, Text: answer.Text, Count: answer.Count})
+		}
+
+		response.Questions = append(response.Questions, item)
+	}
+
+	s.writeResponse(r.Context(), w, response)
+}
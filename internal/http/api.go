@@ -3,43 +3,62 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/getkin/kin-openapi/routers"
 	"github.com/getsentry/sentry-go"
 	sentryhttp "github.com/getsentry/sentry-go/http"
+	"github.com/google/uuid"
 	initdata "github.com/telegram-mini-apps/init-data-golang"
 
+	"git.ykonkov.com/ykonkov/survey-bot/internal/chart"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
 )
 
 type userIDKey string
 
-const userIDKeyType = userIDKey("userID")
+const (
+	userIDKeyType   = userIDKey("userID")
+	nicknameKeyType = userIDKey("nickname")
+)
 
 type apiServer struct {
-	server *http.Server
-	svc    service.Service
-	log    logger.Logger
+	server  *http.Server
+	mux     *http.ServeMux
+	svc     service.Service
+	log     logger.Logger
+	openAPI routers.Router
 
 	telegramToken  string
-	allowedOrigins string
+	allowedOrigins []string
 	adminUserIDs   []int64
+
+	// closed on shutdown to finish event streams, the server waits for them otherwise
+	shutdown chan struct{}
 }
 
 func NewAPIServer(
 	port int,
 	telegramToken string,
-	allowedOrigins string,
+	allowedOrigins []string,
 	adminUserIDs []int64,
 	svc service.Service,
 	log logger.Logger,
-) *apiServer {
-	sentryHandler := sentryhttp.New(sentryhttp.Options{})
+) (*apiServer, error) {
+	// panics are reported by sentry and handled by the recovery middleware
+	sentryHandler := sentryhttp.New(sentryhttp.Options{Repanic: true})
+
+	_, openAPI, err := loadOpenAPI()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi: %w", err)
+	}
 
 	server := &apiServer{
 		svc:            svc,
@@ -47,21 +66,58 @@ func NewAPIServer(
 		allowedOrigins: allowedOrigins,
 		adminUserIDs:   adminUserIDs,
 		log:            log,
+		openAPI:        openAPI,
+		mux:            http.NewServeMux(),
+		shutdown:       make(chan struct{}),
 		server: &http.Server{
 			Addr: ":" + fmt.Sprintf("%d", port),
 		},
 	}
+	server.server.RegisterOnShutdown(func() {
+		close(server.shutdown)
+	})
+
+	// requests are validated after authentication, so unauthenticated callers don't get details of the schema
+	handle := func(pattern string, handler http.HandlerFunc, middlewares ...middleware) {
+		middlewares = append(middlewares, server.validateMiddleware)
+		server.mux.Handle(pattern, sentryHandler.HandleFunc(chain(handler, middlewares...)))
+	}
 
-	handler := http.NewServeMux()
-	handler.Handle("/api/surveys", sentryHandler.HandleFunc(server.optionsMiddleware(server.authMiddleware(server.handleCompletedSurveys))))
-	handler.Handle("/api/is-admin", sentryHandler.HandleFunc(server.optionsMiddleware(server.authMiddleware(server.handleIsAdmin))))
-	handler.Handle("/api/admin/users", sentryHandler.HandleFunc(
-		server.optionsMiddleware(server.authMiddleware(server.authAdminMiddleware(server.handleUsersList)))),
+	var (
+		auth      = server.authMiddleware
+		authAdmin = server.authAdminMiddleware
 	)
 
-	server.server.Handler = handler
+	handle("/api/openapi.json", server.handleOpenAPI)
+	handle("/api/surveys", server.handleCompletedSurveys, auth)
+	handle("/api/surveys/{guid}/attempts", server.handleSurveyAttempts, auth)
+	handle("/api/surveys/{guid}/attempts/{started_at}/chart.png", server.handleSurveyChart, auth)
+	handle("/api/surveys/{guid}/attempts/{started_at}/report.pdf", server.handleSurveyReport, auth)
+	handle("/api/surveys/available", server.handleAvailableSurveys, auth)
+	handle("/api/surveys/{guid}/start", server.handleStartSurvey, auth)
+	handle("/api/surveys/{guid}/question", server.handleSurveyQuestion, auth)
+	handle("/api/surveys/{guid}/answers", server.handleSurveyAnswer, auth)
+	handle("/api/is-admin", server.handleIsAdmin, auth)
+	handle("/api/admin/users", server.handleUsersList, auth, authAdmin)
+	handle("/api/admin/users/{guid}", server.handleAdminUser, auth, authAdmin)
+	handle("/api/admin/users/{guid}/current-survey", server.handleAdminUserCurrentSurvey, auth, authAdmin)
+	handle("/api/admin/users/{guid}/surveys/{survey_guid}", server.handleAdminUserSurvey, auth, authAdmin)
+	handle("/api/admin/results", server.handleAdminResults, server.authKeyMiddleware(entity.APIKeyScopeResultsRead))
+	handle("/api/admin/surveys", server.handleAdminSurveys, server.authKeyMiddleware(entity.APIKeyScopeSurveysManage))
+	handle("/api/admin/surveys/{guid}", server.handleAdminSurvey, server.authKeyMiddleware(entity.APIKeyScopeSurveysManage))
+	handle("/api/admin/surveys/{guid}/analytics", server.handleAdminSurveyAnalytics, server.authKeyMiddleware(entity.APIKeyScopeResultsRead))
+	handle("/api/admin/events", server.handleAdminEvents, server.authKeyMiddleware(entity.APIKeyScopeResultsRead))
+
+	server.server.Handler = chain(
+		server.mux.ServeHTTP,
+		server.requestIDMiddleware,
+		server.accessLogMiddleware,
+		server.metricsMiddleware,
+		server.recoveryMiddleware,
+		server.corsMiddleware,
+	)
 
-	return server
+	return server, nil
 }
 
 func (s *apiServer) Start() error {
@@ -72,17 +128,6 @@ func (s *apiServer) Stop() error {
 	return s.server.Shutdown(context.Background())
 }
 
-func (s *apiServer) optionsMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodOptions {
-			s.optionsResponse(w)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	}
-}
-
 func (s *apiServer) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		span := sentry.StartSpan(
@@ -127,7 +172,13 @@ func (s *apiServer) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 		span.SetData("userID", fmt.Sprintf("%d", data.User.ID))
 
+		// the same format as the nickname of users registered by the bot
+		nickname := fmt.Sprintf("%s %s (%s)", data.User.FirstName, data.User.LastName, data.User.Username)
+
 		ctx := context.WithValue(r.Context(), userIDKeyType, data.User.ID)
+		ctx = context.WithValue(ctx, nicknameKeyType, nickname)
+		ctx = context.WithValue(ctx, logger.UserIDField, data.User.ID)
+		ctx = context.WithValue(ctx, logger.UserName, nickname)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
@@ -166,6 +217,52 @@ func (s *apiServer) authAdminMiddleware(next http.HandlerFunc) http.HandlerFunc
 	}
 }
 
+// authKeyMiddleware authenticates machine clients by "Bearer" API key with the scope,
+// other requests are authenticated by Telegram and allowed to admins only
+func (s *apiServer) authKeyMiddleware(scope entity.APIKeyScope) middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				chain(next, s.authMiddleware, s.authAdminMiddleware)(w, r)
+				return
+			}
+
+			span := sentry.StartSpan(
+				r.Context(),
+				"authKeyMiddleware",
+				sentry.ContinueFromHeaders(r.Header.Get(sentry.SentryTraceHeader), r.Header.Get(sentry.SentryBaggageHeader)),
+			)
+			defer span.Finish()
+
+			key, err := s.svc.AuthenticateAPIKey(r.Context(), secret)
+			switch {
+			case errors.Is(err, service.ErrNotFound):
+				s.log.Errorf(r.Context(), "invalid api key")
+				s.writeError(r.Context(), w, Error{Code: http.StatusUnauthorized, Description: "Invalid token"})
+
+				return
+			case err != nil:
+				s.log.Errorf(r.Context(), "failed to authenticate api key: %v", err)
+				s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+				return
+			}
+
+			span.SetData("apiKey", key.GUID.String())
+
+			if !key.HasScope(scope) {
+				s.log.Errorf(r.Context(), "api key %s has no scope %s", key.GUID, scope)
+				s.writeError(r.Context(), w, Error{Code: http.StatusForbidden, Description: "Forbidden"})
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
 func (s *apiServer) handleCompletedSurveys(w http.ResponseWriter, r *http.Request) {
 	span := sentry.StartSpan(r.Context(), "handleCompletedSurveys")
 	defer span.Finish()
@@ -197,9 +294,14 @@ func (s *apiServer) handleCompletedSurveys(w http.ResponseWriter, r *http.Reques
 	for i, survey := range surveysResults {
 		response.CompetetedSurveys = append(response.CompetetedSurveys, CompletedSurvey{
 			ID:          fmt.Sprintf("%d", i),
+			SurveyGUID:  survey.SurveyGUID.String(),
 			Name:        survey.SurveyName,
 			Description: survey.Description,
+			StartedAt:   survey.StartedAt,
+			FinishedAt:  survey.FinishedAt,
 			Results:     survey.Results.Text,
+			Scales:      newScaleResults(survey.Results.Scales),
+			Changes:     newScaleChanges(survey.Results.Changes),
 		})
 	}
 
@@ -211,6 +313,189 @@ func (s *apiServer) handleCompletedSurveys(w http.ResponseWriter, r *http.Reques
 	s.writeResponse(r.Context(), w, response)
 }
 
+// handleSurveyChart returns PNG chart of the finished attempt of the survey
+func (s *apiServer) handleSurveyChart(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleSurveyChart")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userID, ok := r.Context().Value(userIDKeyType).(int64)
+	if !ok {
+		s.log.Errorf(r.Context(), "failed to get userID from context")
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	surveyGUID, startedAt, err := parseAttempt(r)
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse attempt: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	kind, err := chart.ParseKind(r.URL.Query().Get("kind"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse kind: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	survey, err := s.svc.GetCompletedSurvey(r.Context(), userID, surveyGUID, startedAt)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s started at %s not found", surveyGUID, startedAt)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to get completed survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	image, err := chart.Render(kind, survey.SurveyName, survey.Results.Scales)
+	switch {
+	case errors.Is(err, chart.ErrNoScales):
+		s.log.Errorf(r.Context(), "survey %s has no scales", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to render chart: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.writeFile(r.Context(), w, "image/png", image)
+}
+
+// parseAttempt returns the survey and the time the attempt is started at, they identify the finished attempt
+// the same way regardless of other surveys finished by the user
+func parseAttempt(r *http.Request) (uuid.UUID, time.Time, error) {
+	surveyGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		return uuid.Nil, time.Time{}, fmt.Errorf("failed to parse survey guid: %w", err)
+	}
+
+	startedAt, err := time.Parse(time.RFC3339Nano, r.PathValue("started_at"))
+	if err != nil {
+		return uuid.Nil, time.Time{}, fmt.Errorf("failed to parse started_at: %w", err)
+	}
+
+	return surveyGUID, startedAt, nil
+}
+
+// handleSurveyReport returns PDF report of the finished attempt of the survey
+func (s *apiServer) handleSurveyReport(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleSurveyReport")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userID, ok := r.Context().Value(userIDKeyType).(int64)
+	if !ok {
+		s.log.Errorf(r.Context(), "failed to get userID from context")
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	surveyGUID, startedAt, err := parseAttempt(r)
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse attempt: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	withAnswers := r.URL.Query().Get("answers") == "true"
+
+	report, err := s.svc.GetCompletedSurveyReport(r.Context(), userID, surveyGUID, startedAt, withAnswers)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s started at %s not found", surveyGUID, startedAt)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to get report: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.writeFile(r.Context(), w, "application/pdf", report)
+}
+
+// handleSurveyAttempts returns all finished attempts of the survey by the user, the oldest first
+func (s *apiServer) handleSurveyAttempts(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleSurveyAttempts")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userID, ok := r.Context().Value(userIDKeyType).(int64)
+	if !ok {
+		s.log.Errorf(r.Context(), "failed to get userID from context")
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	surveyGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse survey guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	attempts, err := s.svc.GetSurveyAttempts(r.Context(), userID, surveyGUID)
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to get survey attempts: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	response := SurveyAttempts{Attempts: []SurveyAttempt{}}
+	for _, attempt := range attempts {
+		if attempt.Results == nil {
+			continue
+		}
+
+		response.Attempts = append(response.Attempts, SurveyAttempt{
+			StartedAt:  attempt.StartedAt,
+			FinishedAt: attempt.FinishedAt,
+			Scales:     newScaleResults(attempt.Results.Scales),
+			Changes:    newScaleChanges(attempt.Results.Changes),
+		})
+	}
+
+	s.writeResponse(r.Context(), w, response)
+}
+
 func (s *apiServer) handleIsAdmin(w http.ResponseWriter, r *http.Request) {
 	span := sentry.StartSpan(r.Context(), "handleIsAdmin")
 	defer span.Finish()
@@ -294,6 +579,11 @@ func (s *apiServer) handleUsersList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// frontend expects an empty array if there are no users
+	if usersList.Users == nil {
+		usersList.Users = []service.UserReport{}
+	}
+
 	s.writeResponse(r.Context(), w, usersList)
 }
 
@@ -302,37 +592,149 @@ type CompletedSurveys struct {
 }
 
 type CompletedSurvey struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Results     string `json:"results"`
+	ID          string        `json:"id"`
+	SurveyGUID  string        `json:"survey_guid"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	StartedAt   time.Time     `json:"started_at"`
+	FinishedAt  time.Time     `json:"finished_at"`
+	Results     string        `json:"results"`
+	Scales      []ScaleResult `json:"scales"`
+
+	// changes relatively to the previous attempt, empty for the first attempt
+	Changes []ScaleChange `json:"changes"`
+}
+
+type SurveyAttempts struct {
+	Attempts []SurveyAttempt `json:"attempts"`
+}
+
+type SurveyAttempt struct {
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Scales     []ScaleResult `json:"scales"`
+	Changes    []ScaleChange `json:"changes"`
+}
+
+type ScaleChange struct {
+	Key      string  `json:"key"`
+	Name     string  `json:"name"`
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+	Delta    float64 `json:"delta"`
+	Trend    string  `json:"trend"`
+	Attempts int     `json:"attempts"`
+}
+
+type ScaleResult struct {
+	Key   string  `json:"key"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+	Level string  `json:"level,omitempty"`
+
+	// empty if norms for the scale are not available
+	Percentile *float64 `json:"percentile,omitempty"`
+	ZScore     *float64 `json:"z_score,omitempty"`
+	SampleSize int      `json:"sample_size,omitempty"`
+}
+
+func newScaleResults(scales []entity.ResultsScale) []ScaleResult {
+	result := []ScaleResult{}
+	for _, scale := range scales {
+		item := ScaleResult{
+			Key:   scale.Key,
+			Name:  scale.Name,
+			Score: scale.Score,
+			Level: scale.Level,
+		}
+
+		if scale.Norm != nil {
+			percentile, zScore := scale.Norm.Percentile, scale.Norm.ZScore
+			item.Percentile = &percentile
+			item.ZScore = &zScore
+			item.SampleSize = scale.Norm.SampleSize
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+func newScaleChanges(changes []entity.ScaleChange) []ScaleChange {
+	result := []ScaleChange{}
+	for _, change := range changes {
+		result = append(result, ScaleChange{
+			Key:      change.Key,
+			Name:     change.Name,
+			Previous: change.Previous,
+			Current:  change.Current,
+			Delta:    change.Delta,
+			Trend:    string(change.Trend),
+			Attempts: change.Attempts,
+		})
+	}
+
+	return result
 }
 
+// ErrorCode is a machine-readable reason of the error, the same values are listed in the spec
+type ErrorCode string
+
+const (
+	ErrorCodeBadRequest            ErrorCode = "bad_request"
+	ErrorCodeInvalidRequest        ErrorCode = "invalid_request"
+	ErrorCodeInvalidSurvey         ErrorCode = "invalid_survey"
+	ErrorCodeInvalidAnswer         ErrorCode = "invalid_answer"
+	ErrorCodeInvalidCursor         ErrorCode = "invalid_cursor"
+	ErrorCodeSurveyAlreadyFinished ErrorCode = "survey_already_finished"
+	ErrorCodeUnauthorized          ErrorCode = "unauthorized"
+	ErrorCodeForbidden             ErrorCode = "forbidden"
+	ErrorCodeNotFound              ErrorCode = "not_found"
+	ErrorCodeMethodNotAllowed      ErrorCode = "method_not_allowed"
+	ErrorCodeInternal              ErrorCode = "internal_error"
+)
+
 type Error struct {
-	Code        int    `json:"code"`
-	Description string `json:"description"`
+	// HTTP status code of the response
+	Code int `json:"code"`
+
+	// defaults to the code of the HTTP status if empty
+	ErrorCode   ErrorCode `json:"error_code"`
+	Description string    `json:"description"`
 }
 
-func (s *apiServer) optionsResponse(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Methods", "GET")
-	w.Header().Set("Access-Control-Allow-Origin", s.allowedOrigins)
-	w.Header().Set("Access-Control-Allow-Headers", "Authorization, sentry-trace, baggage")
+func errorCodeByStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrorCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrorCodeForbidden
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrorCodeMethodNotAllowed
+	default:
+		return ErrorCodeInternal
+	}
 }
 
 func (s *apiServer) writeError(ctx context.Context, w http.ResponseWriter, err Error) {
 	span := sentry.StartSpan(ctx, "writeError")
 	defer span.Finish()
 
-	w.Header().Set("Access-Control-Allow-Methods", "GET")
-	w.Header().Set("Access-Control-Allow-Origin", s.allowedOrigins)
-	w.Header().Set("Access-Control-Allow-Headers", "Authorization, sentry-trace, baggage")
+	if err.ErrorCode == "" {
+		err.ErrorCode = errorCodeByStatus(err.Code)
+	}
 
 	s.log.Errorf(ctx, "response error: %+v", err)
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.Code)
 	if err := json.NewEncoder(w).Encode(err); err != nil {
 		s.log.Errorf(ctx, "failed to write error: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
@@ -340,13 +742,19 @@ func (s *apiServer) writeResponse(ctx context.Context, w http.ResponseWriter, da
 	span := sentry.StartSpan(ctx, "writeResponse")
 	defer span.Finish()
 
-	w.Header().Set("Access-Control-Allow-Methods", "GET")
-	w.Header().Set("Access-Control-Allow-Origin", s.allowedOrigins)
-	w.Header().Set("Access-Control-Allow-Headers", "Authorization, sentry-trace, baggage")
-
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		s.log.Errorf(ctx, "failed to write response: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
+
+func (s *apiServer) writeFile(ctx context.Context, w http.ResponseWriter, contentType string, data []byte) {
+	span := sentry.StartSpan(ctx, "writeFile")
+	defer span.Finish()
+
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write(data); err != nil {
+		s.log.Errorf(ctx, "failed to write response: %v", err)
+	}
+}
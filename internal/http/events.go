@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// eventsHeartbeatInterval keeps the stream open through proxies closing idle connections
+const eventsHeartbeatInterval = 15 * time.Second
+
+// handleAdminEvents streams live events of users activity as Server-Sent Events until the client disconnects
+// or the server is shutting down
+func (s *apiServer) handleAdminEvents(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleAdminEvents")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	// the stream lasts longer than write timeout of the server
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.log.Errorf(r.Context(), "failed to reset write deadline: %v", err)
+	}
+
+	events, unsubscribe := s.svc.SubscribeEvents(r.Context())
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx buffers responses by default
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...any) error {
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	}
+
+	if err := write(": connected\n\n"); err != nil {
+		s.log.Errorf(r.Context(), "failed to write event: %v", err)
+		return
+	}
+
+	ticker := time.NewTicker(eventsHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+			err = write(": heartbeat\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, jsonErr := json.Marshal(event)
+			if jsonErr != nil {
+				s.log.Errorf(r.Context(), "failed to marshal event: %v", jsonErr)
+				continue
+			}
+
+			err = write("event: %s\ndata: %s\n\n", event.Type, data)
+		}
+
+		if err != nil {
+			s.log.Errorf(r.Context(), "failed to write event: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service/mocks"
+)
+
+func TestAdminEvents(t *testing.T) {
+	events := make(chan entity.Event, 1)
+	unsubscribed := make(chan struct{})
+
+	svc := mocks.NewService(t)
+	svc.On("SubscribeEvents", mock.Anything).Return((<-chan entity.Event)(events), func() { close(unsubscribed) })
+
+	server, err := NewAPIServer(0, testToken, nil, []int64{testAdminID}, svc, logger.New("test", "info", "test", io.Discard))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(server.server.Handler)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/admin/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", tmaAuth(t, testAdminID))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readMessage := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	require.Equal(t, ": connected\n", readMessage())
+
+	event := entity.Event{
+		Type:       entity.EventSurveyFinished,
+		UserGUID:   testUserGUID,
+		Nickname:   "user",
+		SurveyGUID: &testSurveyGUID,
+		SurveyName: "Survey",
+		Answered:   2,
+		Questions:  2,
+		CreatedAt:  testTime,
+	}
+	events <- event
+
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	require.Equal(t, "event: survey_finished\ndata: "+string(data)+"\n", readMessage())
+
+	// the subscription is cancelled when the client disconnects
+	cancel()
+	<-unsubscribed
+}
+
+func TestAdminEvents_NotAdmin(t *testing.T) {
+	server, err := NewAPIServer(0, testToken, nil, []int64{testAdminID}, mocks.NewService(t), logger.New("test", "info", "test", io.Discard))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/events", nil)
+	req.Header.Set("Authorization", tmaAuth(t, testUserID))
+
+	rec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// readinessTimeout limits time of all readiness checks
+const readinessTimeout = 5 * time.Second
+
+type (
+	// ReadinessCheck is a dependency of the bot, the bot is not ready if any readiness check fails
+	ReadinessCheck struct {
+		Name  string
+		Check func(ctx context.Context) error
+	}
+
+	BuildInfo struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit"`
+		GoVersion string `json:"go_version"`
+	}
+
+	Readiness struct {
+		Status string           `json:"status"`
+		Checks []ReadinessState `json:"checks"`
+	}
+
+	ReadinessState struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+
+		// empty if the check is passed
+		Error string `json:"error,omitempty"`
+	}
+)
+
+// NewBuildInfo returns build info of the binary, commit is taken from the VCS info of the build if it is empty
+func NewBuildInfo(version, commit string) BuildInfo {
+	if info, ok := debug.ReadBuildInfo(); ok && commit == "" {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				commit = setting.Value
+			}
+		}
+	}
+
+	return BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// handleHealthz reports that the process is alive, failed health checks are only listed in the response
+func (s *metricsServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	states, _ := s.runChecks(ctx, s.healthChecks)
+
+	s.writeJSON(w, r, http.StatusOK, Readiness{Status: "ok", Checks: states})
+}
+
+// handleReadyz runs all readiness checks, the bot is not ready during the graceful shutdown as well
+func (s *metricsServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	response := Readiness{Status: "ok", Checks: []ReadinessState{}}
+
+	if s.shuttingDown.Load() {
+		response.Status = "failed"
+		response.Checks = append(response.Checks, ReadinessState{Name: "shutdown", Status: "failed", Error: "shutting down"})
+	}
+
+	states, ok := s.runChecks(ctx, s.checks)
+	if !ok {
+		response.Status = "failed"
+	}
+	response.Checks = append(response.Checks, states...)
+
+	status := http.StatusOK
+	if response.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	s.writeJSON(w, r, status, response)
+}
+
+// runChecks returns states of all checks and whether all of them passed
+func (s *metricsServer) runChecks(ctx context.Context, checks []ReadinessCheck) ([]ReadinessState, bool) {
+	states := []ReadinessState{}
+	ok := true
+
+	for _, check := range checks {
+		state := ReadinessState{Name: check.Name, Status: "ok"}
+		if err := check.Check(ctx); err != nil {
+			s.log.Errorf(ctx, "check %s failed: %v", check.Name, err)
+
+			ok = false
+			state.Status = "failed"
+			state.Error = err.Error()
+		}
+
+		states = append(states, state)
+	}
+
+	return states, ok
+}
+
+func (s *metricsServer) handleVersion(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, s.buildInfo)
+}
+
+func (s *metricsServer) writeJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		s.log.Errorf(r.Context(), "failed to write response: %v", err)
+	}
+}
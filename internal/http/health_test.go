@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+)
+
+func TestReadyz(t *testing.T) {
+	var dbErr error
+
+	server := NewMetricsServer(0, []ReadinessCheck{
+		{Name: "db", Check: func(context.Context) error { return dbErr }},
+	}, []ReadinessCheck{
+		{Name: "listener", Check: func(context.Context) error { return errors.New("poller is not running") }},
+	}, NewBuildInfo("v1.0.0", "abc"), logger.New("test", "info", "test", io.Discard))
+
+	readyz := func() (int, Readiness) {
+		rec := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var got Readiness
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+
+		return rec.Code, got
+	}
+
+	// health checks don't affect readiness
+	status, got := readyz()
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, Readiness{Status: "ok", Checks: []ReadinessState{
+		{Name: "db", Status: "ok"},
+	}}, got)
+
+	dbErr = errors.New("connection refused")
+
+	status, got = readyz()
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, Readiness{Status: "failed", Checks: []ReadinessState{
+		{Name: "db", Status: "failed", Error: "connection refused"},
+	}}, got)
+
+	dbErr = nil
+	server.SetShuttingDown()
+
+	status, got = readyz()
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, Readiness{Status: "failed", Checks: []ReadinessState{
+		{Name: "shutdown", Status: "failed", Error: "shutting down"},
+		{Name: "db", Status: "ok"},
+	}}, got)
+}
+
+func TestHealthz(t *testing.T) {
+	var listenerErr error
+
+	server := NewMetricsServer(0, []ReadinessCheck{
+		{Name: "db", Check: func(context.Context) error { return errors.New("connection refused") }},
+	}, []ReadinessCheck{
+		{Name: "listener", Check: func(context.Context) error { return listenerErr }},
+	}, NewBuildInfo("v1.0.0", "abc"), logger.New("test", "info", "test", io.Discard))
+
+	healthz := func() (int, Readiness) {
+		rec := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		var got Readiness
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+
+		return rec.Code, got
+	}
+
+	status, got := healthz()
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, Readiness{Status: "ok", Checks: []ReadinessState{
+		{Name: "listener", Status: "ok"},
+	}}, got)
+
+	// the process is alive until it stops, failed checks are only reported
+	listenerErr = errors.New("poller is not running")
+	server.SetShuttingDown()
+
+	status, got = healthz()
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, Readiness{Status: "ok", Checks: []ReadinessState{
+		{Name: "listener", Status: "failed", Error: "poller is not running"},
+	}}, got)
+}
+
+func TestVersion(t *testing.T) {
+	server := NewMetricsServer(0, nil, nil, NewBuildInfo("v1.0.0", "abc"), logger.New("test", "info", "test", io.Discard))
+
+	rec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got BuildInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, BuildInfo{Version: "v1.0.0", Commit: "abc", GoVersion: runtime.Version()}, got)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -16,19 +17,44 @@ type Server interface {
 	Stop()
 }
 
+// metricsServer serves metrics, probes and build info on the port not exposed to users
 type metricsServer struct {
-	server *http.Server
-	log    logger.Logger
+	server    *http.Server
+	log       logger.Logger
+	checks    []ReadinessCheck
+	buildInfo BuildInfo
+
+	// healthChecks are reported by the liveness probe, they don't make the bot unready or restarted
+	healthChecks []ReadinessCheck
+
+	shuttingDown atomic.Bool
 }
 
-func NewMetricsServer(metricsPort int, log logger.Logger) *metricsServer {
-	return &metricsServer{
-		log: log,
+func NewMetricsServer(metricsPort int, checks, healthChecks []ReadinessCheck, buildInfo BuildInfo, log logger.Logger) *metricsServer {
+	server := &metricsServer{
+		log:          log,
+		checks:       checks,
+		healthChecks: healthChecks,
+		buildInfo:    buildInfo,
 		server: &http.Server{
-			Addr:    ":" + fmt.Sprintf("%d", metricsPort),
-			Handler: promhttp.Handler(),
+			Addr: ":" + fmt.Sprintf("%d", metricsPort),
 		},
 	}
+
+	handler := http.NewServeMux()
+	handler.Handle("/metrics", promhttp.Handler())
+	handler.HandleFunc("GET /healthz", server.handleHealthz)
+	handler.HandleFunc("GET /readyz", server.handleReadyz)
+	handler.HandleFunc("GET /version", server.handleVersion)
+
+	server.server.Handler = handler
+
+	return server
+}
+
+// SetShuttingDown makes the readiness probe fail, so no traffic is routed to the bot while it stops
+func (s *metricsServer) SetShuttingDown() {
+	s.shuttingDown.Store(true)
 }
 
 func (s *metricsServer) Start() {
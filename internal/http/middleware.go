@@ -0,0 +1,244 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength limits request id taken from the client
+	maxRequestIDLength = 128
+
+	// unmatchedRoute is a metrics label of requests not matched by any route, so paths don't blow up cardinality
+	unmatchedRoute = "unmatched"
+)
+
+var (
+	apiRequestsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_requests_total",
+			Help: "Total number of requests processed by the API server",
+		},
+		[]string{"route", "method", "status"},
+	)
+	apiRequestsDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "api_requests_duration_seconds",
+			Help:    "Duration of request processing by the API server",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "method"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(apiRequestsCounter)
+	prometheus.MustRegister(apiRequestsDuration)
+}
+
+// middleware wraps the handler, e.g. to authenticate the request before it
+type middleware func(next http.HandlerFunc) http.HandlerFunc
+
+// chain wraps the handler by middlewares, the first middleware is the outermost one
+func chain(handler http.HandlerFunc, middlewares ...middleware) http.HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// statusRecorder remembers status and size of the response for logs and metrics
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(data)
+	r.size += n
+
+	return n, err
+}
+
+// Unwrap allows http.ResponseController to reach the original writer, e.g. to flush streamed responses
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func recordStatus(w http.ResponseWriter) *statusRecorder {
+	if recorder, ok := w.(*statusRecorder); ok {
+		return recorder
+	}
+
+	return &statusRecorder{ResponseWriter: w}
+}
+
+// requestIDMiddleware takes request id from the header or generates it, the id is added to the logs and the response
+func (s *apiServer) requestIDMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+
+		ctx := context.WithValue(r.Context(), logger.ReqID, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// accessLogMiddleware logs every request with its route, status and duration
+func (s *apiServer) accessLogMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := recordStatus(w)
+
+		next.ServeHTTP(recorder, r)
+
+		s.log.WithFields(map[string]any{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"route":       s.route(r),
+			"status":      recorder.status,
+			"size":        recorder.size,
+			"duration_ms": time.Since(start).Milliseconds(),
+			"remote_addr": r.RemoteAddr,
+		}).Infof(r.Context(), "%s %s %d", r.Method, r.URL.Path, recorder.status)
+	}
+}
+
+// metricsMiddleware counts requests and observes their duration by route patterns
+func (s *apiServer) metricsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route := s.route(r)
+		timer := prometheus.NewTimer(apiRequestsDuration.WithLabelValues(route, r.Method))
+		recorder := recordStatus(w)
+
+		next.ServeHTTP(recorder, r)
+
+		timer.ObserveDuration()
+		apiRequestsCounter.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+	}
+}
+
+// recoveryMiddleware turns panic of the handler into the internal error, so one request can't stop the server
+func (s *apiServer) recoveryMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := recordStatus(w)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			// the server aborts the response itself
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			s.log.Errorf(r.Context(), "panic: %v\n%s", rec, debug.Stack())
+
+			if recorder.status == 0 {
+				s.writeError(r.Context(), recorder, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	}
+}
+
+// corsMiddleware allows the origins from the list and answers preflight requests
+func (s *apiServer) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+
+		if origin := r.Header.Get("Origin"); origin != "" && s.isAllowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, sentry-trace, baggage, "+requestIDHeader)
+			w.Header().Set("Access-Control-Expose-Headers", requestIDHeader+", Content-Disposition")
+		}
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// isAllowedOrigin matches the origin with the list, "*" allows any origin,
+// "https://*.example.com" allows subdomains of example.com
+func (s *apiServer) isAllowedOrigin(origin string) bool {
+	for _, allowed := range s.allowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+
+		if prefix, suffix, ok := strings.Cut(allowed, "*."); ok &&
+			strings.HasPrefix(origin, prefix) &&
+			strings.HasSuffix(origin, "."+suffix) &&
+			len(origin) > len(prefix)+len(suffix)+1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// route returns the pattern of the route handling the request
+func (s *apiServer) route(r *http.Request) string {
+	if _, pattern := s.mux.Handler(r); pattern != "" {
+		return pattern
+	}
+
+	return unmatchedRoute
+}
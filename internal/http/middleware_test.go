@@ -0,0 +1,139 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service/mocks"
+)
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+
+	server, err := NewAPIServer(0, testToken, nil, nil, mocks.NewService(t), logger.New("test", "info", "test", &logs))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/is-admin", nil))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	requestID := rec.Header().Get(requestIDHeader)
+	_, err = uuid.Parse(requestID)
+	require.NoError(t, err)
+
+	// the id of the client is kept
+	req := httptest.NewRequest(http.MethodGet, "/api/is-admin", nil)
+	req.Header.Set(requestIDHeader, "client-request-id")
+
+	rec = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, req)
+	require.Equal(t, "client-request-id", rec.Header().Get(requestIDHeader))
+
+	var found bool
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(line, &entry))
+
+		if entry["req_id"] == "client-request-id" && entry["route"] == "/api/is-admin" {
+			require.Equal(t, float64(http.StatusUnauthorized), entry["status"])
+			found = true
+		}
+	}
+	require.True(t, found, logs.String())
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "exact origin", origin: "https://sv.ykonkov.com", allowed: true},
+		{name: "subdomain", origin: "https://admin.example.com", allowed: true},
+		{name: "nested subdomain", origin: "https://a.b.example.com", allowed: true},
+		{name: "wildcard doesn't match domain itself", origin: "https://example.com", allowed: false},
+		{name: "other scheme", origin: "http://admin.example.com", allowed: false},
+		{name: "suffix of other domain", origin: "https://notexample.com", allowed: false},
+		{name: "other origin", origin: "https://evil.com", allowed: false},
+	}
+
+	server, err := NewAPIServer(
+		0,
+		testToken,
+		[]string{"https://sv.ykonkov.com", "https://*.example.com"},
+		nil,
+		mocks.NewService(t),
+		logger.New("test", "info", "test", io.Discard),
+	)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/api/admin/surveys", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+
+			rec := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusNoContent, rec.Code)
+			require.Equal(t, "Origin", rec.Header().Get("Vary"))
+
+			if !tt.allowed {
+				require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+				return
+			}
+
+			require.Equal(t, tt.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			require.Equal(t, "GET, POST, PUT, PATCH, DELETE, OPTIONS", rec.Header().Get("Access-Control-Allow-Methods"))
+		})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	server, err := NewAPIServer(0, testToken, nil, nil, mocks.NewService(t), logger.New("test", "info", "test", io.Discard))
+	require.NoError(t, err)
+
+	handler := chain(func(http.ResponseWriter, *http.Request) {
+		panic("unexpected")
+	}, server.requestIDMiddleware, server.recoveryMiddleware)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/surveys", nil))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	var got Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, Error{Code: http.StatusInternalServerError, ErrorCode: ErrorCodeInternal, Description: "Internal Server Error"}, got)
+}
+
+func TestMetrics(t *testing.T) {
+	server, err := NewAPIServer(0, testToken, nil, nil, mocks.NewService(t), logger.New("test", "info", "test", io.Discard))
+	require.NoError(t, err)
+
+	counter := apiRequestsCounter.WithLabelValues("/api/is-admin", http.MethodGet, "401")
+	before := testutil.ToFloat64(counter)
+
+	rec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/is-admin", nil))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, before+1, testutil.ToFloat64(counter))
+
+	// unknown paths are not separate series
+	unmatched := apiRequestsCounter.WithLabelValues(unmatchedRoute, http.MethodGet, "404")
+	before = testutil.ToFloat64(unmatched)
+
+	rec = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/unknown/123", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, before+1, testutil.ToFloat64(unmatched))
+}
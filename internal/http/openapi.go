@@ -0,0 +1,127 @@
+package http
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/getsentry/sentry-go"
+)
+
+// openAPISpec describes all endpoints of the API server, requests are validated against it
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func loadOpenAPI() (*openapi3.T, routers.Router, error) {
+	loader := openapi3.NewLoader()
+
+	doc, err := loader.LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load spec: %w", err)
+	}
+
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, nil, fmt.Errorf("failed to validate spec: %w", err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create router: %w", err)
+	}
+
+	return doc, router, nil
+}
+
+func (s *apiServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleOpenAPI")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	s.writeFile(r.Context(), w, "application/json", openAPISpec)
+}
+
+// bodySizes limits bodies of the operations larger than maxRequestSize
+var bodySizes = map[string]int64{
+	"createSurvey": maxSurveySize,
+	"updateSurvey": maxSurveySize,
+}
+
+// validateMiddleware validates parameters and body of the request against the spec, it runs after the
+// authentication middlewares of the route. Requests not described in the spec are left to the handlers.
+func (s *apiServer) validateMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := s.openAPI.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// body is read by validation, so it is limited the same as by the handlers
+		size, ok := bodySizes[route.Operation.OperationID]
+		if !ok {
+			size = maxRequestSize
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, size)
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			s.log.Errorf(r.Context(), "invalid request: %v", err)
+			s.writeError(r.Context(), w, Error{
+				Code:        http.StatusBadRequest,
+				ErrorCode:   ErrorCodeInvalidRequest,
+				Description: validationErrorText(err),
+			})
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// validationErrorText returns short description of the validation error without the schema dump
+func validationErrorText(err error) string {
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return "Invalid request"
+	}
+
+	reason := reqErr.Reason
+	var schemaErr *openapi3.SchemaError
+	switch {
+	case errors.As(reqErr.Err, &schemaErr):
+		reason = schemaErr.Reason
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			reason = fmt.Sprintf("%s: %s", strings.Join(pointer, "."), reason)
+		}
+	case reqErr.Err != nil:
+		reason = reqErr.Err.Error()
+	}
+
+	if reqErr.Parameter != nil {
+		return fmt.Sprintf("Invalid %s parameter %s: %s", reqErr.Parameter.In, reqErr.Parameter.Name, reason)
+	}
+
+	return fmt.Sprintf("Invalid request body: %s", reason)
+}
@@ -0,0 +1,601 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service/mocks"
+)
+
+const (
+	testToken   = "test-token"
+	testUserID  = int64(101)
+	testAdminID = int64(102)
+)
+
+var (
+	testSurveyGUID = uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	testUserGUID   = uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C")
+	testTime       = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testSurvey = entity.Survey{
+		GUID:             testSurveyGUID,
+		ID:               1,
+		Name:             "Survey",
+		Description:      "Description",
+		CalculationsType: "test_1",
+		Questions: []entity.Question{
+			{
+				Text:            "Question 1?",
+				AnswerType:      entity.AnswerTypeSelect,
+				PossibleAnswers: []int{1, 2},
+				AnswersText:     []string{"variant 1", "variant 2"},
+			},
+			{
+				Text:            "Question 2?",
+				AnswerType:      entity.AnswerTypeSegment,
+				PossibleAnswers: []int{1, 5},
+			},
+		},
+	}
+
+	testResults = &entity.Results{
+		Text:   "Results",
+		Scales: []entity.ResultsScale{{Key: "scale", Name: "Scale", Score: 3, Level: "high"}},
+	}
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	doc, _, err := loadOpenAPI()
+	require.NoError(t, err)
+
+	var enum []ErrorCode
+	for _, value := range doc.Components.Schemas["Error"].Value.Properties["error_code"].Value.Enum {
+		enum = append(enum, ErrorCode(value.(string)))
+	}
+
+	require.ElementsMatch(t, []ErrorCode{
+		ErrorCodeBadRequest,
+		ErrorCodeInvalidRequest,
+		ErrorCodeInvalidSurvey,
+		ErrorCodeInvalidAnswer,
+		ErrorCodeInvalidCursor,
+		ErrorCodeSurveyAlreadyFinished,
+		ErrorCodeUnauthorized,
+		ErrorCodeForbidden,
+		ErrorCodeNotFound,
+		ErrorCodeMethodNotAllowed,
+		ErrorCodeInternal,
+	}, enum)
+}
+
+// TestHandlersMatchSpec checks that statuses and bodies of the responses are described in the spec
+func TestHandlersMatchSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		auth      string
+		body      string
+		setup     func(svc *mocks.Service)
+		status    int
+		errorCode ErrorCode
+	}{
+		{
+			name:   "openapi",
+			method: http.MethodGet,
+			path:   "/api/openapi.json",
+			status: http.StatusOK,
+		},
+		{
+			name:      "no auth header",
+			method:    http.MethodGet,
+			path:      "/api/surveys",
+			status:    http.StatusUnauthorized,
+			errorCode: ErrorCodeUnauthorized,
+		},
+		{
+			name:      "invalid token",
+			method:    http.MethodGet,
+			path:      "/api/surveys",
+			auth:      "tma user=1&hash=invalid",
+			status:    http.StatusUnauthorized,
+			errorCode: ErrorCodeUnauthorized,
+		},
+		{
+			name:   "completed surveys",
+			method: http.MethodGet,
+			path:   "/api/surveys",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetCompletedSurveys", mock.Anything, testUserID).Return([]entity.SurveyStateReport{{
+					SurveyGUID: testSurveyGUID,
+					SurveyName: testSurvey.Name,
+					FinishedAt: testTime,
+					Results:    testResults,
+				}}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:      "completed surveys, method not allowed",
+			method:    http.MethodPut,
+			path:      "/api/surveys",
+			auth:      tmaAuth(t, testUserID),
+			status:    http.StatusMethodNotAllowed,
+			errorCode: ErrorCodeMethodNotAllowed,
+		},
+		{
+			name:   "chart",
+			method: http.MethodGet,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/attempts/" + testTime.Format(time.RFC3339Nano) + "/chart.png?kind=bar",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetCompletedSurvey", mock.Anything, testUserID, testSurveyGUID, testTime).Return(entity.SurveyStateReport{
+					SurveyGUID: testSurveyGUID,
+					SurveyName: testSurvey.Name,
+					StartedAt:  testTime,
+					FinishedAt: testTime,
+					Results:    testResults,
+				}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:      "chart, invalid started_at",
+			method:    http.MethodGet,
+			path:      "/api/surveys/" + testSurveyGUID.String() + "/attempts/yesterday/chart.png",
+			auth:      tmaAuth(t, testUserID),
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidRequest,
+		},
+		{
+			name:   "report, not found",
+			method: http.MethodGet,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/attempts/" + testTime.Format(time.RFC3339Nano) + "/report.pdf",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetCompletedSurveyReport", mock.Anything, testUserID, testSurveyGUID, testTime, false).Return(nil, service.ErrNotFound)
+			},
+			status:    http.StatusNotFound,
+			errorCode: ErrorCodeNotFound,
+		},
+		{
+			name:   "survey attempts",
+			method: http.MethodGet,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/attempts",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetSurveyAttempts", mock.Anything, testUserID, testSurveyGUID).Return([]entity.SurveyStateReport{{
+					SurveyGUID: testSurveyGUID,
+					StartedAt:  testTime,
+					FinishedAt: testTime,
+					Results:    testResults,
+				}}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "available surveys",
+			method: http.MethodGet,
+			path:   "/api/surveys/available",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetUserSurveys", mock.Anything, testUserID).Return([]service.UserSurveyState{{
+					Survey:    testSurvey,
+					State:     entity.ActiveState,
+					IsCurrent: true,
+				}}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "start survey",
+			method: http.MethodPost,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/start",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("StartSurvey", mock.Anything, mock.Anything, testSurveyGUID).Return(service.SurveyProgress{
+					Survey:   testSurvey,
+					State:    entity.SurveyState{State: entity.ActiveState},
+					Question: &testSurvey.Questions[0],
+				}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "start survey, not found",
+			method: http.MethodPost,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/start",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("StartSurvey", mock.Anything, mock.Anything, testSurveyGUID).Return(service.SurveyProgress{}, service.ErrNotFound)
+			},
+			status:    http.StatusNotFound,
+			errorCode: ErrorCodeNotFound,
+		},
+		{
+			name:   "start survey, already finished",
+			method: http.MethodPost,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/start",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("StartSurvey", mock.Anything, mock.Anything, testSurveyGUID).Return(service.SurveyProgress{}, service.ErrSurveyAlreadyFinished)
+			},
+			status:    http.StatusConflict,
+			errorCode: ErrorCodeSurveyAlreadyFinished,
+		},
+		{
+			name:   "survey question",
+			method: http.MethodGet,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/question",
+			auth:   tmaAuth(t, testUserID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetSurveyProgress", mock.Anything, testUserID, testSurveyGUID).Return(service.SurveyProgress{
+					Survey:   testSurvey,
+					State:    entity.SurveyState{State: entity.ActiveState, Answers: []entity.Answer{{Type: entity.AnswerTypeSelect, Data: []int{1}}}},
+					Question: &testSurvey.Questions[1],
+				}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:      "answer survey, body too large",
+			method:    http.MethodPost,
+			path:      "/api/surveys/" + testSurveyGUID.String() + "/answers",
+			auth:      tmaAuth(t, testUserID),
+			body:      `{"answer": "3"` + strings.Repeat(" ", maxRequestSize) + `}`,
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidRequest,
+		},
+		{
+			name:   "answer survey, finished",
+			method: http.MethodPost,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/answers",
+			auth:   tmaAuth(t, testUserID),
+			body:   `{"answer": "3"}`,
+			setup: func(svc *mocks.Service) {
+				svc.On("AnswerSurvey", mock.Anything, testUserID, testSurveyGUID, "3").Return(service.SurveyProgress{
+					Survey: testSurvey,
+					State:  entity.SurveyState{State: entity.FinishedState, Results: testResults},
+				}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "answer survey, invalid answer",
+			method: http.MethodPost,
+			path:   "/api/surveys/" + testSurveyGUID.String() + "/answers",
+			auth:   tmaAuth(t, testUserID),
+			body:   `{"answer": "10"}`,
+			setup: func(svc *mocks.Service) {
+				svc.On("AnswerSurvey", mock.Anything, testUserID, testSurveyGUID, "10").Return(service.SurveyProgress{}, service.ErrInvalidAnswer)
+			},
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidAnswer,
+		},
+		{
+			name:      "answer survey, answer is not string",
+			method:    http.MethodPost,
+			path:      "/api/surveys/" + testSurveyGUID.String() + "/answers",
+			auth:      tmaAuth(t, testUserID),
+			body:      `{"answer": 3}`,
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidRequest,
+		},
+		{
+			name:   "is admin",
+			method: http.MethodGet,
+			path:   "/api/is-admin",
+			auth:   tmaAuth(t, testUserID),
+			status: http.StatusOK,
+		},
+		{
+			name:      "users list, not admin",
+			method:    http.MethodGet,
+			path:      "/api/admin/users",
+			auth:      tmaAuth(t, testUserID),
+			status:    http.StatusForbidden,
+			errorCode: ErrorCodeForbidden,
+		},
+		{
+			name:   "users list, empty",
+			method: http.MethodGet,
+			path:   "/api/admin/users?limit=5",
+			auth:   tmaAuth(t, testAdminID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetUsersList", mock.Anything, 5, 0, "").Return(service.UserListResponse{}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:      "users list, invalid limit",
+			method:    http.MethodGet,
+			path:      "/api/admin/users?limit=abc",
+			auth:      tmaAuth(t, testAdminID),
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidRequest,
+		},
+		{
+			name:      "users list, invalid limit without auth",
+			method:    http.MethodGet,
+			path:      "/api/admin/users?limit=abc",
+			status:    http.StatusUnauthorized,
+			errorCode: ErrorCodeUnauthorized,
+		},
+		{
+			name:      "users list, invalid limit not by admin",
+			method:    http.MethodGet,
+			path:      "/api/admin/users?limit=abc",
+			auth:      tmaAuth(t, testUserID),
+			status:    http.StatusForbidden,
+			errorCode: ErrorCodeForbidden,
+		},
+		{
+			name:   "user details",
+			method: http.MethodGet,
+			path:   "/api/admin/users/" + testUserGUID.String(),
+			auth:   tmaAuth(t, testAdminID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetUserDetails", mock.Anything, testUserGUID).Return(service.UserDetails{
+					User: entity.User{GUID: testUserGUID, UserID: testUserID, CurrentSurvey: &testSurveyGUID},
+					Surveys: []service.UserSurveyDetails{{
+						Report:  entity.SurveyStateReport{SurveyGUID: testSurveyGUID, State: entity.FinishedState, Results: testResults},
+						Answers: []service.QuestionAnswer{{Question: "Question 1?", Answer: "variant 1"}},
+					}},
+				}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "delete user",
+			method: http.MethodDelete,
+			path:   "/api/admin/users/" + testUserGUID.String(),
+			auth:   tmaAuth(t, testAdminID),
+			setup: func(svc *mocks.Service) {
+				svc.On("DeleteUser", mock.Anything, testUserGUID).Return(nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "results by api key",
+			method: http.MethodGet,
+			path:   "/api/admin/results?limit=10",
+			auth:   "Bearer sbk_test",
+			setup: func(svc *mocks.Service) {
+				svc.On("AuthenticateAPIKey", mock.Anything, "sbk_test").Return(entity.APIKey{
+					Scopes: []entity.APIKeyScope{entity.APIKeyScopeResultsRead},
+				}, nil)
+				svc.On("GetResults", mock.Anything, service.ResultsFilter{}, "", 10).Return(service.ResultsPage{
+					Results: []entity.SurveyStateReport{{
+						SurveyGUID: testSurveyGUID,
+						UserGUID:   testUserGUID.String(),
+						Answers:    []entity.Answer{{Type: entity.AnswerTypeSelect, Data: []int{1}}},
+						Results:    testResults,
+					}},
+					NextCursor: "cursor",
+				}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "results, invalid cursor",
+			method: http.MethodGet,
+			path:   "/api/admin/results?cursor=invalid",
+			auth:   tmaAuth(t, testAdminID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetResults", mock.Anything, service.ResultsFilter{}, "invalid", defaultResultsLimit).Return(service.ResultsPage{}, service.ErrInvalidCursor)
+			},
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidCursor,
+		},
+		{
+			name:      "results, limit out of range",
+			method:    http.MethodGet,
+			path:      "/api/admin/results?limit=1000",
+			auth:      tmaAuth(t, testAdminID),
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidRequest,
+		},
+		{
+			name:   "results, api key without scope",
+			method: http.MethodGet,
+			path:   "/api/admin/results",
+			auth:   "Bearer sbk_test",
+			setup: func(svc *mocks.Service) {
+				svc.On("AuthenticateAPIKey", mock.Anything, "sbk_test").Return(entity.APIKey{
+					Scopes: []entity.APIKeyScope{entity.APIKeyScopeSurveysManage},
+				}, nil)
+			},
+			status:    http.StatusForbidden,
+			errorCode: ErrorCodeForbidden,
+		},
+		{
+			name:   "surveys",
+			method: http.MethodGet,
+			path:   "/api/admin/surveys",
+			auth:   tmaAuth(t, testAdminID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetSurveys", mock.Anything).Return([]entity.Survey{testSurvey}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "create survey, invalid",
+			method: http.MethodPost,
+			path:   "/api/admin/surveys",
+			auth:   tmaAuth(t, testAdminID),
+			body:   `{"name": "Survey", "description": "Description", "calculations_type": "unknown", "questions": [{"text": "Question?", "answer_type": "segment", "possible_answers": [1, 5]}]}`,
+			setup: func(svc *mocks.Service) {
+				svc.On("CreateSurvey", mock.Anything, mock.Anything).Return(entity.Survey{}, service.ErrInvalidSurvey)
+			},
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidSurvey,
+		},
+		{
+			name:      "create survey, unknown field",
+			method:    http.MethodPost,
+			path:      "/api/admin/surveys",
+			auth:      tmaAuth(t, testAdminID),
+			body:      `{"name": "Survey", "description": "Description", "calculations_type": "test_1", "questions": [], "unknown": 1}`,
+			status:    http.StatusBadRequest,
+			errorCode: ErrorCodeInvalidRequest,
+		},
+		{
+			name:   "delete survey, not found",
+			method: http.MethodDelete,
+			path:   "/api/admin/surveys/" + testSurveyGUID.String(),
+			auth:   tmaAuth(t, testAdminID),
+			setup: func(svc *mocks.Service) {
+				svc.On("DeleteSurvey", mock.Anything, testSurveyGUID, true).Return(service.ErrNotFound)
+			},
+			status:    http.StatusNotFound,
+			errorCode: ErrorCodeNotFound,
+		},
+		{
+			name:   "delete survey with active states",
+			method: http.MethodDelete,
+			path:   "/api/admin/surveys/" + testSurveyGUID.String(),
+			auth:   tmaAuth(t, testAdminID),
+			setup: func(svc *mocks.Service) {
+				svc.On("DeleteSurvey", mock.Anything, testSurveyGUID, true).Return(nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "survey analytics",
+			method: http.MethodGet,
+			path:   "/api/admin/surveys/" + testSurveyGUID.String() + "/analytics?abandoned_after=1",
+			auth:   tmaAuth(t, testAdminID),
+			setup: func(svc *mocks.Service) {
+				svc.On("GetSurveyAnalytics", mock.Anything, testSurveyGUID, 24*time.Hour).Return(service.SurveyAnalytics{
+					Survey: testSurvey,
+					SurveyStatesStats: service.SurveyStatesStats{
+						Started:  1,
+						Finished: 1,
+						Funnel:   []service.FunnelStep{{Answered: 0}, {Answered: 1, Count: 1}},
+					},
+					CompletionRate: 0.5,
+					Questions: []service.QuestionAnalytics{{
+						Question: testSurvey.Questions[0],
+						Answers:  []service.AnswerStats{{Value: 1, Text: "variant 1", Count: 1}},
+					}},
+				}, nil)
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	doc, router, err := loadOpenAPI()
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := mocks.NewService(t)
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+
+			server, err := NewAPIServer(0, testToken, []string{"*"}, []int64{testAdminID}, svc, logger.New("test", "info", "test", io.Discard))
+			require.NoError(t, err)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			rec := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+
+			if tt.errorCode != "" {
+				var got Error
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+				require.Equal(t, Error{Code: tt.status, ErrorCode: tt.errorCode, Description: got.Description}, got)
+			}
+
+			validateResponse(t, doc, router, tt.method, tt.path, rec)
+		})
+	}
+}
+
+// validateResponse checks the response against the spec, responses of the methods missing in the spec are checked by the error schema
+func validateResponse(t *testing.T, doc *openapi3.T, router routers.Router, method, path string, rec *httptest.ResponseRecorder) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+
+	route, pathParams, err := router.FindRoute(req)
+	if err != nil {
+		var value any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &value))
+		require.NoError(t, doc.Components.Schemas["Error"].Value.VisitJSON(value))
+
+		return
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+		},
+		Status: rec.Code,
+		Header: rec.Header(),
+		Body:   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			// images and reports are checked by the content type only
+			ExcludeResponseBody: !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json"),
+		},
+	}
+
+	require.NoError(t, openapi3filter.ValidateResponse(context.Background(), input))
+}
+
+// tmaAuth returns authorization header of the mini app signed by the test token
+func tmaAuth(t *testing.T, userID int64) string {
+	t.Helper()
+
+	user, err := json.Marshal(map[string]any{"id": userID, "first_name": "First", "last_name": "Last", "username": "user"})
+	require.NoError(t, err)
+
+	authDate := time.Now()
+	payload := map[string]string{"user": string(user)}
+
+	values := url.Values{}
+	values.Set("user", string(user))
+	values.Set("auth_date", strconv.FormatInt(authDate.Unix(), 10))
+	values.Set("hash", initdata.Sign(payload, testToken, authDate))
+
+	return "tma " + values.Encode()
+}
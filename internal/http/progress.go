@@ -0,0 +1,277 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+type AvailableSurveys struct {
+	Surveys []AvailableSurvey `json:"surveys"`
+}
+
+type AvailableSurvey struct {
+	GUID           string `json:"guid"`
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	State          string `json:"state"`
+	IsCurrent      bool   `json:"is_current"`
+	QuestionsCount int    `json:"questions_count"`
+}
+
+type SurveyProgress struct {
+	SurveyGUID     string `json:"survey_guid"`
+	Name           string `json:"name"`
+	State          string `json:"state"`
+	Answered       int    `json:"answered"`
+	QuestionsCount int    `json:"questions_count"`
+
+	// null if the survey is finished
+	Question *entity.Question `json:"question"`
+
+	// null until the survey is finished
+	Results *SurveyResults `json:"results"`
+}
+
+type SurveyResults struct {
+	Text    string        `json:"text"`
+	Scales  []ScaleResult `json:"scales"`
+	Changes []ScaleChange `json:"changes"`
+}
+
+type SurveyAnswerRequest struct {
+	// the same as the answer in the chat: a number or comma separated numbers for multiselect
+	Answer string `json:"answer"`
+}
+
+// handleAvailableSurveys returns all surveys with the state of the user, the same as in the survey list of the bot
+func (s *apiServer) handleAvailableSurveys(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleAvailableSurveys")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userID, ok := r.Context().Value(userIDKeyType).(int64)
+	if !ok {
+		s.log.Errorf(r.Context(), "failed to get userID from context")
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	states, err := s.svc.GetUserSurveys(r.Context(), userID)
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to get user surveys: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	response := AvailableSurveys{Surveys: []AvailableSurvey{}}
+	for _, state := range states {
+		response.Surveys = append(response.Surveys, AvailableSurvey{
+			GUID:           state.Survey.GUID.String(),
+			ID:             state.Survey.ID,
+			Name:           state.Survey.Name,
+			Description:    state.Survey.Description,
+			State:          string(state.State),
+			IsCurrent:      state.IsCurrent,
+			QuestionsCount: len(state.Survey.Questions),
+		})
+	}
+
+	s.writeResponse(r.Context(), w, response)
+}
+
+// handleStartSurvey starts the survey or resumes the active one, it can be continued in the bot as well
+func (s *apiServer) handleStartSurvey(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleStartSurvey")
+	defer span.Finish()
+
+	if r.Method != http.MethodPost {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userID, ok := r.Context().Value(userIDKeyType).(int64)
+	if !ok {
+		s.log.Errorf(r.Context(), "failed to get userID from context")
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+	nickname, _ := r.Context().Value(nicknameKeyType).(string)
+
+	surveyGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse survey guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	// mini app is opened from the private chat with the bot, so chat id equals to user id
+	identity := service.UserIdentity{UserID: userID, ChatID: userID, Nickname: nickname}
+
+	progress, err := s.svc.StartSurvey(r.Context(), identity, surveyGUID)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s not found", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case errors.Is(err, service.ErrSurveyAlreadyFinished):
+		s.log.Errorf(r.Context(), "survey %s already finished", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusConflict, ErrorCode: ErrorCodeSurveyAlreadyFinished, Description: "Survey Already Finished"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to start survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.writeResponse(r.Context(), w, newSurveyProgress(progress))
+}
+
+// handleSurveyQuestion returns the next question of the active survey
+func (s *apiServer) handleSurveyQuestion(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleSurveyQuestion")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userID, ok := r.Context().Value(userIDKeyType).(int64)
+	if !ok {
+		s.log.Errorf(r.Context(), "failed to get userID from context")
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	surveyGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse survey guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	progress, err := s.svc.GetSurveyProgress(r.Context(), userID, surveyGUID)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s is not started", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to get survey progress: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.writeResponse(r.Context(), w, newSurveyProgress(progress))
+}
+
+// handleSurveyAnswer answers the next question of the active survey, results are returned after the last answer
+func (s *apiServer) handleSurveyAnswer(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleSurveyAnswer")
+	defer span.Finish()
+
+	if r.Method != http.MethodPost {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userID, ok := r.Context().Value(userIDKeyType).(int64)
+	if !ok {
+		s.log.Errorf(r.Context(), "failed to get userID from context")
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	surveyGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse survey guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	var req SurveyAnswerRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.log.Errorf(r.Context(), "failed to decode answer: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	progress, err := s.svc.AnswerSurvey(r.Context(), userID, surveyGUID, req.Answer)
+	switch {
+	case errors.Is(err, service.ErrInvalidAnswer):
+		s.log.Errorf(r.Context(), "invalid answer: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, ErrorCode: ErrorCodeInvalidAnswer, Description: service.AnswerErrorText(err)})
+
+		return
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s is not started", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to answer survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.writeResponse(r.Context(), w, newSurveyProgress(progress))
+}
+
+func newSurveyProgress(progress service.SurveyProgress) SurveyProgress {
+	result := SurveyProgress{
+		SurveyGUID:     progress.Survey.GUID.String(),
+		Name:           progress.Survey.Name,
+		State:          string(progress.State.State),
+		Answered:       len(progress.State.Answers),
+		QuestionsCount: len(progress.Survey.Questions),
+		Question:       progress.Question,
+	}
+
+	if progress.State.Results != nil {
+		result.Results = &SurveyResults{
+			Text:    progress.State.Results.Text,
+			Scales:  newScaleResults(progress.State.Results.Scales),
+			Changes: newScaleChanges(progress.State.Results.Changes),
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+const (
+	defaultResultsLimit = 50
+	maxResultsLimit     = 500
+)
+
+type AdminResults struct {
+	Results []AdminResult `json:"results"`
+
+	// empty if it is the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type AdminResult struct {
+	SurveyGUID string          `json:"survey_guid"`
+	SurveyName string          `json:"survey_name"`
+	UserGUID   string          `json:"user_guid"`
+	UserID     int64           `json:"user_id"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Text       string          `json:"text"`
+	Scales     []ScaleResult   `json:"scales"`
+	Answers    []entity.Answer `json:"answers"`
+}
+
+// handleAdminResults returns page of finished surveys as JSON or all of them as CSV if format=csv
+func (s *apiServer) handleAdminResults(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleAdminResults")
+	defer span.Finish()
+
+	if r.Method != http.MethodGet {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	f, err := service.ParseResultsFilter(service.ResultsFilterParams{
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Survey: query.Get("survey"),
+		User:   query.Get("user"),
+		Scale:  query.Get("scale"),
+		Level:  query.Get("level"),
+	})
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse filter: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: err.Error()})
+
+		return
+	}
+
+	switch query.Get("format") {
+	case "", "json":
+	case "csv":
+		s.writeResultsCSV(w, r, f)
+		return
+	default:
+		s.log.Errorf(r.Context(), "unknown format: %s", query.Get("format"))
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Unknown format"})
+
+		return
+	}
+
+	limit := defaultResultsLimit
+	if query.Get("limit") != "" {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit <= 0 || limit > maxResultsLimit {
+			s.log.Errorf(r.Context(), "invalid limit: %s", query.Get("limit"))
+			s.writeError(r.Context(), w, Error{
+				Code:        http.StatusBadRequest,
+				Description: fmt.Sprintf("Limit should be from 1 to %d", maxResultsLimit),
+			})
+
+			return
+		}
+	}
+
+	page, err := s.svc.GetResults(r.Context(), f, query.Get("cursor"), limit)
+	switch {
+	case errors.Is(err, service.ErrInvalidCursor):
+		s.log.Errorf(r.Context(), "invalid cursor: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, ErrorCode: ErrorCodeInvalidCursor, Description: "Invalid cursor"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to get results: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	response := AdminResults{Results: []AdminResult{}, NextCursor: page.NextCursor}
+	for _, result := range page.Results {
+		item := AdminResult{
+			SurveyGUID: result.SurveyGUID.String(),
+			SurveyName: result.SurveyName,
+			UserGUID:   result.UserGUID,
+			UserID:     result.UserID,
+			StartedAt:  result.StartedAt,
+			FinishedAt: result.FinishedAt,
+			Scales:     []ScaleResult{},
+			Answers:    result.Answers,
+		}
+
+		if item.Answers == nil {
+			item.Answers = []entity.Answer{}
+		}
+
+		if result.Results != nil {
+			item.Text = result.Results.Text
+			item.Scales = newScaleResults(result.Results.Scales)
+		}
+
+		response.Results = append(response.Results, item)
+	}
+
+	s.writeResponse(r.Context(), w, response)
+}
+
+// writeResultsCSV streams CSV to the response by batches, errors after the first batch can be only logged
+func (s *apiServer) writeResultsCSV(w http.ResponseWriter, r *http.Request, f service.ResultsFilter) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="results.csv"`)
+
+	total, err := s.svc.ExportResults(r.Context(), w, service.ResultsExport{Filter: f, Format: service.ResultsFormatCSV})
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to export results: %v", err)
+		return
+	}
+
+	s.log.Infof(r.Context(), "exported %d results", total)
+}
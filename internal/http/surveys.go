@@ -0,0 +1,241 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+const (
+	// maxSurveySize limits size of the survey in the request body
+	maxSurveySize = 1 << 20
+	// maxRequestSize limits size of the body of requests other than survey uploads
+	maxRequestSize = 1 << 10
+)
+
+type AdminSurveys struct {
+	Surveys []AdminSurvey `json:"surveys"`
+}
+
+type AdminSurvey struct {
+	GUID             string            `json:"guid"`
+	ID               int64             `json:"id"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description"`
+	CalculationsType string            `json:"calculations_type"`
+	Questions        []entity.Question `json:"questions"`
+}
+
+// AdminSurveyRequest is a body of create and update requests, it has the same format as survey file
+type AdminSurveyRequest struct {
+	Name             string            `json:"name"`
+	Description      string            `json:"description"`
+	CalculationsType string            `json:"calculations_type"`
+	Questions        []entity.Question `json:"questions"`
+}
+
+func newAdminSurvey(survey entity.Survey) AdminSurvey {
+	questions := survey.Questions
+	if questions == nil {
+		questions = []entity.Question{}
+	}
+
+	return AdminSurvey{
+		GUID:             survey.GUID.String(),
+		ID:               survey.ID,
+		Name:             survey.Name,
+		Description:      survey.Description,
+		CalculationsType: survey.CalculationsType,
+		Questions:        questions,
+	}
+}
+
+// handleAdminSurveys lists surveys on GET and creates survey on POST
+func (s *apiServer) handleAdminSurveys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleAdminSurveysList(w, r)
+	case http.MethodPost:
+		s.handleAdminSurveyCreate(w, r)
+	default:
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+	}
+}
+
+// handleAdminSurvey returns survey on GET, updates it on PUT and soft deletes it on DELETE
+func (s *apiServer) handleAdminSurvey(w http.ResponseWriter, r *http.Request) {
+	surveyGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse survey guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.handleAdminSurveyGet(w, r, surveyGUID)
+	case http.MethodPut:
+		s.handleAdminSurveyUpdate(w, r, surveyGUID)
+	case http.MethodDelete:
+		s.handleAdminSurveyDelete(w, r, surveyGUID)
+	default:
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+	}
+}
+
+func (s *apiServer) handleAdminSurveysList(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleAdminSurveysList")
+	defer span.Finish()
+
+	surveys, err := s.svc.GetSurveys(r.Context())
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to get surveys: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	response := AdminSurveys{Surveys: []AdminSurvey{}}
+	for _, survey := range surveys {
+		response.Surveys = append(response.Surveys, newAdminSurvey(survey))
+	}
+
+	s.writeResponse(r.Context(), w, response)
+}
+
+func (s *apiServer) handleAdminSurveyGet(w http.ResponseWriter, r *http.Request, surveyGUID uuid.UUID) {
+	span := sentry.StartSpan(r.Context(), "handleAdminSurveyGet")
+	defer span.Finish()
+
+	survey, err := s.svc.GetSurvey(r.Context(), surveyGUID)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s not found", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to get survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.writeResponse(r.Context(), w, newAdminSurvey(survey))
+}
+
+func (s *apiServer) handleAdminSurveyCreate(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleAdminSurveyCreate")
+	defer span.Finish()
+
+	survey, ok := s.readAdminSurvey(w, r)
+	if !ok {
+		return
+	}
+
+	survey, err := s.svc.CreateSurvey(r.Context(), survey)
+	switch {
+	case errors.Is(err, service.ErrInvalidSurvey):
+		s.log.Errorf(r.Context(), "invalid survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, ErrorCode: ErrorCodeInvalidSurvey, Description: err.Error()})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to create survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.log.Infof(r.Context(), "survey created with id %d and guid %s", survey.ID, survey.GUID)
+
+	s.writeResponse(r.Context(), w, newAdminSurvey(survey))
+}
+
+func (s *apiServer) handleAdminSurveyUpdate(w http.ResponseWriter, r *http.Request, surveyGUID uuid.UUID) {
+	span := sentry.StartSpan(r.Context(), "handleAdminSurveyUpdate")
+	defer span.Finish()
+
+	survey, ok := s.readAdminSurvey(w, r)
+	if !ok {
+		return
+	}
+	survey.GUID = surveyGUID
+
+	err := s.svc.UpdateSurvey(r.Context(), survey)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s not found", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case errors.Is(err, service.ErrInvalidSurvey):
+		s.log.Errorf(r.Context(), "invalid survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, ErrorCode: ErrorCodeInvalidSurvey, Description: err.Error()})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to update survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.log.Infof(r.Context(), "survey updated with guid %s", surveyGUID)
+
+	s.handleAdminSurveyGet(w, r, surveyGUID)
+}
+
+func (s *apiServer) handleAdminSurveyDelete(w http.ResponseWriter, r *http.Request, surveyGUID uuid.UUID) {
+	span := sentry.StartSpan(r.Context(), "handleAdminSurveyDelete")
+	defer span.Finish()
+
+	// surveys are deleted regardless of active states of users, they are checked by the cli only
+	err := s.svc.DeleteSurvey(r.Context(), surveyGUID, true)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "survey %s not found", surveyGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to delete survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.log.Infof(r.Context(), "survey deleted with guid %s", surveyGUID)
+
+	s.writeResponse(r.Context(), w, map[string]bool{"deleted": true})
+}
+
+// readAdminSurvey decodes survey from the request body, error is written to the response if it fails
+func (s *apiServer) readAdminSurvey(w http.ResponseWriter, r *http.Request) (entity.Survey, bool) {
+	var req AdminSurveyRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSurveySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.log.Errorf(r.Context(), "failed to decode survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return entity.Survey{}, false
+	}
+
+	return entity.Survey{
+		Name:             req.Name,
+		Description:      req.Description,
+		CalculationsType: req.CalculationsType,
+		Questions:        req.Questions,
+	}, true
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+type AdminUser struct {
+	GUID          string  `json:"guid"`
+	UserID        int64   `json:"user_id"`
+	ChatID        int64   `json:"chat_id"`
+	Nickname      string  `json:"nickname"`
+	Cohort        string  `json:"cohort"`
+	CurrentSurvey *string `json:"current_survey"`
+
+	LastActivity time.Time         `json:"last_activity"`
+	Surveys      []AdminUserSurvey `json:"surveys"`
+}
+
+type AdminUserSurvey struct {
+	SurveyGUID string    `json:"survey_guid"`
+	SurveyName string    `json:"survey_name"`
+	State      string    `json:"state"`
+	StartedAt  time.Time `json:"started_at"`
+
+	// time of the last answer, equals to the finish time for finished surveys
+	UpdatedAt time.Time           `json:"updated_at"`
+	Answers   []AdminAnswer       `json:"answers"`
+	Results   *AdminSurveyResults `json:"results,omitempty"`
+}
+
+type AdminAnswer struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+type AdminSurveyResults struct {
+	Text   string        `json:"text"`
+	Scales []ScaleResult `json:"scales"`
+}
+
+// handleAdminUser returns user with all surveys on GET and deletes user on DELETE
+func (s *apiServer) handleAdminUser(w http.ResponseWriter, r *http.Request) {
+	userGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse user guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.handleAdminUserGet(w, r, userGUID)
+	case http.MethodDelete:
+		s.handleAdminUserDelete(w, r, userGUID)
+	default:
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+	}
+}
+
+func (s *apiServer) handleAdminUserGet(w http.ResponseWriter, r *http.Request, userGUID uuid.UUID) {
+	span := sentry.StartSpan(r.Context(), "handleAdminUserGet")
+	defer span.Finish()
+
+	details, err := s.svc.GetUserDetails(r.Context(), userGUID)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "user %s not found", userGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to get user details: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	response := AdminUser{
+		GUID:         details.User.GUID.String(),
+		UserID:       details.User.UserID,
+		ChatID:       details.User.ChatID,
+		Nickname:     details.User.Nickname,
+		Cohort:       details.User.Cohort,
+		LastActivity: details.User.LastActivity,
+		Surveys:      []AdminUserSurvey{},
+	}
+
+	if details.User.CurrentSurvey != nil {
+		currentSurvey := details.User.CurrentSurvey.String()
+		response.CurrentSurvey = &currentSurvey
+	}
+
+	for _, survey := range details.Surveys {
+		item := AdminUserSurvey{
+			SurveyGUID: survey.Report.SurveyGUID.String(),
+			SurveyName: survey.Report.SurveyName,
+			State:      string(survey.Report.State),
+			StartedAt:  survey.Report.StartedAt,
+			UpdatedAt:  survey.Report.FinishedAt,
+			Answers:    []AdminAnswer{},
+		}
+
+		for _, answer := range survey.Answers {
+			item.Answers = append(item.Answers, AdminAnswer{Question: answer.Question, Answer: answer.Answer})
+		}
+
+		if survey.Report.Results != nil {
+			item.Results = &AdminSurveyResults{
+				Text:   survey.Report.Results.Text,
+				Scales: newScaleResults(survey.Report.Results.Scales),
+			}
+		}
+
+		response.Surveys = append(response.Surveys, item)
+	}
+
+	s.writeResponse(r.Context(), w, response)
+}
+
+func (s *apiServer) handleAdminUserDelete(w http.ResponseWriter, r *http.Request, userGUID uuid.UUID) {
+	span := sentry.StartSpan(r.Context(), "handleAdminUserDelete")
+	defer span.Finish()
+
+	err := s.svc.DeleteUser(r.Context(), userGUID)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "user %s not found", userGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to delete user: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.log.Infof(r.Context(), "user %s deleted", userGUID)
+
+	s.writeResponse(r.Context(), w, map[string]bool{"deleted": true})
+}
+
+// handleAdminUserCurrentSurvey clears current survey of the user on DELETE, answers are kept
+func (s *apiServer) handleAdminUserCurrentSurvey(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleAdminUserCurrentSurvey")
+	defer span.Finish()
+
+	if r.Method != http.MethodDelete {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse user guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	if _, ok := s.getAdminUser(w, r, userGUID); !ok {
+		return
+	}
+
+	if err := s.svc.SetUserCurrentSurveyToNil(r.Context(), userGUID); err != nil {
+		s.log.Errorf(r.Context(), "failed to clear current survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.log.Infof(r.Context(), "current survey of user %s cleared", userGUID)
+
+	s.writeResponse(r.Context(), w, map[string]bool{"cleared": true})
+}
+
+// handleAdminUserSurvey resets state of the survey on DELETE the same way as survey-state-delete command,
+// the user can take the survey from the beginning
+func (s *apiServer) handleAdminUserSurvey(w http.ResponseWriter, r *http.Request) {
+	span := sentry.StartSpan(r.Context(), "handleAdminUserSurvey")
+	defer span.Finish()
+
+	if r.Method != http.MethodDelete {
+		s.log.Errorf(r.Context(), "method not allowed")
+		s.writeError(r.Context(), w, Error{Code: http.StatusMethodNotAllowed, Description: "Method Not Allowed"})
+
+		return
+	}
+
+	userGUID, err := uuid.Parse(r.PathValue("guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse user guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	surveyGUID, err := uuid.Parse(r.PathValue("survey_guid"))
+	if err != nil {
+		s.log.Errorf(r.Context(), "failed to parse survey guid: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusBadRequest, Description: "Bad Request"})
+
+		return
+	}
+
+	if _, ok := s.getAdminUser(w, r, userGUID); !ok {
+		return
+	}
+
+	// current survey is cleared in the same transaction, so the user doesn't point to the deleted state
+	_, err = s.svc.ResetUserSurvey(r.Context(), userGUID, surveyGUID, true)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		// the user has no states of the survey, there is nothing to reset
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to reset user survey: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return
+	}
+
+	s.log.Infof(r.Context(), "survey %s of user %s reset", surveyGUID, userGUID)
+
+	s.writeResponse(r.Context(), w, map[string]bool{"reset": true})
+}
+
+// getAdminUser returns user by guid, error is written to the response if it fails
+func (s *apiServer) getAdminUser(w http.ResponseWriter, r *http.Request, userGUID uuid.UUID) (entity.User, bool) {
+	user, err := s.svc.GetUserByGUID(r.Context(), userGUID)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		s.log.Errorf(r.Context(), "user %s not found", userGUID)
+		s.writeError(r.Context(), w, Error{Code: http.StatusNotFound, Description: "Not Found"})
+
+		return entity.User{}, false
+	case err != nil:
+		s.log.Errorf(r.Context(), "failed to get user: %v", err)
+		s.writeError(r.Context(), w, Error{Code: http.StatusInternalServerError, Description: "Internal Server Error"})
+
+		return entity.User{}, false
+	}
+
+	return user, true
+}
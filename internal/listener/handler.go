@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	tele "gopkg.in/telebot.v3"
 
 	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
@@ -72,7 +73,7 @@ func (l *listener) handleResultsCommand(ctx context.Context, c tele.Context) (er
 	return l.svc.HandleResultsCommand(ctx, f)
 }
 
-func (l *listener) handleStartCommand(ctx context.Context) (err error) {
+func (l *listener) handleStartCommand(ctx context.Context, c tele.Context) (err error) {
 	l.logger.Infof(ctx, "handle /start command")
 
 	defer func() {
@@ -81,7 +82,13 @@ func (l *listener) handleStartCommand(ctx context.Context) (err error) {
 		}
 	}()
 
-	return l.svc.HandleStartCommand(ctx)
+	// payload of deep link t.me/<bot>?start=<cohort>
+	var cohort string
+	if c.Message() != nil {
+		cohort = c.Message().Payload
+	}
+
+	return l.svc.HandleStartCommand(ctx, cohort)
 }
 
 func (l *listener) handleSurveyCommand(ctx context.Context, c tele.Context) (err error) {
@@ -272,3 +279,69 @@ func (l *listener) handleListOfSurveyCallback(ctx context.Context, c tele.Contex
 
 	return c.Respond()
 }
+
+func (l *listener) handleReportCallback(ctx context.Context, c tele.Context) (err error) {
+	l.logger.Infof(ctx, "handle report callback")
+
+	defer func() {
+		if err := c.Respond(); err != nil {
+			l.logger.Errorf(ctx, "failed to respond to callback: %w", err)
+		}
+	}()
+
+	defer func() {
+		if errP := recover(); errP != nil {
+			err = fmt.Errorf("panic: %v", errP)
+		}
+	}()
+
+	callback := c.Callback()
+	if callback == nil {
+		return fmt.Errorf("callback is nil")
+	}
+
+	// data of the callback is id of the survey
+	surveyID, err := strconv.ParseInt(callback.Data, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid survey id: %w", err)
+	}
+
+	if err := l.svc.HandleReportCommand(ctx, surveyID); err != nil {
+		return fmt.Errorf("failed to handle callback: %w", err)
+	}
+
+	return nil
+}
+
+func (l *listener) handleAlertAckCallback(ctx context.Context, c tele.Context) (err error) {
+	l.logger.Infof(ctx, "handle alert acknowledge callback")
+
+	defer func() {
+		if err := c.Respond(); err != nil {
+			l.logger.Errorf(ctx, "failed to respond to callback: %w", err)
+		}
+	}()
+
+	defer func() {
+		if errP := recover(); errP != nil {
+			err = fmt.Errorf("panic: %v", errP)
+		}
+	}()
+
+	callback := c.Callback()
+	if callback == nil {
+		return fmt.Errorf("callback is nil")
+	}
+
+	// data of the callback is guid of the alert
+	alertGUID, err := uuid.Parse(callback.Data)
+	if err != nil {
+		return fmt.Errorf("invalid alert guid: %w", err)
+	}
+
+	if err := l.svc.HandleAlertAcknowledge(ctx, alertGUID); err != nil {
+		return fmt.Errorf("failed to handle callback: %w", err)
+	}
+
+	return nil
+}
@@ -24,6 +24,10 @@ type (
 		logger logger.Logger
 		b      *tele.Bot
 		svc    service.Service
+		poller *poller
+
+		// maxPollAge is the time without successful getUpdates after which the listener is not ready
+		maxPollAge time.Duration
 	}
 )
 
@@ -35,13 +39,15 @@ func New(
 	svc service.Service,
 ) (*listener, error) {
 	l := &listener{
-		logger: logger,
-		svc:    svc,
+		logger:     logger,
+		svc:        svc,
+		poller:     &poller{logger: logger, timeout: pollInterval},
+		maxPollAge: 2 * pollInterval,
 	}
 
 	pref := tele.Settings{
 		Token:  token,
-		Poller: &tele.LongPoller{Timeout: pollInterval},
+		Poller: l.poller,
 	}
 
 	b, err := tele.NewBot(pref)
@@ -75,7 +81,7 @@ func New(
 		timer := prometheus.NewTimer(listenerDuration.WithLabelValues("handleStartCommand"))
 		defer timer.ObserveDuration()
 
-		if err := l.handleStartCommand(ctx); err != nil {
+		if err := l.handleStartCommand(ctx, c); err != nil {
 			listenerCounter.WithLabelValues("failed", "handleStartCommand").Inc()
 			l.logger.WithError(err).Errorf(ctx, "failed to handle /start command")
 		} else {
@@ -162,6 +168,44 @@ func New(
 		selector.Data("", "answer_11"),
 	}
 	listOfSurveysBtn := selector.Data("", "menu")
+	reportBtn := selector.Data("", "report")
+	alertAckBtn := selector.Data("", "alert_ack")
+
+	b.Handle(&alertAckBtn, func(c tele.Context) error {
+		span := l.initSentryContext(stdcontext.Background(), "handleAlertAckCallback")
+		defer span.Finish()
+		ctx := context.New(span.Context(), c, span.TraceID.String())
+
+		timer := prometheus.NewTimer(listenerDuration.WithLabelValues("handleAlertAckCallback"))
+		defer timer.ObserveDuration()
+
+		if err := l.handleAlertAckCallback(ctx, c); err != nil {
+			listenerCounter.WithLabelValues("failed", "handleAlertAckCallback").Inc()
+			l.logger.WithError(err).Errorf(ctx, "failed to handle alert acknowledge callback")
+		} else {
+			listenerCounter.WithLabelValues("success", "handleAlertAckCallback").Inc()
+		}
+
+		return nil
+	}, NewAdminMiddleware(adminUserIDs, logger))
+
+	b.Handle(&reportBtn, func(c tele.Context) error {
+		span := l.initSentryContext(stdcontext.Background(), "handleReportCallback")
+		defer span.Finish()
+		ctx := context.New(span.Context(), c, span.TraceID.String())
+
+		timer := prometheus.NewTimer(listenerDuration.WithLabelValues("handleReportCallback"))
+		defer timer.ObserveDuration()
+
+		if err := l.handleReportCallback(ctx, c); err != nil {
+			listenerCounter.WithLabelValues("failed", "handleReportCallback").Inc()
+			l.logger.WithError(err).Errorf(ctx, "failed to handle report callback")
+		} else {
+			listenerCounter.WithLabelValues("success", "handleReportCallback").Inc()
+		}
+
+		return nil
+	})
 
 	b.Handle(&listOfSurveysBtn, func(c tele.Context) error {
 		span := l.initSentryContext(stdcontext.Background(), "handleListOfSurveyCallback")
@@ -235,6 +279,11 @@ func (l *listener) Stop() {
 	l.b.Stop()
 }
 
+// CheckPolling returns error if updates are not polled from Telegram, it is reported by the liveness probe
+func (l *listener) CheckPolling(_ stdcontext.Context) error {
+	return l.poller.check(l.maxPollAge)
+}
+
 func (l *listener) initSentryContext(ctx stdcontext.Context, name string) *sentry.Span {
 	hub := sentry.GetHubFromContext(ctx)
 	if hub == nil {
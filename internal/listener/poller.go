@@ -0,0 +1,102 @@
+package listener
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+)
+
+// pollRetryInterval is a pause after failed getUpdates, so the poller doesn't spin while Telegram is unavailable
+const pollRetryInterval = time.Second
+
+var now = time.Now
+
+// poller is the same as tele.LongPoller, but it tracks the last successful getUpdates for the readiness probe
+type poller struct {
+	logger       logger.Logger
+	timeout      time.Duration
+	lastUpdateID int
+
+	running atomic.Bool
+
+	// unix nano of the poller start and the last successful getUpdates
+	startedAt atomic.Int64
+	lastPoll  atomic.Int64
+}
+
+func (p *poller) Poll(b *tele.Bot, dest chan tele.Update, stop chan struct{}) {
+	p.startedAt.Store(now().UnixNano())
+	p.running.Store(true)
+	defer p.running.Store(false)
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		updates, err := p.getUpdates(b)
+		if err != nil {
+			p.logger.Errorf(stdcontext.Background(), "failed to get updates: %v", err)
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(pollRetryInterval):
+			}
+
+			continue
+		}
+
+		p.lastPoll.Store(now().UnixNano())
+
+		for _, update := range updates {
+			p.lastUpdateID = update.ID
+			dest <- update
+		}
+	}
+}
+
+// check returns error if the poller is stopped or getUpdates has not succeeded for maxAge,
+// a long poll lasts up to the timeout, so maxAge should be greater than it
+func (p *poller) check(maxAge time.Duration) error {
+	if !p.running.Load() {
+		return errors.New("poller is not running")
+	}
+
+	// getUpdates may not have returned yet after start
+	last := max(p.lastPoll.Load(), p.startedAt.Load())
+	if age := now().Sub(time.Unix(0, last)); age > maxAge {
+		return fmt.Errorf("no successful getUpdates for %s", age.Round(time.Second))
+	}
+
+	return nil
+}
+
+func (p *poller) getUpdates(b *tele.Bot) ([]tele.Update, error) {
+	data, err := b.Raw("getUpdates", map[string]string{
+		"offset":  strconv.Itoa(p.lastUpdateID + 1),
+		"timeout": strconv.Itoa(int(p.timeout / time.Second)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Result []tele.Update
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal updates: %w", err)
+	}
+
+	return resp.Result, nil
+}
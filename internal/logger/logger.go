@@ -33,6 +33,7 @@ type (
 		Warnf(ctx context.Context, format string, args ...any)
 		Errorf(ctx context.Context, format string, args ...any)
 		WithPrefix(k, v string) Logger
+		WithFields(fields map[string]any) Logger
 		WithError(err error) Logger
 	}
 
@@ -100,6 +101,11 @@ func (l *logger) WithPrefix(k, v string) Logger {
 	return &logger{l.l.WithField(k, v), l.env, l.releaseVersion}
 }
 
+// WithFields adds structured fields, unlike WithPrefix values keep their types, e.g. numbers
+func (l *logger) WithFields(fields map[string]any) Logger {
+	return &logger{l.l.WithFields(fields), l.env, l.releaseVersion}
+}
+
 func (l *logger) WithError(err error) Logger {
 	return &logger{l.l.WithError(err), l.env, l.releaseVersion}
 }
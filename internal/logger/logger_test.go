@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"os"
 	"testing"
@@ -30,6 +32,18 @@ func TestWithPrefix(t *testing.T) {
 	l.Warnf(ctx, "test %s", "info")
 }
 
+func TestWithFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New("test", "info", "unknown", &buf).WithFields(map[string]any{"status": 200, "route": "/api/surveys"})
+	l.Infof(context.Background(), "test")
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, float64(200), got["status"])
+	require.Equal(t, "/api/surveys", got["route"])
+}
+
 func TestWithError(t *testing.T) {
 	l := New("test", "info", "unknown", os.Stdout).WithPrefix("app-name", "test-app")
 	l.WithError(errors.New("some error")).Infof(context.Background(), "test")
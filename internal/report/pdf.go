@@ -0,0 +1,175 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/chart"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+const (
+	fontFamily = "go"
+
+	pageWidth  = 210
+	margin     = 15
+	lineHeight = 5
+	chartWidth = 150
+
+	// widths of the scales table columns
+	scaleColumn      = 60
+	scoreColumn      = 18
+	levelColumn      = 74
+	percentileColumn = 28
+
+	dateFormat = "02.01.2006 15:04"
+)
+
+// PDF renders report of the finished survey.
+// Answers are included if questions of the survey are passed.
+func PDF(r entity.SurveyStateReport, questions []entity.Question) ([]byte, error) {
+	if r.Results == nil {
+		return nil, errors.New("survey is not finished")
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(r.SurveyName, true)
+	pdf.SetCreator("survey-bot", true)
+	pdf.AddUTF8FontFromBytes(fontFamily, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(fontFamily, "B", gobold.TTF)
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin)
+		pdf.SetFont(fontFamily, "", 8)
+		pdf.CellFormat(0, lineHeight, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont(fontFamily, "B", 16)
+	pdf.MultiCell(0, 8, r.SurveyName, "", "L", false)
+	pdf.Ln(2)
+
+	pdf.SetFont(fontFamily, "", 10)
+	pdf.MultiCell(0, lineHeight, r.Description, "", "L", false)
+	pdf.Ln(2)
+	pdf.MultiCell(0, lineHeight, "Дата прохождения: "+r.FinishedAt.Format(dateFormat), "", "L", false)
+
+	if len(r.Results.Scales) > 0 {
+		if err := writeChart(pdf, r.Results.Scales); err != nil {
+			return nil, err
+		}
+
+		writeHeader(pdf, "Результаты по шкалам")
+		writeScales(pdf, r.Results.Scales)
+	}
+
+	writeHeader(pdf, "Интерпретация")
+	pdf.SetFont(fontFamily, "", 10)
+	pdf.MultiCell(0, lineHeight, r.Results.Text, "", "L", false)
+
+	if len(questions) > 0 {
+		writeHeader(pdf, "Ответы")
+		writeAnswers(pdf, questions, r.Answers)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render pdf: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeHeader(pdf *fpdf.Fpdf, text string) {
+	pdf.Ln(6)
+	pdf.SetFont(fontFamily, "B", 12)
+	pdf.MultiCell(0, 7, text, "", "L", false)
+	pdf.Ln(1)
+}
+
+func writeChart(pdf *fpdf.Fpdf, scales []entity.ResultsScale) error {
+	image, err := chart.Render("", "", scales)
+	if err != nil {
+		return fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	options := fpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("chart", options, bytes.NewReader(image))
+	pdf.Ln(4)
+	pdf.ImageOptions("chart", (pageWidth-chartWidth)/2, -1, chartWidth, 0, true, options, 0, "")
+
+	return nil
+}
+
+func writeScales(pdf *fpdf.Fpdf, scales []entity.ResultsScale) {
+	widths := []float64{scaleColumn, scoreColumn, levelColumn, percentileColumn}
+
+	pdf.SetFont(fontFamily, "B", 10)
+	writeRow(pdf, widths, []string{"Шкала", "Баллы", "Уровень", "Процентиль"})
+
+	pdf.SetFont(fontFamily, "", 10)
+	for _, scale := range scales {
+		percentile := "—"
+		if scale.Norm != nil {
+			percentile = fmt.Sprintf("%.0f", scale.Norm.Percentile)
+		}
+
+		level := scale.Level
+		if level == "" {
+			level = "—"
+		}
+
+		writeRow(pdf, widths, []string{
+			scale.Name,
+			strconv.FormatFloat(scale.Score, 'f', -1, 64),
+			level,
+			percentile,
+		})
+	}
+}
+
+// writeRow writes row of the table with the cells of the same height
+func writeRow(pdf *fpdf.Fpdf, widths []float64, cells []string) {
+	lines := 1
+	for i, cell := range cells {
+		lines = max(lines, len(pdf.SplitText(cell, widths[i]-2)))
+	}
+	height := float64(lines) * lineHeight
+
+	_, pageHeight := pdf.GetPageSize()
+	if pdf.GetY()+height > pageHeight-margin {
+		pdf.AddPage()
+	}
+
+	x, y := pdf.GetXY()
+	for i, cell := range cells {
+		pdf.Rect(x, y, widths[i], height, "D")
+		pdf.SetXY(x, y)
+		pdf.MultiCell(widths[i], lineHeight, cell, "", "L", false)
+		x += widths[i]
+	}
+
+	pdf.SetXY(margin, y+height)
+}
+
+func writeAnswers(pdf *fpdf.Fpdf, questions []entity.Question, answers []entity.Answer) {
+	for i, answer := range answers {
+		if i >= len(questions) {
+			break
+		}
+
+		pdf.SetFont(fontFamily, "B", 10)
+		pdf.MultiCell(0, lineHeight, fmt.Sprintf("%d. %s", i+1, questions[i].Text), "", "L", false)
+		pdf.SetFont(fontFamily, "", 10)
+		pdf.MultiCell(0, lineHeight, "Ответ: "+questions[i].AnswerText(answer), "", "L", false)
+		pdf.Ln(1)
+	}
+}
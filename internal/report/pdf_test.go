@@ -0,0 +1,48 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+func TestPDF(t *testing.T) {
+	report := entity.SurveyStateReport{
+		SurveyName:  "Опросник выгорания",
+		Description: "Опросник предназначен для оценки профессионального выгорания.",
+		FinishedAt:  time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		Answers: []entity.Answer{
+			{Type: entity.AnswerTypeSelect, Data: []int{1}},
+			{Type: entity.AnswerTypeSegment, Data: []int{7}},
+		},
+		Results: &entity.Results{
+			Text: "Эмоциональное истощение - высокий уровень",
+			Scales: []entity.ResultsScale{
+				{Key: "s1", Name: "Эмоциональное истощение", Score: 28, Level: "высокий уровень", Norm: &entity.ScaleNorm{Percentile: 81}},
+				{Key: "s2", Name: "Деперсонализация", Score: 4.5},
+			},
+		},
+	}
+	questions := []entity.Question{
+		{Text: "Я чувствую себя эмоционально опустошенным.", AnswerType: entity.AnswerTypeSelect, PossibleAnswers: []int{1, 2}, AnswersText: []string{"Никогда", "Часто"}},
+		{Text: "Оцените ваше самочувствие.", AnswerType: entity.AnswerTypeSegment, PossibleAnswers: []int{1, 10}},
+	}
+
+	withoutAnswers, err := PDF(report, nil)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(withoutAnswers, []byte("%PDF-")))
+
+	withAnswers, err := PDF(report, questions)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(withAnswers, []byte("%PDF-")))
+	require.Greater(t, len(withAnswers), len(withoutAnswers))
+}
+
+func TestPDF_NotFinished(t *testing.T) {
+	_, err := PDF(entity.SurveyStateReport{SurveyName: "Опросник"}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+func (r *repository) GetBackupSurveys(ctx context.Context, tx service.DBTransaction, limit, offset int) ([]service.BackupSurvey, error) {
+	span := sentry.StartSpan(ctx, "GetBackupSurveys")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []survey
+	query := `SELECT * FROM surveys ORDER BY guid LIMIT $1 OFFSET $2`
+	if err := exec.SelectContext(ctx, &models, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	surveys := make([]service.BackupSurvey, 0, len(models))
+	for _, model := range models {
+		surveys = append(surveys, model.ExportBackup())
+	}
+
+	return surveys, nil
+}
+
+func (r *repository) GetBackupUsers(ctx context.Context, tx service.DBTransaction, limit, offset int) ([]service.BackupUser, error) {
+	span := sentry.StartSpan(ctx, "GetBackupUsers")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []user
+	query := `SELECT * FROM users ORDER BY guid LIMIT $1 OFFSET $2`
+	if err := exec.SelectContext(ctx, &models, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	users := make([]service.BackupUser, 0, len(models))
+	for _, model := range models {
+		users = append(users, model.ExportBackup())
+	}
+
+	return users, nil
+}
+
+func (r *repository) GetBackupSurveyStates(ctx context.Context, tx service.DBTransaction, limit, offset int) ([]service.BackupSurveyState, error) {
+	span := sentry.StartSpan(ctx, "GetBackupSurveyStates")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []surveyState
+	query := `SELECT * FROM survey_states ORDER BY user_guid, survey_guid, created_at, state LIMIT $1 OFFSET $2`
+	if err := exec.SelectContext(ctx, &models, query, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	states := make([]service.BackupSurveyState, 0, len(models))
+	for _, model := range models {
+		states = append(states, model.ExportBackup())
+	}
+
+	return states, nil
+}
+
+// RestoreSurvey inserts the survey, the stored survey with the same guid is updated if policy is overwrite.
+// The guid the survey is stored under is returned, with skip policy it is the guid of the stored survey
+// with the same guid or slug.
+func (r *repository) RestoreSurvey(ctx context.Context, tx service.DBTransaction, s service.BackupSurvey, policy service.RestorePolicy) (uuid.UUID, bool, error) {
+	span := sentry.StartSpan(ctx, "RestoreSurvey")
+	defer span.Finish()
+
+	onConflict, err := restoreOnConflict(policy, `ON CONFLICT (guid) DO UPDATE SET
+		id = EXCLUDED.id, name = EXCLUDED.name, questions = EXCLUDED.questions, calculations_type = EXCLUDED.calculations_type,
+		description = EXCLUDED.description, slug = EXCLUDED.slug, created_at = EXCLUDED.created_at,
+		updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at`)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	var model survey
+	model.LoadBackup(s)
+
+	query := `INSERT INTO surveys (guid, id, name, questions, calculations_type, description, slug, created_at, updated_at, deleted_at)
+		VALUES (:guid, :id, :name, :questions, :calculations_type, :description, :slug, :created_at, :updated_at, :deleted_at) ` + onConflict
+
+	restored, err := r.restore(ctx, tx, query, model)
+	if err != nil || restored || policy != service.RestoreSkip {
+		return s.GUID, restored, err
+	}
+
+	// slug is unique among not deleted surveys only
+	guid, err := r.restoredGUID(ctx, tx, `SELECT guid FROM surveys WHERE guid = $1 OR (slug = $2 AND deleted_at IS NULL)
+		ORDER BY guid = $1 DESC LIMIT 1`, s.GUID, s.Slug)
+
+	return guid, false, err
+}
+
+// RestoreUser inserts the user, the stored user with the same guid is updated if policy is overwrite.
+// The guid the user is stored under is returned, with skip policy it is the guid of the stored user
+// with the same guid or user_id.
+func (r *repository) RestoreUser(ctx context.Context, tx service.DBTransaction, u service.BackupUser, policy service.RestorePolicy) (uuid.UUID, bool, error) {
+	span := sentry.StartSpan(ctx, "RestoreUser")
+	defer span.Finish()
+
+	onConflict, err := restoreOnConflict(policy, `ON CONFLICT (guid) DO UPDATE SET
+		current_survey = EXCLUDED.current_survey, user_id = EXCLUDED.user_id, chat_id = EXCLUDED.chat_id,
+		nickname = EXCLUDED.nickname, cohort = EXCLUDED.cohort, created_at = EXCLUDED.created_at,
+		updated_at = EXCLUDED.updated_at, last_activity = EXCLUDED.last_activity`)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	var model user
+	model.LoadBackup(u)
+
+	query := `INSERT INTO users (guid, current_survey, user_id, chat_id, nickname, cohort, created_at, updated_at, last_activity)
+		VALUES (:guid, :current_survey, :user_id, :chat_id, :nickname, :cohort, :created_at, :updated_at, :last_activity) ` + onConflict
+
+	restored, err := r.restore(ctx, tx, query, model)
+	if err != nil || restored || policy != service.RestoreSkip {
+		return u.GUID, restored, err
+	}
+
+	guid, err := r.restoredGUID(ctx, tx, `SELECT guid FROM users WHERE guid = $1 OR user_id = $2
+		ORDER BY guid = $1 DESC LIMIT 1`, u.GUID, u.UserID)
+
+	return guid, false, err
+}
+
+// RestoreSurveyState inserts the state. States have no primary key, so the state of the same user and survey
+// created at the same time is considered the same one.
+func (r *repository) RestoreSurveyState(ctx context.Context, tx service.DBTransaction, state service.BackupSurveyState, policy service.RestorePolicy) (bool, error) {
+	span := sentry.StartSpan(ctx, "RestoreSurveyState")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return false, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var model surveyState
+	model.LoadBackup(state)
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM survey_states WHERE user_guid = $1 AND survey_guid = $2 AND created_at = $3)`
+	if err := exec.GetContext(ctx, &exists, query, model.UserGUID, model.SurveyGUID, model.CreatedAt); err != nil {
+		return false, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	if !exists {
+		query = `INSERT INTO survey_states (user_guid, survey_guid, state, answers, results, created_at, updated_at)
+			VALUES (:user_guid, :survey_guid, :state, :answers, :results, :created_at, :updated_at)`
+		if _, err := exec.NamedExecContext(ctx, query, model); err != nil {
+			return false, fmt.Errorf("failed to exec query: %w", err)
+		}
+
+		return true, nil
+	}
+
+	switch policy {
+	case service.RestoreFail:
+		return false, fmt.Errorf("%w: survey state of user %s and survey %s created at %s",
+			service.ErrAlreadyExists, model.UserGUID, model.SurveyGUID, model.CreatedAt)
+	case service.RestoreSkip:
+		return false, nil
+	case service.RestoreOverwrite:
+		query = `UPDATE survey_states SET state = :state, answers = :answers, results = :results, updated_at = :updated_at
+			WHERE user_guid = :user_guid AND survey_guid = :survey_guid AND created_at = :created_at`
+		if _, err := exec.NamedExecContext(ctx, query, model); err != nil {
+			return false, fmt.Errorf("failed to exec query: %w", err)
+		}
+
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown restore policy: %s", policy)
+	}
+}
+
+// restore executes insert of the record, false is returned if nothing is inserted or updated
+func (r *repository) restore(ctx context.Context, tx service.DBTransaction, query string, model any) (bool, error) {
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return false, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	result, err := exec.NamedExecContext(ctx, query, model)
+	switch {
+	case err != nil && strings.Contains(err.Error(), "pq: duplicate key value violates unique constraint"):
+		return false, fmt.Errorf("%w: %w", service.ErrAlreadyExists, err)
+	case err != nil:
+		return false, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// restoredGUID returns guid of the stored record the skipped one conflicts with
+func (r *repository) restoredGUID(ctx context.Context, tx service.DBTransaction, query string, args ...any) (uuid.UUID, error) {
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var guid uuid.UUID
+	if err := exec.GetContext(ctx, &guid, query, args...); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return guid, nil
+}
+
+// restoreOnConflict returns ON CONFLICT clause of the policy, overwrite is the clause updating the stored record
+func restoreOnConflict(policy service.RestorePolicy, overwrite string) (string, error) {
+	switch policy {
+	case service.RestoreFail:
+		return "", nil
+	case service.RestoreSkip:
+		// all unique constraints are checked, e.g. user_id of users and slug of surveys
+		return "ON CONFLICT DO NOTHING", nil
+	case service.RestoreOverwrite:
+		return overwrite, nil
+	default:
+		return "", fmt.Errorf("unknown restore policy: %s", policy)
+	}
+}
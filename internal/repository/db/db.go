@@ -46,6 +46,19 @@ func (r *repository) BeginTx(ctx context.Context) (service.DBTransaction, error)
 	return tx, nil
 }
 
+// BeginSnapshotTx begins read-only transaction, all queries of it see the same snapshot of data
+func (r *repository) BeginSnapshotTx(ctx context.Context) (service.DBTransaction, error) {
+	span := sentry.StartSpan(ctx, "BeginSnapshotTx")
+	defer span.Finish()
+
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
 func (r *repository) castExec(tx service.DBTransaction) (dbExecutor, error) {
 	var exec dbExecutor
 	switch tx {
@@ -92,6 +105,7 @@ func (r *repository) GetSurveysList(ctx context.Context, tx service.DBTransactio
 	return surveys, nil
 }
 
+// CreateSurvey creates new survey in DB and returns ErrAlreadyExists if survey with the slug already exists
 func (r *repository) CreateSurvey(ctx context.Context, tx service.DBTransaction, s entity.Survey) error {
 	span := sentry.StartSpan(ctx, "CreateSurvey")
 	defer span.Finish()
@@ -110,9 +124,13 @@ func (r *repository) CreateSurvey(ctx context.Context, tx service.DBTransaction,
 	model.CreatedAt = nowTime
 	model.UpdatedAt = nowTime
 
-	query := `INSERT INTO surveys (guid, id, name, questions, calculations_type, description, created_at, updated_at)
-		VALUES (:guid, :id, :name, :questions, :calculations_type, :description, :created_at, :updated_at)`
-	if _, err := exec.NamedExecContext(ctx, query, model); err != nil {
+	query := `INSERT INTO surveys (guid, id, name, questions, calculations_type, description, slug, created_at, updated_at)
+		VALUES (:guid, :id, :name, :questions, :calculations_type, :description, :slug, :created_at, :updated_at)`
+	_, err = exec.NamedExecContext(ctx, query, model)
+	switch {
+	case err != nil && strings.Contains(err.Error(), `pq: duplicate key value violates unique constraint "surveys_slug_key"`):
+		return service.ErrAlreadyExists
+	case err != nil:
 		return fmt.Errorf("failed to exec query: %w", err)
 	}
 
@@ -224,8 +242,8 @@ func (r *repository) CreateUser(ctx context.Context, tx service.DBTransaction, u
 	model.UpdatedAt = nowTime
 	model.LastActivity = nowTime
 
-	query := `INSERT INTO users (guid, user_id, chat_id, nickname, current_survey, created_at, updated_at, last_activity)
-        VALUES (:guid, :user_id, :chat_id, :nickname, :current_survey, :created_at, :updated_at, :last_activity)`
+	query := `INSERT INTO users (guid, user_id, chat_id, nickname, current_survey, created_at, updated_at, last_activity, cohort)
+        VALUES (:guid, :user_id, :chat_id, :nickname, :current_survey, :created_at, :updated_at, :last_activity, :cohort)`
 	_, err = exec.NamedExecContext(ctx, query, model)
 	switch {
 	case err != nil && strings.Contains(err.Error(), `pq: duplicate key value violates unique constraint "users_pk"`):
@@ -411,6 +429,42 @@ func (r *repository) DeleteUserSurveyState(ctx context.Context, tx service.DBTra
 	return nil
 }
 
+// DeleteUserSurveyStates deletes states of the survey by the user in filterStates, returns number of deleted states
+func (r *repository) DeleteUserSurveyStates(
+	ctx context.Context,
+	tx service.DBTransaction,
+	userGUID, surveyGUID uuid.UUID,
+	filterStates []entity.State,
+) (int, error) {
+	span := sentry.StartSpan(ctx, "DeleteUserSurveyStates")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	query, args, err := sqlx.In(
+		"DELETE FROM survey_states WHERE user_guid = ? AND survey_guid = ? AND state IN(?)",
+		userGUID, surveyGUID, filterStates,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := exec.ExecContext(ctx, r.db.Rebind(query), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(deleted), nil
+}
+
 func (r *repository) GetUserByGUID(ctx context.Context, tx service.DBTransaction, userGUID uuid.UUID) (entity.User, error) {
 	span := sentry.StartSpan(ctx, "GetUserByGUID")
 	defer span.Finish()
@@ -423,12 +477,44 @@ func (r *repository) GetUserByGUID(ctx context.Context, tx service.DBTransaction
 	var model user
 	query := `SELECT * FROM users WHERE guid = $1`
 	if err := exec.GetContext(ctx, &model, query, userGUID); err != nil {
+		if err == sql.ErrNoRows {
+			return entity.User{}, service.ErrNotFound
+		}
+
 		return entity.User{}, fmt.Errorf("failed to exec query: %w", err)
 	}
 
 	return model.Export(), nil
 }
 
+// GetUsersByNickname returns users with the nickname equal to the given one or to "First Last (nickname)",
+// so they are found by the telegram username as well
+func (r *repository) GetUsersByNickname(ctx context.Context, tx service.DBTransaction, nickname string) ([]entity.User, error) {
+	span := sentry.StartSpan(ctx, "GetUsersByNickname")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []user
+	// the suffix is compared as is, LIKE would treat underscores of usernames as wildcards
+	query := `SELECT * FROM users
+	WHERE LOWER(nickname) = LOWER($1) OR RIGHT(LOWER(nickname), LENGTH($1) + 2) = '(' || LOWER($1) || ')'
+	ORDER BY created_at`
+	if err := exec.SelectContext(ctx, &models, query, nickname); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	users := make([]entity.User, 0, len(models))
+	for _, model := range models {
+		users = append(users, model.Export())
+	}
+
+	return users, nil
+}
+
 func (r *repository) GetFinishedSurveys(ctx context.Context, tx service.DBTransaction, f service.ResultsFilter, batchSize int, offset int) ([]entity.SurveyStateReport, error) {
 	span := sentry.StartSpan(ctx, "GetFinishedSurveys")
 	defer span.Finish()
@@ -440,26 +526,9 @@ func (r *repository) GetFinishedSurveys(ctx context.Context, tx service.DBTransa
 
 	var models []surveyStateReport
 
-	var (
-		args   []any
-		filter string
-	)
-
-	args = append(args, batchSize, offset)
-
-	switch {
-	case f.From == nil && f.To == nil:
-		filter = "state = $3"
-		args = append(args, entity.FinishedState)
-	case f.From == nil && f.To != nil:
-		filter = "state = $3 AND updated_at < $4"
-		args = append(args, entity.FinishedState, f.To)
-	case f.From != nil && f.To == nil:
-		filter = "state = $3 AND updated_at >= $5"
-		args = append(args, entity.FinishedState, f.From)
-	case f.From != nil && f.To != nil:
-		filter = "state = $3 AND updated_at >= $4 AND updated_at < $5"
-		args = append(args, entity.FinishedState, f.From, f.To)
+	filter, args, err := finishedSurveysFilter(f, []any{batchSize, offset})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build filter: %w", err)
 	}
 
 	query := `
@@ -470,7 +539,7 @@ func (r *repository) GetFinishedSurveys(ctx context.Context, tx service.DBTransa
 			results, 
 			created_at state_created_at, 
 			updated_at state_updated_at 
-		FROM survey_states WHERE %s ORDER BY updated_at LIMIT $1 OFFSET $2
+		FROM survey_states ss WHERE %s ORDER BY updated_at LIMIT $1 OFFSET $2
 	),
 	cte_users AS (
 		SELECT answers,
@@ -518,6 +587,106 @@ func (r *repository) GetFinishedSurveys(ctx context.Context, tx service.DBTransa
 	return states, nil
 }
 
+// GetFinishedSurveysAfter returns finished surveys matching the filter ordered by finish time,
+// page starts after the cursor or from the beginning if cursor is nil
+func (r *repository) GetFinishedSurveysAfter(
+	ctx context.Context,
+	tx service.DBTransaction,
+	f service.ResultsFilter,
+	cursor *service.ResultsCursor,
+	limit int,
+) ([]entity.SurveyStateReport, error) {
+	span := sentry.StartSpan(ctx, "GetFinishedSurveysAfter")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	filter, args, err := finishedSurveysFilter(f, []any{limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build filter: %w", err)
+	}
+
+	if cursor != nil {
+		filter += fmt.Sprintf(" AND (ss.updated_at, ss.user_guid, ss.survey_guid) > ($%d, $%d, $%d)", len(args)+1, len(args)+2, len(args)+3)
+		args = append(args, cursor.FinishedAt, cursor.UserGUID, cursor.SurveyGUID)
+	}
+
+	query := `
+	SELECT ss.survey_guid, s.name as survey_name, s.description, ss.created_at, ss.updated_at, ss.user_guid, u.user_id, ss.answers, ss.results
+	FROM survey_states ss
+	JOIN surveys s ON ss.survey_guid = s.guid
+	JOIN users u ON ss.user_guid = u.guid
+	WHERE %s
+	ORDER BY ss.updated_at, ss.user_guid, ss.survey_guid
+	LIMIT $1
+	`
+
+	var models []surveyStateReport
+	if err := exec.SelectContext(ctx, &models, fmt.Sprintf(query, filter), args...); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var states []entity.SurveyStateReport
+	for _, model := range models {
+		s, err := model.Export()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export survey state: %w", err)
+		}
+
+		states = append(states, s)
+	}
+
+	return states, nil
+}
+
+// finishedSurveysFilter returns condition on survey_states aliased as "ss",
+// placeholders of the condition are numbered after the given args
+func finishedSurveysFilter(f service.ResultsFilter, args []any) (string, []any, error) {
+	conditions := []string{fmt.Sprintf("ss.state = $%d", len(args)+1)}
+	args = append(args, entity.FinishedState)
+
+	if f.From != nil {
+		conditions = append(conditions, fmt.Sprintf("ss.updated_at >= $%d", len(args)+1))
+		args = append(args, *f.From)
+	}
+
+	if f.To != nil {
+		conditions = append(conditions, fmt.Sprintf("ss.updated_at < $%d", len(args)+1))
+		args = append(args, *f.To)
+	}
+
+	if f.SurveyGUID != nil {
+		conditions = append(conditions, fmt.Sprintf("ss.survey_guid = $%d", len(args)+1))
+		args = append(args, *f.SurveyGUID)
+	}
+
+	if f.UserGUID != nil {
+		conditions = append(conditions, fmt.Sprintf("ss.user_guid = $%d", len(args)+1))
+		args = append(args, *f.UserGUID)
+	}
+
+	if f.Scale != "" {
+		scale := map[string]string{"Key": f.Scale}
+		if f.Level != "" {
+			scale["Level"] = f.Level
+		}
+
+		data, err := json.Marshal([]map[string]string{scale})
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal scale: %w", err)
+		}
+
+		conditions = append(conditions, fmt.Sprintf("ss.results->'Scales' @> $%d::jsonb", len(args)+1))
+		args = append(args, string(data))
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// UpdateSurvey returns ErrAlreadyExists if another survey with the slug already exists
 func (r *repository) UpdateSurvey(ctx context.Context, tx service.DBTransaction, s entity.Survey) error {
 	span := sentry.StartSpan(ctx, "UpdateSurvey")
 	defer span.Finish()
@@ -535,9 +704,14 @@ func (r *repository) UpdateSurvey(ctx context.Context, tx service.DBTransaction,
 	nowTime := now()
 	model.UpdatedAt = nowTime
 
-	query := `UPDATE surveys SET name = :name, questions = :questions, calculations_type = :calculations_type, description = :description, updated_at = :updated_at
+	query := `UPDATE surveys SET name = :name, questions = :questions, calculations_type = :calculations_type, description = :description,
+		slug = :slug, updated_at = :updated_at
 		WHERE guid = :guid`
-	if _, err := exec.NamedExecContext(ctx, query, model); err != nil {
+	_, err = exec.NamedExecContext(ctx, query, model)
+	switch {
+	case err != nil && strings.Contains(err.Error(), `pq: duplicate key value violates unique constraint "surveys_slug_key"`):
+		return service.ErrAlreadyExists
+	case err != nil:
 		return fmt.Errorf("failed to exec query: %w", err)
 	}
 
@@ -580,6 +754,126 @@ func (r *repository) GetCompletedSurveys(ctx context.Context, tx service.DBTrans
 	return states, nil
 }
 
+// GetUserSurveyReports returns active and finished states of all surveys by the user, the oldest first.
+// States of deleted surveys are returned as well.
+func (r *repository) GetUserSurveyReports(ctx context.Context, tx service.DBTransaction, userGUID uuid.UUID) ([]entity.SurveyStateReport, error) {
+	span := sentry.StartSpan(ctx, "GetUserSurveyReports")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []surveyStateReport
+
+	query := `
+	SELECT ss.survey_guid, s.name as survey_name, s.description, ss.created_at, ss.updated_at, ss.user_guid, u.user_id, ss.answers, ss.results, ss.state
+	FROM survey_states ss
+	JOIN surveys s ON ss.survey_guid = s.guid
+	JOIN users u ON ss.user_guid = u.guid
+	WHERE ss.user_guid = $1
+	ORDER BY ss.created_at
+	`
+	if err := exec.SelectContext(ctx, &models, query, userGUID); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var states []entity.SurveyStateReport
+	for _, model := range models {
+		s, err := model.Export()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export survey state: %w", err)
+		}
+
+		states = append(states, s)
+	}
+
+	return states, nil
+}
+
+// DeleteUser deletes the user with all survey states and alerts
+func (r *repository) DeleteUser(ctx context.Context, tx service.DBTransaction, userGUID uuid.UUID) error {
+	span := sentry.StartSpan(ctx, "DeleteUser")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	for _, query := range []string{
+		`DELETE FROM alerts WHERE user_guid = $1`,
+		`DELETE FROM survey_states WHERE user_guid = $1`,
+		`DELETE FROM users WHERE guid = $1`,
+	} {
+		if _, err := exec.ExecContext(ctx, query, userGUID); err != nil {
+			return fmt.Errorf("failed to exec query: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AnonymizeUser replaces telegram identity of the user and deletes its alerts, survey states are kept
+func (r *repository) AnonymizeUser(ctx context.Context, tx service.DBTransaction, user entity.User) error {
+	span := sentry.StartSpan(ctx, "AnonymizeUser")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	if _, err := exec.ExecContext(ctx, `DELETE FROM alerts WHERE user_guid = $1`, user.GUID); err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	query := `UPDATE users SET user_id = $1, chat_id = $2, nickname = $3, current_survey = $4, updated_at = $5 WHERE guid = $6`
+	if _, err := exec.ExecContext(ctx, query, user.UserID, user.ChatID, user.Nickname, user.CurrentSurvey, now(), user.GUID); err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserSurveyAttempts returns finished attempts of the survey by the user, the oldest first
+func (r *repository) GetUserSurveyAttempts(ctx context.Context, tx service.DBTransaction, userGUID, surveyGUID uuid.UUID) ([]entity.SurveyStateReport, error) {
+	span := sentry.StartSpan(ctx, "GetUserSurveyAttempts")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []surveyStateReport
+
+	query := `
+	SELECT ss.survey_guid, s.name as survey_name, s.description, ss.created_at, ss.updated_at, ss.user_guid, u.user_id, ss.answers, ss.results
+	FROM survey_states ss
+	JOIN surveys s ON ss.survey_guid = s.guid
+	JOIN users u ON ss.user_guid = u.guid
+	WHERE ss.user_guid = $1 AND ss.survey_guid = $2 AND ss.state = $3
+	ORDER BY ss.updated_at ASC
+	`
+	if err := exec.SelectContext(ctx, &models, query, userGUID, surveyGUID, entity.FinishedState); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var states []entity.SurveyStateReport
+	for _, model := range models {
+		s, err := model.Export()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export survey state: %w", err)
+		}
+
+		states = append(states, s)
+	}
+
+	return states, nil
+}
+
 func (r *repository) UpdateUserLastActivity(ctx context.Context, tx service.DBTransaction, userGUID uuid.UUID) error {
 	span := sentry.StartSpan(ctx, "UpdateUserLastActivity")
 	defer span.Finish()
@@ -599,6 +893,25 @@ func (r *repository) UpdateUserLastActivity(ctx context.Context, tx service.DBTr
 	return nil
 }
 
+func (r *repository) UpdateUserCohort(ctx context.Context, tx service.DBTransaction, userGUID uuid.UUID, cohort string) error {
+	span := sentry.StartSpan(ctx, "UpdateUserCohort")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	nowTime := now()
+
+	query := `UPDATE users SET cohort = $1, updated_at = $2 WHERE guid = $3`
+	if _, err := exec.ExecContext(ctx, query, cohort, nowTime, userGUID); err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return nil
+}
+
 func (r *repository) GetUsersList(ctx context.Context, tx service.DBTransaction, limit, offset int, search string) (service.UserListResponse, error) {
 	span := sentry.StartSpan(ctx, "GetUsersList")
 	defer span.Finish()
@@ -690,3 +1003,502 @@ func (r *repository) GetUsersList(ctx context.Context, tx service.DBTransaction,
 		Total: total,
 	}, nil
 }
+
+// SearchUsers returns users by last activity with progress of their surveys, the search matches part of
+// the nickname or the whole telegram id
+func (r *repository) SearchUsers(ctx context.Context, tx service.DBTransaction, limit, offset int, search string) (service.UserSummaries, error) {
+	span := sentry.StartSpan(ctx, "SearchUsers")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return service.UserSummaries{}, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []userSummary
+	query := `SELECT
+		U.guid, U.user_id, U.nickname, U.created_at, U.last_activity,
+		COUNT(SS.user_guid) FILTER (WHERE SS.state = 'finished') AS completed_tests,
+		COALESCE(SUM(jsonb_array_length(SS.answers)), 0) AS answered_questions
+	FROM users U
+	LEFT JOIN survey_states SS ON SS.user_guid = U.guid
+	WHERE U.nickname ILIKE $1 OR U.user_id::text = $2
+	GROUP BY U.guid
+	ORDER BY U.last_activity DESC, U.guid
+	LIMIT $3 OFFSET $4`
+	if err := exec.SelectContext(ctx, &models, query, "%"+search+"%", search, limit, offset); err != nil {
+		return service.UserSummaries{}, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM users WHERE nickname ILIKE $1 OR user_id::text = $2`
+	if err := exec.GetContext(ctx, &total, countQuery, "%"+search+"%", search); err != nil {
+		return service.UserSummaries{}, fmt.Errorf("failed to exec count query: %w", err)
+	}
+
+	users := make([]service.UserSummary, 0, len(models))
+	for _, model := range models {
+		users = append(users, service.UserSummary{
+			GUID:              model.GUID,
+			UserID:            model.UserID,
+			NickName:          model.NickName,
+			CompletedTests:    model.CompletedTests,
+			AnsweredQuestions: model.AnsweredQuestions,
+			RegisteredAt:      model.CreatedAt,
+			LastActivity:      model.LastActivity,
+		})
+	}
+
+	return service.UserSummaries{Users: users, Total: total}, nil
+}
+
+// CountSurveyStates returns number of states in filterStates by surveys, surveys without them are not returned
+func (r *repository) CountSurveyStates(ctx context.Context, tx service.DBTransaction, filterStates []entity.State) (map[uuid.UUID]int, error) {
+	span := sentry.StartSpan(ctx, "CountSurveyStates")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []struct {
+		SurveyGUID uuid.UUID `db:"survey_guid"`
+		Count      int       `db:"count"`
+	}
+
+	query, args, err := sqlx.In("SELECT survey_guid, COUNT(*) AS count FROM survey_states WHERE state IN(?) GROUP BY survey_guid", filterStates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	if err := exec.SelectContext(ctx, &models, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	counts := make(map[uuid.UUID]int, len(models))
+	for _, model := range models {
+		counts[model.SurveyGUID] = model.Count
+	}
+
+	return counts, nil
+}
+
+// CountStatesOfSurvey returns number of states of the survey in filterStates
+func (r *repository) CountStatesOfSurvey(
+	ctx context.Context,
+	tx service.DBTransaction,
+	surveyGUID uuid.UUID,
+	filterStates []entity.State,
+) (int, error) {
+	span := sentry.StartSpan(ctx, "CountStatesOfSurvey")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	query, args, err := sqlx.In("SELECT COUNT(*) FROM survey_states WHERE survey_guid = ? AND state IN(?)", surveyGUID, filterStates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	var count int
+	if err := exec.GetContext(ctx, &count, query, args...); err != nil {
+		return 0, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetSurveyStatesStats aggregates states of the survey, active states not updated for abandonedAfter are abandoned
+func (r *repository) GetSurveyStatesStats(
+	ctx context.Context,
+	tx service.DBTransaction,
+	surveyGUID uuid.UUID,
+	abandonedAfter time.Duration,
+) (service.SurveyStatesStats, error) {
+	span := sentry.StartSpan(ctx, "GetSurveyStatesStats")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return service.SurveyStatesStats{}, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var counts surveyStatesCounts
+	query := `
+	SELECT COUNT(*) started,
+		COUNT(*) FILTER (WHERE ss.state = $2) finished,
+		COUNT(*) FILTER (WHERE ss.state = $3 AND ss.updated_at < $4) abandoned,
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM ss.updated_at - ss.created_at))
+			FILTER (WHERE ss.state = $2) median_duration
+	FROM survey_states ss
+	WHERE ss.survey_guid = $1
+	`
+	if err := exec.GetContext(ctx, &counts, query, surveyGUID, entity.FinishedState, entity.ActiveState, now().Add(-abandonedAfter)); err != nil {
+		return service.SurveyStatesStats{}, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var funnel []funnelStep
+	query = `
+	SELECT jsonb_array_length(ss.answers) answered, COUNT(*) count
+	FROM survey_states ss
+	WHERE ss.survey_guid = $1 AND ss.state = $2
+	GROUP BY 1
+	ORDER BY 1
+	`
+	if err := exec.SelectContext(ctx, &funnel, query, surveyGUID, entity.ActiveState); err != nil {
+		return service.SurveyStatesStats{}, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var answers []answerCount
+	query = `
+	SELECT a.idx - 1 question, d.value::int value, COUNT(*) count
+	FROM survey_states ss
+	CROSS JOIN LATERAL jsonb_array_elements(ss.answers) WITH ORDINALITY a(answer, idx)
+	CROSS JOIN LATERAL jsonb_array_elements_text(a.answer->'data') d(value)
+	WHERE ss.survey_guid = $1
+	GROUP BY 1, 2
+	ORDER BY 1, 2
+	`
+	if err := exec.SelectContext(ctx, &answers, query, surveyGUID); err != nil {
+		return service.SurveyStatesStats{}, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	stats := service.SurveyStatesStats{
+		Started:   counts.Started,
+		Finished:  counts.Finished,
+		Abandoned: counts.Abandoned,
+	}
+
+	if counts.MedianDuration != nil {
+		median := time.Duration(*counts.MedianDuration * float64(time.Second))
+		stats.MedianDuration = &median
+	}
+
+	for _, step := range funnel {
+		stats.Funnel = append(stats.Funnel, step.Export())
+	}
+
+	for _, answer := range answers {
+		stats.Answers = append(stats.Answers, answer.Export())
+	}
+
+	return stats, nil
+}
+
+// GetScaleScoresDistribution returns histogram of scores of finished surveys grouped by survey, scale and user's cohort
+func (r *repository) GetScaleScoresDistribution(ctx context.Context, tx service.DBTransaction) ([]service.ScaleScoreCount, error) {
+	span := sentry.StartSpan(ctx, "GetScaleScoresDistribution")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []scaleScoreCount
+	query := `
+	SELECT ss.survey_guid,
+		kv.key scale,
+		u.cohort,
+		(kv.value #>> '{}')::float8 score,
+		COUNT(*) count
+	FROM survey_states ss
+	JOIN users u ON ss.user_guid = u.guid
+	CROSS JOIN LATERAL jsonb_each(ss.results->'Metadata'->'Raw') kv
+	WHERE ss.state = $1 AND jsonb_typeof(kv.value) = 'number'
+	GROUP BY 1, 2, 3, 4
+	ORDER BY 1, 2, 3, 4
+	`
+	if err := exec.SelectContext(ctx, &models, query, entity.FinishedState); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var result []service.ScaleScoreCount
+	for _, model := range models {
+		result = append(result, model.Export())
+	}
+
+	return result, nil
+}
+
+// SaveNorms creates norms or updates them if norm with the same survey, scale, cohort and source exists
+func (r *repository) SaveNorms(ctx context.Context, tx service.DBTransaction, norms []entity.Norm) error {
+	span := sentry.StartSpan(ctx, "SaveNorms")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	nowTime := now()
+
+	query := `INSERT INTO survey_norms (survey_guid, scale, cohort, source, mean, std_dev, sample_size, distribution, created_at, updated_at)
+		VALUES (:survey_guid, :scale, :cohort, :source, :mean, :std_dev, :sample_size, :distribution, :created_at, :updated_at)
+		ON CONFLICT (survey_guid, scale, cohort, source) DO UPDATE SET
+			mean = EXCLUDED.mean,
+			std_dev = EXCLUDED.std_dev,
+			sample_size = EXCLUDED.sample_size,
+			distribution = EXCLUDED.distribution,
+			updated_at = EXCLUDED.updated_at`
+
+	for _, n := range norms {
+		var model norm
+		if err := model.Load(n); err != nil {
+			return fmt.Errorf("failed to load norm: %w", err)
+		}
+
+		model.CreatedAt = nowTime
+		model.UpdatedAt = nowTime
+
+		if _, err := exec.NamedExecContext(ctx, query, model); err != nil {
+			return fmt.Errorf("failed to exec query: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteNorms(ctx context.Context, tx service.DBTransaction, source entity.NormSource) error {
+	span := sentry.StartSpan(ctx, "DeleteNorms")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	query := `DELETE FROM survey_norms WHERE source = $1`
+	if _, err := exec.ExecContext(ctx, query, source); err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) GetSurveyNorms(ctx context.Context, tx service.DBTransaction, surveyGUID uuid.UUID) ([]entity.Norm, error) {
+	span := sentry.StartSpan(ctx, "GetSurveyNorms")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []norm
+	query := `SELECT * FROM survey_norms WHERE survey_guid = $1 ORDER BY scale, cohort, source`
+	if err := exec.SelectContext(ctx, &models, query, surveyGUID); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var norms []entity.Norm
+	for _, model := range models {
+		n, err := model.Export()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export norm: %w", err)
+		}
+
+		norms = append(norms, n)
+	}
+
+	return norms, nil
+}
+
+func (r *repository) CreateAlert(ctx context.Context, tx service.DBTransaction, a entity.Alert) error {
+	span := sentry.StartSpan(ctx, "CreateAlert")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var model alert
+	model.Load(a)
+	model.CreatedAt = now()
+
+	query := `INSERT INTO alerts (guid, user_guid, survey_guid, scale, scale_name, score, level, created_at, acknowledged_at, acknowledged_by)
+		VALUES (:guid, :user_guid, :survey_guid, :scale, :scale_name, :score, :level, :created_at, :acknowledged_at, :acknowledged_by)`
+	if _, err := exec.NamedExecContext(ctx, query, model); err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return nil
+}
+
+// AcknowledgeAlert marks alert as acknowledged by the admin, returns service.ErrNotFound if alert
+// does not exist or is already acknowledged
+func (r *repository) AcknowledgeAlert(ctx context.Context, tx service.DBTransaction, alertGUID uuid.UUID, adminUserID int64) error {
+	span := sentry.StartSpan(ctx, "AcknowledgeAlert")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	nowTime := now()
+
+	query := `UPDATE alerts SET acknowledged_at = $1, acknowledged_by = $2 WHERE guid = $3 AND acknowledged_at IS NULL`
+	result, err := exec.ExecContext(ctx, query, nowTime, adminUserID, alertGUID)
+	if err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return service.ErrNotFound
+	}
+
+	return nil
+}
+
+// GetAlerts returns alerts, the latest first
+func (r *repository) GetAlerts(ctx context.Context, tx service.DBTransaction, unacknowledgedOnly bool) ([]entity.Alert, error) {
+	span := sentry.StartSpan(ctx, "GetAlerts")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []alert
+	query := `SELECT * FROM alerts WHERE (NOT $1 OR acknowledged_at IS NULL) ORDER BY created_at DESC`
+	if err := exec.SelectContext(ctx, &models, query, unacknowledgedOnly); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var alerts []entity.Alert
+	for _, model := range models {
+		alerts = append(alerts, model.Export())
+	}
+
+	return alerts, nil
+}
+
+func (r *repository) CreateAPIKey(ctx context.Context, tx service.DBTransaction, key entity.APIKey, keyHash string) error {
+	span := sentry.StartSpan(ctx, "CreateAPIKey")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var model apiKey
+	model.Load(key, keyHash)
+	model.CreatedAt = now()
+
+	query := `INSERT INTO api_keys (guid, name, key_hash, scopes, created_at, last_used_at, revoked_at)
+		VALUES (:guid, :name, :key_hash, :scopes, :created_at, :last_used_at, :revoked_at)`
+	if _, err := exec.NamedExecContext(ctx, query, model); err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash returns not revoked API key, service.ErrNotFound if there is no such key
+func (r *repository) GetAPIKeyByHash(ctx context.Context, tx service.DBTransaction, keyHash string) (entity.APIKey, error) {
+	span := sentry.StartSpan(ctx, "GetAPIKeyByHash")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return entity.APIKey{}, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var model apiKey
+	query := `SELECT * FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+	if err := exec.GetContext(ctx, &model, query, keyHash); err != nil {
+		if err == sql.ErrNoRows {
+			return entity.APIKey{}, service.ErrNotFound
+		}
+
+		return entity.APIKey{}, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return model.Export(), nil
+}
+
+// GetAPIKeys returns all API keys including revoked ones, the oldest first
+func (r *repository) GetAPIKeys(ctx context.Context, tx service.DBTransaction) ([]entity.APIKey, error) {
+	span := sentry.StartSpan(ctx, "GetAPIKeys")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	var models []apiKey
+	query := `SELECT * FROM api_keys ORDER BY created_at`
+	if err := exec.SelectContext(ctx, &models, query); err != nil {
+		return nil, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	var keys []entity.APIKey
+	for _, model := range models {
+		keys = append(keys, model.Export())
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes API key, returns service.ErrNotFound if key does not exist or is already revoked
+func (r *repository) RevokeAPIKey(ctx context.Context, tx service.DBTransaction, keyGUID uuid.UUID) error {
+	span := sentry.StartSpan(ctx, "RevokeAPIKey")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE guid = $2 AND revoked_at IS NULL`
+	result, err := exec.ExecContext(ctx, query, now(), keyGUID)
+	if err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return service.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) UpdateAPIKeyLastUsed(ctx context.Context, tx service.DBTransaction, keyGUID uuid.UUID) error {
+	span := sentry.StartSpan(ctx, "UpdateAPIKeyLastUsed")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE guid = $2`
+	if _, err := exec.ExecContext(ctx, query, now(), keyGUID); err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return nil
+}
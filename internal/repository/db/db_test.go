@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"zombiezen.com/go/postgrestest"
 
 	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
 )
 
@@ -25,23 +28,25 @@ type repisotoryTestSuite struct {
 
 	repo service.DBRepo
 	db   *sqlx.DB
+	dsn  string
 	f    func()
 }
 
 func (suite *repisotoryTestSuite) SetupSuite() {
-	dbConnect, f, err := connectToPG()
+	dbConnect, dsn, f, err := connectToPG()
 	if err != nil {
 		suite.T().Fatal(err)
 	}
 
 	suite.repo = New(dbConnect)
 	suite.db = dbConnect
+	suite.dsn = dsn
 	suite.f = f
 }
 
 func (suite *repisotoryTestSuite) AfterTest(suiteName, testName string) {
 	// truncate all tables here
-	_, err := suite.db.Exec("TRUNCATE TABLE users, surveys, survey_states")
+	_, err := suite.db.Exec("TRUNCATE TABLE users, surveys, survey_states, survey_norms, alerts, api_keys")
 	suite.NoError(err)
 }
 
@@ -1127,6 +1132,34 @@ func (suite *repisotoryTestSuite) TestGetUserByGUIDFailNotFound() {
 	suite.Error(err)
 }
 
+func (suite *repisotoryTestSuite) TestGetUsersByNickname() {
+	nicknames := []string{"Ivan Ivanov (ivan_i)", "Ivan Petrov (ivanxi)", "ivan_i", "unknown"}
+	for i, nickname := range nicknames {
+		_, err := suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, nickname, created_at, updated_at, last_activity) VALUES ($1, $2, $3, $4, $5, $5, $5)",
+			uuid.New(),
+			i+1,
+			i+1,
+			nickname,
+			time.Date(2021, 1, 1, 0, 0, i, 0, time.UTC),
+		)
+		suite.NoError(err)
+	}
+
+	got, err := suite.repo.GetUsersByNickname(context.Background(), nil, "IVAN_I")
+	suite.NoError(err)
+	suite.Len(got, 2)
+	suite.Equal("Ivan Ivanov (ivan_i)", got[0].Nickname)
+	suite.Equal("ivan_i", got[1].Nickname)
+
+	got, err = suite.repo.GetUsersByNickname(context.Background(), nil, "Ivan Petrov (ivanxi)")
+	suite.NoError(err)
+	suite.Len(got, 1)
+
+	got, err = suite.repo.GetUsersByNickname(context.Background(), nil, "ivan")
+	suite.NoError(err)
+	suite.Empty(got)
+}
+
 func (suite *repisotoryTestSuite) TestDeleteUserSurveyState() {
 	// insert user
 	u := user{
@@ -1578,6 +1611,933 @@ func (suite *repisotoryTestSuite) TestGetCompletedSurveys() {
 	}, got)
 }
 
+func (suite *repisotoryTestSuite) TestGetUserSurveyAttempts() {
+	userGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+
+	_, err := suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, current_survey, created_at, updated_at, last_activity) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		userGUID,
+		1,
+		1,
+		nil,
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	)
+	suite.NoError(err)
+
+	_, err = suite.db.Exec("INSERT INTO surveys (guid, id, name, questions, calculations_type, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, '', $6, $7)",
+		surveyGUID,
+		1,
+		"Survey 1",
+		[]byte(`[{"text":"Question 1"}]`),
+		"type1",
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	)
+	suite.NoError(err)
+
+	states := []surveyState{
+		{
+			State:      entity.FinishedState,
+			UserGUID:   userGUID,
+			SurveyGUID: surveyGUID,
+			Answers:    []byte(`[{"data": [2], "type": "segment"}]`),
+			CreatedAt:  time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:  time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			State:      entity.FinishedState,
+			UserGUID:   userGUID,
+			SurveyGUID: surveyGUID,
+			Answers:    []byte(`[{"data": [1], "type": "segment"}]`),
+			CreatedAt:  time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:  time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			State:      entity.ActiveState,
+			UserGUID:   userGUID,
+			SurveyGUID: surveyGUID,
+			Answers:    []byte(`[]`),
+			CreatedAt:  time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:  time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, ss := range states {
+		_, err = suite.db.Exec("INSERT INTO survey_states (state, user_guid, survey_guid, answers, results, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			ss.State,
+			ss.UserGUID,
+			ss.SurveyGUID,
+			ss.Answers,
+			ss.Results,
+			ss.CreatedAt,
+			ss.UpdatedAt,
+		)
+		suite.NoError(err)
+	}
+
+	got, err := suite.repo.GetUserSurveyAttempts(context.Background(), nil, userGUID, surveyGUID)
+	suite.NoError(err)
+
+	suite.equalSurveyStateReports([]entity.SurveyStateReport{
+		{
+			SurveyGUID: surveyGUID,
+			SurveyName: "Survey 1",
+			StartedAt:  time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+			FinishedAt: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+			UserGUID:   "ae2b602c-f255-47e5-b661-a3f17b163adc",
+			UserID:     1,
+			Answers:    []entity.Answer{{Type: entity.AnswerTypeSegment, Data: []int{1}}},
+		},
+		{
+			SurveyGUID: surveyGUID,
+			SurveyName: "Survey 1",
+			StartedAt:  time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			FinishedAt: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			UserGUID:   "ae2b602c-f255-47e5-b661-a3f17b163adc",
+			UserID:     1,
+			Answers:    []entity.Answer{{Type: entity.AnswerTypeSegment, Data: []int{2}}},
+		},
+	}, got)
+}
+
+func (suite *repisotoryTestSuite) TestGetFinishedSurveysAfter() {
+	userGUID1 := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+	userGUID2 := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADF")
+	surveyGUID1 := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+	surveyGUID2 := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADE")
+
+	for i, guid := range []uuid.UUID{userGUID1, userGUID2} {
+		_, err := suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, current_survey, created_at, updated_at, last_activity) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			guid,
+			i+1,
+			i+1,
+			nil,
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+	}
+
+	for i, guid := range []uuid.UUID{surveyGUID1, surveyGUID2} {
+		_, err := suite.db.Exec("INSERT INTO surveys (guid, id, name, questions, calculations_type, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, '', $6, $7)",
+			guid,
+			i+1,
+			fmt.Sprintf("Survey %d", i+1),
+			[]byte(`[{"text":"Question 1"}]`),
+			"type1",
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+	}
+
+	high := []byte(`{"Text": "high", "Scales": [{"Key": "s1", "Name": "Scale 1", "Score": 30, "Level": "high"}]}`)
+	low := []byte(`{"Text": "low", "Scales": [{"Key": "s1", "Name": "Scale 1", "Score": 3, "Level": "low"}]}`)
+
+	states := []surveyState{
+		{State: entity.FinishedState, UserGUID: userGUID1, SurveyGUID: surveyGUID1, Results: &high, UpdatedAt: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{State: entity.FinishedState, UserGUID: userGUID2, SurveyGUID: surveyGUID1, Results: &low, UpdatedAt: time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{State: entity.FinishedState, UserGUID: userGUID2, SurveyGUID: surveyGUID1, Results: &high, UpdatedAt: time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{State: entity.FinishedState, UserGUID: userGUID1, SurveyGUID: surveyGUID2, Results: &high, UpdatedAt: time.Date(2021, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{State: entity.ActiveState, UserGUID: userGUID2, SurveyGUID: surveyGUID1, UpdatedAt: time.Date(2021, 2, 5, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, ss := range states {
+		_, err := suite.db.Exec("INSERT INTO survey_states (state, user_guid, survey_guid, answers, results, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			ss.State,
+			ss.UserGUID,
+			ss.SurveyGUID,
+			[]byte(`[]`),
+			ss.Results,
+			ss.UpdatedAt,
+			ss.UpdatedAt,
+		)
+		suite.NoError(err)
+	}
+
+	finishedAt := func(reports []entity.SurveyStateReport) []time.Time {
+		var result []time.Time
+		for _, report := range reports {
+			result = append(result, report.FinishedAt.UTC())
+		}
+
+		return result
+	}
+
+	got, err := suite.repo.GetFinishedSurveysAfter(context.Background(), nil, service.ResultsFilter{}, nil, 2)
+	suite.NoError(err)
+	suite.Equal([]time.Time{
+		time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC),
+	}, finishedAt(got))
+	suite.Equal("Survey 1", got[0].SurveyName)
+	suite.Equal(int64(1), got[0].UserID)
+
+	got, err = suite.repo.GetFinishedSurveysAfter(context.Background(), nil, service.ResultsFilter{}, &service.ResultsCursor{
+		FinishedAt: time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC),
+		UserGUID:   userGUID2,
+		SurveyGUID: surveyGUID1,
+	}, 10)
+	suite.NoError(err)
+	suite.Equal([]time.Time{
+		time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 2, 4, 0, 0, 0, 0, time.UTC),
+	}, finishedAt(got))
+
+	got, err = suite.repo.GetFinishedSurveysAfter(context.Background(), nil, service.ResultsFilter{
+		SurveyGUID: &surveyGUID1,
+		Scale:      "s1",
+		Level:      "high",
+	}, nil, 10)
+	suite.NoError(err)
+	suite.Equal([]time.Time{
+		time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC),
+	}, finishedAt(got))
+
+	from := time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC)
+	got, err = suite.repo.GetFinishedSurveysAfter(context.Background(), nil, service.ResultsFilter{
+		From:     &from,
+		UserGUID: &userGUID1,
+	}, nil, 10)
+	suite.NoError(err)
+	suite.Equal([]time.Time{
+		time.Date(2021, 2, 4, 0, 0, 0, 0, time.UTC),
+	}, finishedAt(got))
+}
+
+func (suite *repisotoryTestSuite) TestGetUserSurveyReportsAndDeleteUser() {
+	userGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+	otherUserGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADF")
+	surveyGUID1 := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+	surveyGUID2 := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADE")
+
+	for i, guid := range []uuid.UUID{userGUID, otherUserGUID} {
+		_, err := suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, current_survey, created_at, updated_at, last_activity) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			guid,
+			i+1,
+			i+1,
+			nil,
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+	}
+
+	for i, guid := range []uuid.UUID{surveyGUID1, surveyGUID2} {
+		_, err := suite.db.Exec("INSERT INTO surveys (guid, id, name, questions, calculations_type, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, '', $6, $7)",
+			guid,
+			i+1,
+			fmt.Sprintf("Survey %d", i+1),
+			[]byte(`[{"text":"Question 1"}]`),
+			"type1",
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+	}
+
+	results := []byte(`{"Text": "high"}`)
+	states := []surveyState{
+		{State: entity.FinishedState, UserGUID: userGUID, SurveyGUID: surveyGUID1, Results: &results, CreatedAt: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{State: entity.ActiveState, UserGUID: userGUID, SurveyGUID: surveyGUID2, CreatedAt: time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{State: entity.ActiveState, UserGUID: otherUserGUID, SurveyGUID: surveyGUID1, CreatedAt: time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, ss := range states {
+		_, err := suite.db.Exec("INSERT INTO survey_states (state, user_guid, survey_guid, answers, results, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			ss.State,
+			ss.UserGUID,
+			ss.SurveyGUID,
+			[]byte(`[{"type":"select","data":[1]}]`),
+			ss.Results,
+			ss.CreatedAt,
+			ss.CreatedAt,
+		)
+		suite.NoError(err)
+	}
+
+	got, err := suite.repo.GetUserSurveyReports(context.Background(), nil, userGUID)
+	suite.NoError(err)
+	suite.Len(got, 2)
+	suite.Equal(surveyGUID1, got[0].SurveyGUID)
+	suite.Equal(entity.FinishedState, got[0].State)
+	suite.Equal("high", got[0].Results.Text)
+	suite.Equal(surveyGUID2, got[1].SurveyGUID)
+	suite.Equal(entity.ActiveState, got[1].State)
+	suite.Nil(got[1].Results)
+	suite.Equal([]entity.Answer{{Type: entity.AnswerTypeSelect, Data: []int{1}}}, got[1].Answers)
+
+	err = suite.repo.DeleteUser(context.Background(), nil, userGUID)
+	suite.NoError(err)
+
+	_, err = suite.repo.GetUserByGUID(context.Background(), nil, userGUID)
+	suite.ErrorIs(err, service.ErrNotFound)
+
+	got, err = suite.repo.GetUserSurveyReports(context.Background(), nil, userGUID)
+	suite.NoError(err)
+	suite.Empty(got)
+
+	got, err = suite.repo.GetUserSurveyReports(context.Background(), nil, otherUserGUID)
+	suite.NoError(err)
+	suite.Len(got, 1)
+}
+
+func (suite *repisotoryTestSuite) TestDeleteUserSurveyStatesAndAnonymizeUser() {
+	userGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+
+	_, err := suite.db.Exec("INSERT INTO surveys (guid, id, name, questions, calculations_type, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, '', $6, $7)",
+		surveyGUID,
+		1,
+		"Survey 1",
+		[]byte(`[{"text":"Question 1"}]`),
+		"type1",
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	)
+	suite.NoError(err)
+
+	_, err = suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, nickname, current_survey, created_at, updated_at, last_activity) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		userGUID,
+		1,
+		1,
+		"ivan",
+		surveyGUID,
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	)
+	suite.NoError(err)
+
+	for i, state := range []entity.State{entity.FinishedState, entity.FinishedState, entity.ActiveState} {
+		_, err := suite.db.Exec("INSERT INTO survey_states (state, user_guid, survey_guid, answers, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)",
+			state,
+			userGUID,
+			surveyGUID,
+			[]byte(`[{"type":"select","data":[1]}]`),
+			time.Date(2021, 2, i+1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 2, i+1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+	}
+
+	deleted, err := suite.repo.DeleteUserSurveyStates(context.Background(), nil, userGUID, surveyGUID, []entity.State{entity.ActiveState})
+	suite.NoError(err)
+	suite.Equal(1, deleted)
+
+	got, err := suite.repo.GetUserSurveyReports(context.Background(), nil, userGUID)
+	suite.NoError(err)
+	suite.Len(got, 2)
+
+	err = suite.repo.CreateAlert(context.Background(), nil, entity.Alert{
+		GUID:       uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADE"),
+		UserGUID:   userGUID,
+		SurveyGUID: surveyGUID,
+		Scale:      "s1",
+		ScaleName:  "Scale 1",
+		Score:      25,
+		Level:      "high",
+	})
+	suite.NoError(err)
+
+	err = suite.repo.AnonymizeUser(context.Background(), nil, entity.User{GUID: userGUID, UserID: -5, ChatID: -5, Nickname: "anonymous"})
+	suite.NoError(err)
+
+	_, err = suite.repo.GetUserByID(context.Background(), nil, 1)
+	suite.ErrorIs(err, service.ErrNotFound)
+
+	user, err := suite.repo.GetUserByGUID(context.Background(), nil, userGUID)
+	suite.NoError(err)
+	suite.Equal(int64(-5), user.UserID)
+	suite.Equal("anonymous", user.Nickname)
+	suite.Nil(user.CurrentSurvey)
+
+	alerts, err := suite.repo.GetAlerts(context.Background(), nil, false)
+	suite.NoError(err)
+	suite.Empty(alerts)
+
+	// finished states are kept for statistics
+	got, err = suite.repo.GetUserSurveyReports(context.Background(), nil, userGUID)
+	suite.NoError(err)
+	suite.Len(got, 2)
+}
+
+func (suite *repisotoryTestSuite) TestBackupAndRestore() {
+	ctx := context.Background()
+	userGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+	slug := "survey-1"
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	survey := service.BackupSurvey{
+		GUID:             surveyGUID,
+		ID:               1,
+		Slug:             &slug,
+		Name:             "Survey 1",
+		CalculationsType: "type1",
+		Questions:        []byte(`[{"text":"Question 1"}]`),
+		CreatedAt:        createdAt,
+		UpdatedAt:        createdAt,
+	}
+	user := service.BackupUser{
+		GUID:          userGUID,
+		UserID:        1,
+		ChatID:        1,
+		Nickname:      "ivan",
+		CurrentSurvey: &surveyGUID,
+		Cohort:        "default",
+		LastActivity:  createdAt,
+		CreatedAt:     createdAt,
+		UpdatedAt:     createdAt,
+	}
+	state := service.BackupSurveyState{
+		UserGUID:   userGUID,
+		SurveyGUID: surveyGUID,
+		State:      entity.FinishedState,
+		Answers:    []byte(`[{"type":"select","data":[1]}]`),
+		Results:    []byte(`{"text":"good"}`),
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+
+	for _, policy := range []service.RestorePolicy{service.RestoreFail, service.RestoreSkip} {
+		guid, restored, err := suite.repo.RestoreSurvey(ctx, nil, survey, policy)
+		suite.NoError(err)
+		suite.Equal(policy == service.RestoreFail, restored)
+		suite.Equal(surveyGUID, guid)
+
+		guid, restored, err = suite.repo.RestoreUser(ctx, nil, user, policy)
+		suite.NoError(err)
+		suite.Equal(policy == service.RestoreFail, restored)
+		suite.Equal(userGUID, guid)
+
+		restored, err = suite.repo.RestoreSurveyState(ctx, nil, state, policy)
+		suite.NoError(err)
+		suite.Equal(policy == service.RestoreFail, restored)
+	}
+
+	_, _, err := suite.repo.RestoreSurvey(ctx, nil, survey, service.RestoreFail)
+	suite.ErrorIs(err, service.ErrAlreadyExists)
+	_, _, err = suite.repo.RestoreUser(ctx, nil, user, service.RestoreFail)
+	suite.ErrorIs(err, service.ErrAlreadyExists)
+	_, err = suite.repo.RestoreSurveyState(ctx, nil, state, service.RestoreFail)
+	suite.ErrorIs(err, service.ErrAlreadyExists)
+
+	survey.Name = "Survey 2"
+	user.Nickname = "petr"
+	state.State = entity.ActiveState
+	state.Results = nil
+
+	_, restored, err := suite.repo.RestoreSurvey(ctx, nil, survey, service.RestoreOverwrite)
+	suite.NoError(err)
+	suite.True(restored)
+	_, restored, err = suite.repo.RestoreUser(ctx, nil, user, service.RestoreOverwrite)
+	suite.NoError(err)
+	suite.True(restored)
+	restored, err = suite.repo.RestoreSurveyState(ctx, nil, state, service.RestoreOverwrite)
+	suite.NoError(err)
+	suite.True(restored)
+
+	surveys, err := suite.repo.GetBackupSurveys(ctx, nil, 10, 0)
+	suite.NoError(err)
+	suite.Len(surveys, 1)
+	suite.Equal("Survey 2", surveys[0].Name)
+	suite.Equal(&slug, surveys[0].Slug)
+	suite.JSONEq(`[{"text":"Question 1"}]`, string(surveys[0].Questions))
+	suite.True(createdAt.Equal(surveys[0].CreatedAt))
+
+	users, err := suite.repo.GetBackupUsers(ctx, nil, 10, 0)
+	suite.NoError(err)
+	suite.Len(users, 1)
+	suite.Equal("petr", users[0].Nickname)
+	suite.Equal(&surveyGUID, users[0].CurrentSurvey)
+	suite.Equal("default", users[0].Cohort)
+
+	states, err := suite.repo.GetBackupSurveyStates(ctx, nil, 10, 0)
+	suite.NoError(err)
+	suite.Len(states, 1)
+	suite.Equal(entity.ActiveState, states[0].State)
+	suite.Nil(states[0].Results)
+	suite.JSONEq(`[{"type":"select","data":[1]}]`, string(states[0].Answers))
+
+	states, err = suite.repo.GetBackupSurveyStates(ctx, nil, 10, 1)
+	suite.NoError(err)
+	suite.Empty(states)
+}
+
+func (suite *repisotoryTestSuite) TestRestoreSkip_NotEmptyDatabase() {
+	ctx := context.Background()
+	storedUserGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+	storedSurveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+	slug := "survey-1"
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := suite.db.Exec("INSERT INTO surveys (guid, id, name, questions, calculations_type, description, slug, created_at, updated_at) VALUES ($1, 1, 'Survey 1', '[]', 'type1', '', $2, $3, $3)",
+		storedSurveyGUID, slug, createdAt)
+	suite.NoError(err)
+	_, err = suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, current_survey, created_at, updated_at, last_activity) VALUES ($1, 1, 1, NULL, $2, $2, $2)",
+		storedUserGUID, createdAt)
+	suite.NoError(err)
+
+	// the same survey and user are created in another environment under other guids
+	survey := service.BackupSurvey{
+		GUID:             uuid.MustParse("BE2B602C-F255-47E5-B661-A3F17B163ADD"),
+		ID:               2,
+		Slug:             &slug,
+		Name:             "Survey 1",
+		CalculationsType: "type1",
+		Questions:        []byte(`[]`),
+		CreatedAt:        createdAt,
+		UpdatedAt:        createdAt,
+	}
+	user := service.BackupUser{
+		GUID:      uuid.MustParse("BE2B602C-F255-47E5-B661-A3F17B163ADC"),
+		UserID:    1,
+		ChatID:    1,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+
+	guid, restored, err := suite.repo.RestoreSurvey(ctx, nil, survey, service.RestoreSkip)
+	suite.NoError(err)
+	suite.False(restored)
+	suite.Equal(storedSurveyGUID, guid)
+
+	guid, restored, err = suite.repo.RestoreUser(ctx, nil, user, service.RestoreSkip)
+	suite.NoError(err)
+	suite.False(restored)
+	suite.Equal(storedUserGUID, guid)
+
+	// the state refers the stored records
+	restored, err = suite.repo.RestoreSurveyState(ctx, nil, service.BackupSurveyState{
+		UserGUID:   storedUserGUID,
+		SurveyGUID: storedSurveyGUID,
+		State:      entity.FinishedState,
+		Answers:    []byte(`[]`),
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}, service.RestoreSkip)
+	suite.NoError(err)
+	suite.True(restored)
+
+	_, _, err = suite.repo.RestoreUser(ctx, nil, user, service.RestoreOverwrite)
+	suite.ErrorIs(err, service.ErrAlreadyExists)
+}
+
+func (suite *repisotoryTestSuite) TestGetSurveyStatesStats() {
+	now = func() time.Time {
+		return time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+	_, err := suite.db.Exec("INSERT INTO surveys (guid, id, name, questions, calculations_type, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, '', $6, $7)",
+		surveyGUID,
+		1,
+		"Survey 1",
+		[]byte(`[{"text":"Question 1"},{"text":"Question 2"},{"text":"Question 3"}]`),
+		"type1",
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	)
+	suite.NoError(err)
+
+	states := []struct {
+		state     entity.State
+		answers   string
+		createdAt time.Time
+		updatedAt time.Time
+	}{
+		{entity.FinishedState, `[{"type":"select","data":[1]},{"type":"multiselect","data":[1,2]},{"type":"segment","data":[3]}]`, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 1, 0, 10, 0, 0, time.UTC)},
+		{entity.FinishedState, `[{"type":"select","data":[2]},{"type":"multiselect","data":[2]},{"type":"segment","data":[3]}]`, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 1, 0, 20, 0, 0, time.UTC)},
+		{entity.FinishedState, `[{"type":"select","data":[1]},{"type":"multiselect","data":[1]},{"type":"segment","data":[5]}]`, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 1, 0, 30, 0, 0, time.UTC)},
+		{entity.ActiveState, `[{"type":"select","data":[1]}]`, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{entity.ActiveState, `[{"type":"select","data":[2]}]`, time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{entity.ActiveState, `[]`, time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for i, ss := range states {
+		userGUID := uuid.New()
+		_, err := suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, current_survey, created_at, updated_at, last_activity) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			userGUID,
+			i+1,
+			i+1,
+			nil,
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+
+		_, err = suite.db.Exec("INSERT INTO survey_states (state, user_guid, survey_guid, answers, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)",
+			ss.state,
+			userGUID,
+			surveyGUID,
+			[]byte(ss.answers),
+			ss.createdAt,
+			ss.updatedAt,
+		)
+		suite.NoError(err)
+	}
+
+	got, err := suite.repo.GetSurveyStatesStats(context.Background(), nil, surveyGUID, 7*24*time.Hour)
+	suite.NoError(err)
+
+	median := 20 * time.Minute
+	suite.Equal(service.SurveyStatesStats{
+		Started:        6,
+		Finished:       3,
+		Abandoned:      1,
+		MedianDuration: &median,
+		Funnel: []service.FunnelStep{
+			{Answered: 0, Count: 1},
+			{Answered: 1, Count: 2},
+		},
+		Answers: []service.AnswerCount{
+			{Question: 0, Value: 1, Count: 3},
+			{Question: 0, Value: 2, Count: 2},
+			{Question: 1, Value: 1, Count: 2},
+			{Question: 1, Value: 2, Count: 2},
+			{Question: 2, Value: 3, Count: 2},
+			{Question: 2, Value: 5, Count: 1},
+		},
+	}, got)
+
+	got, err = suite.repo.GetSurveyStatesStats(context.Background(), nil, uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADE"), 7*24*time.Hour)
+	suite.NoError(err)
+	suite.Equal(service.SurveyStatesStats{}, got)
+}
+
+func (suite *repisotoryTestSuite) TestAPIKeys() {
+	now = func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	key := entity.APIKey{
+		GUID:   uuid.MustParse("3C1B9B6E-6B7A-4A0E-9C57-4E2F1D8A7B10"),
+		Name:   "bi",
+		Scopes: []entity.APIKeyScope{entity.APIKeyScopeResultsRead, entity.APIKeyScopeSurveysManage},
+	}
+
+	err := suite.repo.CreateAPIKey(context.Background(), nil, key, "hash")
+	suite.NoError(err)
+
+	err = suite.repo.CreateAPIKey(context.Background(), nil, entity.APIKey{GUID: uuid.New(), Name: "other", Scopes: key.Scopes}, "hash")
+	suite.Error(err)
+
+	got, err := suite.repo.GetAPIKeyByHash(context.Background(), nil, "hash")
+	suite.NoError(err)
+	suite.Equal(key.GUID, got.GUID)
+	suite.Equal(key.Scopes, got.Scopes)
+	suite.Nil(got.LastUsedAt)
+
+	_, err = suite.repo.GetAPIKeyByHash(context.Background(), nil, "unknown")
+	suite.ErrorIs(err, service.ErrNotFound)
+
+	now = func() time.Time {
+		return time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	}
+
+	err = suite.repo.UpdateAPIKeyLastUsed(context.Background(), nil, key.GUID)
+	suite.NoError(err)
+
+	err = suite.repo.RevokeAPIKey(context.Background(), nil, key.GUID)
+	suite.NoError(err)
+
+	err = suite.repo.RevokeAPIKey(context.Background(), nil, key.GUID)
+	suite.ErrorIs(err, service.ErrNotFound)
+
+	_, err = suite.repo.GetAPIKeyByHash(context.Background(), nil, "hash")
+	suite.ErrorIs(err, service.ErrNotFound)
+
+	keys, err := suite.repo.GetAPIKeys(context.Background(), nil)
+	suite.NoError(err)
+	suite.Len(keys, 1)
+	suite.Equal(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), keys[0].LastUsedAt.UTC())
+	suite.Equal(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), keys[0].RevokedAt.UTC())
+}
+
+func (suite *repisotoryTestSuite) TestCheckSchemaVersion() {
+	suite.NoError(CheckSchemaVersion(context.Background(), suite.db))
+
+	latest, err := LatestMigration()
+	suite.NoError(err)
+
+	_, err = suite.db.Exec("UPDATE schema_migrations SET dirty = true")
+	suite.NoError(err)
+	defer func() {
+		_, err := suite.db.Exec("UPDATE schema_migrations SET dirty = false")
+		suite.NoError(err)
+	}()
+
+	suite.EqualError(CheckSchemaVersion(context.Background(), suite.db), fmt.Sprintf("migration %d is dirty", latest))
+}
+
+func (suite *repisotoryTestSuite) TestCheckSchemaNotBehind() {
+	suite.NoError(CheckSchemaNotBehind(context.Background(), suite.db))
+
+	latest, err := LatestMigration()
+	suite.NoError(err)
+
+	_, err = suite.db.Exec("UPDATE schema_migrations SET version = $1", latest+1)
+	suite.NoError(err)
+	defer func() {
+		_, err := suite.db.Exec("UPDATE schema_migrations SET version = $1", latest)
+		suite.NoError(err)
+	}()
+
+	// the schema is migrated by a newer release
+	suite.NoError(CheckSchemaNotBehind(context.Background(), suite.db))
+	suite.EqualError(CheckSchemaVersion(context.Background(), suite.db), fmt.Sprintf("schema version is %d, expected %d", latest+1, latest))
+
+	_, err = suite.db.Exec("UPDATE schema_migrations SET version = $1", latest-1)
+	suite.NoError(err)
+	suite.EqualError(CheckSchemaNotBehind(context.Background(), suite.db), fmt.Sprintf("schema version is %d, expected %d", latest-1, latest))
+}
+
+func (suite *repisotoryTestSuite) TestMigrator() {
+	migrations, err := Migrations()
+	suite.NoError(err)
+	suite.Greater(len(migrations), 1)
+	suite.Equal(Migration{Version: 1696449042, Name: "init"}, migrations[0])
+
+	latest := migrations[len(migrations)-1].Version
+	previous := migrations[len(migrations)-2].Version
+
+	m, err := NewMigrator(suite.dsn, nil)
+	suite.NoError(err)
+	defer func() { suite.NoError(m.Close()) }()
+
+	// nothing to apply
+	suite.NoError(m.Up(0))
+	suite.NoError(m.Up(3))
+
+	suite.NoError(m.Down(1))
+	version, dirty, err := m.Version()
+	suite.NoError(err)
+	suite.Equal(previous, version)
+	suite.False(dirty)
+	suite.EqualError(CheckSchemaVersion(context.Background(), suite.db), fmt.Sprintf("schema version is %d, expected %d", previous, latest))
+
+	// fewer migrations are pending than requested
+	suite.NoError(m.Up(3))
+	version, _, err = m.Version()
+	suite.NoError(err)
+	suite.Equal(latest, version)
+
+	suite.Error(m.Force(latest + 1))
+	suite.Error(m.Down(0))
+
+	_, err = suite.db.Exec("UPDATE schema_migrations SET dirty = true")
+	suite.NoError(err)
+	suite.Error(m.Up(0))
+
+	suite.NoError(m.Force(latest))
+	suite.NoError(CheckSchemaVersion(context.Background(), suite.db))
+}
+
+func (suite *repisotoryTestSuite) TestCountSurveyStates() {
+	surveyGUIDs := []uuid.UUID{
+		uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD"),
+		uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADE"),
+	}
+	for i, surveyGUID := range surveyGUIDs {
+		_, err := suite.db.Exec("INSERT INTO surveys (guid, id, name, questions, calculations_type, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, '', $6, $7)",
+			surveyGUID,
+			i+1,
+			fmt.Sprintf("Survey %d", i+1),
+			[]byte(`[{"text":"Question 1"}]`),
+			"type1",
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+	}
+
+	states := []struct {
+		surveyGUID uuid.UUID
+		state      entity.State
+	}{
+		{surveyGUIDs[0], entity.FinishedState},
+		{surveyGUIDs[0], entity.FinishedState},
+		{surveyGUIDs[0], entity.ActiveState},
+		{surveyGUIDs[1], entity.FinishedState},
+	}
+
+	for i, ss := range states {
+		userGUID := uuid.New()
+		_, err := suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, current_survey, created_at, updated_at, last_activity) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			userGUID,
+			i+1,
+			i+1,
+			nil,
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+
+		_, err = suite.db.Exec("INSERT INTO survey_states (state, user_guid, survey_guid, answers, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)",
+			ss.state,
+			userGUID,
+			ss.surveyGUID,
+			[]byte(`[]`),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		)
+		suite.NoError(err)
+	}
+
+	got, err := suite.repo.CountSurveyStates(context.Background(), nil, []entity.State{entity.FinishedState})
+	suite.NoError(err)
+	suite.Equal(map[uuid.UUID]int{surveyGUIDs[0]: 2, surveyGUIDs[1]: 1}, got)
+
+	got, err = suite.repo.CountSurveyStates(context.Background(), nil, []entity.State{entity.ActiveState})
+	suite.NoError(err)
+	suite.Equal(map[uuid.UUID]int{surveyGUIDs[0]: 1}, got)
+
+	count, err := suite.repo.CountStatesOfSurvey(context.Background(), nil, surveyGUIDs[0], []entity.State{entity.FinishedState})
+	suite.NoError(err)
+	suite.Equal(2, count)
+
+	count, err = suite.repo.CountStatesOfSurvey(context.Background(), nil, surveyGUIDs[1], []entity.State{entity.ActiveState})
+	suite.NoError(err)
+	suite.Equal(0, count)
+}
+
+func (suite *repisotoryTestSuite) TestNotifyEvent() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan entity.Event, 10)
+	done := make(chan error)
+	go func() {
+		done <- ListenEvents(ctx, suite.dsn, logger.New("test", "info", "test", io.Discard), func(event entity.Event) {
+			events <- event
+		})
+	}()
+
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+	expected := entity.Event{
+		Type:       entity.EventSurveyFinished,
+		UserGUID:   uuid.MustParse("5E2C1C36-3C2C-4E8A-9E0A-4E4A3E4B7C3D"),
+		Nickname:   "user",
+		SurveyGUID: &surveyGUID,
+		SurveyName: "survey",
+		Answered:   2,
+		Questions:  2,
+		CreatedAt:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	// the event of the rolled back transaction is not delivered
+	tx, err := suite.repo.BeginTx(ctx)
+	suite.NoError(err)
+	suite.NoError(suite.repo.NotifyEvent(ctx, tx, entity.Event{Type: entity.EventUserRegistered}))
+	suite.NoError(tx.Rollback())
+
+	// the listener may not be subscribed yet, so the event is published until it is received
+	var got entity.Event
+	suite.Eventually(func() bool {
+		tx, err := suite.repo.BeginTx(ctx)
+		suite.NoError(err)
+		suite.NoError(suite.repo.NotifyEvent(ctx, tx, expected))
+		suite.NoError(tx.Commit())
+
+		select {
+		case got = <-events:
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	suite.Equal(expected, got)
+
+	cancel()
+	suite.NoError(<-done)
+}
+
+func (suite *repisotoryTestSuite) TestSurveySlug() {
+	now = func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	first := entity.Survey{
+		GUID:             uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC"),
+		CalculationsType: "test_1",
+		ID:               1,
+		Name:             "abc",
+		Questions:        []entity.Question{},
+		Slug:             "abc",
+	}
+	suite.NoError(suite.repo.CreateSurvey(context.Background(), nil, first))
+
+	got, err := suite.repo.GetSurvey(context.Background(), nil, first.GUID)
+	suite.NoError(err)
+	suite.Equal(first, got)
+
+	second := first
+	second.GUID = uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	second.ID = 2
+	suite.ErrorIs(suite.repo.CreateSurvey(context.Background(), nil, second), service.ErrAlreadyExists)
+
+	second.Slug = ""
+	suite.NoError(suite.repo.CreateSurvey(context.Background(), nil, second))
+
+	second.Slug = "abc"
+	suite.ErrorIs(suite.repo.UpdateSurvey(context.Background(), nil, second), service.ErrAlreadyExists)
+
+	// slugs of deleted surveys could be used again
+	suite.NoError(suite.repo.DeleteSurvey(context.Background(), nil, first.GUID))
+	suite.NoError(suite.repo.UpdateSurvey(context.Background(), nil, second))
+
+	got, err = suite.repo.GetSurvey(context.Background(), nil, second.GUID)
+	suite.NoError(err)
+	suite.Equal("abc", got.Slug)
+}
+
+func (suite *repisotoryTestSuite) TestBeginSnapshotTx() {
+	now = func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	first := entity.Survey{
+		GUID:             uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC"),
+		CalculationsType: "test_1",
+		ID:               1,
+		Name:             "abc",
+		Questions:        []entity.Question{},
+	}
+	suite.NoError(suite.repo.CreateSurvey(context.Background(), nil, first))
+
+	tx, err := suite.repo.BeginSnapshotTx(context.Background())
+	suite.NoError(err)
+	defer func() { _ = tx.Rollback() }()
+
+	surveys, err := suite.repo.GetSurveysList(context.Background(), tx)
+	suite.NoError(err)
+	suite.Len(surveys, 1)
+
+	// surveys created after the first query are not seen by the transaction
+	second := first
+	second.GUID = uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	second.ID = 2
+	suite.NoError(suite.repo.CreateSurvey(context.Background(), nil, second))
+
+	surveys, err = suite.repo.GetSurveysList(context.Background(), tx)
+	suite.NoError(err)
+	suite.Len(surveys, 1)
+
+	// the transaction is read-only
+	third := first
+	third.GUID = uuid.MustParse("01046E3D-A8D2-4B1F-9B0B-6A4A3D3B3C4E")
+	third.ID = 3
+	suite.Error(suite.repo.CreateSurvey(context.Background(), tx, third))
+}
+
 func TestRepisotoryTestSuite(t *testing.T) {
 	suite.Run(t, new(repisotoryTestSuite))
 }
@@ -1776,6 +2736,269 @@ func (suite *repisotoryTestSuite) TestGetUsersListWithEmptySearch() {
 	suite.Equal(expected, got)
 }
 
+func (suite *repisotoryTestSuite) TestSearchUsers() {
+	now = func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	users := []user{
+		{
+			GUID:         uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC"),
+			UserID:       12,
+			Nickname:     "user1",
+			CreatedAt:    now(),
+			UpdatedAt:    now(),
+			LastActivity: now(),
+		},
+		{
+			GUID:         uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADF"),
+			UserID:       123,
+			Nickname:     "user2",
+			CreatedAt:    now(),
+			UpdatedAt:    now(),
+			LastActivity: now(),
+		},
+	}
+
+	for _, u := range users {
+		_, err := suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, nickname, created_at, updated_at, last_activity) VALUES ($1, $2, 0, $3, $4, $5, $6)",
+			u.GUID, u.UserID, u.Nickname, u.CreatedAt, u.UpdatedAt, u.LastActivity)
+		suite.NoError(err)
+	}
+
+	// the telegram id matches as a whole, 12 doesn't match 123
+	got, err := suite.repo.SearchUsers(context.Background(), nil, 10, 0, "12")
+	suite.NoError(err)
+
+	suite.Equal(1, got.Total)
+	suite.Len(got.Users, 1)
+	suite.Equal(users[0].GUID, got.Users[0].GUID)
+	suite.Equal(int64(12), got.Users[0].UserID)
+	suite.Equal("user1", got.Users[0].NickName)
+
+	got, err = suite.repo.SearchUsers(context.Background(), nil, 10, 0, "USER")
+	suite.NoError(err)
+
+	suite.Equal(2, got.Total)
+	suite.Len(got.Users, 2)
+}
+
+func (suite *repisotoryTestSuite) TestSaveNorms() {
+	now = func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+	err := suite.repo.CreateSurvey(context.Background(), nil, entity.Survey{
+		GUID:      surveyGUID,
+		ID:        1,
+		Questions: []entity.Question{},
+	})
+	suite.NoError(err)
+
+	norms := []entity.Norm{
+		{
+			SurveyGUID:   surveyGUID,
+			Scale:        "s1",
+			Source:       entity.NormSourcePopulation,
+			Mean:         3,
+			StdDev:       1,
+			SampleSize:   4,
+			Distribution: []entity.NormPoint{{Score: 2, Count: 2}, {Score: 4, Count: 2}},
+		},
+		{
+			SurveyGUID: surveyGUID,
+			Scale:      "s1",
+			Cohort:     "campaign",
+			Source:     entity.NormSourceImported,
+			Mean:       10,
+			StdDev:     2,
+			SampleSize: 100,
+		},
+	}
+
+	err = suite.repo.SaveNorms(context.Background(), nil, norms)
+	suite.NoError(err)
+
+	// update existing norm
+	norms[0].Mean = 5
+	err = suite.repo.SaveNorms(context.Background(), nil, norms[:1])
+	suite.NoError(err)
+
+	got, err := suite.repo.GetSurveyNorms(context.Background(), nil, surveyGUID)
+	suite.NoError(err)
+
+	suite.Equal([]entity.Norm{
+		{
+			SurveyGUID:   surveyGUID,
+			Scale:        "s1",
+			Source:       entity.NormSourcePopulation,
+			Mean:         5,
+			StdDev:       1,
+			SampleSize:   4,
+			Distribution: []entity.NormPoint{{Score: 2, Count: 2}, {Score: 4, Count: 2}},
+		},
+		{
+			SurveyGUID:   surveyGUID,
+			Scale:        "s1",
+			Cohort:       "campaign",
+			Source:       entity.NormSourceImported,
+			Mean:         10,
+			StdDev:       2,
+			SampleSize:   100,
+			Distribution: []entity.NormPoint{},
+		},
+	}, got)
+
+	err = suite.repo.DeleteNorms(context.Background(), nil, entity.NormSourcePopulation)
+	suite.NoError(err)
+
+	got, err = suite.repo.GetSurveyNorms(context.Background(), nil, surveyGUID)
+	suite.NoError(err)
+	suite.Len(got, 1)
+	suite.Equal(entity.NormSourceImported, got[0].Source)
+}
+
+func (suite *repisotoryTestSuite) TestGetScaleScoresDistribution() {
+	now = func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+	err := suite.repo.CreateSurvey(context.Background(), nil, entity.Survey{
+		GUID:      surveyGUID,
+		ID:        1,
+		Questions: []entity.Question{},
+	})
+	suite.NoError(err)
+
+	users := []entity.User{
+		{GUID: uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163AD1"), UserID: 1, ChatID: 1},
+		{GUID: uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163AD2"), UserID: 2, ChatID: 2},
+		{GUID: uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163AD3"), UserID: 3, ChatID: 3, Cohort: "campaign"},
+		{GUID: uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163AD4"), UserID: 4, ChatID: 4},
+	}
+	for _, u := range users {
+		err := suite.repo.CreateUser(context.Background(), nil, u)
+		suite.NoError(err)
+	}
+
+	states := []entity.SurveyState{
+		{
+			State:      entity.FinishedState,
+			UserGUID:   users[0].GUID,
+			SurveyGUID: surveyGUID,
+			Answers:    []entity.Answer{},
+			Results:    &entity.Results{Metadata: entity.ResultsMetadata{Raw: map[string]interface{}{"s1": 2, "name": "abc"}}},
+		},
+		{
+			State:      entity.FinishedState,
+			UserGUID:   users[1].GUID,
+			SurveyGUID: surveyGUID,
+			Answers:    []entity.Answer{},
+			Results:    &entity.Results{Metadata: entity.ResultsMetadata{Raw: map[string]interface{}{"s1": 2}}},
+		},
+		{
+			State:      entity.FinishedState,
+			UserGUID:   users[2].GUID,
+			SurveyGUID: surveyGUID,
+			Answers:    []entity.Answer{},
+			Results:    &entity.Results{Metadata: entity.ResultsMetadata{Raw: map[string]interface{}{"s1": 0.5}}},
+		},
+		{
+			State:      entity.ActiveState,
+			UserGUID:   users[3].GUID,
+			SurveyGUID: surveyGUID,
+			Answers:    []entity.Answer{},
+		},
+	}
+	for _, state := range states {
+		err := suite.repo.CreateUserSurveyState(context.Background(), nil, state)
+		suite.NoError(err)
+	}
+
+	got, err := suite.repo.GetScaleScoresDistribution(context.Background(), nil)
+	suite.NoError(err)
+
+	suite.Equal([]service.ScaleScoreCount{
+		{SurveyGUID: surveyGUID, Scale: "s1", Cohort: "", Score: 2, Count: 2},
+		{SurveyGUID: surveyGUID, Scale: "s1", Cohort: "campaign", Score: 0.5, Count: 1},
+	}, got)
+}
+
+func (suite *repisotoryTestSuite) TestAlerts() {
+	userGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADC")
+	surveyGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADD")
+	alertGUID := uuid.MustParse("AE2B602C-F255-47E5-B661-A3F17B163ADE")
+
+	_, err := suite.db.Exec("INSERT INTO users (guid, user_id, chat_id, current_survey, created_at, updated_at, last_activity) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		userGUID,
+		1,
+		1,
+		nil,
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	)
+	suite.NoError(err)
+
+	_, err = suite.db.Exec("INSERT INTO surveys (guid, id, name, questions, calculations_type, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, '', $6, $7)",
+		surveyGUID,
+		1,
+		"Survey 1",
+		[]byte(`[{"text":"Question 1"}]`),
+		"type1",
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	)
+	suite.NoError(err)
+
+	now = func() time.Time {
+		return time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	err = suite.repo.CreateAlert(context.Background(), nil, entity.Alert{
+		GUID:       alertGUID,
+		UserGUID:   userGUID,
+		SurveyGUID: surveyGUID,
+		Scale:      "s1",
+		ScaleName:  "Scale 1",
+		Score:      25,
+		Level:      "high",
+	})
+	suite.NoError(err)
+
+	got, err := suite.repo.GetAlerts(context.Background(), nil, true)
+	suite.NoError(err)
+	suite.Len(got, 1)
+	suite.Equal(alertGUID, got[0].GUID)
+	suite.Equal(25.0, got[0].Score)
+	suite.Equal("high", got[0].Level)
+	suite.Equal(time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), got[0].CreatedAt.UTC())
+	suite.Nil(got[0].AcknowledgedAt)
+	suite.Nil(got[0].AcknowledgedBy)
+
+	now = func() time.Time {
+		return time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC)
+	}
+
+	err = suite.repo.AcknowledgeAlert(context.Background(), nil, alertGUID, 42)
+	suite.NoError(err)
+
+	err = suite.repo.AcknowledgeAlert(context.Background(), nil, alertGUID, 43)
+	suite.ErrorIs(err, service.ErrNotFound)
+
+	got, err = suite.repo.GetAlerts(context.Background(), nil, true)
+	suite.NoError(err)
+	suite.Empty(got)
+
+	got, err = suite.repo.GetAlerts(context.Background(), nil, false)
+	suite.NoError(err)
+	suite.Len(got, 1)
+	suite.Equal(time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC), got[0].AcknowledgedAt.UTC())
+	suite.Equal(int64(42), *got[0].AcknowledgedBy)
+}
+
 func (suite *repisotoryTestSuite) equalUsers(expected, actual []user) {
 	suite.Len(actual, len(expected))
 	for i := range expected {
@@ -1864,29 +3087,35 @@ func (suite *repisotoryTestSuite) equalSurvey(expected, actual survey) {
 	suite.Equal(expected, actual)
 }
 
-func connectToPG() (*sqlx.DB, func(), error) {
+func connectToPG() (*sqlx.DB, string, func(), error) {
 	ctx := context.Background()
 	srv, err := postgrestest.Start(ctx)
 	if err != nil {
-		return nil, func() {}, fmt.Errorf("Failed to start container: %w", err)
+		return nil, "", func() {}, fmt.Errorf("Failed to start container: %w", err)
+	}
+
+	dsn, err := srv.CreateDatabase(ctx)
+	if err != nil {
+		srv.Cleanup()
+		return nil, "", func() {}, fmt.Errorf("Failed to create database: %w", err)
 	}
 
-	db, err := srv.NewDatabase(ctx)
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		srv.Cleanup()
-		return nil, func() {}, fmt.Errorf("Failed to create database: %w", err)
+		return nil, "", func() {}, fmt.Errorf("Failed to open database: %w", err)
 	}
 
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
 		srv.Cleanup()
-		return nil, func() {}, fmt.Errorf("Failed to create driver: %w", err)
+		return nil, "", func() {}, fmt.Errorf("Failed to create driver: %w", err)
 	}
 
 	d, err := iofs.New(fs, "sql")
 	if err != nil {
 		srv.Cleanup()
-		return nil, func() {}, fmt.Errorf("Failed to create iofs source: %w", err)
+		return nil, "", func() {}, fmt.Errorf("Failed to create iofs source: %w", err)
 	}
 
 	m, err := migrate.NewWithInstance(
@@ -1897,16 +3126,16 @@ func connectToPG() (*sqlx.DB, func(), error) {
 	)
 	if err != nil {
 		srv.Cleanup()
-		return nil, func() {}, fmt.Errorf("Failed to create migrate instance: %w", err)
+		return nil, "", func() {}, fmt.Errorf("Failed to create migrate instance: %w", err)
 	}
 
 	err = m.Up()
 	if err != nil {
 		srv.Cleanup()
-		return nil, func() {}, fmt.Errorf("Failed to migrate: %w", err)
+		return nil, "", func() {}, fmt.Errorf("Failed to migrate: %w", err)
 	}
 
 	dbx := sqlx.NewDb(db, "postgres")
 
-	return dbx, func() { srv.Cleanup() }, nil
+	return dbx, dsn, func() { srv.Cleanup() }, nil
 }
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/lib/pq"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/logger"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+const (
+	// eventsChannel is the channel of LISTEN/NOTIFY events of all replicas are published to
+	eventsChannel = "survey_bot_events"
+
+	listenerMinReconnect = time.Second
+	listenerMaxReconnect = time.Minute
+
+	// listenerPingInterval checks the connection of the listener, notifications are not received without it
+	listenerPingInterval = 90 * time.Second
+)
+
+func (r *repository) NotifyEvent(ctx context.Context, tx service.DBTransaction, event entity.Event) error {
+	span := sentry.StartSpan(ctx, "NotifyEvent")
+	defer span.Finish()
+
+	exec, err := r.castExec(tx)
+	if err != nil {
+		return fmt.Errorf("failed to cast exec: %w", err)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	query := "SELECT pg_notify($1, $2)"
+	if _, err := exec.ExecContext(ctx, query, eventsChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return nil
+}
+
+// ListenEvents receives events published by all replicas until ctx is done, the connection is restored
+// if it is lost, events published meanwhile are not received.
+func ListenEvents(ctx context.Context, connectionString string, log logger.Logger, handle func(entity.Event)) error {
+	listener := pq.NewListener(connectionString, listenerMinReconnect, listenerMaxReconnect, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Errorf(ctx, "events listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(eventsChannel); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := listener.Ping(); err != nil {
+				log.Errorf(ctx, "failed to ping events listener: %v", err)
+			}
+		case n := <-listener.Notify:
+			// nil is sent after reconnect
+			if n == nil {
+				continue
+			}
+
+			var event entity.Event
+			if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+				log.Errorf(ctx, "failed to unmarshal event: %v", err)
+				continue
+			}
+
+			handle(event)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migration is the migration embedded into the binary
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// Migrator applies embedded migrations to the database
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// migrateLogger reports applied migrations, verbose messages of migrate are skipped
+type migrateLogger func(format string, args ...interface{})
+
+func (l migrateLogger) Printf(format string, args ...interface{}) {
+	l(format, args...)
+}
+
+func (l migrateLogger) Verbose() bool {
+	return false
+}
+
+// Migrations returns embedded migrations ordered by version
+func Migrations() ([]Migration, error) {
+	d, err := iofs.New(fs, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create iofs source: %w", err)
+	}
+	defer d.Close()
+
+	version, err := d.First()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get first migration: %w", err)
+	}
+
+	var migrations []Migration
+	for {
+		r, name, err := d.ReadUp(version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, err)
+		}
+		_ = r.Close()
+
+		migrations = append(migrations, Migration{Version: version, Name: name})
+
+		next, err := d.Next(version)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			return migrations, nil
+		case err != nil:
+			return nil, fmt.Errorf("failed to get next migration: %w", err)
+		}
+
+		version = next
+	}
+}
+
+// NewMigrator connects to the database, applied migrations are reported to log if it is not nil
+func NewMigrator(dsn string, log func(format string, args ...interface{})) (*Migrator, error) {
+	d, err := iofs.New(fs, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create iofs source: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", d, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	if log != nil {
+		m.Log = migrateLogger(log)
+	}
+
+	return &Migrator{m: m}, nil
+}
+
+// Version returns version of the applied migrations, 0 if none is applied. Dirty is true if the last
+// migration failed.
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+		return 0, false, nil
+	case err != nil:
+		return 0, false, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// Up applies n pending migrations, all of them if n is 0. It is not an error if there are fewer of them.
+func (m *Migrator) Up(n int) error {
+	var err error
+	if n == 0 {
+		err = m.m.Up()
+	} else {
+		err = m.m.Steps(n)
+	}
+
+	if err := ignoreNoChange(err); err != nil {
+		return fmt.Errorf("failed to migrate up: %w", err)
+	}
+
+	return nil
+}
+
+// Down rolls back n applied migrations. It is not an error if there are fewer of them.
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid number of migrations: %d", n)
+	}
+
+	if err := ignoreNoChange(m.m.Steps(-n)); err != nil {
+		return fmt.Errorf("failed to migrate down: %w", err)
+	}
+
+	return nil
+}
+
+// Force sets the version without running migrations and clears the dirty flag, it is used to recover after
+// the failed migration is fixed by hand. Version 0 means no migration is applied.
+func (m *Migrator) Force(version uint) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	forced := int(version)
+	if version == 0 {
+		forced = -1
+	} else if !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Version == version }) {
+		return fmt.Errorf("unknown migration %d", version)
+	}
+
+	if err := m.m.Force(forced); err != nil {
+		return fmt.Errorf("failed to force version: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	if err := errors.Join(srcErr, dbErr); err != nil {
+		return fmt.Errorf("failed to close migrate instance: %w", err)
+	}
+
+	return nil
+}
+
+// ignoreNoChange returns nil if there is nothing to migrate or fewer migrations than requested are applied
+func ignoreNoChange(err error) error {
+	var short migrate.ErrShortLimit
+	switch {
+	case errors.Is(err, migrate.ErrNoChange), errors.Is(err, os.ErrNotExist), errors.As(err, &short):
+		return nil
+	}
+
+	return err
+}
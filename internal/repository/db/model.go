@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type (
@@ -17,6 +19,7 @@ type (
 		Nickname      string     `db:"nickname"`
 		CurrentSurvey *uuid.UUID `db:"current_survey"`
 		LastActivity  time.Time  `db:"last_activity"`
+		Cohort        string     `db:"cohort"`
 
 		CreatedAt time.Time `db:"created_at"`
 		UpdatedAt time.Time `db:"updated_at"`
@@ -29,6 +32,7 @@ type (
 		Name             string    `db:"name"`
 		Description      string    `db:"description"`
 		Questions        []byte    `db:"questions"`
+		Slug             *string   `db:"slug"`
 
 		CreatedAt time.Time  `db:"created_at"`
 		UpdatedAt time.Time  `db:"updated_at"`
@@ -55,6 +59,8 @@ type (
 		UserID      int64     `db:"user_id"`
 		Answers     []byte    `db:"answers"`
 		Results     *[]byte   `db:"results"`
+
+		State entity.State `db:"state"`
 	}
 
 	userListReportResponse struct {
@@ -65,6 +71,81 @@ type (
 		SurveyState  *entity.State `db:"state"`
 		Answers      *[]byte       `db:"answers"`
 	}
+
+	userSummary struct {
+		GUID              uuid.UUID `db:"guid"`
+		UserID            int64     `db:"user_id"`
+		NickName          string    `db:"nickname"`
+		CreatedAt         time.Time `db:"created_at"`
+		LastActivity      time.Time `db:"last_activity"`
+		CompletedTests    int       `db:"completed_tests"`
+		AnsweredQuestions int       `db:"answered_questions"`
+	}
+
+	norm struct {
+		SurveyGUID   uuid.UUID         `db:"survey_guid"`
+		Scale        string            `db:"scale"`
+		Cohort       string            `db:"cohort"`
+		Source       entity.NormSource `db:"source"`
+		Mean         float64           `db:"mean"`
+		StdDev       float64           `db:"std_dev"`
+		SampleSize   int               `db:"sample_size"`
+		Distribution []byte            `db:"distribution"`
+
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+
+	alert struct {
+		GUID           uuid.UUID  `db:"guid"`
+		UserGUID       uuid.UUID  `db:"user_guid"`
+		SurveyGUID     uuid.UUID  `db:"survey_guid"`
+		Scale          string     `db:"scale"`
+		ScaleName      string     `db:"scale_name"`
+		Score          float64    `db:"score"`
+		Level          string     `db:"level"`
+		CreatedAt      time.Time  `db:"created_at"`
+		AcknowledgedAt *time.Time `db:"acknowledged_at"`
+		AcknowledgedBy *int64     `db:"acknowledged_by"`
+	}
+
+	apiKey struct {
+		GUID       uuid.UUID      `db:"guid"`
+		Name       string         `db:"name"`
+		KeyHash    string         `db:"key_hash"`
+		Scopes     pq.StringArray `db:"scopes"`
+		CreatedAt  time.Time      `db:"created_at"`
+		LastUsedAt *time.Time     `db:"last_used_at"`
+		RevokedAt  *time.Time     `db:"revoked_at"`
+	}
+
+	surveyStatesCounts struct {
+		Started   int `db:"started"`
+		Finished  int `db:"finished"`
+		Abandoned int `db:"abandoned"`
+
+		// seconds
+		MedianDuration *float64 `db:"median_duration"`
+	}
+
+	funnelStep struct {
+		Answered int `db:"answered"`
+		Count    int `db:"count"`
+	}
+
+	answerCount struct {
+		Question int `db:"question"`
+		Value    int `db:"value"`
+		Count    int `db:"count"`
+	}
+
+	scaleScoreCount struct {
+		SurveyGUID uuid.UUID `db:"survey_guid"`
+		Scale      string    `db:"scale"`
+		Cohort     string    `db:"cohort"`
+		Score      float64   `db:"score"`
+		Count      int       `db:"count"`
+	}
 )
 
 func (u user) Export() entity.User {
@@ -75,6 +156,7 @@ func (u user) Export() entity.User {
 		Nickname:      u.Nickname,
 		CurrentSurvey: u.CurrentSurvey,
 		LastActivity:  u.LastActivity,
+		Cohort:        u.Cohort,
 	}
 }
 
@@ -84,14 +166,20 @@ func (s survey) Export() (entity.Survey, error) {
 		return entity.Survey{}, fmt.Errorf("failed to unmarshal questions: %w", err)
 	}
 
-	return entity.Survey{
+	survey := entity.Survey{
 		GUID:             s.GUID,
 		ID:               s.ID,
 		Name:             s.Name,
 		Description:      s.Description,
 		Questions:        questions,
 		CalculationsType: s.CalculationsType,
-	}, nil
+	}
+
+	if s.Slug != nil {
+		survey.Slug = *s.Slug
+	}
+
+	return survey, nil
 }
 
 func (s surveyState) Export() (entity.SurveyState, error) {
@@ -124,6 +212,7 @@ func (um *user) Load(u entity.User) {
 	um.Nickname = u.Nickname
 	um.CurrentSurvey = u.CurrentSurvey
 	um.LastActivity = u.LastActivity
+	um.Cohort = u.Cohort
 }
 
 func (s *survey) Load(survey entity.Survey) error {
@@ -139,6 +228,10 @@ func (s *survey) Load(survey entity.Survey) error {
 	s.ID = survey.ID
 	s.Questions = questions
 
+	if survey.Slug != "" {
+		s.Slug = &survey.Slug
+	}
+
 	return nil
 }
 
@@ -164,6 +257,92 @@ func (s *surveyState) Load(state entity.SurveyState) error {
 	return nil
 }
 
+func (u user) ExportBackup() service.BackupUser {
+	return service.BackupUser{
+		GUID:          u.GUID,
+		UserID:        u.UserID,
+		ChatID:        u.ChatID,
+		Nickname:      u.Nickname,
+		CurrentSurvey: u.CurrentSurvey,
+		Cohort:        u.Cohort,
+		LastActivity:  u.LastActivity,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
+
+func (s survey) ExportBackup() service.BackupSurvey {
+	return service.BackupSurvey{
+		GUID:             s.GUID,
+		ID:               s.ID,
+		Slug:             s.Slug,
+		Name:             s.Name,
+		Description:      s.Description,
+		CalculationsType: s.CalculationsType,
+		Questions:        s.Questions,
+		CreatedAt:        s.CreatedAt,
+		UpdatedAt:        s.UpdatedAt,
+		DeletedAt:        s.DeletedAt,
+	}
+}
+
+func (s surveyState) ExportBackup() service.BackupSurveyState {
+	state := service.BackupSurveyState{
+		UserGUID:   s.UserGUID,
+		SurveyGUID: s.SurveyGUID,
+		State:      s.State,
+		Answers:    s.Answers,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+
+	if s.Results != nil {
+		state.Results = *s.Results
+	}
+
+	return state
+}
+
+func (um *user) LoadBackup(u service.BackupUser) {
+	um.GUID = u.GUID
+	um.UserID = u.UserID
+	um.ChatID = u.ChatID
+	um.Nickname = u.Nickname
+	um.CurrentSurvey = u.CurrentSurvey
+	um.Cohort = u.Cohort
+	um.LastActivity = u.LastActivity
+	um.CreatedAt = u.CreatedAt
+	um.UpdatedAt = u.UpdatedAt
+}
+
+func (s *survey) LoadBackup(survey service.BackupSurvey) {
+	s.GUID = survey.GUID
+	s.ID = survey.ID
+	s.Slug = survey.Slug
+	s.Name = survey.Name
+	s.Description = survey.Description
+	s.CalculationsType = survey.CalculationsType
+	s.Questions = survey.Questions
+	s.CreatedAt = survey.CreatedAt
+	s.UpdatedAt = survey.UpdatedAt
+	s.DeletedAt = survey.DeletedAt
+}
+
+func (s *surveyState) LoadBackup(state service.BackupSurveyState) {
+	s.UserGUID = state.UserGUID
+	s.SurveyGUID = state.SurveyGUID
+	s.State = state.State
+	s.Answers = state.Answers
+	s.CreatedAt = state.CreatedAt
+	s.UpdatedAt = state.UpdatedAt
+
+	// null results are omitted in the backup
+	if len(state.Results) > 0 && string(state.Results) != "null" {
+		results := []byte(state.Results)
+		s.Results = &results
+	}
+}
+
 func (s *surveyStateReport) Export() (entity.SurveyStateReport, error) {
 	var answers []entity.Answer
 	if err := json.Unmarshal(s.Answers, &answers); err != nil {
@@ -179,6 +358,7 @@ func (s *surveyStateReport) Export() (entity.SurveyStateReport, error) {
 		UserGUID:    s.UserGUID,
 		UserID:      s.UserID,
 		Answers:     answers,
+		State:       s.State,
 	}
 
 	if (s.Results != nil) && (len(*s.Results) > 0) {
@@ -192,3 +372,126 @@ func (s *surveyStateReport) Export() (entity.SurveyStateReport, error) {
 
 	return exported, nil
 }
+
+func (n norm) Export() (entity.Norm, error) {
+	var distribution []entity.NormPoint
+	if err := json.Unmarshal(n.Distribution, &distribution); err != nil {
+		return entity.Norm{}, fmt.Errorf("failed to unmarshal distribution: %w", err)
+	}
+
+	return entity.Norm{
+		SurveyGUID:   n.SurveyGUID,
+		Scale:        n.Scale,
+		Cohort:       n.Cohort,
+		Source:       n.Source,
+		Mean:         n.Mean,
+		StdDev:       n.StdDev,
+		SampleSize:   n.SampleSize,
+		Distribution: distribution,
+	}, nil
+}
+
+func (n *norm) Load(e entity.Norm) error {
+	distribution := e.Distribution
+	if distribution == nil {
+		distribution = []entity.NormPoint{}
+	}
+
+	data, err := json.Marshal(distribution)
+	if err != nil {
+		return fmt.Errorf("failed to marshal distribution: %w", err)
+	}
+
+	n.SurveyGUID = e.SurveyGUID
+	n.Scale = e.Scale
+	n.Cohort = e.Cohort
+	n.Source = e.Source
+	n.Mean = e.Mean
+	n.StdDev = e.StdDev
+	n.SampleSize = e.SampleSize
+	n.Distribution = data
+
+	return nil
+}
+
+func (s scaleScoreCount) Export() service.ScaleScoreCount {
+	return service.ScaleScoreCount{
+		SurveyGUID: s.SurveyGUID,
+		Scale:      s.Scale,
+		Cohort:     s.Cohort,
+		Score:      s.Score,
+		Count:      s.Count,
+	}
+}
+
+func (f funnelStep) Export() service.FunnelStep {
+	return service.FunnelStep{
+		Answered: f.Answered,
+		Count:    f.Count,
+	}
+}
+
+func (a answerCount) Export() service.AnswerCount {
+	return service.AnswerCount{
+		Question: a.Question,
+		Value:    a.Value,
+		Count:    a.Count,
+	}
+}
+
+func (a alert) Export() entity.Alert {
+	return entity.Alert{
+		GUID:           a.GUID,
+		UserGUID:       a.UserGUID,
+		SurveyGUID:     a.SurveyGUID,
+		Scale:          a.Scale,
+		ScaleName:      a.ScaleName,
+		Score:          a.Score,
+		Level:          a.Level,
+		CreatedAt:      a.CreatedAt,
+		AcknowledgedAt: a.AcknowledgedAt,
+		AcknowledgedBy: a.AcknowledgedBy,
+	}
+}
+
+func (a *alert) Load(e entity.Alert) {
+	a.GUID = e.GUID
+	a.UserGUID = e.UserGUID
+	a.SurveyGUID = e.SurveyGUID
+	a.Scale = e.Scale
+	a.ScaleName = e.ScaleName
+	a.Score = e.Score
+	a.Level = e.Level
+	a.CreatedAt = e.CreatedAt
+	a.AcknowledgedAt = e.AcknowledgedAt
+	a.AcknowledgedBy = e.AcknowledgedBy
+}
+
+func (k apiKey) Export() entity.APIKey {
+	scopes := make([]entity.APIKeyScope, 0, len(k.Scopes))
+	for _, scope := range k.Scopes {
+		scopes = append(scopes, entity.APIKeyScope(scope))
+	}
+
+	return entity.APIKey{
+		GUID:       k.GUID,
+		Name:       k.Name,
+		Scopes:     scopes,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+func (k *apiKey) Load(e entity.APIKey, keyHash string) {
+	k.GUID = e.GUID
+	k.Name = e.Name
+	k.KeyHash = keyHash
+	k.Scopes = make(pq.StringArray, 0, len(e.Scopes))
+	for _, scope := range e.Scopes {
+		k.Scopes = append(k.Scopes, string(scope))
+	}
+	k.CreatedAt = e.CreatedAt
+	k.LastUsedAt = e.LastUsedAt
+	k.RevokedAt = e.RevokedAt
+}
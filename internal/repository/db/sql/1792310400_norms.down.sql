@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS survey_norms;
+
+DO $$ BEGIN
+    ALTER TABLE users DROP COLUMN cohort;
+EXCEPTION
+    WHEN undefined_column THEN null;
+END $$;
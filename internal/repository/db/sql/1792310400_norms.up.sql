@@ -0,0 +1,25 @@
+DO $$ BEGIN
+    ALTER TABLE users ADD cohort varchar NOT NULL DEFAULT '';
+EXCEPTION
+    WHEN duplicate_column THEN null;
+END $$;
+
+CREATE TABLE IF NOT EXISTS survey_norms (
+    survey_guid UUID NOT NULL,
+    scale varchar NOT NULL,
+    cohort varchar NOT NULL DEFAULT '',
+    source varchar NOT NULL,
+    mean DOUBLE PRECISION NOT NULL,
+    std_dev DOUBLE PRECISION NOT NULL,
+    sample_size INTEGER NOT NULL,
+    distribution JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT survey_norms_pk PRIMARY KEY (survey_guid, scale, cohort, source)
+);
+
+ALTER TABLE survey_norms DROP CONSTRAINT IF EXISTS survey_norms_survey_guid_fk;
+ALTER TABLE
+    survey_norms
+ADD
+    CONSTRAINT survey_norms_survey_guid_fk FOREIGN KEY (survey_guid) REFERENCES surveys(guid);
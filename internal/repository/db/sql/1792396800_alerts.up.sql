@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS alerts (
+    guid UUID NOT NULL,
+    user_guid UUID NOT NULL,
+    survey_guid UUID NOT NULL,
+    scale varchar NOT NULL,
+    scale_name varchar NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    level varchar NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by BIGINT,
+    CONSTRAINT alerts_pk PRIMARY KEY (guid)
+);
+
+ALTER TABLE alerts DROP CONSTRAINT IF EXISTS alerts_user_guid_fk;
+ALTER TABLE
+    alerts
+ADD
+    CONSTRAINT alerts_user_guid_fk FOREIGN KEY (user_guid) REFERENCES users(guid);
+
+ALTER TABLE alerts DROP CONSTRAINT IF EXISTS alerts_survey_guid_fk;
+ALTER TABLE
+    alerts
+ADD
+    CONSTRAINT alerts_survey_guid_fk FOREIGN KEY (survey_guid) REFERENCES surveys(guid);
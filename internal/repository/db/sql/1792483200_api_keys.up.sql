@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    guid UUID NOT NULL,
+    name varchar NOT NULL,
+    key_hash varchar NOT NULL,
+    scopes varchar[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    CONSTRAINT api_keys_pk PRIMARY KEY (guid),
+    CONSTRAINT api_keys_key_hash_key UNIQUE (key_hash)
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS surveys_slug_key;
+
+DO $$ BEGIN
+    ALTER TABLE surveys DROP COLUMN slug;
+EXCEPTION
+    WHEN undefined_column THEN null;
+END $$;
@@ -0,0 +1,7 @@
+DO $$ BEGIN
+    ALTER TABLE surveys ADD slug varchar;
+EXCEPTION
+    WHEN duplicate_column THEN null;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS surveys_slug_key ON surveys (slug) WHERE deleted_at IS NULL;
@@ -2,15 +2,13 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"embed"
-	"errors"
 	"fmt"
 	"log"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
 
 	"git.ykonkov.com/ykonkov/survey-bot/internal/config"
@@ -33,23 +31,15 @@ func ConnectWithTimeout(timeout time.Duration, cnf config.DatabaseConfig) (*sqlx
 
 	select {
 	case db := <-dbCh:
-		d, err := iofs.New(fs, "sql")
-		if err != nil {
-			return nil, fmt.Errorf("failed to create iofs source: %w", err)
-		}
-
-		m, err := migrate.NewWithSourceInstance("iofs", d, cnf.ConnectionString())
-		if err != nil {
-			return nil, fmt.Errorf("failed to create migrate instance: %w", err)
-		}
-
 		if cnf.MigrationsUp {
-			err := m.Up()
-			switch {
-			case errors.Is(err, migrate.ErrNoChange):
-				return db, nil
-			case err != nil:
-				return nil, fmt.Errorf("failed to migrate: %w", err)
+			m, err := NewMigrator(cnf.ConnectionString(), nil)
+			if err != nil {
+				return nil, err
+			}
+			defer m.Close()
+
+			if err := m.Up(0); err != nil {
+				return nil, err
 			}
 		}
 
@@ -77,3 +67,57 @@ func connect(dbCh chan *sqlx.DB, errCh chan error, cnf config.DatabaseConfig) {
 
 	dbCh <- db
 }
+
+// LatestMigration returns version of the last embedded migration, the schema is expected to be migrated to it
+func LatestMigration() (uint, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// SchemaVersion returns version of the applied migrations, dirty is true if the last migration failed
+func SchemaVersion(ctx context.Context, db *sqlx.DB) (version uint, dirty bool, err error) {
+	row := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if err := row.Scan(&version, &dirty); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+
+		return 0, false, fmt.Errorf("failed to exec query: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// CheckSchemaVersion returns error if the schema is not migrated to the latest embedded migration
+func CheckSchemaVersion(ctx context.Context, db *sqlx.DB) error {
+	return checkSchemaVersion(ctx, db, false)
+}
+
+// CheckSchemaNotBehind returns error if the schema is older than the latest embedded migration,
+// a schema already migrated by a newer release is accepted
+func CheckSchemaNotBehind(ctx context.Context, db *sqlx.DB) error {
+	return checkSchemaVersion(ctx, db, true)
+}
+
+func checkSchemaVersion(ctx context.Context, db *sqlx.DB, allowAhead bool) error {
+	latest, err := LatestMigration()
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := SchemaVersion(ctx, db)
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed to get schema version: %w", err)
+	case dirty:
+		return fmt.Errorf("migration %d is dirty", version)
+	case version < latest, version > latest && !allowAhead:
+		return fmt.Errorf("schema version is %d, expected %d", version, latest)
+	}
+
+	return nil
+}
@@ -1,7 +1,11 @@
 package telegram
 
 import (
+	"bytes"
+	stdcontext "context"
+	"errors"
 	"fmt"
+	"html"
 	"strconv"
 	"strings"
 
@@ -9,6 +13,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	tele "gopkg.in/telebot.v3"
 
+	"git.ykonkov.com/ykonkov/survey-bot/internal/chart"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
 	"git.ykonkov.com/ykonkov/survey-bot/internal/responses"
@@ -16,10 +21,12 @@ import (
 )
 
 type client struct {
+	// bot sends messages to chats by id outside of handlers of updates, e.g. alerts to the chat of admins
+	bot *tele.Bot
 }
 
-func NewClient() *client {
-	return &client{}
+func NewClient(bot *tele.Bot) *client {
+	return &client{bot: bot}
 }
 
 func (c *client) SendSurveyList(ctx context.Context, states []service.UserSurveyState) error {
@@ -163,6 +170,58 @@ func (c *client) SendMessage(ctx context.Context, msg string) error {
 	return nil
 }
 
+// SendResults sends chart of the scales as a photo followed by the text of the results
+func (c *client) SendResults(ctx context.Context, survey entity.Survey, results entity.Results) error {
+	span := sentry.StartSpan(ctx, "SendResults")
+	defer span.Finish()
+
+	var chartErr error
+	if len(results.Scales) > 0 {
+		chartErr = c.sendChart(ctx, survey, results)
+	}
+
+	selector := &tele.ReplyMarkup{}
+	selector.Inline(
+		selector.Row(selector.Data(responses.DownloadReport, "report", strconv.FormatInt(survey.ID, 10))),
+		selector.Row(selector.Data("Назад к списку тестов", "menu")),
+	)
+
+	timer := prometheus.NewTimer(messageDuration.WithLabelValues("SendResults"))
+	defer timer.ObserveDuration()
+
+	if err := ctx.Send(results.Text, selector); err != nil {
+		messageCounter.WithLabelValues("failed", "SendResults").Inc()
+		return errors.Join(chartErr, fmt.Errorf("failed to send msg: %w", err))
+	}
+
+	messageCounter.WithLabelValues("success", "SendResults").Inc()
+
+	return chartErr
+}
+
+func (c *client) sendChart(ctx context.Context, survey entity.Survey, results entity.Results) error {
+	image, err := chart.Render("", survey.Name, results.Scales)
+	if err != nil {
+		return fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	photo := &tele.Photo{
+		File: tele.FromReader(bytes.NewReader(image)),
+	}
+
+	timer := prometheus.NewTimer(messageDuration.WithLabelValues("SendChart"))
+	defer timer.ObserveDuration()
+
+	if err := ctx.Send(photo); err != nil {
+		messageCounter.WithLabelValues("failed", "SendChart").Inc()
+		return fmt.Errorf("failed to send chart: %w", err)
+	}
+
+	messageCounter.WithLabelValues("success", "SendChart").Inc()
+
+	return nil
+}
+
 func (c *client) SendFile(ctx context.Context, path string) error {
 	span := sentry.StartSpan(ctx, "SendFile")
 	defer span.Finish()
@@ -185,3 +244,62 @@ func (c *client) SendFile(ctx context.Context, path string) error {
 
 	return nil
 }
+
+func (c *client) SendDocument(ctx context.Context, fileName string, data []byte) error {
+	span := sentry.StartSpan(ctx, "SendDocument")
+	defer span.Finish()
+
+	a := &tele.Document{
+		File:     tele.FromReader(bytes.NewReader(data)),
+		FileName: fileName,
+	}
+
+	timer := prometheus.NewTimer(messageDuration.WithLabelValues("SendDocument"))
+	defer timer.ObserveDuration()
+
+	if err := ctx.Send(a); err != nil {
+		messageCounter.WithLabelValues("failed", "SendDocument").Inc()
+		return fmt.Errorf("failed to send document: %w", err)
+	}
+
+	messageCounter.WithLabelValues("success", "SendDocument").Inc()
+
+	return nil
+}
+
+// SendAlert notifies admins in the chat about the high-risk result with the button to acknowledge it
+func (c *client) SendAlert(ctx stdcontext.Context, chatID int64, alert entity.Alert, user entity.User, surveyName string) error {
+	span := sentry.StartSpan(ctx, "SendAlert")
+	defer span.Finish()
+
+	msg := fmt.Sprintf(
+		responses.AlertNotification,
+		user.UserID,
+		html.EscapeString(user.Nickname),
+		html.EscapeString(surveyName),
+		html.EscapeString(alert.ScaleName),
+		strconv.FormatFloat(alert.Score, 'f', -1, 64),
+		html.EscapeString(alert.Level),
+	)
+
+	selector := &tele.ReplyMarkup{}
+	selector.Inline(
+		selector.Row(selector.Data(responses.AlertAcknowledge, "alert_ack", alert.GUID.String())),
+	)
+
+	timer := prometheus.NewTimer(messageDuration.WithLabelValues("SendAlert"))
+	defer timer.ObserveDuration()
+
+	if c.bot == nil {
+		return errors.New("bot is not set")
+	}
+
+	if _, err := c.bot.Send(tele.ChatID(chatID), msg, selector, tele.ModeHTML); err != nil {
+		messageCounter.WithLabelValues("failed", "SendAlert").Inc()
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+
+	messageCounter.WithLabelValues("success", "SendAlert").Inc()
+
+	return nil
+}
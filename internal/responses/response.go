@@ -11,4 +11,26 @@ const (
 	AnswerNotANumber  = "Ответ не число"
 	InvalidDateFormat = "Некорректный формат даты - 2006-01-20"
 	NoResults         = "Нет результатов"
+	ReportNotFound    = "Отчет не найден, сначала пройдите тест"
+	DownloadReport    = "Скачать отчет (PDF)"
+
+	NormsTitle = "Сравнение с другими респондентами:"
+	NormsScale = "%s: выше, чем у %.0f%% респондентов"
+
+	ChangesTitle    = "Изменения по сравнению с прошлым прохождением:"
+	ChangesScale    = "%s: %s → %s, %s"
+	ChangesAttempts = " (за %d прохождений: %s)"
+	TrendUp         = "рост"
+	TrendDown       = "снижение"
+	TrendStable     = "без изменений"
+
+	AlertHelp = "Если вам сейчас тяжело, пожалуйста, обратитесь к специалисту. " +
+		"Бесплатный круглосуточный телефон доверия: 8-800-2000-122."
+	AlertNotification = "⚠️ Результат высокого риска\n" +
+		"Пользователь: <a href=\"tg://user?id=%d\">%s</a>\n" +
+		"Тест: %s\n" +
+		"Шкала: %s — %s (%s)"
+	AlertAcknowledge         = "Принято"
+	AlertAcknowledged        = "Оповещение принято"
+	AlertAlreadyAcknowledged = "Оповещение уже принято"
 )
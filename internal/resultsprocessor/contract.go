@@ -0,0 +1,123 @@
+package resultsprocessor
+
+import (
+	"fmt"
+	"math"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+// questionContract is the question the calculation relies on, possible answers should be within [min, max]
+type questionContract struct {
+	answerType entity.AnswerType
+	min        int
+	max        int
+}
+
+var (
+	// contracts describe questions of the survey expected by the calculations, answers are taken
+	// by the number of the question, so the order matters
+	contracts = map[string][]questionContract{
+		"test_1": repeatQuestion(22, selectQuestion(1, 7)),
+		"test_2": append(
+			repeatQuestion(6, selectQuestion(1, 5)),
+			// the last answer is not used in calculations
+			questionContract{answerType: entity.AnswerTypeSegment, min: math.MinInt, max: math.MaxInt},
+		),
+		"test_3": repeatQuestion(40, selectQuestion(1, 4)),
+		"test_4": concatQuestions(
+			repeatQuestion(19, selectQuestion(1, 4)),
+			// answer 1 of the 20th question enables the 19th one
+			repeatQuestion(1, selectQuestion(1, 2)),
+			repeatQuestion(2, selectQuestion(1, 4)),
+		),
+		"test_5": repeatQuestion(57, selectQuestion(1, 2)),
+		"test_6": repeatQuestion(42, selectQuestion(1, 2)),
+	}
+)
+
+func (p *processor) ValidationErrors(survey entity.Survey) []entity.ValidationError {
+	errs := survey.ValidationErrors()
+
+	if survey.CalculationsType == "" {
+		return errs
+	}
+
+	if _, ok := calculationsType[survey.CalculationsType]; !ok {
+		return append(errs, entity.ValidationError{
+			Path:    "calculations_type",
+			Message: fmt.Sprintf("unknown calculations type: %s", survey.CalculationsType),
+		})
+	}
+
+	return append(errs, contractErrors(survey)...)
+}
+
+// contractErrors checks that questions of the survey could be processed by its calculations type
+func contractErrors(survey entity.Survey) []entity.ValidationError {
+	contract, ok := contracts[survey.CalculationsType]
+	if !ok {
+		return nil
+	}
+
+	var errs []entity.ValidationError
+
+	if len(survey.Questions) != len(contract) {
+		errs = append(errs, entity.ValidationError{
+			Path:    "questions",
+			Message: fmt.Sprintf("%s expects %d questions, got %d", survey.CalculationsType, len(contract), len(survey.Questions)),
+		})
+	}
+
+	for i, question := range survey.Questions {
+		if i >= len(contract) {
+			break
+		}
+
+		expected := contract[i]
+		if question.AnswerType != expected.answerType {
+			errs = append(errs, entity.ValidationError{
+				Path:    fmt.Sprintf("questions[%d].answer_type", i),
+				Message: fmt.Sprintf("%s expects %s answer type, got %s", survey.CalculationsType, expected.answerType, question.AnswerType),
+			})
+
+			continue
+		}
+
+		for j, possibleAnswer := range question.PossibleAnswers {
+			if possibleAnswer < expected.min || possibleAnswer > expected.max {
+				errs = append(errs, entity.ValidationError{
+					Path: fmt.Sprintf("questions[%d].possible_answers[%d]", i, j),
+					Message: fmt.Sprintf(
+						"%s expects possible answers from %d to %d, got %d",
+						survey.CalculationsType, expected.min, expected.max, possibleAnswer,
+					),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+func selectQuestion(min, max int) questionContract {
+	return questionContract{answerType: entity.AnswerTypeSelect, min: min, max: max}
+}
+
+func repeatQuestion(n int, question questionContract) []questionContract {
+	result := make([]questionContract, n)
+	for i := range result {
+		result[i] = question
+	}
+
+	return result
+}
+
+func concatQuestions(parts ...[]questionContract) []questionContract {
+	var result []questionContract
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+
+	return result
+}
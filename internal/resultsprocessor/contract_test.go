@@ -0,0 +1,97 @@
+package resultsprocessor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+func Test_processor_ValidationErrors(t *testing.T) {
+	files, err := filepath.Glob("../../surveytests/*.json")
+	require.NoError(t, err)
+	require.Len(t, files, len(contracts))
+
+	p := New()
+
+	// surveys the calculations are written for match the contracts
+	for _, file := range files {
+		survey, err := service.ReadSurveyFromFile(file)
+		require.NoError(t, err)
+		require.Empty(t, p.ValidationErrors(survey), file)
+		require.NoError(t, p.Validate(survey), file)
+	}
+
+	test4, err := service.ReadSurveyFromFile("../../surveytests/4.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		modify func(survey *entity.Survey)
+		want   []entity.ValidationError
+	}{
+		{
+			name: "unknown calculations type",
+			modify: func(survey *entity.Survey) {
+				survey.CalculationsType = "test_0"
+			},
+			want: []entity.ValidationError{
+				{Path: "calculations_type", Message: "unknown calculations type: test_0"},
+			},
+		},
+		{
+			name: "missing question",
+			modify: func(survey *entity.Survey) {
+				survey.Questions = survey.Questions[:21]
+			},
+			want: []entity.ValidationError{
+				{Path: "questions", Message: "test_4 expects 22 questions, got 21"},
+			},
+		},
+		{
+			name: "answer type",
+			modify: func(survey *entity.Survey) {
+				survey.Questions[3].AnswerType = entity.AnswerTypeMultiSelect
+			},
+			want: []entity.ValidationError{
+				{Path: "questions[3].answer_type", Message: "test_4 expects select answer type, got multiselect"},
+			},
+		},
+		{
+			name: "possible answers",
+			modify: func(survey *entity.Survey) {
+				survey.Questions[19].PossibleAnswers = []int{1, 2, 3}
+				survey.Questions[19].AnswersText = []string{"Да", "Нет", "Не знаю"}
+			},
+			want: []entity.ValidationError{
+				{Path: "questions[19].possible_answers[2]", Message: "test_4 expects possible answers from 1 to 2, got 3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			survey := test4
+			survey.Questions = append([]entity.Question(nil), test4.Questions...)
+			tt.modify(&survey)
+
+			require.Equal(t, tt.want, p.ValidationErrors(survey))
+		})
+	}
+}
+
+// Test_processor_ValidateWithoutContract checks that surveys not matching their calculations type are still created and
+// updated, the contract is checked only by survey-validate
+func Test_processor_ValidateWithoutContract(t *testing.T) {
+	p := New()
+
+	survey, err := service.ReadSurveyFromFile("../../surveytests/4.json")
+	require.NoError(t, err)
+
+	survey.Questions = survey.Questions[:21]
+
+	require.NotEmpty(t, p.ValidationErrors(survey))
+	require.NoError(t, p.Validate(survey))
+}
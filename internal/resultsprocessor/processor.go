@@ -113,6 +113,11 @@ func calculateTest1(survey entity.Survey, answers []entity.Answer) entity.Result
 				"s3": s3,
 			},
 		},
+		Scales: []entity.ResultsScale{
+			{Key: "s1", Name: "Эмоциональное истощение", Score: float64(s1), Level: s1Level},
+			{Key: "s2", Name: "Деперсонализация", Score: float64(s2), Level: s2Level},
+			{Key: "s3", Name: "Редукция профессионализма", Score: float64(s3), Level: s3Level},
+		},
 	}
 }
 
@@ -182,6 +187,9 @@ func calculateTest2(survey entity.Survey, answers []entity.Answer) entity.Result
 				"s": s,
 			},
 		},
+		Scales: []entity.ResultsScale{
+			{Key: "s", Name: "Качество жизни", Score: s},
+		},
 	}
 }
 
@@ -240,6 +248,10 @@ func calculateTest3(survey entity.Survey, answers []entity.Answer) entity.Result
 				"s2": s2,
 			},
 		},
+		Scales: []entity.ResultsScale{
+			{Key: "s1", Name: "Реактивная тревожность", Score: float64(s1), Level: s1Level},
+			{Key: "s2", Name: "Личностная тревожность", Score: float64(s2), Level: s2Level},
+		},
 	}
 }
 
@@ -280,6 +292,9 @@ func calculateTest4(survey entity.Survey, answers []entity.Answer) entity.Result
 				"s": s,
 			},
 		},
+		Scales: []entity.ResultsScale{
+			{Key: "s", Name: "Депрессия", Score: float64(s), Level: s1Level},
+		},
 	}
 }
 
@@ -415,6 +430,11 @@ func calculateTest5(survey entity.Survey, answers []entity.Answer) entity.Result
 				"lie":                     s3,
 			},
 		},
+		Scales: []entity.ResultsScale{
+			{Key: "estraversia-introversia", Name: "Экстраверсия - интроверсия", Score: float64(s1), Level: s1Level},
+			{Key: "neurotism", Name: "Нейротизм", Score: float64(s2), Level: s2Level},
+			{Key: "lie", Name: "Шкала лжи", Score: float64(s3), Level: s3Level},
+		},
 	}
 }
 
@@ -558,5 +578,13 @@ func calculateTest6(survey entity.Survey, answers []entity.Answer) entity.Result
 				"artistic":     s6,
 			},
 		},
+		Scales: []entity.ResultsScale{
+			{Key: "realistic", Name: "Реалистический тип", Score: float64(s1)},
+			{Key: "intillectual", Name: "Интеллектуальный тип", Score: float64(s2)},
+			{Key: "social", Name: "Социальный тип", Score: float64(s3)},
+			{Key: "conventional", Name: "Конвенциальный тип", Score: float64(s4)},
+			{Key: "enterprising", Name: "Предприимчивый тип", Score: float64(s5)},
+			{Key: "artistic", Name: "Артистический тип", Score: float64(s6)},
+		},
 	}
 }
@@ -37,6 +37,11 @@ func Test_calculateTest1(t *testing.T) {
 						"s3": 33,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "s1", Name: "Эмоциональное истощение", Score: 10, Level: "низкий уровень"},
+					{Key: "s2", Name: "Деперсонализация", Score: 9, Level: "средний уровень"},
+					{Key: "s3", Name: "Редукция профессионализма", Score: 33, Level: "средний уровень"},
+				},
 			},
 		},
 		{
@@ -54,6 +59,11 @@ func Test_calculateTest1(t *testing.T) {
 						"s3": 39,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "s1", Name: "Эмоциональное истощение", Score: 6, Level: "низкий уровень"},
+					{Key: "s2", Name: "Деперсонализация", Score: 2, Level: "низкий уровень"},
+					{Key: "s3", Name: "Редукция профессионализма", Score: 39, Level: "низкий уровень"},
+				},
 			},
 		},
 	}
@@ -105,6 +115,9 @@ func Test_calculateTest4(t *testing.T) {
 						"s": 5,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "s", Name: "Депрессия", Score: 5, Level: "отсутствие депрессивных симптомов"},
+				},
 			},
 		},
 		{
@@ -125,6 +138,9 @@ func Test_calculateTest4(t *testing.T) {
 						"s": 12,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "s", Name: "Депрессия", Score: 12, Level: "легкая депрессия (субдепрессия)"},
+				},
 			},
 		},
 	}
@@ -164,6 +180,10 @@ func Test_calculateTest3(t *testing.T) {
 						"s2": 35,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "s1", Name: "Реактивная тревожность", Score: 32, Level: "средний уровень"},
+					{Key: "s2", Name: "Личностная тревожность", Score: 35, Level: "средний уровень"},
+				},
 			},
 		},
 		{
@@ -180,6 +200,10 @@ func Test_calculateTest3(t *testing.T) {
 						"s2": 46,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "s1", Name: "Реактивная тревожность", Score: 51, Level: "высокий уровень"},
+					{Key: "s2", Name: "Личностная тревожность", Score: 46, Level: "высокий уровень"},
+				},
 			},
 		},
 	}
@@ -218,6 +242,9 @@ func Test_calculateTest2(t *testing.T) {
 						"s": 0.71,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "s", Name: "Качество жизни", Score: 0.71},
+				},
 			},
 		},
 		{
@@ -233,6 +260,9 @@ func Test_calculateTest2(t *testing.T) {
 						"s": 0.259,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "s", Name: "Качество жизни", Score: 0.259},
+				},
 			},
 		},
 	}
@@ -277,6 +307,11 @@ func Test_calculateTest5(t *testing.T) {
 						"lie":                     3,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "estraversia-introversia", Name: "Экстраверсия - интроверсия", Score: 15, Level: "норма"},
+					{Key: "neurotism", Name: "Нейротизм", Score: 24, Level: "очень высокий уровень нейротизма"},
+					{Key: "lie", Name: "Шкала лжи", Score: 3, Level: "норма"},
+				},
 			},
 		},
 		{
@@ -298,6 +333,11 @@ func Test_calculateTest5(t *testing.T) {
 						"lie":                     6,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "estraversia-introversia", Name: "Экстраверсия - интроверсия", Score: 9, Level: "интроверт"},
+					{Key: "neurotism", Name: "Нейротизм", Score: 0, Level: "низкий уровень нейротизма"},
+					{Key: "lie", Name: "Шкала лжи", Score: 6, Level: "неискренность в ответах"},
+				},
 			},
 		},
 	}
@@ -348,6 +388,14 @@ func Test_calculateTest6(t *testing.T) {
 						"artistic":     2,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "realistic", Name: "Реалистический тип", Score: 14},
+					{Key: "intillectual", Name: "Интеллектуальный тип", Score: 11},
+					{Key: "social", Name: "Социальный тип", Score: 8},
+					{Key: "conventional", Name: "Конвенциальный тип", Score: 6},
+					{Key: "enterprising", Name: "Предприимчивый тип", Score: 2},
+					{Key: "artistic", Name: "Артистический тип", Score: 2},
+				},
 			},
 		},
 		{
@@ -375,6 +423,14 @@ func Test_calculateTest6(t *testing.T) {
 						"artistic":     12,
 					},
 				},
+				Scales: []entity.ResultsScale{
+					{Key: "realistic", Name: "Реалистический тип", Score: 0},
+					{Key: "intillectual", Name: "Интеллектуальный тип", Score: 3},
+					{Key: "social", Name: "Социальный тип", Score: 6},
+					{Key: "conventional", Name: "Конвенциальный тип", Score: 8},
+					{Key: "enterprising", Name: "Предприимчивый тип", Score: 11},
+					{Key: "artistic", Name: "Артистический тип", Score: 12},
+				},
 			},
 		},
 	}
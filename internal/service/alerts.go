@@ -0,0 +1,125 @@
+package service
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/responses"
+)
+
+// WithAlerts enables alerts of high-risk results, admins are notified in the chat if chatID is not zero
+func WithAlerts(rules []entity.AlertRule, chatID int64) Option {
+	return func(s *service) {
+		s.alertRules = rules
+		s.alertChatID = chatID
+	}
+}
+
+// createAlerts records alerts of the results matching the rules
+func (s *service) createAlerts(
+	ctx stdcontext.Context,
+	tx DBTransaction,
+	user entity.User,
+	survey entity.Survey,
+	results entity.Results,
+) ([]entity.Alert, error) {
+	var alerts []entity.Alert
+	for _, match := range entity.FindAlerts(s.alertRules, survey.CalculationsType, results) {
+		alert := entity.Alert{
+			GUID:       UUIDProvider(),
+			UserGUID:   user.GUID,
+			SurveyGUID: survey.GUID,
+			Scale:      match.Scale.Key,
+			ScaleName:  match.Scale.Name,
+			Score:      match.Scale.Score,
+			Level:      match.Scale.Level,
+		}
+
+		if err := s.dbRepo.CreateAlert(ctx, tx, alert); err != nil {
+			return nil, fmt.Errorf("failed to create alert: %w", err)
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// helpText returns support resources for the results matching the alert rules, every resource once
+func (s *service) helpText(calculationsType string, results entity.Results) string {
+	var helps []string
+	for _, match := range entity.FindAlerts(s.alertRules, calculationsType, results) {
+		help := match.Rule.Help
+		if help == "" {
+			help = responses.AlertHelp
+		}
+		if !slices.Contains(helps, help) {
+			helps = append(helps, help)
+		}
+	}
+
+	builder := strings.Builder{}
+	for _, help := range helps {
+		builder.WriteString("\n\n")
+		builder.WriteString(help)
+	}
+
+	return builder.String()
+}
+
+// notifyAlerts sends alerts to the chat of admins by the bot, so they are sent wherever the survey is finished
+func (s *service) notifyAlerts(ctx stdcontext.Context, alerts []entity.Alert, user entity.User, survey entity.Survey) {
+	if s.alertChatID == 0 {
+		return
+	}
+
+	for _, alert := range alerts {
+		if err := s.telegramRepo.SendAlert(ctx, s.alertChatID, alert, user, survey.Name); err != nil {
+			s.logger.Errorf(ctx, "failed to send alert %s: %v", alert.GUID, err)
+		}
+	}
+}
+
+// HandleAlertAcknowledge marks alert as acknowledged by the admin pressed the button
+func (s *service) HandleAlertAcknowledge(ctx context.Context, alertGUID uuid.UUID) error {
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		return s.dbRepo.AcknowledgeAlert(ctx, tx, alertGUID, ctx.UserID())
+	}); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			if err := s.telegramRepo.SendMessage(ctx, responses.AlertAlreadyAcknowledged); err != nil {
+				s.logger.Errorf(ctx, "failed to send error message: %w", err)
+			}
+		}
+
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	s.logger.Infof(ctx, "alert %s acknowledged", alertGUID)
+
+	if err := s.telegramRepo.SendMessage(ctx, responses.AlertAcknowledged); err != nil {
+		s.logger.Errorf(ctx, "failed to send message: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) GetAlerts(ctx stdcontext.Context, unacknowledgedOnly bool) ([]entity.Alert, error) {
+	var alerts []entity.Alert
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		alerts, err = s.dbRepo.GetAlerts(ctx, tx, unacknowledgedOnly)
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return alerts, nil
+}
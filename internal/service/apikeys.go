@@ -0,0 +1,120 @@
+package service
+
+import (
+	stdcontext "context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+// apiKeyPrefix makes API keys recognizable, e.g. by secret scanners
+const apiKeyPrefix = "sbk_"
+
+// CreateAPIKey creates API key with the scopes, the secret is returned only once and only its hash is stored
+func (s *service) CreateAPIKey(ctx stdcontext.Context, name string, scopes []entity.APIKeyScope) (entity.APIKey, string, error) {
+	if name == "" {
+		return entity.APIKey{}, "", errors.New("empty name")
+	}
+
+	if len(scopes) == 0 {
+		return entity.APIKey{}, "", errors.New("empty scopes")
+	}
+
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return entity.APIKey{}, "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	key := entity.APIKey{
+		GUID:   UUIDProvider(),
+		Name:   name,
+		Scopes: scopes,
+	}
+
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		if err := s.dbRepo.CreateAPIKey(ctx, tx, key, hashAPIKey(secret)); err != nil {
+			return fmt.Errorf("failed to create api key: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return entity.APIKey{}, "", fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return key, secret, nil
+}
+
+func (s *service) GetAPIKeys(ctx stdcontext.Context) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		keys, err = s.dbRepo.GetAPIKeys(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to get api keys: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (s *service) RevokeAPIKey(ctx stdcontext.Context, keyGUID uuid.UUID) error {
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		if err := s.dbRepo.RevokeAPIKey(ctx, tx, keyGUID); err != nil {
+			return fmt.Errorf("failed to revoke api key: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) AuthenticateAPIKey(ctx stdcontext.Context, secret string) (entity.APIKey, error) {
+	var key entity.APIKey
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		key, err = s.dbRepo.GetAPIKeyByHash(ctx, tx, hashAPIKey(secret))
+		if err != nil {
+			return fmt.Errorf("failed to get api key: %w", err)
+		}
+
+		if err := s.dbRepo.UpdateAPIKeyLastUsed(ctx, tx, key.GUID); err != nil {
+			return fmt.Errorf("failed to update api key last use: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return entity.APIKey{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	s.logger.Infof(ctx, "api key %s (%s) is used", key.GUID, key.Name)
+
+	return key, nil
+}
+
+func newAPIKeySecret() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func hashAPIKey(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	stdcontext "context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/responses"
+)
+
+func (s *service) GetSurveyAttempts(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID) ([]entity.SurveyStateReport, error) {
+	var attempts []entity.SurveyStateReport
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByID(ctx, tx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		attempts, err = s.dbRepo.GetUserSurveyAttempts(ctx, tx, user.GUID, surveyGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get user survey attempts: %w", err)
+		}
+
+		var history []entity.Results
+		for _, attempt := range attempts {
+			if attempt.Results == nil {
+				continue
+			}
+
+			if err := s.applyNorms(ctx, tx, surveyGUID, user.Cohort, attempt.Results); err != nil {
+				return fmt.Errorf("failed to apply norms: %w", err)
+			}
+
+			history = append(history, *attempt.Results)
+			attempt.Results.Changes = entity.CompareAttempts(history)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// applyChanges compares results with the previous finished attempts of the survey
+func (s *service) applyChanges(ctx stdcontext.Context, tx DBTransaction, userGUID, surveyGUID uuid.UUID, results *entity.Results) error {
+	if len(results.Scales) == 0 {
+		return nil
+	}
+
+	attempts, err := s.dbRepo.GetUserSurveyAttempts(ctx, tx, userGUID, surveyGUID)
+	if err != nil {
+		return fmt.Errorf("failed to get user survey attempts: %w", err)
+	}
+
+	var history []entity.Results
+	for _, attempt := range attempts {
+		if attempt.Results != nil {
+			history = append(history, *attempt.Results)
+		}
+	}
+
+	results.Changes = entity.CompareAttempts(append(history, *results))
+
+	return nil
+}
+
+// setChanges compares every survey with the previous attempts, surveys are ordered from the latest
+func setChanges(surveys []entity.SurveyStateReport) {
+	history := make(map[uuid.UUID][]entity.Results)
+	for i := len(surveys) - 1; i >= 0; i-- {
+		if surveys[i].Results == nil {
+			continue
+		}
+
+		guid := surveys[i].SurveyGUID
+		history[guid] = append(history[guid], *surveys[i].Results)
+		surveys[i].Results.Changes = entity.CompareAttempts(history[guid])
+	}
+}
+
+func changesText(results entity.Results) string {
+	if len(results.Changes) == 0 {
+		return ""
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("\n\n")
+	builder.WriteString(responses.ChangesTitle)
+	for _, change := range results.Changes {
+		builder.WriteString("\n")
+		builder.WriteString(fmt.Sprintf(
+			responses.ChangesScale,
+			change.Name,
+			formatScore(change.Previous),
+			formatScore(change.Current),
+			trendText(deltaTrend(change.Delta)),
+		))
+
+		if change.Attempts > 2 {
+			builder.WriteString(fmt.Sprintf(responses.ChangesAttempts, change.Attempts, trendText(change.Trend)))
+		}
+	}
+
+	return builder.String()
+}
+
+func deltaTrend(delta float64) entity.Trend {
+	switch {
+	case delta > 0:
+		return entity.TrendUp
+	case delta < 0:
+		return entity.TrendDown
+	default:
+		return entity.TrendStable
+	}
+}
+
+func trendText(trend entity.Trend) string {
+	switch trend {
+	case entity.TrendUp:
+		return responses.TrendUp
+	case entity.TrendDown:
+		return responses.TrendDown
+	default:
+		return responses.TrendStable
+	}
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(math.Round(score*100)/100, 'f', -1, 64)
+}
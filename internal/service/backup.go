@@ -0,0 +1,333 @@
+package service
+
+import (
+	"archive/zip"
+	stdcontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// BackupVersion is the version of the archive format, it is increased when records are changed incompatibly
+	BackupVersion = 1
+
+	// RestoreFail stops the restore if any record is already stored
+	RestoreFail RestorePolicy = "fail"
+	// RestoreSkip keeps stored records, the records of the backup referring the skipped ones refer the stored ones
+	RestoreSkip RestorePolicy = "skip"
+	// RestoreOverwrite replaces stored records with the ones of the backup
+	RestoreOverwrite RestorePolicy = "overwrite"
+
+	backupManifestName     = "manifest.json"
+	backupSurveysName      = "surveys.jsonl"
+	backupUsersName        = "users.jsonl"
+	backupSurveyStatesName = "survey_states.jsonl"
+
+	// backupBatchSize is a number of records read from db at once during backup
+	backupBatchSize = 1000
+)
+
+// backupFiles are files of the archive in the order they are restored, referenced records go first
+var backupFiles = []string{backupSurveysName, backupUsersName, backupSurveyStatesName}
+
+func (s *service) Backup(ctx stdcontext.Context, w io.Writer, schemaVersion uint) (BackupManifest, error) {
+	manifest := BackupManifest{
+		Version:       BackupVersion,
+		CreatedAt:     now().UTC(),
+		SchemaVersion: schemaVersion,
+	}
+
+	archive := zip.NewWriter(w)
+
+	// all files are consistent with each other, e.g. states reference users of the backup
+	if err := s.transactSnapshot(ctx, func(tx DBTransaction) error {
+		surveys, err := writeBackupFile(archive, backupSurveysName, func(limit, offset int) ([]BackupSurvey, error) {
+			return s.dbRepo.GetBackupSurveys(ctx, tx, limit, offset)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backup surveys: %w", err)
+		}
+
+		users, err := writeBackupFile(archive, backupUsersName, func(limit, offset int) ([]BackupUser, error) {
+			return s.dbRepo.GetBackupUsers(ctx, tx, limit, offset)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backup users: %w", err)
+		}
+
+		states, err := writeBackupFile(archive, backupSurveyStatesName, func(limit, offset int) ([]BackupSurveyState, error) {
+			return s.dbRepo.GetBackupSurveyStates(ctx, tx, limit, offset)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backup survey states: %w", err)
+		}
+
+		manifest.Files = []BackupFile{surveys, users, states}
+
+		return nil
+	}); err != nil {
+		return BackupManifest{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	// the manifest is the last file, as checksums are known only after all records are written
+	file, err := archive.Create(backupManifestName)
+	if err != nil {
+		return BackupManifest{}, fmt.Errorf("failed to create manifest: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return BackupManifest{}, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return BackupManifest{}, fmt.Errorf("failed to close archive: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func (s *service) Restore(ctx stdcontext.Context, r io.ReaderAt, size int64, policy RestorePolicy) (BackupManifest, RestoreStats, error) {
+	switch policy {
+	case RestoreFail, RestoreSkip, RestoreOverwrite:
+	default:
+		return BackupManifest{}, RestoreStats{}, fmt.Errorf("unknown restore policy: %s", policy)
+	}
+
+	// nothing is restored from the broken archive
+	manifest, err := CheckBackup(r, size)
+	if err != nil {
+		return BackupManifest{}, RestoreStats{}, fmt.Errorf("failed to check backup: %w", err)
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return BackupManifest{}, RestoreStats{}, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	// skipped surveys and users could be stored under other guids, e.g. ones created in another environment
+	// with the same slug or user_id, so the records referring them are restored with the stored guids
+	var (
+		stats       RestoreStats
+		surveyGUIDs = make(map[uuid.UUID]uuid.UUID)
+		userGUIDs   = make(map[uuid.UUID]uuid.UUID)
+	)
+	storedGUID := func(guids map[uuid.UUID]uuid.UUID, guid uuid.UUID) uuid.UUID {
+		if stored, ok := guids[guid]; ok {
+			return stored
+		}
+
+		return guid
+	}
+
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+
+		stats.Surveys, err = restoreBackupFile(archive, backupSurveysName, func(survey BackupSurvey) (bool, error) {
+			guid, restored, err := s.dbRepo.RestoreSurvey(ctx, tx, survey, policy)
+			surveyGUIDs[survey.GUID] = guid
+
+			return restored, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore surveys: %w", err)
+		}
+
+		stats.Users, err = restoreBackupFile(archive, backupUsersName, func(user BackupUser) (bool, error) {
+			if user.CurrentSurvey != nil {
+				current := storedGUID(surveyGUIDs, *user.CurrentSurvey)
+				user.CurrentSurvey = &current
+			}
+
+			guid, restored, err := s.dbRepo.RestoreUser(ctx, tx, user, policy)
+			userGUIDs[user.GUID] = guid
+
+			return restored, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore users: %w", err)
+		}
+
+		stats.SurveyStates, err = restoreBackupFile(archive, backupSurveyStatesName, func(state BackupSurveyState) (bool, error) {
+			state.UserGUID = storedGUID(userGUIDs, state.UserGUID)
+			state.SurveyGUID = storedGUID(surveyGUIDs, state.SurveyGUID)
+
+			return s.dbRepo.RestoreSurveyState(ctx, tx, state, policy)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore survey states: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return BackupManifest{}, RestoreStats{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return manifest, stats, nil
+}
+
+// CheckBackup reads the manifest of the archive and checks that all files match their checksums and numbers
+// of records
+func CheckBackup(r io.ReaderAt, size int64) (BackupManifest, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return BackupManifest{}, fmt.Errorf("%w: failed to open archive: %w", ErrInvalidBackup, err)
+	}
+
+	file, err := archive.Open(backupManifestName)
+	if err != nil {
+		return BackupManifest{}, fmt.Errorf("%w: failed to open manifest: %w", ErrInvalidBackup, err)
+	}
+	defer file.Close()
+
+	var manifest BackupManifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		return BackupManifest{}, fmt.Errorf("%w: failed to read manifest: %w", ErrInvalidBackup, err)
+	}
+
+	if manifest.Version != BackupVersion {
+		return BackupManifest{}, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidBackup, manifest.Version, BackupVersion)
+	}
+
+	var names []string
+	for _, f := range manifest.Files {
+		if !slices.Contains(backupFiles, f.Name) {
+			return BackupManifest{}, fmt.Errorf("%w: unknown file %s", ErrInvalidBackup, f.Name)
+		}
+
+		if slices.Contains(names, f.Name) {
+			return BackupManifest{}, fmt.Errorf("%w: duplicate file %s", ErrInvalidBackup, f.Name)
+		}
+		names = append(names, f.Name)
+
+		records, checksum, err := readBackupFile(archive, f.Name)
+		if err != nil {
+			return BackupManifest{}, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+		}
+
+		switch {
+		case checksum != f.SHA256:
+			return BackupManifest{}, fmt.Errorf("%w: checksum of %s doesn't match", ErrInvalidBackup, f.Name)
+		case records != f.Records:
+			return BackupManifest{}, fmt.Errorf("%w: %s has %d records, expected %d", ErrInvalidBackup, f.Name, records, f.Records)
+		}
+	}
+
+	for _, name := range backupFiles {
+		if !slices.Contains(names, name) {
+			return BackupManifest{}, fmt.Errorf("%w: file %s is missing in manifest", ErrInvalidBackup, name)
+		}
+	}
+
+	return manifest, nil
+}
+
+// writeBackupFile writes records returned by get in batches as JSON lines
+func writeBackupFile[T any](archive *zip.Writer, name string, get func(limit, offset int) ([]T, error)) (BackupFile, error) {
+	file, err := archive.Create(name)
+	if err != nil {
+		return BackupFile{}, fmt.Errorf("failed to create %s: %w", name, err)
+	}
+
+	hash := sha256.New()
+	encoder := json.NewEncoder(io.MultiWriter(file, hash))
+	records := 0
+
+	for offset := 0; ; offset += backupBatchSize {
+		batch, err := get(backupBatchSize, offset)
+		if err != nil {
+			return BackupFile{}, fmt.Errorf("failed to get records: %w", err)
+		}
+
+		for _, record := range batch {
+			if err := encoder.Encode(record); err != nil {
+				return BackupFile{}, fmt.Errorf("failed to write record: %w", err)
+			}
+		}
+
+		records += len(batch)
+
+		if len(batch) < backupBatchSize {
+			break
+		}
+	}
+
+	return BackupFile{Name: name, Records: records, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// readBackupFile returns number of records and checksum of the file
+func readBackupFile(archive *zip.Reader, name string) (int, string, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	decoder := json.NewDecoder(io.TeeReader(file, hash))
+
+	records := 0
+	for {
+		var record json.RawMessage
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to read record %d of %s: %w", records+1, name, err)
+		}
+
+		records++
+	}
+
+	// the decoder could stop before the end of the file
+	if _, err := io.Copy(hash, file); err != nil {
+		return 0, "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return records, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// restoreBackupFile restores records of the file one by one
+func restoreBackupFile[T any](archive *zip.Reader, name string, restore func(T) (bool, error)) (RestoreCount, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return RestoreCount{}, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer file.Close()
+
+	var count RestoreCount
+
+	decoder := json.NewDecoder(file)
+	for i := 1; ; i++ {
+		var record T
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return RestoreCount{}, fmt.Errorf("failed to read record %d: %w", i, err)
+		}
+
+		restored, err := restore(record)
+		if err != nil {
+			return RestoreCount{}, fmt.Errorf("failed to restore record %d: %w", i, err)
+		}
+
+		if restored {
+			count.Restored++
+		} else {
+			count.Skipped++
+		}
+	}
+
+	return count, nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	stdcontext "context"
+	"encoding/json"
 	"io"
 	"time"
 
@@ -15,6 +16,48 @@ type (
 	ResultsFilter struct {
 		From *time.Time
 		To   *time.Time
+
+		SurveyGUID *uuid.UUID
+		UserGUID   *uuid.UUID
+
+		// Scale filters results having the scale, with the level on it if Level is set
+		Scale string
+		Level string
+	}
+
+	// ResultsFilterParams are values of the filter as they are given in flags or query parameters,
+	// empty values don't filter results
+	ResultsFilterParams struct {
+		From   string
+		To     string
+		Survey string
+		User   string
+		Scale  string
+		Level  string
+	}
+
+	// ResultsCursor points to the last finished survey of the page
+	ResultsCursor struct {
+		FinishedAt time.Time `json:"finished_at"`
+		UserGUID   uuid.UUID `json:"user_guid"`
+		SurveyGUID uuid.UUID `json:"survey_guid"`
+	}
+
+	ResultsFormat string
+
+	ResultsExport struct {
+		Filter ResultsFilter
+		Format ResultsFormat
+
+		// Progress is called with the number of exported results after every batch, it could be nil
+		Progress func(exported int)
+	}
+
+	ResultsPage struct {
+		Results []entity.SurveyStateReport
+
+		// empty if it is the last page
+		NextCursor string
 	}
 
 	UserSurveyState struct {
@@ -24,6 +67,22 @@ type (
 		IsCurrent bool
 	}
 
+	// UserIdentity is a telegram user, e.g. authenticated by the mini app
+	UserIdentity struct {
+		UserID   int64
+		ChatID   int64
+		Nickname string
+	}
+
+	// SurveyProgress is an active or just finished state of the survey taken by the user
+	SurveyProgress struct {
+		Survey entity.Survey
+		State  entity.SurveyState
+
+		// nil if the survey is finished
+		Question *entity.Question
+	}
+
 	UserListResponse struct {
 		Users []UserReport `json:"users"`
 		Total int          `json:"total"`
@@ -38,32 +97,328 @@ type (
 		LastActivity      time.Time `json:"last_activity"`
 	}
 
+	// UserSummaries are users listed by the CLI, they are found by the telegram id as well
+	UserSummaries struct {
+		Users []UserSummary `json:"users"`
+		Total int           `json:"total"`
+	}
+
+	UserSummary struct {
+		GUID              uuid.UUID `json:"guid"`
+		UserID            int64     `json:"user_id"`
+		NickName          string    `json:"nick_name"`
+		CompletedTests    int       `json:"completed_tests"`
+		AnsweredQuestions int       `json:"answered_questions"`
+		RegisteredAt      time.Time `json:"registered_at"`
+		LastActivity      time.Time `json:"last_activity"`
+	}
+
+	UserDetails struct {
+		User    entity.User
+		Surveys []UserSurveyDetails
+	}
+
+	UserSurveyDetails struct {
+		Report  entity.SurveyStateReport
+		Answers []QuestionAnswer
+
+		// Questions is the number of questions of the survey, zero if the survey is deleted
+		Questions int
+	}
+
+	// QuestionAnswer is an answer of the user mapped to the text of the question
+	QuestionAnswer struct {
+		Question string
+		Answer   string
+	}
+
+	// ScaleScoreCount is a number of finished surveys with the score on the scale
+	ScaleScoreCount struct {
+		SurveyGUID uuid.UUID
+		Scale      string
+		Cohort     string
+		Score      float64
+		Count      int
+	}
+
+	// SurveyStatesStats is aggregated states of the survey, answers are counted in active and finished states
+	SurveyStatesStats struct {
+		Started   int
+		Finished  int
+		Abandoned int
+
+		// nil if there are no finished states
+		MedianDuration *time.Duration
+
+		Funnel  []FunnelStep
+		Answers []AnswerCount
+	}
+
+	// FunnelStep is a number of active states with the number of answered questions
+	FunnelStep struct {
+		Answered int
+		Count    int
+	}
+
+	// AnswerCount is a number of answers to the question with the value, value is a chosen answer or a value of the segment
+	AnswerCount struct {
+		Question int
+		Value    int
+		Count    int
+	}
+
+	SurveyAnalytics struct {
+		Survey entity.Survey
+		SurveyStatesStats
+
+		// CompletionRate is a share of finished states among all started ones
+		CompletionRate float64
+		Questions      []QuestionAnalytics
+	}
+
+	QuestionAnalytics struct {
+		Question entity.Question
+		Answers  []AnswerStats
+	}
+
+	// SurveySummary is a survey with numbers of its states
+	SurveySummary struct {
+		Survey   entity.Survey
+		Active   int
+		Finished int
+	}
+
+	SurveySyncAction string
+
+	// SurveySyncItem is the change of the stored survey made by the sync, surveys are matched by slugs
+	SurveySyncItem struct {
+		Action SurveySyncAction
+		Slug   string
+
+		// Survey is the survey of the file, the stored one for orphaned surveys
+		Survey entity.Survey
+		// Current is the stored survey, nil for created surveys
+		Current *entity.Survey
+		// Adopted is set if the stored survey without slug is matched by name, the slug is set by the sync
+		Adopted bool
+
+		// Changes are fields of the survey changed by the update, e.g. name or questions[2]
+		Changes []string
+		// Active is a number of users with active states of the stored survey
+		Active int
+		// Refused is the reason the update can't be applied, nothing is synced if any update is refused
+		Refused error
+	}
+
+	// SurveySyncPlan is ordered by slugs, orphaned surveys are the last ones
+	SurveySyncPlan []SurveySyncItem
+
+	// SurveyChange is the change of the field of the survey or of its question
+	SurveyChange struct {
+		// Question is the index of the question, -1 for fields of the survey
+		Question int
+		// Field is the changed field, e.g. text, or the possible answer for changes of its label: answer 3
+		Field string
+		// Old and New are empty if the field or the answer is added or removed
+		Old string
+		New string
+
+		// Breaking is the reason answers already stored mean something else after the change, empty if they don't
+		Breaking string
+	}
+
+	SurveyDiff struct {
+		Changes []SurveyChange
+
+		// Refused is the reason UpdateSurvey refuses the update
+		Refused error
+	}
+
+	// BackupManifest describes files of the backup archive
+	BackupManifest struct {
+		Version   int       `json:"version"`
+		CreatedAt time.Time `json:"created_at"`
+		// SchemaVersion is the version of migrations applied to the database the backup is made of
+		SchemaVersion uint         `json:"schema_version"`
+		Files         []BackupFile `json:"files"`
+	}
+
+	BackupFile struct {
+		Name    string `json:"name"`
+		Records int    `json:"records"`
+		SHA256  string `json:"sha256"`
+	}
+
+	BackupUser struct {
+		GUID          uuid.UUID  `json:"guid"`
+		UserID        int64      `json:"user_id"`
+		ChatID        int64      `json:"chat_id"`
+		Nickname      string     `json:"nickname"`
+		CurrentSurvey *uuid.UUID `json:"current_survey"`
+		Cohort        string     `json:"cohort"`
+		LastActivity  time.Time  `json:"last_activity"`
+		CreatedAt     time.Time  `json:"created_at"`
+		UpdatedAt     time.Time  `json:"updated_at"`
+	}
+
+	BackupSurvey struct {
+		GUID             uuid.UUID       `json:"guid"`
+		ID               int64           `json:"id"`
+		Slug             *string         `json:"slug"`
+		Name             string          `json:"name"`
+		Description      string          `json:"description"`
+		CalculationsType string          `json:"calculations_type"`
+		Questions        json.RawMessage `json:"questions"`
+		CreatedAt        time.Time       `json:"created_at"`
+		UpdatedAt        time.Time       `json:"updated_at"`
+		DeletedAt        *time.Time      `json:"deleted_at"`
+	}
+
+	// BackupSurveyState is identified by the user, the survey and the time it is created at, as the table has
+	// no primary key
+	BackupSurveyState struct {
+		UserGUID   uuid.UUID       `json:"user_guid"`
+		SurveyGUID uuid.UUID       `json:"survey_guid"`
+		State      entity.State    `json:"state"`
+		Answers    json.RawMessage `json:"answers"`
+		Results    json.RawMessage `json:"results,omitempty"`
+		CreatedAt  time.Time       `json:"created_at"`
+		UpdatedAt  time.Time       `json:"updated_at"`
+	}
+
+	// RestorePolicy tells what to do with records of the backup already stored in the database
+	RestorePolicy string
+
+	RestoreStats struct {
+		Surveys      RestoreCount
+		Users        RestoreCount
+		SurveyStates RestoreCount
+	}
+
+	RestoreCount struct {
+		Restored int
+		Skipped  int
+	}
+
+	AnswerStats struct {
+		Value int
+		Text  string
+		Count int
+	}
+
 	Service interface {
 		HandleResultsCommand(ctx context.Context, f ResultsFilter) error
-		HandleStartCommand(ctx context.Context) error
+		HandleStartCommand(ctx context.Context, cohort string) error
 		HandleSurveyCommand(ctx context.Context, surveyID int64) error
 		HandleListCommand(ctx context.Context) error
 		HandleAnswer(ctx context.Context, msg string) error
+		HandleReportCommand(ctx context.Context, surveyID int64) error
+		HandleAlertAcknowledge(ctx context.Context, alertGUID uuid.UUID) error
 
 		GetCompletedSurveys(ctx stdcontext.Context, userID int64) ([]entity.SurveyStateReport, error)
+
+		// Returns all surveys ordered by id with the state of the user, the same as in the survey list of the bot.
+		GetUserSurveys(ctx stdcontext.Context, userID int64) ([]UserSurveyState, error)
+		// Starts the survey or resumes the active one, the survey becomes current for the user.
+		StartSurvey(ctx stdcontext.Context, identity UserIdentity, surveyGUID uuid.UUID) (SurveyProgress, error)
+		// Returns the active state of the survey, ErrNotFound if the survey is not started.
+		GetSurveyProgress(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID) (SurveyProgress, error)
+		// Answers the next question of the active survey, the survey is finished with the last answer.
+		AnswerSurvey(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID, answer string) (SurveyProgress, error)
+		// Returns the finished attempt of the survey by the user started at the time, ErrNotFound if there is no such attempt.
+		GetCompletedSurvey(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID, startedAt time.Time) (entity.SurveyStateReport, error)
+		GetCompletedSurveyReport(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID, startedAt time.Time, withAnswers bool) ([]byte, error)
+
+		// Returns finished attempts of the survey by the user, the oldest first.
+		GetSurveyAttempts(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID) ([]entity.SurveyStateReport, error)
 		GetUsersList(ctx stdcontext.Context, limit, offset int, search string) (UserListResponse, error)
+		// Returns users by last activity, the search matches part of the nickname or the whole telegram id.
+		SearchUsers(ctx stdcontext.Context, limit, offset int, search string) (UserSummaries, error)
+		GetUserByGUID(ctx stdcontext.Context, guid uuid.UUID) (entity.User, error)
+		// Finds the user by guid, telegram id or nickname, ErrAmbiguousUser is returned if the nickname matches
+		// several users.
+		FindUser(ctx stdcontext.Context, query string) (entity.User, error)
+
+		// Returns profile of the user with all survey states, answers are mapped to the questions.
+		GetUserDetails(ctx stdcontext.Context, userGUID uuid.UUID) (UserDetails, error)
+		SetUserCurrentSurveyToNil(ctx stdcontext.Context, userGUID uuid.UUID) error
+		DeleteUserSurvey(ctx stdcontext.Context, userGUID uuid.UUID, surveyGUID uuid.UUID) error
+
+		// Deletes the user with all survey states and alerts.
+		DeleteUser(ctx stdcontext.Context, userGUID uuid.UUID) error
+		// Removes telegram identity of the user and its alerts, survey states are kept for statistics.
+		AnonymizeUser(ctx stdcontext.Context, userGUID uuid.UUID) error
+		// Deletes the active state of the survey by the user, so it is started again, and finished attempts
+		// if withFinished is set. Returns number of deleted states.
+		ResetUserSurvey(ctx stdcontext.Context, userGUID, surveyGUID uuid.UUID, withFinished bool) (int, error)
 
 		SaveFinishedSurveys(ctx stdcontext.Context, tx DBTransaction, w io.Writer, f ResultsFilter, batchSize int) (int, error)
+
+		// Returns page of finished surveys after the cursor, empty cursor means the first page.
+		GetResults(ctx stdcontext.Context, f ResultsFilter, cursor string, limit int) (ResultsPage, error)
+		// Writes all finished surveys matching the filter in the format, returns their number.
+		ExportResults(ctx stdcontext.Context, w io.Writer, export ResultsExport) (int, error)
+
+		// Returns not deleted surveys ordered by id.
+		GetSurveys(ctx stdcontext.Context) ([]entity.Survey, error)
+		// Returns not deleted surveys ordered by id with numbers of active and finished states.
+		GetSurveySummaries(ctx stdcontext.Context) ([]SurveySummary, error)
+		GetSurvey(ctx stdcontext.Context, surveyGUID uuid.UUID) (entity.Survey, error)
+		// Returns the funnel, completion and answers distribution of the survey, active states not updated
+		// for abandonedAfter are counted as abandoned.
+		GetSurveyAnalytics(ctx stdcontext.Context, surveyGUID uuid.UUID, abandonedAfter time.Duration) (SurveyAnalytics, error)
 		CreateSurvey(ctx stdcontext.Context, s entity.Survey) (entity.Survey, error)
 
 		// Updates "name", "questions" and "calculations_type" fields.
 		UpdateSurvey(ctx stdcontext.Context, s entity.Survey) error
+
+		// Returns changes of the stored survey made by UpdateSurvey with the new one, nothing is changed.
+		DiffSurvey(ctx stdcontext.Context, new entity.Survey) (SurveyDiff, error)
+
+		// Returns the plan of syncing surveys of files with the stored ones, nothing is changed.
+		PlanSurveySync(ctx stdcontext.Context, surveys []entity.Survey) (SurveySyncPlan, error)
+		// Applies the plan in one transaction. The plan is made again and ErrSyncPlanOutdated is returned if it
+		// differs, refused updates are returned as errors. Orphaned surveys are kept.
+		SyncSurveys(ctx stdcontext.Context, plan SurveySyncPlan) error
+
+		// Soft deletes the survey, it is not shown to users anymore. ErrSurveyInUse is returned
+		// if users have active states of the survey, unless force is set.
+		DeleteSurvey(ctx stdcontext.Context, surveyGUID uuid.UUID, force bool) error
+
+		// Writes the archive of surveys, users and survey states read from the same snapshot of data.
+		Backup(ctx stdcontext.Context, w io.Writer, schemaVersion uint) (BackupManifest, error)
+		// Checks the archive and imports it in one transaction, records already stored are handled by the policy.
+		Restore(ctx stdcontext.Context, r io.ReaderAt, size int64, policy RestorePolicy) (BackupManifest, RestoreStats, error)
+
+		// Creates API key, the returned secret is not stored and can't be shown again.
+		CreateAPIKey(ctx stdcontext.Context, name string, scopes []entity.APIKeyScope) (entity.APIKey, string, error)
+		GetAPIKeys(ctx stdcontext.Context) ([]entity.APIKey, error)
+		RevokeAPIKey(ctx stdcontext.Context, keyGUID uuid.UUID) error
+		// Returns not revoked API key by its secret and records its use, ErrNotFound if the key is unknown.
+		AuthenticateAPIKey(ctx stdcontext.Context, secret string) (entity.APIKey, error)
+
+		// Recalculates population norms of all surveys from finished results.
+		RefreshNorms(ctx stdcontext.Context) error
+		ImportNorms(ctx stdcontext.Context, norms []entity.Norm) error
+
+		// Returns live events of users activity and the function to unsubscribe, the channel is nil if events are not enabled.
+		SubscribeEvents(ctx stdcontext.Context) (<-chan entity.Event, func())
 	}
 
 	TelegramRepo interface {
 		SendSurveyList(ctx context.Context, states []UserSurveyState) error
 		SendSurveyQuestion(ctx context.Context, question entity.Question) error
 		SendMessage(ctx context.Context, msg string) error
+		SendResults(ctx context.Context, survey entity.Survey, results entity.Results) error
 		SendFile(ctx context.Context, path string) error
+		SendDocument(ctx context.Context, fileName string, data []byte) error
+		SendAlert(ctx stdcontext.Context, chatID int64, alert entity.Alert, user entity.User, surveyName string) error
 	}
 
 	DBRepo interface {
 		BeginTx(ctx stdcontext.Context) (DBTransaction, error)
+		// Begins read-only repeatable read transaction.
+		BeginSnapshotTx(ctx stdcontext.Context) (DBTransaction, error)
 
 		CreateSurvey(ctx stdcontext.Context, exec DBTransaction, s entity.Survey) error
 		DeleteSurvey(ctx stdcontext.Context, exec DBTransaction, surveyGUID uuid.UUID) error
@@ -74,20 +429,61 @@ type (
 
 		GetUserByGUID(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID) (entity.User, error)
 		GetUserByID(ctx stdcontext.Context, exec DBTransaction, userID int64) (entity.User, error)
+		// Returns users with the nickname or the telegram username in it, case is ignored.
+		GetUsersByNickname(ctx stdcontext.Context, exec DBTransaction, nickname string) ([]entity.User, error)
 		CreateUser(ctx stdcontext.Context, exec DBTransaction, user entity.User) error
 		UpdateUserCurrentSurvey(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID, surveyGUID uuid.UUID) error
 		UpdateUserLastActivity(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID) error
+		UpdateUserCohort(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID, cohort string) error
 		SetUserCurrentSurveyToNil(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID) error
 		GetCompletedSurveys(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID) ([]entity.SurveyStateReport, error)
+		GetUserSurveyAttempts(ctx stdcontext.Context, exec DBTransaction, userGUID, surveyGUID uuid.UUID) ([]entity.SurveyStateReport, error)
+		GetUserSurveyReports(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID) ([]entity.SurveyStateReport, error)
+		DeleteUser(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID) error
+		AnonymizeUser(ctx stdcontext.Context, exec DBTransaction, user entity.User) error
 		GetUsersList(ctx stdcontext.Context, exec DBTransaction, limit, offset int, search string) (UserListResponse, error)
+		SearchUsers(ctx stdcontext.Context, exec DBTransaction, limit, offset int, search string) (UserSummaries, error)
 
 		GetFinishedSurveys(ctx stdcontext.Context, exec DBTransaction, f ResultsFilter, batchSize int, offset int) ([]entity.SurveyStateReport, error)
+		GetFinishedSurveysAfter(ctx stdcontext.Context, exec DBTransaction, f ResultsFilter, cursor *ResultsCursor, limit int) ([]entity.SurveyStateReport, error)
 		GetUserSurveyStates(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID, states []entity.State) ([]entity.SurveyState, error)
 		GetUserSurveyState(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID, surveyGUID uuid.UUID, states []entity.State) (entity.SurveyState, error)
 
 		CreateUserSurveyState(ctx stdcontext.Context, exec DBTransaction, state entity.SurveyState) error
 		UpdateActiveUserSurveyState(ctx stdcontext.Context, exec DBTransaction, state entity.SurveyState) error
 		DeleteUserSurveyState(ctx stdcontext.Context, exec DBTransaction, userGUID uuid.UUID, surveyGUID uuid.UUID) error
+		DeleteUserSurveyStates(ctx stdcontext.Context, exec DBTransaction, userGUID, surveyGUID uuid.UUID, states []entity.State) (int, error)
+
+		GetSurveyStatesStats(ctx stdcontext.Context, exec DBTransaction, surveyGUID uuid.UUID, abandonedAfter time.Duration) (SurveyStatesStats, error)
+		CountSurveyStates(ctx stdcontext.Context, exec DBTransaction, states []entity.State) (map[uuid.UUID]int, error)
+		CountStatesOfSurvey(ctx stdcontext.Context, exec DBTransaction, surveyGUID uuid.UUID, states []entity.State) (int, error)
+
+		GetScaleScoresDistribution(ctx stdcontext.Context, exec DBTransaction) ([]ScaleScoreCount, error)
+		SaveNorms(ctx stdcontext.Context, exec DBTransaction, norms []entity.Norm) error
+		DeleteNorms(ctx stdcontext.Context, exec DBTransaction, source entity.NormSource) error
+		GetSurveyNorms(ctx stdcontext.Context, exec DBTransaction, surveyGUID uuid.UUID) ([]entity.Norm, error)
+
+		CreateAlert(ctx stdcontext.Context, exec DBTransaction, alert entity.Alert) error
+		AcknowledgeAlert(ctx stdcontext.Context, exec DBTransaction, alertGUID uuid.UUID, adminUserID int64) error
+		GetAlerts(ctx stdcontext.Context, exec DBTransaction, unacknowledgedOnly bool) ([]entity.Alert, error)
+
+		CreateAPIKey(ctx stdcontext.Context, exec DBTransaction, key entity.APIKey, keyHash string) error
+		GetAPIKeyByHash(ctx stdcontext.Context, exec DBTransaction, keyHash string) (entity.APIKey, error)
+		GetAPIKeys(ctx stdcontext.Context, exec DBTransaction) ([]entity.APIKey, error)
+		RevokeAPIKey(ctx stdcontext.Context, exec DBTransaction, keyGUID uuid.UUID) error
+		UpdateAPIKeyLastUsed(ctx stdcontext.Context, exec DBTransaction, keyGUID uuid.UUID) error
+
+		GetBackupSurveys(ctx stdcontext.Context, exec DBTransaction, limit, offset int) ([]BackupSurvey, error)
+		GetBackupUsers(ctx stdcontext.Context, exec DBTransaction, limit, offset int) ([]BackupUser, error)
+		GetBackupSurveyStates(ctx stdcontext.Context, exec DBTransaction, limit, offset int) ([]BackupSurveyState, error)
+		// Restore methods return false if the record is skipped by the policy. Surveys and users are returned with
+		// the guid they are stored under, it differs from the backup one if the skipped record conflicts by slug or user_id.
+		RestoreSurvey(ctx stdcontext.Context, exec DBTransaction, survey BackupSurvey, policy RestorePolicy) (uuid.UUID, bool, error)
+		RestoreUser(ctx stdcontext.Context, exec DBTransaction, user BackupUser, policy RestorePolicy) (uuid.UUID, bool, error)
+		RestoreSurveyState(ctx stdcontext.Context, exec DBTransaction, state BackupSurveyState, policy RestorePolicy) (bool, error)
+
+		// Publishes the event to all replicas, it is delivered when the transaction is committed.
+		NotifyEvent(ctx stdcontext.Context, exec DBTransaction, event entity.Event) error
 	}
 
 	DBTransaction interface {
@@ -0,0 +1,205 @@
+package service
+
+import (
+	stdcontext "context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+func (s *service) DiffSurvey(ctx stdcontext.Context, new entity.Survey) (SurveyDiff, error) {
+	var diff SurveyDiff
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		old, err := s.dbRepo.GetSurvey(ctx, tx, new.GUID)
+		if err != nil {
+			return fmt.Errorf("failed to find survey: %w", err)
+		}
+
+		diff.Changes = DiffSurveys(old, new)
+
+		// the same checks as UpdateSurvey makes
+		switch err := s.rsltProc.Validate(new); {
+		case err != nil:
+			diff.Refused = fmt.Errorf("failed to validate survey: %w: %w", ErrInvalidSurvey, err)
+		case len(old.Questions) != len(new.Questions):
+			diff.Refused = fmt.Errorf("%w: cannot update survey with different number of questions", ErrInvalidSurvey)
+		}
+
+		return nil
+	}); err != nil {
+		return SurveyDiff{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return diff, nil
+}
+
+// DiffSurveys returns changes of the updated fields of the survey, questions are compared by their positions
+// as answers are stored by them
+func DiffSurveys(old, new entity.Survey) []SurveyChange {
+	var changes []SurveyChange
+
+	add := func(question int, field, oldValue, newValue, breaking string) {
+		changes = append(changes, SurveyChange{
+			Question: question,
+			Field:    field,
+			Old:      oldValue,
+			New:      newValue,
+			Breaking: breaking,
+		})
+	}
+
+	if old.Name != new.Name {
+		add(-1, "name", old.Name, new.Name, "")
+	}
+	if old.Description != new.Description {
+		add(-1, "description", old.Description, new.Description, "")
+	}
+	if old.CalculationsType != new.CalculationsType {
+		add(-1, "calculations_type", old.CalculationsType, new.CalculationsType, "stored answers are calculated differently")
+	}
+
+	for i := 0; i < max(len(old.Questions), len(new.Questions)); i++ {
+		switch {
+		case i >= len(old.Questions):
+			add(i, "question", "", new.Questions[i].Text, "stored answers don't have the question")
+		case i >= len(new.Questions):
+			add(i, "question", old.Questions[i].Text, "", "stored answers of the question are lost")
+		default:
+			changes = append(changes, diffQuestion(i, old.Questions[i], new.Questions[i])...)
+		}
+	}
+
+	return changes
+}
+
+func diffQuestion(i int, old, new entity.Question) []SurveyChange {
+	var changes []SurveyChange
+
+	add := func(field, oldValue, newValue, breaking string) {
+		changes = append(changes, SurveyChange{
+			Question: i,
+			Field:    field,
+			Old:      oldValue,
+			New:      newValue,
+			Breaking: breaking,
+		})
+	}
+
+	if old.Text != new.Text {
+		add("text", old.Text, new.Text, "")
+	}
+
+	possibleAnswersChanged := !slices.Equal(old.PossibleAnswers, new.PossibleAnswers)
+
+	if old.AnswerType != new.AnswerType {
+		add("answer_type", string(old.AnswerType), string(new.AnswerType), fmt.Sprintf("stored answers are %s answers", old.AnswerType))
+
+		if possibleAnswersChanged {
+			add("possible_answers", formatValues(old.PossibleAnswers), formatValues(new.PossibleAnswers), "")
+		}
+
+		return changes
+	}
+
+	if old.AnswerType == entity.AnswerTypeSegment {
+		if possibleAnswersChanged {
+			add("possible_answers", formatValues(old.PossibleAnswers), formatValues(new.PossibleAnswers), "stored answers are from another range")
+		}
+
+		return changes
+	}
+
+	if possibleAnswersChanged {
+		add("possible_answers", formatValues(old.PossibleAnswers), formatValues(new.PossibleAnswers), "")
+	}
+
+	values := slices.Clone(old.PossibleAnswers)
+	for _, value := range new.PossibleAnswers {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	sort.Ints(values)
+
+	for _, value := range values {
+		oldLabel, inOld := answerLabel(old, value)
+		newLabel, inNew := answerLabel(new, value)
+
+		field := fmt.Sprintf("answer %d", value)
+
+		switch {
+		case !inOld:
+			add(field, "", newLabel, "")
+		case !inNew:
+			add(field, oldLabel, "", "stored answers with it are not possible anymore")
+		case oldLabel != newLabel:
+			var breaking string
+			// the label is moved to another value, e.g. possible answers are re-numbered
+			for _, other := range old.PossibleAnswers {
+				if label, _ := answerLabel(old, other); other != value && label == newLabel {
+					breaking = fmt.Sprintf("stored answers %d meant %q, it was the label of answer %d", value, oldLabel, other)
+					break
+				}
+			}
+
+			add(field, oldLabel, newLabel, breaking)
+		}
+	}
+
+	return changes
+}
+
+// String returns the change in the readable form, e.g. text: "Old?" -> "New?"
+func (c SurveyChange) String() string {
+	format := func(value string) string {
+		switch {
+		case value == "":
+			return "none"
+		case c.Field == "possible_answers":
+			return value
+		default:
+			return strconv.Quote(value)
+		}
+	}
+
+	return fmt.Sprintf("%s: %s -> %s", c.Field, format(c.Old), format(c.New))
+}
+
+// Breaking returns changes altering the meaning of answers already stored
+func (d SurveyDiff) Breaking() []SurveyChange {
+	var changes []SurveyChange
+	for _, change := range d.Changes {
+		if change.Breaking != "" {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// answerLabel returns text of the possible answer, false if the question doesn't have it
+func answerLabel(q entity.Question, value int) (string, bool) {
+	i := slices.Index(q.PossibleAnswers, value)
+	if i < 0 {
+		return "", false
+	}
+
+	if i >= len(q.AnswersText) {
+		return "", true
+	}
+
+	return q.AnswersText[i], true
+}
+
+func formatValues(values []int) string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, strconv.Itoa(value))
+	}
+
+	return "[" + strings.Join(result, ", ") + "]"
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	stdcontext "context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+// eventsBufferSize is a number of events kept for the slow subscriber, newer events are dropped for it
+const eventsBufferSize = 64
+
+var now = time.Now
+
+// EventBus delivers events to the subscribers of the replica. Events are published by the service
+// through the database, so the bus is fed by the listener of events of all replicas.
+type EventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan entity.Event]struct{}
+}
+
+type answerEventKey struct {
+	userGUID   uuid.UUID
+	surveyGUID uuid.UUID
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[chan entity.Event]struct{})}
+}
+
+// Publish sends the event to all subscribers, it doesn't wait for the slow ones
+func (b *EventBus) Publish(event entity.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns channel of events and the function to unsubscribe, the channel is closed by it
+func (b *EventBus) Subscribe() (<-chan entity.Event, func()) {
+	ch := make(chan entity.Event, eventsBufferSize)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+
+			close(ch)
+		})
+	}
+}
+
+// WithEvents enables events of users activity, answers of the user to the survey are published
+// at most once per answersThrottle
+func WithEvents(bus *EventBus, answersThrottle time.Duration) Option {
+	return func(s *service) {
+		s.events = bus
+		s.answersThrottle = answersThrottle
+		s.answerEvents = make(map[answerEventKey]time.Time)
+	}
+}
+
+// SubscribeEvents returns events of all replicas, the channel is nil if events are not enabled
+func (s *service) SubscribeEvents(_ stdcontext.Context) (<-chan entity.Event, func()) {
+	if s.events == nil {
+		return nil, func() {}
+	}
+
+	return s.events.Subscribe()
+}
+
+// publishEvent notifies about the event in the transaction, so it is delivered only if the transaction is committed
+func (s *service) publishEvent(ctx stdcontext.Context, tx DBTransaction, event entity.Event) error {
+	if s.events == nil {
+		return nil
+	}
+
+	var key answerEventKey
+	if event.Type == entity.EventSurveyAnswered {
+		key = answerEventKey{userGUID: event.UserGUID, surveyGUID: *event.SurveyGUID}
+		if !s.allowAnswerEvent(key) {
+			return nil
+		}
+	}
+
+	event.CreatedAt = now()
+	if err := s.dbRepo.NotifyEvent(ctx, tx, event); err != nil {
+		return fmt.Errorf("failed to notify event: %w", err)
+	}
+
+	if event.Type == entity.EventSurveyAnswered {
+		// the answer of the rolled back transaction doesn't throttle the next one
+		s.onCommit(tx, func() { s.recordAnswerEvent(key, event.CreatedAt) })
+	}
+
+	return nil
+}
+
+// allowAnswerEvent throttles answers of the user to the survey
+func (s *service) allowAnswerEvent(key answerEventKey) bool {
+	s.answerEventsMu.Lock()
+	defer s.answerEventsMu.Unlock()
+
+	last, ok := s.answerEvents[key]
+	return !ok || now().Sub(last) >= s.answersThrottle
+}
+
+// recordAnswerEvent remembers the time of the published answer, expired answers are pruned once per throttle interval
+func (s *service) recordAnswerEvent(key answerEventKey, t time.Time) {
+	s.answerEventsMu.Lock()
+	defer s.answerEventsMu.Unlock()
+
+	if t.Sub(s.answerEventsPruned) >= s.answersThrottle {
+		for k, last := range s.answerEvents {
+			if t.Sub(last) >= s.answersThrottle {
+				delete(s.answerEvents, k)
+			}
+		}
+		s.answerEventsPruned = t
+	}
+
+	s.answerEvents[key] = t
+}
+
+func userEvent(user entity.User) entity.Event {
+	return entity.Event{
+		Type:     entity.EventUserRegistered,
+		UserGUID: user.GUID,
+		Nickname: user.Nickname,
+	}
+}
+
+func surveyEvent(eventType entity.EventType, user entity.User, survey entity.Survey, answered int) entity.Event {
+	return entity.Event{
+		Type:       eventType,
+		UserGUID:   user.GUID,
+		Nickname:   user.Nickname,
+		SurveyGUID: &survey.GUID,
+		SurveyName: survey.Name,
+		Answered:   answered,
+		Questions:  len(survey.Questions),
+	}
+}
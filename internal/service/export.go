@@ -0,0 +1,129 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+const (
+	ResultsFormatCSV   ResultsFormat = "csv"
+	ResultsFormatJSONL ResultsFormat = "jsonl"
+	ResultsFormatXLSX  ResultsFormat = "xlsx"
+)
+
+// resultsColumns are columns of the exported results, answers take the rest columns of the row
+var resultsColumns = []string{
+	"survey_guid", "survey_name", "description", "user_guid", "user_id", "text", "metadata", "started_at", "finished_at", "answers",
+}
+
+// resultsWriter writes finished surveys in one of the export formats
+type resultsWriter interface {
+	Write(report entity.SurveyStateReport) error
+	// Flush is called after every batch of results
+	Flush() error
+	// Close finishes the output, the underlying writer is not closed
+	Close() error
+}
+
+func newResultsWriter(w io.Writer, format ResultsFormat) (resultsWriter, error) {
+	switch format {
+	case ResultsFormatCSV, "":
+		return newCSVResultsWriter(w)
+	case ResultsFormatJSONL:
+		return &jsonlResultsWriter{encoder: json.NewEncoder(w)}, nil
+	case ResultsFormatXLSX:
+		return newXLSXResultsWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown results format: %s", format)
+	}
+}
+
+type csvResultsWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVResultsWriter(w io.Writer) (*csvResultsWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(resultsColumns); err != nil {
+		return nil, fmt.Errorf("failed to write header to csv: %w", err)
+	}
+
+	return &csvResultsWriter{writer: writer}, nil
+}
+
+func (c *csvResultsWriter) Write(report entity.SurveyStateReport) error {
+	columns, err := report.ToCSV()
+	if err != nil {
+		return fmt.Errorf("failed to convert survey state to csv: %w", err)
+	}
+
+	if err := c.writer.Write(columns); err != nil {
+		return fmt.Errorf("failed to write survey to csv: %w", err)
+	}
+
+	return nil
+}
+
+func (c *csvResultsWriter) Flush() error {
+	c.writer.Flush()
+
+	return c.writer.Error()
+}
+
+func (c *csvResultsWriter) Close() error {
+	return c.Flush()
+}
+
+// resultsRecord is the line of JSONL export, keys are the same as columns of CSV
+type resultsRecord struct {
+	SurveyGUID  uuid.UUID       `json:"survey_guid"`
+	SurveyName  string          `json:"survey_name"`
+	Description string          `json:"description"`
+	UserGUID    string          `json:"user_guid"`
+	UserID      int64           `json:"user_id"`
+	Text        string          `json:"text"`
+	Metadata    map[string]any  `json:"metadata"`
+	StartedAt   time.Time       `json:"started_at"`
+	FinishedAt  time.Time       `json:"finished_at"`
+	Answers     []entity.Answer `json:"answers"`
+}
+
+type jsonlResultsWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlResultsWriter) Write(report entity.SurveyStateReport) error {
+	record := resultsRecord{
+		SurveyGUID:  report.SurveyGUID,
+		SurveyName:  report.SurveyName,
+		Description: report.Description,
+		UserGUID:    report.UserGUID,
+		UserID:      report.UserID,
+		StartedAt:   report.StartedAt,
+		FinishedAt:  report.FinishedAt,
+		Answers:     report.Answers,
+	}
+
+	if report.Results != nil {
+		record.Text = report.Results.Text
+		record.Metadata = report.Results.Metadata.Raw
+	}
+
+	// encoder writes every record at once, so there is nothing to flush
+	if err := j.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to encode survey: %w", err)
+	}
+
+	return nil
+}
+
+func (j *jsonlResultsWriter) Flush() error { return nil }
+
+func (j *jsonlResultsWriter) Close() error { return nil }
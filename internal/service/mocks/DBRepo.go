@@ -10,6 +10,8 @@ import (
 
 	service "git.ykonkov.com/ykonkov/survey-bot/internal/service"
 
+	time "time"
+
 	uuid "github.com/google/uuid"
 )
 
@@ -18,6 +20,72 @@ type DBRepo struct {
 	mock.Mock
 }
 
+// AcknowledgeAlert provides a mock function with given fields: ctx, exec, alertGUID, adminUserID
+func (_m *DBRepo) AcknowledgeAlert(ctx context.Context, exec service.DBTransaction, alertGUID uuid.UUID, adminUserID int64) error {
+	ret := _m.Called(ctx, exec, alertGUID, adminUserID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AcknowledgeAlert")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, int64) error); ok {
+		r0 = rf(ctx, exec, alertGUID, adminUserID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AnonymizeUser provides a mock function with given fields: ctx, exec, user
+func (_m *DBRepo) AnonymizeUser(ctx context.Context, exec service.DBTransaction, user entity.User) error {
+	ret := _m.Called(ctx, exec, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AnonymizeUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, entity.User) error); ok {
+		r0 = rf(ctx, exec, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BeginSnapshotTx provides a mock function with given fields: ctx
+func (_m *DBRepo) BeginSnapshotTx(ctx context.Context) (service.DBTransaction, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BeginSnapshotTx")
+	}
+
+	var r0 service.DBTransaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (service.DBTransaction, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) service.DBTransaction); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(service.DBTransaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // BeginTx provides a mock function with given fields: ctx
 func (_m *DBRepo) BeginTx(ctx context.Context) (service.DBTransaction, error) {
 	ret := _m.Called(ctx)
@@ -48,6 +116,100 @@ func (_m *DBRepo) BeginTx(ctx context.Context) (service.DBTransaction, error) {
 	return r0, r1
 }
 
+// CountStatesOfSurvey provides a mock function with given fields: ctx, exec, surveyGUID, states
+func (_m *DBRepo) CountStatesOfSurvey(ctx context.Context, exec service.DBTransaction, surveyGUID uuid.UUID, states []entity.State) (int, error) {
+	ret := _m.Called(ctx, exec, surveyGUID, states)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountStatesOfSurvey")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, []entity.State) (int, error)); ok {
+		return rf(ctx, exec, surveyGUID, states)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, []entity.State) int); ok {
+		r0 = rf(ctx, exec, surveyGUID, states)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, uuid.UUID, []entity.State) error); ok {
+		r1 = rf(ctx, exec, surveyGUID, states)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountSurveyStates provides a mock function with given fields: ctx, exec, states
+func (_m *DBRepo) CountSurveyStates(ctx context.Context, exec service.DBTransaction, states []entity.State) (map[uuid.UUID]int, error) {
+	ret := _m.Called(ctx, exec, states)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountSurveyStates")
+	}
+
+	var r0 map[uuid.UUID]int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, []entity.State) (map[uuid.UUID]int, error)); ok {
+		return rf(ctx, exec, states)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, []entity.State) map[uuid.UUID]int); ok {
+		r0 = rf(ctx, exec, states)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[uuid.UUID]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, []entity.State) error); ok {
+		r1 = rf(ctx, exec, states)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateAPIKey provides a mock function with given fields: ctx, exec, key, keyHash
+func (_m *DBRepo) CreateAPIKey(ctx context.Context, exec service.DBTransaction, key entity.APIKey, keyHash string) error {
+	ret := _m.Called(ctx, exec, key, keyHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, entity.APIKey, string) error); ok {
+		r0 = rf(ctx, exec, key, keyHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateAlert provides a mock function with given fields: ctx, exec, alert
+func (_m *DBRepo) CreateAlert(ctx context.Context, exec service.DBTransaction, alert entity.Alert) error {
+	ret := _m.Called(ctx, exec, alert)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAlert")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, entity.Alert) error); ok {
+		r0 = rf(ctx, exec, alert)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateSurvey provides a mock function with given fields: ctx, exec, s
 func (_m *DBRepo) CreateSurvey(ctx context.Context, exec service.DBTransaction, s entity.Survey) error {
 	ret := _m.Called(ctx, exec, s)
@@ -102,6 +264,24 @@ func (_m *DBRepo) CreateUserSurveyState(ctx context.Context, exec service.DBTran
 	return r0
 }
 
+// DeleteNorms provides a mock function with given fields: ctx, exec, source
+func (_m *DBRepo) DeleteNorms(ctx context.Context, exec service.DBTransaction, source entity.NormSource) error {
+	ret := _m.Called(ctx, exec, source)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteNorms")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, entity.NormSource) error); ok {
+		r0 = rf(ctx, exec, source)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteSurvey provides a mock function with given fields: ctx, exec, surveyGUID
 func (_m *DBRepo) DeleteSurvey(ctx context.Context, exec service.DBTransaction, surveyGUID uuid.UUID) error {
 	ret := _m.Called(ctx, exec, surveyGUID)
@@ -120,6 +300,24 @@ func (_m *DBRepo) DeleteSurvey(ctx context.Context, exec service.DBTransaction,
 	return r0
 }
 
+// DeleteUser provides a mock function with given fields: ctx, exec, userGUID
+func (_m *DBRepo) DeleteUser(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID) error {
+	ret := _m.Called(ctx, exec, userGUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID) error); ok {
+		r0 = rf(ctx, exec, userGUID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteUserSurveyState provides a mock function with given fields: ctx, exec, userGUID, surveyGUID
 func (_m *DBRepo) DeleteUserSurveyState(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID, surveyGUID uuid.UUID) error {
 	ret := _m.Called(ctx, exec, userGUID, surveyGUID)
@@ -138,6 +336,212 @@ func (_m *DBRepo) DeleteUserSurveyState(ctx context.Context, exec service.DBTran
 	return r0
 }
 
+// DeleteUserSurveyStates provides a mock function with given fields: ctx, exec, userGUID, surveyGUID, states
+func (_m *DBRepo) DeleteUserSurveyStates(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID, surveyGUID uuid.UUID, states []entity.State) (int, error) {
+	ret := _m.Called(ctx, exec, userGUID, surveyGUID, states)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUserSurveyStates")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, uuid.UUID, []entity.State) (int, error)); ok {
+		return rf(ctx, exec, userGUID, surveyGUID, states)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, uuid.UUID, []entity.State) int); ok {
+		r0 = rf(ctx, exec, userGUID, surveyGUID, states)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, uuid.UUID, uuid.UUID, []entity.State) error); ok {
+		r1 = rf(ctx, exec, userGUID, surveyGUID, states)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAPIKeyByHash provides a mock function with given fields: ctx, exec, keyHash
+func (_m *DBRepo) GetAPIKeyByHash(ctx context.Context, exec service.DBTransaction, keyHash string) (entity.APIKey, error) {
+	ret := _m.Called(ctx, exec, keyHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKeyByHash")
+	}
+
+	var r0 entity.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, string) (entity.APIKey, error)); ok {
+		return rf(ctx, exec, keyHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, string) entity.APIKey); ok {
+		r0 = rf(ctx, exec, keyHash)
+	} else {
+		r0 = ret.Get(0).(entity.APIKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, string) error); ok {
+		r1 = rf(ctx, exec, keyHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAPIKeys provides a mock function with given fields: ctx, exec
+func (_m *DBRepo) GetAPIKeys(ctx context.Context, exec service.DBTransaction) ([]entity.APIKey, error) {
+	ret := _m.Called(ctx, exec)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKeys")
+	}
+
+	var r0 []entity.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction) ([]entity.APIKey, error)); ok {
+		return rf(ctx, exec)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction) []entity.APIKey); ok {
+		r0 = rf(ctx, exec)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction) error); ok {
+		r1 = rf(ctx, exec)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAlerts provides a mock function with given fields: ctx, exec, unacknowledgedOnly
+func (_m *DBRepo) GetAlerts(ctx context.Context, exec service.DBTransaction, unacknowledgedOnly bool) ([]entity.Alert, error) {
+	ret := _m.Called(ctx, exec, unacknowledgedOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAlerts")
+	}
+
+	var r0 []entity.Alert
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, bool) ([]entity.Alert, error)); ok {
+		return rf(ctx, exec, unacknowledgedOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, bool) []entity.Alert); ok {
+		r0 = rf(ctx, exec, unacknowledgedOnly)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Alert)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, bool) error); ok {
+		r1 = rf(ctx, exec, unacknowledgedOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBackupSurveyStates provides a mock function with given fields: ctx, exec, limit, offset
+func (_m *DBRepo) GetBackupSurveyStates(ctx context.Context, exec service.DBTransaction, limit int, offset int) ([]service.BackupSurveyState, error) {
+	ret := _m.Called(ctx, exec, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBackupSurveyStates")
+	}
+
+	var r0 []service.BackupSurveyState
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, int, int) ([]service.BackupSurveyState, error)); ok {
+		return rf(ctx, exec, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, int, int) []service.BackupSurveyState); ok {
+		r0 = rf(ctx, exec, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]service.BackupSurveyState)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, int, int) error); ok {
+		r1 = rf(ctx, exec, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBackupSurveys provides a mock function with given fields: ctx, exec, limit, offset
+func (_m *DBRepo) GetBackupSurveys(ctx context.Context, exec service.DBTransaction, limit int, offset int) ([]service.BackupSurvey, error) {
+	ret := _m.Called(ctx, exec, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBackupSurveys")
+	}
+
+	var r0 []service.BackupSurvey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, int, int) ([]service.BackupSurvey, error)); ok {
+		return rf(ctx, exec, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, int, int) []service.BackupSurvey); ok {
+		r0 = rf(ctx, exec, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]service.BackupSurvey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, int, int) error); ok {
+		r1 = rf(ctx, exec, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBackupUsers provides a mock function with given fields: ctx, exec, limit, offset
+func (_m *DBRepo) GetBackupUsers(ctx context.Context, exec service.DBTransaction, limit int, offset int) ([]service.BackupUser, error) {
+	ret := _m.Called(ctx, exec, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBackupUsers")
+	}
+
+	var r0 []service.BackupUser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, int, int) ([]service.BackupUser, error)); ok {
+		return rf(ctx, exec, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, int, int) []service.BackupUser); ok {
+		r0 = rf(ctx, exec, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]service.BackupUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, int, int) error); ok {
+		r1 = rf(ctx, exec, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCompletedSurveys provides a mock function with given fields: ctx, exec, userGUID
 func (_m *DBRepo) GetCompletedSurveys(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID) ([]entity.SurveyStateReport, error) {
 	ret := _m.Called(ctx, exec, userGUID)
@@ -168,29 +572,89 @@ func (_m *DBRepo) GetCompletedSurveys(ctx context.Context, exec service.DBTransa
 	return r0, r1
 }
 
-// GetFinishedSurveys provides a mock function with given fields: ctx, exec, f, batchSize, offset
-func (_m *DBRepo) GetFinishedSurveys(ctx context.Context, exec service.DBTransaction, f service.ResultsFilter, batchSize int, offset int) ([]entity.SurveyStateReport, error) {
-	ret := _m.Called(ctx, exec, f, batchSize, offset)
+// GetFinishedSurveys provides a mock function with given fields: ctx, exec, f, batchSize, offset
+func (_m *DBRepo) GetFinishedSurveys(ctx context.Context, exec service.DBTransaction, f service.ResultsFilter, batchSize int, offset int) ([]entity.SurveyStateReport, error) {
+	ret := _m.Called(ctx, exec, f, batchSize, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinishedSurveys")
+	}
+
+	var r0 []entity.SurveyStateReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.ResultsFilter, int, int) ([]entity.SurveyStateReport, error)); ok {
+		return rf(ctx, exec, f, batchSize, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.ResultsFilter, int, int) []entity.SurveyStateReport); ok {
+		r0 = rf(ctx, exec, f, batchSize, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SurveyStateReport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, service.ResultsFilter, int, int) error); ok {
+		r1 = rf(ctx, exec, f, batchSize, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFinishedSurveysAfter provides a mock function with given fields: ctx, exec, f, cursor, limit
+func (_m *DBRepo) GetFinishedSurveysAfter(ctx context.Context, exec service.DBTransaction, f service.ResultsFilter, cursor *service.ResultsCursor, limit int) ([]entity.SurveyStateReport, error) {
+	ret := _m.Called(ctx, exec, f, cursor, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinishedSurveysAfter")
+	}
+
+	var r0 []entity.SurveyStateReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.ResultsFilter, *service.ResultsCursor, int) ([]entity.SurveyStateReport, error)); ok {
+		return rf(ctx, exec, f, cursor, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.ResultsFilter, *service.ResultsCursor, int) []entity.SurveyStateReport); ok {
+		r0 = rf(ctx, exec, f, cursor, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SurveyStateReport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, service.ResultsFilter, *service.ResultsCursor, int) error); ok {
+		r1 = rf(ctx, exec, f, cursor, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetScaleScoresDistribution provides a mock function with given fields: ctx, exec
+func (_m *DBRepo) GetScaleScoresDistribution(ctx context.Context, exec service.DBTransaction) ([]service.ScaleScoreCount, error) {
+	ret := _m.Called(ctx, exec)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetFinishedSurveys")
+		panic("no return value specified for GetScaleScoresDistribution")
 	}
 
-	var r0 []entity.SurveyStateReport
+	var r0 []service.ScaleScoreCount
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.ResultsFilter, int, int) ([]entity.SurveyStateReport, error)); ok {
-		return rf(ctx, exec, f, batchSize, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction) ([]service.ScaleScoreCount, error)); ok {
+		return rf(ctx, exec)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.ResultsFilter, int, int) []entity.SurveyStateReport); ok {
-		r0 = rf(ctx, exec, f, batchSize, offset)
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction) []service.ScaleScoreCount); ok {
+		r0 = rf(ctx, exec)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]entity.SurveyStateReport)
+			r0 = ret.Get(0).([]service.ScaleScoreCount)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, service.ResultsFilter, int, int) error); ok {
-		r1 = rf(ctx, exec, f, batchSize, offset)
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction) error); ok {
+		r1 = rf(ctx, exec)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -254,6 +718,64 @@ func (_m *DBRepo) GetSurveyByID(ctx context.Context, exec service.DBTransaction,
 	return r0, r1
 }
 
+// GetSurveyNorms provides a mock function with given fields: ctx, exec, surveyGUID
+func (_m *DBRepo) GetSurveyNorms(ctx context.Context, exec service.DBTransaction, surveyGUID uuid.UUID) ([]entity.Norm, error) {
+	ret := _m.Called(ctx, exec, surveyGUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSurveyNorms")
+	}
+
+	var r0 []entity.Norm
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID) ([]entity.Norm, error)); ok {
+		return rf(ctx, exec, surveyGUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID) []entity.Norm); ok {
+		r0 = rf(ctx, exec, surveyGUID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Norm)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, uuid.UUID) error); ok {
+		r1 = rf(ctx, exec, surveyGUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSurveyStatesStats provides a mock function with given fields: ctx, exec, surveyGUID, abandonedAfter
+func (_m *DBRepo) GetSurveyStatesStats(ctx context.Context, exec service.DBTransaction, surveyGUID uuid.UUID, abandonedAfter time.Duration) (service.SurveyStatesStats, error) {
+	ret := _m.Called(ctx, exec, surveyGUID, abandonedAfter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSurveyStatesStats")
+	}
+
+	var r0 service.SurveyStatesStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, time.Duration) (service.SurveyStatesStats, error)); ok {
+		return rf(ctx, exec, surveyGUID, abandonedAfter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, time.Duration) service.SurveyStatesStats); ok {
+		r0 = rf(ctx, exec, surveyGUID, abandonedAfter)
+	} else {
+		r0 = ret.Get(0).(service.SurveyStatesStats)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, uuid.UUID, time.Duration) error); ok {
+		r1 = rf(ctx, exec, surveyGUID, abandonedAfter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetSurveysList provides a mock function with given fields: ctx, exec
 func (_m *DBRepo) GetSurveysList(ctx context.Context, exec service.DBTransaction) ([]entity.Survey, error) {
 	ret := _m.Called(ctx, exec)
@@ -340,6 +862,66 @@ func (_m *DBRepo) GetUserByID(ctx context.Context, exec service.DBTransaction, u
 	return r0, r1
 }
 
+// GetUserSurveyAttempts provides a mock function with given fields: ctx, exec, userGUID, surveyGUID
+func (_m *DBRepo) GetUserSurveyAttempts(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID, surveyGUID uuid.UUID) ([]entity.SurveyStateReport, error) {
+	ret := _m.Called(ctx, exec, userGUID, surveyGUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserSurveyAttempts")
+	}
+
+	var r0 []entity.SurveyStateReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, uuid.UUID) ([]entity.SurveyStateReport, error)); ok {
+		return rf(ctx, exec, userGUID, surveyGUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, uuid.UUID) []entity.SurveyStateReport); ok {
+		r0 = rf(ctx, exec, userGUID, surveyGUID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SurveyStateReport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(ctx, exec, userGUID, surveyGUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserSurveyReports provides a mock function with given fields: ctx, exec, userGUID
+func (_m *DBRepo) GetUserSurveyReports(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID) ([]entity.SurveyStateReport, error) {
+	ret := _m.Called(ctx, exec, userGUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserSurveyReports")
+	}
+
+	var r0 []entity.SurveyStateReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID) ([]entity.SurveyStateReport, error)); ok {
+		return rf(ctx, exec, userGUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID) []entity.SurveyStateReport); ok {
+		r0 = rf(ctx, exec, userGUID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SurveyStateReport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, uuid.UUID) error); ok {
+		r1 = rf(ctx, exec, userGUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUserSurveyState provides a mock function with given fields: ctx, exec, userGUID, surveyGUID, states
 func (_m *DBRepo) GetUserSurveyState(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID, surveyGUID uuid.UUID, states []entity.State) (entity.SurveyState, error) {
 	ret := _m.Called(ctx, exec, userGUID, surveyGUID, states)
@@ -398,6 +980,36 @@ func (_m *DBRepo) GetUserSurveyStates(ctx context.Context, exec service.DBTransa
 	return r0, r1
 }
 
+// GetUsersByNickname provides a mock function with given fields: ctx, exec, nickname
+func (_m *DBRepo) GetUsersByNickname(ctx context.Context, exec service.DBTransaction, nickname string) ([]entity.User, error) {
+	ret := _m.Called(ctx, exec, nickname)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsersByNickname")
+	}
+
+	var r0 []entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, string) ([]entity.User, error)); ok {
+		return rf(ctx, exec, nickname)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, string) []entity.User); ok {
+		r0 = rf(ctx, exec, nickname)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, string) error); ok {
+		r1 = rf(ctx, exec, nickname)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUsersList provides a mock function with given fields: ctx, exec, limit, offset, search
 func (_m *DBRepo) GetUsersList(ctx context.Context, exec service.DBTransaction, limit int, offset int, search string) (service.UserListResponse, error) {
 	ret := _m.Called(ctx, exec, limit, offset, search)
@@ -426,6 +1038,190 @@ func (_m *DBRepo) GetUsersList(ctx context.Context, exec service.DBTransaction,
 	return r0, r1
 }
 
+// NotifyEvent provides a mock function with given fields: ctx, exec, event
+func (_m *DBRepo) NotifyEvent(ctx context.Context, exec service.DBTransaction, event entity.Event) error {
+	ret := _m.Called(ctx, exec, event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NotifyEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, entity.Event) error); ok {
+		r0 = rf(ctx, exec, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreSurvey provides a mock function with given fields: ctx, exec, survey, policy
+func (_m *DBRepo) RestoreSurvey(ctx context.Context, exec service.DBTransaction, survey service.BackupSurvey, policy service.RestorePolicy) (uuid.UUID, bool, error) {
+	ret := _m.Called(ctx, exec, survey, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreSurvey")
+	}
+
+	var r0 uuid.UUID
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.BackupSurvey, service.RestorePolicy) (uuid.UUID, bool, error)); ok {
+		return rf(ctx, exec, survey, policy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.BackupSurvey, service.RestorePolicy) uuid.UUID); ok {
+		r0 = rf(ctx, exec, survey, policy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, service.BackupSurvey, service.RestorePolicy) bool); ok {
+		r1 = rf(ctx, exec, survey, policy)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, service.DBTransaction, service.BackupSurvey, service.RestorePolicy) error); ok {
+		r2 = rf(ctx, exec, survey, policy)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// RestoreSurveyState provides a mock function with given fields: ctx, exec, state, policy
+func (_m *DBRepo) RestoreSurveyState(ctx context.Context, exec service.DBTransaction, state service.BackupSurveyState, policy service.RestorePolicy) (bool, error) {
+	ret := _m.Called(ctx, exec, state, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreSurveyState")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.BackupSurveyState, service.RestorePolicy) (bool, error)); ok {
+		return rf(ctx, exec, state, policy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.BackupSurveyState, service.RestorePolicy) bool); ok {
+		r0 = rf(ctx, exec, state, policy)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, service.BackupSurveyState, service.RestorePolicy) error); ok {
+		r1 = rf(ctx, exec, state, policy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreUser provides a mock function with given fields: ctx, exec, user, policy
+func (_m *DBRepo) RestoreUser(ctx context.Context, exec service.DBTransaction, user service.BackupUser, policy service.RestorePolicy) (uuid.UUID, bool, error) {
+	ret := _m.Called(ctx, exec, user, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreUser")
+	}
+
+	var r0 uuid.UUID
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.BackupUser, service.RestorePolicy) (uuid.UUID, bool, error)); ok {
+		return rf(ctx, exec, user, policy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, service.BackupUser, service.RestorePolicy) uuid.UUID); ok {
+		r0 = rf(ctx, exec, user, policy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(uuid.UUID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, service.BackupUser, service.RestorePolicy) bool); ok {
+		r1 = rf(ctx, exec, user, policy)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, service.DBTransaction, service.BackupUser, service.RestorePolicy) error); ok {
+		r2 = rf(ctx, exec, user, policy)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// RevokeAPIKey provides a mock function with given fields: ctx, exec, keyGUID
+func (_m *DBRepo) RevokeAPIKey(ctx context.Context, exec service.DBTransaction, keyGUID uuid.UUID) error {
+	ret := _m.Called(ctx, exec, keyGUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID) error); ok {
+		r0 = rf(ctx, exec, keyGUID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveNorms provides a mock function with given fields: ctx, exec, norms
+func (_m *DBRepo) SaveNorms(ctx context.Context, exec service.DBTransaction, norms []entity.Norm) error {
+	ret := _m.Called(ctx, exec, norms)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveNorms")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, []entity.Norm) error); ok {
+		r0 = rf(ctx, exec, norms)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SearchUsers provides a mock function with given fields: ctx, exec, limit, offset, search
+func (_m *DBRepo) SearchUsers(ctx context.Context, exec service.DBTransaction, limit int, offset int, search string) (service.UserSummaries, error) {
+	ret := _m.Called(ctx, exec, limit, offset, search)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchUsers")
+	}
+
+	var r0 service.UserSummaries
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, int, int, string) (service.UserSummaries, error)); ok {
+		return rf(ctx, exec, limit, offset, search)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, int, int, string) service.UserSummaries); ok {
+		r0 = rf(ctx, exec, limit, offset, search)
+	} else {
+		r0 = ret.Get(0).(service.UserSummaries)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, service.DBTransaction, int, int, string) error); ok {
+		r1 = rf(ctx, exec, limit, offset, search)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetUserCurrentSurveyToNil provides a mock function with given fields: ctx, exec, userGUID
 func (_m *DBRepo) SetUserCurrentSurveyToNil(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID) error {
 	ret := _m.Called(ctx, exec, userGUID)
@@ -444,6 +1240,24 @@ func (_m *DBRepo) SetUserCurrentSurveyToNil(ctx context.Context, exec service.DB
 	return r0
 }
 
+// UpdateAPIKeyLastUsed provides a mock function with given fields: ctx, exec, keyGUID
+func (_m *DBRepo) UpdateAPIKeyLastUsed(ctx context.Context, exec service.DBTransaction, keyGUID uuid.UUID) error {
+	ret := _m.Called(ctx, exec, keyGUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAPIKeyLastUsed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID) error); ok {
+		r0 = rf(ctx, exec, keyGUID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateActiveUserSurveyState provides a mock function with given fields: ctx, exec, state
 func (_m *DBRepo) UpdateActiveUserSurveyState(ctx context.Context, exec service.DBTransaction, state entity.SurveyState) error {
 	ret := _m.Called(ctx, exec, state)
@@ -480,6 +1294,24 @@ func (_m *DBRepo) UpdateSurvey(ctx context.Context, exec service.DBTransaction,
 	return r0
 }
 
+// UpdateUserCohort provides a mock function with given fields: ctx, exec, userGUID, cohort
+func (_m *DBRepo) UpdateUserCohort(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID, cohort string) error {
+	ret := _m.Called(ctx, exec, userGUID, cohort)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateUserCohort")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DBTransaction, uuid.UUID, string) error); ok {
+		r0 = rf(ctx, exec, userGUID, cohort)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateUserCurrentSurvey provides a mock function with given fields: ctx, exec, userGUID, surveyGUID
 func (_m *DBRepo) UpdateUserCurrentSurvey(ctx context.Context, exec service.DBTransaction, userGUID uuid.UUID, surveyGUID uuid.UUID) error {
 	ret := _m.Called(ctx, exec, userGUID, surveyGUID)
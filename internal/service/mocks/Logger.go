@@ -58,6 +58,26 @@ func (_m *Logger) WithError(err error) logger.Logger {
 	return r0
 }
 
+// WithFields provides a mock function with given fields: fields
+func (_m *Logger) WithFields(fields map[string]interface{}) logger.Logger {
+	ret := _m.Called(fields)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithFields")
+	}
+
+	var r0 logger.Logger
+	if rf, ok := ret.Get(0).(func(map[string]interface{}) logger.Logger); ok {
+		r0 = rf(fields)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(logger.Logger)
+		}
+	}
+
+	return r0
+}
+
 // WithPrefix provides a mock function with given fields: k, v
 func (_m *Logger) WithPrefix(k string, v string) logger.Logger {
 	ret := _m.Called(k, v)
@@ -58,6 +58,26 @@ func (_m *ResultsProcessor) Validate(survey entity.Survey) error {
 	return r0
 }
 
+// ValidationErrors provides a mock function with given fields: survey
+func (_m *ResultsProcessor) ValidationErrors(survey entity.Survey) []entity.ValidationError {
+	ret := _m.Called(survey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidationErrors")
+	}
+
+	var r0 []entity.ValidationError
+	if rf, ok := ret.Get(0).(func(entity.Survey) []entity.ValidationError); ok {
+		r0 = rf(survey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.ValidationError)
+		}
+	}
+
+	return r0
+}
+
 // NewResultsProcessor creates a new instance of ResultsProcessor. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewResultsProcessor(t interface {
@@ -3,7 +3,9 @@
 package mocks
 
 import (
-	context "git.ykonkov.com/ykonkov/survey-bot/internal/context"
+	context "context"
+
+	internalcontext "git.ykonkov.com/ykonkov/survey-bot/internal/context"
 	entity "git.ykonkov.com/ykonkov/survey-bot/internal/entity"
 
 	mock "github.com/stretchr/testify/mock"
@@ -16,8 +18,44 @@ type TelegramRepo struct {
 	mock.Mock
 }
 
+// SendAlert provides a mock function with given fields: ctx, chatID, alert, user, surveyName
+func (_m *TelegramRepo) SendAlert(ctx context.Context, chatID int64, alert entity.Alert, user entity.User, surveyName string) error {
+	ret := _m.Called(ctx, chatID, alert, user, surveyName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendAlert")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, entity.Alert, entity.User, string) error); ok {
+		r0 = rf(ctx, chatID, alert, user, surveyName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SendDocument provides a mock function with given fields: ctx, fileName, data
+func (_m *TelegramRepo) SendDocument(ctx internalcontext.Context, fileName string, data []byte) error {
+	ret := _m.Called(ctx, fileName, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendDocument")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(internalcontext.Context, string, []byte) error); ok {
+		r0 = rf(ctx, fileName, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SendFile provides a mock function with given fields: ctx, path
-func (_m *TelegramRepo) SendFile(ctx context.Context, path string) error {
+func (_m *TelegramRepo) SendFile(ctx internalcontext.Context, path string) error {
 	ret := _m.Called(ctx, path)
 
 	if len(ret) == 0 {
@@ -25,7 +63,7 @@ func (_m *TelegramRepo) SendFile(ctx context.Context, path string) error {
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+	if rf, ok := ret.Get(0).(func(internalcontext.Context, string) error); ok {
 		r0 = rf(ctx, path)
 	} else {
 		r0 = ret.Error(0)
@@ -35,7 +73,7 @@ func (_m *TelegramRepo) SendFile(ctx context.Context, path string) error {
 }
 
 // SendMessage provides a mock function with given fields: ctx, msg
-func (_m *TelegramRepo) SendMessage(ctx context.Context, msg string) error {
+func (_m *TelegramRepo) SendMessage(ctx internalcontext.Context, msg string) error {
 	ret := _m.Called(ctx, msg)
 
 	if len(ret) == 0 {
@@ -43,7 +81,7 @@ func (_m *TelegramRepo) SendMessage(ctx context.Context, msg string) error {
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+	if rf, ok := ret.Get(0).(func(internalcontext.Context, string) error); ok {
 		r0 = rf(ctx, msg)
 	} else {
 		r0 = ret.Error(0)
@@ -52,8 +90,26 @@ func (_m *TelegramRepo) SendMessage(ctx context.Context, msg string) error {
 	return r0
 }
 
+// SendResults provides a mock function with given fields: ctx, survey, results
+func (_m *TelegramRepo) SendResults(ctx internalcontext.Context, survey entity.Survey, results entity.Results) error {
+	ret := _m.Called(ctx, survey, results)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendResults")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(internalcontext.Context, entity.Survey, entity.Results) error); ok {
+		r0 = rf(ctx, survey, results)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SendSurveyList provides a mock function with given fields: ctx, states
-func (_m *TelegramRepo) SendSurveyList(ctx context.Context, states []service.UserSurveyState) error {
+func (_m *TelegramRepo) SendSurveyList(ctx internalcontext.Context, states []service.UserSurveyState) error {
 	ret := _m.Called(ctx, states)
 
 	if len(ret) == 0 {
@@ -61,7 +117,7 @@ func (_m *TelegramRepo) SendSurveyList(ctx context.Context, states []service.Use
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, []service.UserSurveyState) error); ok {
+	if rf, ok := ret.Get(0).(func(internalcontext.Context, []service.UserSurveyState) error); ok {
 		r0 = rf(ctx, states)
 	} else {
 		r0 = ret.Error(0)
@@ -71,7 +127,7 @@ func (_m *TelegramRepo) SendSurveyList(ctx context.Context, states []service.Use
 }
 
 // SendSurveyQuestion provides a mock function with given fields: ctx, question
-func (_m *TelegramRepo) SendSurveyQuestion(ctx context.Context, question entity.Question) error {
+func (_m *TelegramRepo) SendSurveyQuestion(ctx internalcontext.Context, question entity.Question) error {
 	ret := _m.Called(ctx, question)
 
 	if len(ret) == 0 {
@@ -79,7 +135,7 @@ func (_m *TelegramRepo) SendSurveyQuestion(ctx context.Context, question entity.
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.Question) error); ok {
+	if rf, ok := ret.Get(0).(func(internalcontext.Context, entity.Question) error); ok {
 		r0 = rf(ctx, question)
 	} else {
 		r0 = ret.Error(0)
@@ -0,0 +1,154 @@
+package service
+
+import (
+	stdcontext "context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/responses"
+)
+
+// population norms with less results are not used
+const minNormSampleSize = 30
+
+func (s *service) RefreshNorms(ctx stdcontext.Context) error {
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		counts, err := s.dbRepo.GetScaleScoresDistribution(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to get scale scores distribution: %w", err)
+		}
+
+		norms := buildPopulationNorms(counts)
+
+		if err := s.dbRepo.DeleteNorms(ctx, tx, entity.NormSourcePopulation); err != nil {
+			return fmt.Errorf("failed to delete population norms: %w", err)
+		}
+
+		if err := s.dbRepo.SaveNorms(ctx, tx, norms); err != nil {
+			return fmt.Errorf("failed to save norms: %w", err)
+		}
+
+		s.logger.Infof(ctx, "refreshed %d population norms", len(norms))
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return nil
+}
+
+// ImportNorms saves norms from external norm table, existing imported norms with the same key are overwritten
+func (s *service) ImportNorms(ctx stdcontext.Context, norms []entity.Norm) error {
+	for i, norm := range norms {
+		if norm.Scale == "" {
+			return fmt.Errorf("empty scale in norm %d", i)
+		}
+		if norm.StdDev < 0 || norm.SampleSize < 0 {
+			return fmt.Errorf("negative std dev or sample size in norm %d", i)
+		}
+
+		norms[i].Source = entity.NormSourceImported
+	}
+
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		checked := make(map[uuid.UUID]bool)
+		for _, norm := range norms {
+			if checked[norm.SurveyGUID] {
+				continue
+			}
+
+			if _, err := s.dbRepo.GetSurvey(ctx, tx, norm.SurveyGUID); err != nil {
+				return fmt.Errorf("failed to get survey %s: %w", norm.SurveyGUID, err)
+			}
+			checked[norm.SurveyGUID] = true
+		}
+
+		return s.dbRepo.SaveNorms(ctx, tx, norms)
+	}); err != nil {
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) applyNorms(ctx stdcontext.Context, tx DBTransaction, surveyGUID uuid.UUID, cohort string, results *entity.Results) error {
+	if len(results.Scales) == 0 {
+		return nil
+	}
+
+	norms, err := s.dbRepo.GetSurveyNorms(ctx, tx, surveyGUID)
+	if err != nil {
+		return fmt.Errorf("failed to get survey norms: %w", err)
+	}
+
+	results.ApplyNorms(norms, cohort, minNormSampleSize)
+
+	return nil
+}
+
+// buildPopulationNorms builds norms of every cohort and of the whole population
+func buildPopulationNorms(counts []ScaleScoreCount) []entity.Norm {
+	type key struct {
+		surveyGUID uuid.UUID
+		scale      string
+		cohort     string
+	}
+
+	var (
+		keys   []key
+		points = make(map[key][]entity.NormPoint)
+	)
+
+	add := func(k key, point entity.NormPoint) {
+		if _, ok := points[k]; !ok {
+			keys = append(keys, k)
+		}
+		points[k] = append(points[k], point)
+	}
+
+	for _, count := range counts {
+		point := entity.NormPoint{Score: count.Score, Count: count.Count}
+
+		add(key{surveyGUID: count.SurveyGUID, scale: count.Scale}, point)
+		if count.Cohort != "" {
+			add(key{surveyGUID: count.SurveyGUID, scale: count.Scale, cohort: count.Cohort}, point)
+		}
+	}
+
+	norms := make([]entity.Norm, 0, len(keys))
+	for _, k := range keys {
+		norms = append(norms, entity.NewPopulationNorm(k.surveyGUID, k.scale, k.cohort, points[k]))
+	}
+
+	return norms
+}
+
+// withResultsText returns the results with percentile ranks, changes since the previous attempts and support
+// resources in the text, in this order. They depend on the current norms, attempts and alert rules, so they are
+// rendered when the results are sent or read and are not stored.
+func (s *service) withResultsText(calculationsType string, results entity.Results) *entity.Results {
+	results.Text += normsText(results) + changesText(results) + s.helpText(calculationsType, results)
+
+	return &results
+}
+
+func normsText(results entity.Results) string {
+	var lines []string
+	for _, scale := range results.Scales {
+		if scale.Norm == nil {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf(responses.NormsScale, scale.Name, scale.Norm.Percentile))
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return "\n\n" + responses.NormsTitle + "\n" + strings.Join(lines, "\n")
+}
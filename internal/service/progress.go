@@ -0,0 +1,367 @@
+package service
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/responses"
+)
+
+func (s *service) GetUserSurveys(ctx stdcontext.Context, userID int64) ([]UserSurveyState, error) {
+	var states []UserSurveyState
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByID(ctx, tx, userID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			// user is not registered yet, all surveys are not started
+		case err != nil:
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		states, err = s.userSurveyStates(ctx, tx, user)
+		if err != nil {
+			return fmt.Errorf("failed to get user survey states: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return states, nil
+}
+
+func (s *service) StartSurvey(ctx stdcontext.Context, identity UserIdentity, surveyGUID uuid.UUID) (SurveyProgress, error) {
+	var progress SurveyProgress
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.getOrCreateUser(ctx, tx, identity)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		survey, err := s.dbRepo.GetSurvey(ctx, tx, surveyGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get survey: %w", err)
+		}
+
+		progress, err = s.startSurvey(ctx, tx, user, survey)
+		if err != nil {
+			return fmt.Errorf("failed to start survey: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return SurveyProgress{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return progress, nil
+}
+
+func (s *service) GetSurveyProgress(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID) (SurveyProgress, error) {
+	var progress SurveyProgress
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByID(ctx, tx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		state, err := s.dbRepo.GetUserSurveyState(ctx, tx, user.GUID, surveyGUID, []entity.State{entity.ActiveState})
+		if err != nil {
+			return fmt.Errorf("failed to get user survey state: %w", err)
+		}
+
+		survey, err := s.dbRepo.GetSurvey(ctx, tx, surveyGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get survey: %w", err)
+		}
+
+		progress = newSurveyProgress(survey, state)
+
+		return nil
+	}); err != nil {
+		return SurveyProgress{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return progress, nil
+}
+
+func (s *service) AnswerSurvey(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID, answer string) (SurveyProgress, error) {
+	var (
+		user     entity.User
+		progress SurveyProgress
+		alerts   []entity.Alert
+	)
+
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		user, err = s.dbRepo.GetUserByID(ctx, tx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		if err := s.dbRepo.UpdateUserLastActivity(ctx, tx, user.GUID); err != nil {
+			return fmt.Errorf("failed to update user's last activity: %w", err)
+		}
+
+		progress, alerts, err = s.answerSurvey(ctx, tx, user, surveyGUID, answer)
+		if err != nil {
+			return fmt.Errorf("failed to answer survey: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return SurveyProgress{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	s.notifyAlerts(ctx, alerts, user, progress.Survey)
+
+	return progress, nil
+}
+
+// AnswerErrorText returns text for the user explaining why the answer is not accepted
+func AnswerErrorText(err error) string {
+	switch {
+	case errors.Is(err, entity.ErrParseAnswerTypeSegment):
+		return responses.AnswerNotANumber
+	case errors.Is(err, entity.ErrParseAnswerTypeSelect):
+		return responses.AnswerNotANumber
+	case errors.Is(err, entity.ErrParseAnswerTypeMultiSelect):
+		return responses.AnswerNotANumber
+	case errors.Is(err, entity.ErrAnswerOutOfRange):
+		return responses.AnswerOutOfRange
+	case errors.Is(err, entity.ErrAnswerNotFound):
+		return responses.AnswerNotFound
+	default:
+		return "failed to parse answer"
+	}
+}
+
+func identityFromContext(ctx context.Context) UserIdentity {
+	return UserIdentity{
+		UserID:   ctx.UserID(),
+		ChatID:   ctx.ChatID(),
+		Nickname: ctx.Nickname(),
+	}
+}
+
+func (s *service) getOrCreateUser(ctx stdcontext.Context, tx DBTransaction, identity UserIdentity) (entity.User, error) {
+	user, err := s.dbRepo.GetUserByID(ctx, tx, identity.UserID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		user = entity.User{
+			GUID:          UUIDProvider(),
+			UserID:        identity.UserID,
+			ChatID:        identity.ChatID,
+			Nickname:      identity.Nickname,
+			CurrentSurvey: nil,
+		}
+
+		if err := s.dbRepo.CreateUser(ctx, tx, user); err != nil {
+			return entity.User{}, fmt.Errorf("failed to create user: %w", err)
+		}
+
+		if err := s.publishEvent(ctx, tx, userEvent(user)); err != nil {
+			return entity.User{}, fmt.Errorf("failed to publish event: %w", err)
+		}
+	case err != nil:
+		return entity.User{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// startSurvey creates the active state of the survey or resumes the existing one and makes the survey current
+func (s *service) startSurvey(ctx stdcontext.Context, tx DBTransaction, user entity.User, survey entity.Survey) (SurveyProgress, error) {
+	state, err := s.dbRepo.GetUserSurveyState(ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		state = entity.SurveyState{
+			UserGUID:   user.GUID,
+			SurveyGUID: survey.GUID,
+			State:      entity.ActiveState,
+		}
+
+		if err := s.dbRepo.CreateUserSurveyState(ctx, tx, state); err != nil {
+			return SurveyProgress{}, fmt.Errorf("failed to create user survey state: %w", err)
+		}
+
+		if err := s.publishEvent(ctx, tx, surveyEvent(entity.EventSurveyStarted, user, survey, 0)); err != nil {
+			return SurveyProgress{}, fmt.Errorf("failed to publish event: %w", err)
+		}
+	case err != nil:
+		return SurveyProgress{}, fmt.Errorf("failed to get user survey state: %w", err)
+	case state.State == entity.FinishedState:
+		return SurveyProgress{}, ErrSurveyAlreadyFinished
+	}
+
+	if err := s.dbRepo.UpdateUserCurrentSurvey(ctx, tx, user.GUID, survey.GUID); err != nil {
+		return SurveyProgress{}, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if err := s.dbRepo.UpdateUserLastActivity(ctx, tx, user.GUID); err != nil {
+		return SurveyProgress{}, fmt.Errorf("failed to update user's last activity: %w", err)
+	}
+
+	return newSurveyProgress(survey, state), nil
+}
+
+// answerSurvey saves the answer to the next question of the active survey, results are calculated after the last one.
+// The survey becomes current for the user until it is finished, so it can be continued in the bot or in the mini app.
+func (s *service) answerSurvey(
+	ctx stdcontext.Context,
+	tx DBTransaction,
+	user entity.User,
+	surveyGUID uuid.UUID,
+	msg string,
+) (SurveyProgress, []entity.Alert, error) {
+	state, err := s.dbRepo.GetUserSurveyState(ctx, tx, user.GUID, surveyGUID, []entity.State{entity.ActiveState})
+	if err != nil {
+		return SurveyProgress{}, nil, fmt.Errorf("failed to get user survey state: %w", err)
+	}
+
+	survey, err := s.dbRepo.GetSurvey(ctx, tx, surveyGUID)
+	if err != nil {
+		return SurveyProgress{}, nil, fmt.Errorf("failed to get survey: %w", err)
+	}
+
+	lastQuestionNumber := len(state.Answers)
+	if lastQuestionNumber >= len(survey.Questions) {
+		return SurveyProgress{}, nil, fmt.Errorf("last question is out of range")
+	}
+	lastQuestion := survey.Questions[lastQuestionNumber]
+	answer, err := lastQuestion.GetAnswer(msg)
+	if err != nil {
+		return SurveyProgress{}, nil, fmt.Errorf("failed to get answer: %w: %w", ErrInvalidAnswer, err)
+	}
+
+	state.Answers = append(state.Answers, answer)
+
+	isCurrent := user.CurrentSurvey != nil && *user.CurrentSurvey == survey.GUID
+
+	var alerts []entity.Alert
+	if lastQuestionNumber == len(survey.Questions)-1 {
+		// if it was last question
+		results, err := s.rsltProc.GetResults(survey, state.Answers)
+		if err != nil {
+			return SurveyProgress{}, nil, fmt.Errorf("failed to get results: %w", err)
+		}
+
+		if err := s.applyNorms(ctx, tx, survey.GUID, user.Cohort, &results); err != nil {
+			return SurveyProgress{}, nil, fmt.Errorf("failed to apply norms: %w", err)
+		}
+		if err := s.applyChanges(ctx, tx, user.GUID, survey.GUID, &results); err != nil {
+			return SurveyProgress{}, nil, fmt.Errorf("failed to apply changes: %w", err)
+		}
+
+		alerts, err = s.createAlerts(ctx, tx, user, survey, results)
+		if err != nil {
+			return SurveyProgress{}, nil, fmt.Errorf("failed to create alerts: %w", err)
+		}
+
+		if isCurrent {
+			if err := s.dbRepo.SetUserCurrentSurveyToNil(ctx, tx, user.GUID); err != nil {
+				return SurveyProgress{}, nil, fmt.Errorf("failed to set current user survey to null: %w", err)
+			}
+		}
+
+		state.State = entity.FinishedState
+		state.Results = &results
+	} else if !isCurrent {
+		if err := s.dbRepo.UpdateUserCurrentSurvey(ctx, tx, user.GUID, survey.GUID); err != nil {
+			return SurveyProgress{}, nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+
+	if err := s.dbRepo.UpdateActiveUserSurveyState(ctx, tx, state); err != nil {
+		return SurveyProgress{}, nil, fmt.Errorf("failed to update user survey state: %w", err)
+	}
+
+	eventType := entity.EventSurveyAnswered
+	if state.State == entity.FinishedState {
+		eventType = entity.EventSurveyFinished
+	}
+	if err := s.publishEvent(ctx, tx, surveyEvent(eventType, user, survey, len(state.Answers))); err != nil {
+		return SurveyProgress{}, nil, fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	progress := newSurveyProgress(survey, state)
+	if state.Results != nil {
+		progress.State.Results = s.withResultsText(survey.CalculationsType, *state.Results)
+	}
+
+	return progress, alerts, nil
+}
+
+// userSurveyStates returns all surveys ordered by id with the state of the user
+func (s *service) userSurveyStates(ctx stdcontext.Context, tx DBTransaction, user entity.User) ([]UserSurveyState, error) {
+	all, err := s.dbRepo.GetSurveysList(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all surveys: %w", err)
+	}
+
+	var userStates []entity.SurveyState
+	if user.GUID != uuid.Nil {
+		userStates, err = s.dbRepo.GetUserSurveyStates(ctx, tx, user.GUID, []entity.State{entity.ActiveState})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user survey states: %w", err)
+		}
+	}
+
+	// sort surveys by id
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID < all[j].ID
+	})
+
+	var states []UserSurveyState
+	for _, survey := range all {
+		var (
+			state     entity.State
+			isFound   bool
+			isCurrent bool
+		)
+
+		for _, userState := range userStates {
+			if userState.SurveyGUID == survey.GUID {
+				state = userState.State
+				isFound = true
+				break
+			}
+		}
+
+		if !isFound {
+			state = entity.NotStartedState
+		}
+
+		if (user.CurrentSurvey != nil) && (survey.GUID == *user.CurrentSurvey) {
+			isCurrent = true
+		}
+
+		states = append(states, UserSurveyState{
+			UserGUID:  user.GUID,
+			Survey:    survey,
+			State:     state,
+			IsCurrent: isCurrent,
+		})
+	}
+
+	return states, nil
+}
+
+func newSurveyProgress(survey entity.Survey, state entity.SurveyState) SurveyProgress {
+	progress := SurveyProgress{
+		Survey: survey,
+		State:  state,
+	}
+
+	if state.State != entity.FinishedState && len(state.Answers) < len(survey.Questions) {
+		progress.Question = &survey.Questions[len(state.Answers)]
+	}
+
+	return progress
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/report"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/responses"
+)
+
+// HandleReportCommand sends PDF report of the latest finished attempt of the survey
+func (s *service) HandleReportCommand(ctx context.Context, surveyID int64) error {
+	var (
+		data     []byte
+		fileName string
+	)
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByID(ctx, tx, ctx.UserID())
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		survey, err := s.dbRepo.GetSurveyByID(ctx, tx, surveyID)
+		if err != nil {
+			return fmt.Errorf("failed to get survey: %w", err)
+		}
+
+		data, err = s.latestReportPDF(ctx, tx, user, survey.GUID, true)
+		if errors.Is(err, ErrNotFound) {
+			if err := s.telegramRepo.SendMessage(ctx, responses.ReportNotFound); err != nil {
+				s.logger.Errorf(ctx, "failed to send error message: %w", err)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get report: %w", err)
+		}
+
+		fileName = reportFileName(survey.Name)
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	if err := s.telegramRepo.SendDocument(ctx, fileName, data); err != nil {
+		return fmt.Errorf("failed to send report: %w", err)
+	}
+
+	return nil
+}
+
+// GetCompletedSurveyReport returns PDF report of the finished attempt of the survey started at the time
+func (s *service) GetCompletedSurveyReport(
+	ctx stdcontext.Context,
+	userID int64,
+	surveyGUID uuid.UUID,
+	startedAt time.Time,
+	withAnswers bool,
+) ([]byte, error) {
+	var data []byte
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByID(ctx, tx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		survey, err := s.completedSurvey(ctx, tx, user, surveyGUID, startedAt)
+		if err != nil {
+			return err
+		}
+
+		data, err = s.reportPDF(ctx, tx, survey, withAnswers)
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return data, nil
+}
+
+// GetUserSurveyReport returns PDF report of the latest finished attempt of the survey by the user
+func (s *service) GetUserSurveyReport(ctx stdcontext.Context, userGUID, surveyGUID uuid.UUID, withAnswers bool) ([]byte, error) {
+	var data []byte
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByGUID(ctx, tx, userGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		data, err = s.latestReportPDF(ctx, tx, user, surveyGUID, withAnswers)
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return data, nil
+}
+
+func (s *service) latestReportPDF(
+	ctx stdcontext.Context,
+	tx DBTransaction,
+	user entity.User,
+	surveyGUID uuid.UUID,
+	withAnswers bool,
+) ([]byte, error) {
+	surveys, err := s.completedSurveys(ctx, tx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, survey := range surveys {
+		if survey.SurveyGUID == surveyGUID {
+			return s.reportPDF(ctx, tx, survey, withAnswers)
+		}
+	}
+
+	return nil, fmt.Errorf("finished survey %s: %w", surveyGUID, ErrNotFound)
+}
+
+func (s *service) reportPDF(ctx stdcontext.Context, tx DBTransaction, r entity.SurveyStateReport, withAnswers bool) ([]byte, error) {
+	var questions []entity.Question
+	if withAnswers {
+		survey, err := s.dbRepo.GetSurvey(ctx, tx, r.SurveyGUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get survey: %w", err)
+		}
+
+		questions = survey.Questions
+	}
+
+	data, err := report.PDF(r, questions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+
+	return data, nil
+}
+
+func reportFileName(surveyName string) string {
+	return fmt.Sprintf("%s.pdf", surveyName)
+}
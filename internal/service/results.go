@@ -0,0 +1,168 @@
+package service
+
+import (
+	stdcontext "context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// exportBatchSize is a number of finished surveys read from db at once during export
+const exportBatchSize = 100
+
+// ParseResultsFilter parses the filter, dates are in "2006-01-02" or RFC3339 format
+func ParseResultsFilter(p ResultsFilterParams) (ResultsFilter, error) {
+	var f ResultsFilter
+
+	if p.From != "" {
+		from, err := parseTime(p.From)
+		if err != nil {
+			return ResultsFilter{}, fmt.Errorf("invalid from: %w", err)
+		}
+		f.From = &from
+	}
+
+	if p.To != "" {
+		to, err := parseTime(p.To)
+		if err != nil {
+			return ResultsFilter{}, fmt.Errorf("invalid to: %w", err)
+		}
+		f.To = &to
+	}
+
+	if p.Survey != "" {
+		surveyGUID, err := uuid.Parse(p.Survey)
+		if err != nil {
+			return ResultsFilter{}, fmt.Errorf("invalid survey: %w", err)
+		}
+		f.SurveyGUID = &surveyGUID
+	}
+
+	if p.User != "" {
+		userGUID, err := uuid.Parse(p.User)
+		if err != nil {
+			return ResultsFilter{}, fmt.Errorf("invalid user: %w", err)
+		}
+		f.UserGUID = &userGUID
+	}
+
+	f.Scale = p.Scale
+	f.Level = p.Level
+	if f.Level != "" && f.Scale == "" {
+		return ResultsFilter{}, errors.New("level requires scale")
+	}
+
+	return f, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", v)
+}
+
+func (s *service) GetResults(ctx stdcontext.Context, f ResultsFilter, cursor string, limit int) (ResultsPage, error) {
+	if limit <= 0 {
+		return ResultsPage{}, fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	var after *ResultsCursor
+	if cursor != "" {
+		c, err := parseResultsCursor(cursor)
+		if err != nil {
+			return ResultsPage{}, fmt.Errorf("failed to parse cursor: %w", err)
+		}
+
+		after = &c
+	}
+
+	var page ResultsPage
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		// one more result shows if there is the next page
+		results, err := s.dbRepo.GetFinishedSurveysAfter(ctx, tx, f, after, limit+1)
+		if err != nil {
+			return fmt.Errorf("failed to get finished surveys: %w", err)
+		}
+
+		if len(results) <= limit {
+			page.Results = results
+			return nil
+		}
+
+		page.Results = results[:limit]
+
+		last := page.Results[limit-1]
+		userGUID, err := uuid.Parse(last.UserGUID)
+		if err != nil {
+			return fmt.Errorf("failed to parse user guid: %w", err)
+		}
+
+		page.NextCursor, err = encodeResultsCursor(ResultsCursor{
+			FinishedAt: last.FinishedAt,
+			UserGUID:   userGUID,
+			SurveyGUID: last.SurveyGUID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode cursor: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return ResultsPage{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *service) ExportResults(ctx stdcontext.Context, w io.Writer, export ResultsExport) (int, error) {
+	writer, err := newResultsWriter(w, export.Format)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create results writer: %w", err)
+	}
+
+	var total int
+	// results of the export are consistent even if new surveys are finished during it
+	if err := s.transactSnapshot(ctx, func(tx DBTransaction) error {
+		var err error
+		total, err = s.writeFinishedSurveys(ctx, tx, writer, export.Filter, exportBatchSize, export.Progress)
+		if err != nil {
+			return fmt.Errorf("failed to save finished surveys: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return total, nil
+}
+
+func encodeResultsCursor(c ResultsCursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func parseResultsCursor(cursor string) (ResultsCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ResultsCursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	var c ResultsCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return ResultsCursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return c, nil
+}
@@ -2,14 +2,14 @@ package service
 
 import (
 	stdcontext "context"
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
+	"regexp"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,8 +23,20 @@ import (
 var (
 	UUIDProvider = uuid.New
 
-	ErrNotFound      = errors.New("not found")
-	ErrAlreadyExists = errors.New("already exists")
+	ErrNotFound              = errors.New("not found")
+	ErrAlreadyExists         = errors.New("already exists")
+	ErrInvalidSurvey         = errors.New("invalid survey")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrInvalidAnswer         = errors.New("invalid answer")
+	ErrSurveyAlreadyFinished = errors.New("survey already finished")
+	ErrSurveyInUse           = errors.New("survey in use")
+
+	ErrSyncPlanOutdated = errors.New("sync plan is outdated")
+	ErrAmbiguousUser    = errors.New("ambiguous user")
+	ErrInvalidBackup    = errors.New("invalid backup")
+
+	// cohortRegexp matches allowed payload of telegram deep link
+	cohortRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
 )
 
 type service struct {
@@ -32,26 +44,61 @@ type service struct {
 	dbRepo       DBRepo
 	rsltProc     entity.ResultsProcessor
 
+	alertRules  []entity.AlertRule
+	alertChatID int64
+
+	events             *EventBus
+	answersThrottle    time.Duration
+	answerEventsMu     sync.Mutex
+	answerEvents       map[answerEventKey]time.Time
+	answerEventsPruned time.Time
+
+	commitHooksMu sync.Mutex
+	commitHooks   map[DBTransaction][]func()
+
 	logger logger.Logger
 }
 
-func New(tr TelegramRepo, dbRepo DBRepo, rsltProc entity.ResultsProcessor, logger logger.Logger) *service {
-	return &service{
+type Option func(*service)
+
+func New(tr TelegramRepo, dbRepo DBRepo, rsltProc entity.ResultsProcessor, logger logger.Logger, opts ...Option) *service {
+	s := &service{
 
 		telegramRepo: tr,
 		dbRepo:       dbRepo,
 		logger:       logger,
 		rsltProc:     rsltProc,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func (s *service) Transact(ctx stdcontext.Context, fn func(exec DBTransaction) error) error {
-	tx, err := s.dbRepo.BeginTx(ctx)
+	return s.transact(ctx, s.dbRepo.BeginTx, fn)
+}
+
+// transactSnapshot runs fn in read-only transaction seeing the same snapshot of data by all queries
+func (s *service) transactSnapshot(ctx stdcontext.Context, fn func(exec DBTransaction) error) error {
+	return s.transact(ctx, s.dbRepo.BeginSnapshotTx, fn)
+}
+
+func (s *service) transact(
+	ctx stdcontext.Context,
+	begin func(stdcontext.Context) (DBTransaction, error),
+	fn func(exec DBTransaction) error,
+) error {
+	tx, err := begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
 	if err := fn(tx); err != nil {
+		s.takeCommitHooks(tx)
+
 		if err := tx.Rollback(); err != nil {
 			s.logger.Errorf(ctx, "failed to rollback transaction: %w", err)
 		}
@@ -59,35 +106,44 @@ func (s *service) Transact(ctx stdcontext.Context, fn func(exec DBTransaction) e
 		return fmt.Errorf("failed to exec transaction: %w", err)
 	}
 
+	hooks := s.takeCommitHooks(tx)
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	for _, hook := range hooks {
+		hook()
+	}
+
 	return nil
 }
 
-func (s *service) HandleSurveyCommand(ctx context.Context, surveyID int64) error {
-	if err := s.Transact(ctx, func(tx DBTransaction) error {
-		var (
-			user entity.User
-			err  error
-		)
+// onCommit runs hook after the transaction is committed, it is dropped if the transaction is rolled back
+func (s *service) onCommit(tx DBTransaction, hook func()) {
+	s.commitHooksMu.Lock()
+	defer s.commitHooksMu.Unlock()
 
-		user, err = s.dbRepo.GetUserByID(ctx, tx, ctx.UserID())
-		switch {
-		case errors.Is(err, ErrNotFound):
-			user = entity.User{
-				GUID:          UUIDProvider(),
-				UserID:        ctx.UserID(),
-				ChatID:        ctx.ChatID(),
-				Nickname:      ctx.Nickname(),
-				CurrentSurvey: nil,
-			}
+	if s.commitHooks == nil {
+		s.commitHooks = make(map[DBTransaction][]func())
+	}
+	s.commitHooks[tx] = append(s.commitHooks[tx], hook)
+}
 
-			if err := s.dbRepo.CreateUser(ctx, tx, user); err != nil {
-				return fmt.Errorf("failed to create user: %w", err)
-			}
-		case err != nil:
+// takeCommitHooks removes hooks of the finished transaction and returns them
+func (s *service) takeCommitHooks(tx DBTransaction) []func() {
+	s.commitHooksMu.Lock()
+	defer s.commitHooksMu.Unlock()
+
+	hooks := s.commitHooks[tx]
+	delete(s.commitHooks, tx)
+
+	return hooks
+}
+
+func (s *service) HandleSurveyCommand(ctx context.Context, surveyID int64) error {
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.getOrCreateUser(ctx, tx, identityFromContext(ctx))
+		if err != nil {
 			return fmt.Errorf("failed to get user: %w", err)
 		}
 
@@ -96,39 +152,21 @@ func (s *service) HandleSurveyCommand(ctx context.Context, surveyID int64) error
 			return fmt.Errorf("failed to get survey: %w", err)
 		}
 
-		state, err := s.dbRepo.GetUserSurveyState(ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState})
+		progress, err := s.startSurvey(ctx, tx, user, survey)
 		switch {
-		case errors.Is(err, ErrNotFound):
-			state = entity.SurveyState{
-				UserGUID:   user.GUID,
-				SurveyGUID: survey.GUID,
-				State:      entity.ActiveState,
-			}
-
-			if err := s.dbRepo.CreateUserSurveyState(ctx, tx, state); err != nil {
-				return fmt.Errorf("failed to create user survey state: %w", err)
-			}
-		case err != nil:
-			return fmt.Errorf("failed to get user survey state: %w", err)
-		case state.State == entity.FinishedState:
+		case errors.Is(err, ErrSurveyAlreadyFinished):
 			if err := s.telegramRepo.SendMessage(ctx, responses.SurveyAlreadyFinished); err != nil {
 				s.logger.Errorf(ctx, "failed to send error message: %w", err)
 			}
 
-			return fmt.Errorf("survey already finished")
-		}
-
-		if err := s.dbRepo.UpdateUserCurrentSurvey(ctx, tx, user.GUID, survey.GUID); err != nil {
-			return fmt.Errorf("failed to update user: %w", err)
-		}
-
-		if err := s.dbRepo.UpdateUserLastActivity(ctx, tx, user.GUID); err != nil {
-			return fmt.Errorf("failed to update user's last activity: %w", err)
+			return err
+		case err != nil:
+			return fmt.Errorf("failed to start survey: %w", err)
+		case progress.Question == nil:
+			return fmt.Errorf("last question is out of range")
 		}
 
-		lastQuestionNumber := len(state.Answers) - 1
-		lastQuestion := survey.Questions[lastQuestionNumber+1]
-		if err := s.telegramRepo.SendSurveyQuestion(ctx, lastQuestion); err != nil {
+		if err := s.telegramRepo.SendSurveyQuestion(ctx, *progress.Question); err != nil {
 			return fmt.Errorf("failed to send survey question: %w", err)
 		}
 
@@ -142,26 +180,8 @@ func (s *service) HandleSurveyCommand(ctx context.Context, surveyID int64) error
 
 func (s *service) HandleListCommand(ctx context.Context) error {
 	if err := s.Transact(ctx, func(tx DBTransaction) error {
-		var (
-			user entity.User
-			err  error
-		)
-
-		user, err = s.dbRepo.GetUserByID(ctx, tx, ctx.UserID())
-		switch {
-		case errors.Is(err, ErrNotFound):
-			user = entity.User{
-				GUID:          UUIDProvider(),
-				UserID:        ctx.UserID(),
-				ChatID:        ctx.ChatID(),
-				Nickname:      ctx.Nickname(),
-				CurrentSurvey: nil,
-			}
-
-			if err := s.dbRepo.CreateUser(ctx, tx, user); err != nil {
-				return fmt.Errorf("failed to create user: %w", err)
-			}
-		case err != nil:
+		user, err := s.getOrCreateUser(ctx, tx, identityFromContext(ctx))
+		if err != nil {
 			return fmt.Errorf("failed to get user: %w", err)
 		}
 
@@ -182,27 +202,16 @@ func (s *service) HandleListCommand(ctx context.Context) error {
 }
 
 func (s *service) HandleAnswer(ctx context.Context, msg string) error {
-	if err := s.Transact(ctx, func(tx DBTransaction) error {
-		var (
-			user entity.User
-			err  error
-		)
-
-		user, err = s.dbRepo.GetUserByID(ctx, tx, ctx.UserID())
-		switch {
-		case errors.Is(err, ErrNotFound):
-			user = entity.User{
-				GUID:          UUIDProvider(),
-				UserID:        ctx.UserID(),
-				ChatID:        ctx.ChatID(),
-				Nickname:      ctx.Nickname(),
-				CurrentSurvey: nil,
-			}
+	var (
+		user     entity.User
+		progress SurveyProgress
+		alerts   []entity.Alert
+	)
 
-			if err := s.dbRepo.CreateUser(ctx, tx, user); err != nil {
-				return fmt.Errorf("failed to create user: %w", err)
-			}
-		case err != nil:
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		user, err = s.getOrCreateUser(ctx, tx, identityFromContext(ctx))
+		if err != nil {
 			return fmt.Errorf("failed to get user: %w", err)
 		}
 
@@ -217,68 +226,45 @@ func (s *service) HandleAnswer(ctx context.Context, msg string) error {
 			return fmt.Errorf("user does not have current survey")
 		}
 
-		state, err := s.dbRepo.GetUserSurveyState(ctx, tx, user.GUID, *user.CurrentSurvey, []entity.State{entity.ActiveState})
-		if err != nil {
-			return fmt.Errorf("failed to get user survey state: %w", err)
-		}
-
-		survey, err := s.dbRepo.GetSurvey(ctx, tx, *user.CurrentSurvey)
-		if err != nil {
-			return fmt.Errorf("failed to get survey: %w", err)
-		}
-
-		lastQuestionNumber := len(state.Answers)
-		if lastQuestionNumber >= len(survey.Questions) {
-			return fmt.Errorf("last question is out of range")
+		progress, alerts, err = s.answerSurvey(ctx, tx, user, *user.CurrentSurvey, msg)
+		if errors.Is(err, ErrInvalidAnswer) {
+			s.handleAnswerValidateError(ctx, err)
 		}
-		lastQuestion := survey.Questions[lastQuestionNumber]
-		answer, err := lastQuestion.GetAnswer(msg)
 		if err != nil {
-			s.handleAnswerValidateError(ctx, err)
-
-			return fmt.Errorf("failed to get answer: %w", err)
+			return fmt.Errorf("failed to answer survey: %w", err)
 		}
 
-		state.Answers = append(state.Answers, answer)
-
-		if lastQuestionNumber == len(survey.Questions)-1 {
-			// if it was last question
-			results, err := s.rsltProc.GetResults(survey, state.Answers)
-			if err != nil {
-				return fmt.Errorf("failed to get results: %w", err)
-			}
-
-			if err := s.dbRepo.SetUserCurrentSurveyToNil(ctx, tx, user.GUID); err != nil {
-				return fmt.Errorf("failed to set current user survey to null: %w", err)
-			}
-
-			if err := s.telegramRepo.SendMessage(ctx, results.Text); err != nil {
-				s.logger.Errorf(ctx, "failed to send results: %w", err)
-			}
-
-			state.State = entity.FinishedState
-			state.Results = &results
-		} else {
-			// otherwise send next question
-			nextQuestion := survey.Questions[lastQuestionNumber+1]
-			if err := s.telegramRepo.SendSurveyQuestion(ctx, nextQuestion); err != nil {
+		if progress.Question != nil {
+			// send next question
+			if err := s.telegramRepo.SendSurveyQuestion(ctx, *progress.Question); err != nil {
 				return fmt.Errorf("failed to send survey question: %w", err)
 			}
 		}
 
-		if err := s.dbRepo.UpdateActiveUserSurveyState(ctx, tx, state); err != nil {
-			return fmt.Errorf("failed to update user survey state: %w", err)
-		}
-
 		return nil
 	}); err != nil {
 		return fmt.Errorf("failed to transact: %w", err)
 	}
 
+	if progress.Question == nil {
+		// if it was last question, results and alerts are sent only when they are stored
+		if err := s.telegramRepo.SendResults(ctx, progress.Survey, *progress.State.Results); err != nil {
+			s.logger.Errorf(ctx, "failed to send results: %w", err)
+		}
+
+		s.notifyAlerts(ctx, alerts, user, progress.Survey)
+	}
+
 	return nil
 }
 
-func (s *service) HandleStartCommand(ctx context.Context) error {
+// HandleStartCommand registers user. Cohort is a payload of /start deep link, it is assigned
+// to the user only once and ignored if it is not valid.
+func (s *service) HandleStartCommand(ctx context.Context, cohort string) error {
+	if !cohortRegexp.MatchString(cohort) {
+		cohort = ""
+	}
+
 	if err := s.Transact(ctx, func(tx DBTransaction) error {
 		var (
 			user entity.User
@@ -294,15 +280,26 @@ func (s *service) HandleStartCommand(ctx context.Context) error {
 				ChatID:        ctx.ChatID(),
 				Nickname:      ctx.Nickname(),
 				CurrentSurvey: nil,
+				Cohort:        cohort,
 			}
 
 			if err := s.dbRepo.CreateUser(ctx, tx, user); err != nil {
 				return fmt.Errorf("failed to create user: %w", err)
 			}
+
+			if err := s.publishEvent(ctx, tx, userEvent(user)); err != nil {
+				return fmt.Errorf("failed to publish event: %w", err)
+			}
 		case err != nil:
 			return fmt.Errorf("failed to create user: %w", err)
 		default:
 			s.logger.Infof(ctx, "user already pressed start command")
+
+			if user.Cohort == "" && cohort != "" {
+				if err := s.dbRepo.UpdateUserCohort(ctx, tx, user.GUID, cohort); err != nil {
+					return fmt.Errorf("failed to update user's cohort: %w", err)
+				}
+			}
 		}
 
 		if err := s.dbRepo.UpdateUserLastActivity(ctx, tx, user.GUID); err != nil {
@@ -328,31 +325,13 @@ func (s *service) getSurveyList(ctx stdcontext.Context, tx DBTransaction) ([]ent
 func (s *service) CreateSurvey(ctx stdcontext.Context, survey entity.Survey) (entity.Survey, error) {
 	if err := s.Transact(ctx, func(tx DBTransaction) error {
 		if err := s.rsltProc.Validate(survey); err != nil {
-			return fmt.Errorf("failed to validate survey: %w", err)
-		}
-
-		// get surveys list
-		surveys, err := s.getSurveyList(ctx, tx)
-		if err != nil {
-			return fmt.Errorf("failed to get surveys list: %w", err)
+			return fmt.Errorf("failed to validate survey: %w: %w", ErrInvalidSurvey, err)
 		}
 
-		// get max survey id
-		var maxSurveyID int64
-		for _, survey := range surveys {
-			if survey.ID > maxSurveyID {
-				maxSurveyID = survey.ID
-			}
-		}
-
-		survey.ID = maxSurveyID + 1
-		survey.GUID = UUIDProvider()
-
-		if err := s.dbRepo.CreateSurvey(ctx, tx, survey); err != nil {
-			return fmt.Errorf("failed to create survey: %w", err)
-		}
+		var err error
+		survey, err = s.createSurvey(ctx, tx, survey)
 
-		return nil
+		return err
 	}); err != nil {
 		return entity.Survey{}, fmt.Errorf("failed to transact: %w", err)
 	}
@@ -360,52 +339,36 @@ func (s *service) CreateSurvey(ctx stdcontext.Context, survey entity.Survey) (en
 	return survey, nil
 }
 
-func (s *service) sendUserSurveyList(ctx context.Context, tx DBTransaction, user entity.User) error {
-	all, err := s.dbRepo.GetSurveysList(ctx, tx)
+// createSurvey stores the validated survey with new guid and the next id
+func (s *service) createSurvey(ctx stdcontext.Context, tx DBTransaction, survey entity.Survey) (entity.Survey, error) {
+	// get surveys list
+	surveys, err := s.getSurveyList(ctx, tx)
 	if err != nil {
-		return fmt.Errorf("failed to get all surveys: %w", err)
+		return entity.Survey{}, fmt.Errorf("failed to get surveys list: %w", err)
 	}
 
-	userStates, err := s.dbRepo.GetUserSurveyStates(ctx, tx, user.GUID, []entity.State{entity.ActiveState})
-	if err != nil {
-		return fmt.Errorf("failed to get user survey states: %w", err)
+	// get max survey id
+	var maxSurveyID int64
+	for _, survey := range surveys {
+		if survey.ID > maxSurveyID {
+			maxSurveyID = survey.ID
+		}
 	}
 
-	// sort surveys by id
-	sort.Slice(all, func(i, j int) bool {
-		return all[i].ID < all[j].ID
-	})
-
-	var states []UserSurveyState
-	for _, survey := range all {
-		var (
-			state     entity.State
-			isFound   bool
-			isCurrent bool
-		)
-
-		for _, userState := range userStates {
-			if userState.SurveyGUID == survey.GUID {
-				state = userState.State
-				isFound = true
-				break
-			}
-		}
+	survey.ID = maxSurveyID + 1
+	survey.GUID = UUIDProvider()
 
-		if !isFound {
-			state = entity.NotStartedState
-		}
+	if err := s.dbRepo.CreateSurvey(ctx, tx, survey); err != nil {
+		return entity.Survey{}, fmt.Errorf("failed to create survey: %w", err)
+	}
 
-		if (user.CurrentSurvey != nil) && (survey.GUID == *user.CurrentSurvey) {
-			isCurrent = true
-		}
+	return survey, nil
+}
 
-		states = append(states, UserSurveyState{
-			UserGUID:  user.GUID,
-			Survey:    survey,
-			State:     state,
-			IsCurrent: isCurrent,
-		})
+func (s *service) sendUserSurveyList(ctx context.Context, tx DBTransaction, user entity.User) error {
+	states, err := s.userSurveyStates(ctx, tx, user)
+	if err != nil {
+		return fmt.Errorf("failed to get user survey states: %w", err)
 	}
 
 	if err := s.telegramRepo.SendSurveyList(ctx, states); err != nil {
@@ -416,21 +379,7 @@ func (s *service) sendUserSurveyList(ctx context.Context, tx DBTransaction, user
 }
 
 func (s *service) handleAnswerValidateError(ctx context.Context, err error) {
-	errText := "failed to parse answer"
-	switch {
-	case errors.Is(err, entity.ErrParseAnswerTypeSegment):
-		errText = responses.AnswerNotANumber
-	case errors.Is(err, entity.ErrParseAnswerTypeSelect):
-		errText = responses.AnswerNotANumber
-	case errors.Is(err, entity.ErrParseAnswerTypeMultiSelect):
-		errText = responses.AnswerNotANumber
-	case errors.Is(err, entity.ErrAnswerOutOfRange):
-		errText = responses.AnswerOutOfRange
-	case errors.Is(err, entity.ErrAnswerNotFound):
-		errText = responses.AnswerNotFound
-	}
-
-	if err := ctx.Send(errText); err != nil {
+	if err := ctx.Send(AnswerErrorText(err)); err != nil {
 		s.logger.Errorf(ctx, "failed to send error message: %w", err)
 	}
 }
@@ -472,15 +421,26 @@ func (s *service) DeleteUserSurvey(ctx stdcontext.Context, userGUID uuid.UUID, s
 }
 
 func (s *service) SaveFinishedSurveys(ctx stdcontext.Context, tx DBTransaction, w io.Writer, f ResultsFilter, batchSize int) (int, error) {
-	writer := csv.NewWriter(w)
+	writer, err := newCSVResultsWriter(w)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create csv writer: %w", err)
+	}
+
+	return s.writeFinishedSurveys(ctx, tx, writer, f, batchSize, nil)
+}
 
+// writeFinishedSurveys writes finished surveys matching the filter by batches, progress is called after each of them
+func (s *service) writeFinishedSurveys(
+	ctx stdcontext.Context,
+	tx DBTransaction,
+	writer resultsWriter,
+	f ResultsFilter,
+	batchSize int,
+	progress func(exported int),
+) (int, error) {
 	offset := 0
 	total := 0
 
-	if err := writer.Write([]string{"survey_guid", "survey_name", "user_guid", "user_id", "text", "metadata", "started_at", "finished_at", "answers"}); err != nil {
-		return 0, fmt.Errorf("failed to write header to csv: %w", err)
-	}
-
 	for {
 		// get batch of finished surveys
 		states, err := s.dbRepo.GetFinishedSurveys(ctx, tx, f, batchSize, offset)
@@ -494,24 +454,39 @@ func (s *service) SaveFinishedSurveys(ctx stdcontext.Context, tx DBTransaction,
 
 		// save results to file
 		for _, state := range states {
-			columns, err := state.ToCSV()
-			if err != nil {
-				return 0, fmt.Errorf("failed to convert survey state to csv: %w", err)
+			if err := writer.Write(state); err != nil {
+				return 0, fmt.Errorf("failed to write survey: %w", err)
 			}
+		}
 
-			if err := writer.Write(columns); err != nil {
-				return 0, fmt.Errorf("failed to write survey to csv: %w", err)
-			}
+		if err := writer.Flush(); err != nil {
+			return 0, fmt.Errorf("failed to flush results: %w", err)
 		}
 
-		writer.Flush()
 		offset += batchSize
 		total += len(states)
+
+		if progress != nil {
+			progress(total)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close results: %w", err)
 	}
 
 	return total, nil
 }
 
+// surveyFile is the format of survey files, see surveytests
+type surveyFile struct {
+	Slug             string            `json:"slug,omitempty"`
+	Name             string            `json:"name"`
+	CalculationsType string            `json:"calculations_type"`
+	Description      string            `json:"description"`
+	Questions        []entity.Question `json:"questions"`
+}
+
 func ReadSurveyFromFile(filename string) (entity.Survey, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -524,14 +499,7 @@ func ReadSurveyFromFile(filename string) (entity.Survey, error) {
 		return entity.Survey{}, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	type survey struct {
-		Questions        []entity.Question `json:"questions"`
-		Name             string            `json:"name"`
-		CalculationsType string            `json:"calculations_type"`
-		Description      string            `json:"description"`
-	}
-
-	var s survey
+	var s surveyFile
 	if err := json.Unmarshal(data, &s); err != nil {
 		return entity.Survey{}, fmt.Errorf("failed to unmarshal file: %w", err)
 	}
@@ -541,11 +509,45 @@ func ReadSurveyFromFile(filename string) (entity.Survey, error) {
 		Questions:        s.Questions,
 		CalculationsType: s.CalculationsType,
 		Description:      s.Description,
+		Slug:             s.Slug,
 	}, nil
 }
 
+// WriteSurveyFile writes the survey in the format read by ReadSurveyFromFile
+func WriteSurveyFile(w io.Writer, survey entity.Survey) error {
+	questions := make([]entity.Question, 0, len(survey.Questions))
+	for _, question := range survey.Questions {
+		// the same as in the files
+		if question.AnswersText == nil {
+			question.AnswersText = []string{}
+		}
+
+		questions = append(questions, question)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+
+	if err := encoder.Encode(surveyFile{
+		Slug:             survey.Slug,
+		Name:             survey.Name,
+		CalculationsType: survey.CalculationsType,
+		Description:      survey.Description,
+		Questions:        questions,
+	}); err != nil {
+		return fmt.Errorf("failed to encode survey: %w", err)
+	}
+
+	return nil
+}
+
 func (s *service) UpdateSurvey(ctx stdcontext.Context, new entity.Survey) error {
 	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		if err := s.rsltProc.Validate(new); err != nil {
+			return fmt.Errorf("failed to validate survey: %w: %w", ErrInvalidSurvey, err)
+		}
+
 		// find survey by guid
 		old, err := s.dbRepo.GetSurvey(ctx, tx, new.GUID)
 		if err != nil {
@@ -554,7 +556,7 @@ func (s *service) UpdateSurvey(ctx stdcontext.Context, new entity.Survey) error
 
 		// if len of questions is not equal, then do not update
 		if len(old.Questions) != len(new.Questions) {
-			return fmt.Errorf("cannot update survey with different number of questions")
+			return fmt.Errorf("%w: cannot update survey with different number of questions", ErrInvalidSurvey)
 		}
 
 		// update name, questions and calculations_type
@@ -631,7 +633,8 @@ func (s *service) GetCompletedSurveys(ctx stdcontext.Context, userID int64) ([]e
 			return fmt.Errorf("failed to update user's last activity: %w", err)
 		}
 
-		surveys, err = s.dbRepo.GetCompletedSurveys(ctx, tx, user.GUID)
+		surveys, err = s.completedSurveys(ctx, tx, user)
+
 		return err
 	}); err != nil {
 		return nil, fmt.Errorf("failed to transact: %w", err)
@@ -639,6 +642,88 @@ func (s *service) GetCompletedSurveys(ctx stdcontext.Context, userID int64) ([]e
 	return surveys, nil
 }
 
+func (s *service) GetCompletedSurvey(ctx stdcontext.Context, userID int64, surveyGUID uuid.UUID, startedAt time.Time) (entity.SurveyStateReport, error) {
+	var survey entity.SurveyStateReport
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByID(ctx, tx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		survey, err = s.completedSurvey(ctx, tx, user, surveyGUID, startedAt)
+
+		return err
+	}); err != nil {
+		return entity.SurveyStateReport{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return survey, nil
+}
+
+// completedSurvey returns the finished attempt of the survey, the attempt is identified by the time it is started at,
+// so it doesn't change when other surveys are finished
+func (s *service) completedSurvey(
+	ctx stdcontext.Context,
+	tx DBTransaction,
+	user entity.User,
+	surveyGUID uuid.UUID,
+	startedAt time.Time,
+) (entity.SurveyStateReport, error) {
+	surveys, err := s.completedSurveys(ctx, tx, user)
+	if err != nil {
+		return entity.SurveyStateReport{}, err
+	}
+
+	for _, survey := range surveys {
+		if survey.SurveyGUID == surveyGUID && survey.StartedAt.Equal(startedAt) && survey.Results != nil {
+			return survey, nil
+		}
+	}
+
+	return entity.SurveyStateReport{}, fmt.Errorf("completed survey %s started at %s: %w", surveyGUID, startedAt, ErrNotFound)
+}
+
+// completedSurveys returns finished surveys of the user, the latest first, with ranks by the current norms
+func (s *service) completedSurveys(ctx stdcontext.Context, tx DBTransaction, user entity.User) ([]entity.SurveyStateReport, error) {
+	surveys, err := s.dbRepo.GetCompletedSurveys(ctx, tx, user.GUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get completed surveys: %w", err)
+	}
+
+	for _, survey := range surveys {
+		if survey.Results == nil {
+			continue
+		}
+
+		if err := s.applyNorms(ctx, tx, survey.SurveyGUID, user.Cohort, survey.Results); err != nil {
+			return nil, fmt.Errorf("failed to apply norms: %w", err)
+		}
+	}
+
+	setChanges(surveys)
+
+	// calculations types are needed only to match the alert rules
+	calculationsTypes := make(map[uuid.UUID]string)
+	for i := range surveys {
+		if surveys[i].Results == nil {
+			continue
+		}
+
+		guid := surveys[i].SurveyGUID
+		if _, ok := calculationsTypes[guid]; !ok && len(s.alertRules) > 0 {
+			survey, err := s.dbRepo.GetSurvey(ctx, tx, guid)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, fmt.Errorf("failed to get survey: %w", err)
+			}
+			calculationsTypes[guid] = survey.CalculationsType
+		}
+
+		surveys[i].Results = s.withResultsText(calculationsTypes[guid], *surveys[i].Results)
+	}
+
+	return surveys, nil
+}
+
 func (s *service) GetUsersList(ctx stdcontext.Context, limit, offset int, search string) (UserListResponse, error) {
 	var usersList UserListResponse
 	if err := s.Transact(ctx, func(tx DBTransaction) error {
@@ -650,3 +735,16 @@ func (s *service) GetUsersList(ctx stdcontext.Context, limit, offset int, search
 	}
 	return usersList, nil
 }
+
+func (s *service) SearchUsers(ctx stdcontext.Context, limit, offset int, search string) (UserSummaries, error) {
+	var users UserSummaries
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		users, err = s.dbRepo.SearchUsers(ctx, tx, limit, offset, search)
+		return err
+	}); err != nil {
+		return UserSummaries{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return users, nil
+}
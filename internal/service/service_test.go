@@ -1,10 +1,27 @@
 package service_test
 
 import (
+	"archive/zip"
+	"bytes"
 	stdcontext "context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
@@ -99,7 +116,7 @@ func (suite *ServiceTestSuite) TestHandleStartCommand() {
 
 	tx.On("Commit").Return(nil)
 
-	err := suite.svc.HandleStartCommand(ctx)
+	err := suite.svc.HandleStartCommand(ctx, "")
 	suite.NoError(err)
 }
 
@@ -166,7 +183,7 @@ func (suite *ServiceTestSuite) TestHandleStartCommand_UserAlreadyExists() {
 
 	tx.On("Commit").Return(nil)
 
-	err := suite.svc.HandleStartCommand(ctx)
+	err := suite.svc.HandleStartCommand(ctx, "")
 	suite.NoError(err)
 }
 
@@ -508,6 +525,1901 @@ func (suite *ServiceTestSuite) TestHandleSurveyCommand_SurveyNotYetStarted() {
 	suite.NoError(err)
 }
 
+func (suite *ServiceTestSuite) TestHandleStartCommand_SetCohort() {
+	ctx := newTestContext(stdcontext.Background(), 10, 33, []string{"start"})
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(entity.User{
+		GUID:   uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947"),
+		UserID: 10,
+		ChatID: 33,
+	}, nil)
+
+	suite.logger.On("Infof", ctx, "user already pressed start command")
+
+	suite.dbRepo.On(
+		"UpdateUserCohort",
+		ctx,
+		tx,
+		uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947"),
+		"spring_campaign",
+	).Return(nil)
+
+	suite.dbRepo.On(
+		"UpdateUserLastActivity",
+		ctx,
+		tx,
+		uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947"),
+	).Return(nil)
+
+	suite.dbRepo.On("GetSurveysList", ctx, tx).Return(
+		suite.generateTestSurveyList(),
+		nil,
+	)
+
+	suite.dbRepo.On(
+		"GetUserSurveyStates",
+		ctx,
+		tx,
+		uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947"),
+		[]entity.State{entity.ActiveState},
+	).Return(
+		suite.generateSurveyStates(),
+		nil,
+	)
+
+	suite.telegramRepo.On(
+		"SendSurveyList",
+		ctx,
+		suite.generateTestUserSurveyList(),
+	).Return(nil)
+
+	tx.On("Commit").Return(nil)
+
+	err := suite.svc.HandleStartCommand(ctx, "spring_campaign")
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestRefreshNorms() {
+	ctx := stdcontext.Background()
+	surveyGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+
+	suite.dbRepo.On("GetScaleScoresDistribution", ctx, tx).Return([]service.ScaleScoreCount{
+		{SurveyGUID: surveyGUID, Scale: "s1", Cohort: "", Score: 2, Count: 2},
+		{SurveyGUID: surveyGUID, Scale: "s1", Cohort: "campaign", Score: 4, Count: 2},
+	}, nil)
+
+	suite.dbRepo.On("DeleteNorms", ctx, tx, entity.NormSourcePopulation).Return(nil)
+
+	suite.dbRepo.On("SaveNorms", ctx, tx, []entity.Norm{
+		{
+			SurveyGUID:   surveyGUID,
+			Scale:        "s1",
+			Source:       entity.NormSourcePopulation,
+			Mean:         3,
+			StdDev:       1,
+			SampleSize:   4,
+			Distribution: []entity.NormPoint{{Score: 2, Count: 2}, {Score: 4, Count: 2}},
+		},
+		{
+			SurveyGUID:   surveyGUID,
+			Scale:        "s1",
+			Cohort:       "campaign",
+			Source:       entity.NormSourcePopulation,
+			Mean:         4,
+			StdDev:       0,
+			SampleSize:   2,
+			Distribution: []entity.NormPoint{{Score: 4, Count: 2}},
+		},
+	}).Return(nil)
+
+	suite.logger.On("Infof", ctx, "refreshed %d population norms", 2)
+
+	tx.On("Commit").Return(nil)
+
+	err := suite.svc.RefreshNorms(ctx)
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestImportNorms_SurveyNotFound() {
+	ctx := stdcontext.Background()
+	surveyGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, surveyGUID).Return(entity.Survey{}, service.ErrNotFound)
+	tx.On("Rollback").Return(nil)
+
+	err := suite.svc.ImportNorms(ctx, []entity.Norm{{SurveyGUID: surveyGUID, Scale: "s1", Mean: 1, StdDev: 1}})
+	suite.ErrorIs(err, service.ErrNotFound)
+}
+
+func (suite *ServiceTestSuite) TestHandleReportCommand() {
+	ctx := newTestContext(stdcontext.Background(), 10, 33, []string{"report"})
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+
+	survey := suite.generateTestSurveyList()[0]
+	survey.Name = "Survey"
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(entity.User{GUID: userGUID, UserID: 10}, nil)
+	suite.dbRepo.On("GetSurveyByID", ctx, tx, int64(1)).Return(survey, nil)
+	suite.dbRepo.On("GetCompletedSurveys", ctx, tx, userGUID).Return([]entity.SurveyStateReport{
+		{
+			SurveyGUID: survey.GUID,
+			SurveyName: survey.Name,
+			Answers: []entity.Answer{
+				{Type: entity.AnswerTypeSelect, Data: []int{1}},
+				{Type: entity.AnswerTypeSegment, Data: []int{3}},
+				{Type: entity.AnswerTypeSelect, Data: []int{2}},
+			},
+			Results: &entity.Results{
+				Text:   "results",
+				Scales: []entity.ResultsScale{{Key: "s", Name: "Scale", Score: 3}},
+			},
+		},
+	}, nil)
+	suite.dbRepo.On("GetSurveyNorms", ctx, tx, survey.GUID).Return(nil, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	tx.On("Commit").Return(nil)
+
+	suite.telegramRepo.On("SendDocument", ctx, "Survey.pdf", mock.AnythingOfType("[]uint8")).Return(nil)
+
+	err := suite.svc.HandleReportCommand(ctx, 1)
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestHandleReportCommand_NotFinished() {
+	ctx := newTestContext(stdcontext.Background(), 10, 33, []string{"report"})
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	survey := suite.generateTestSurveyList()[0]
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(entity.User{GUID: userGUID, UserID: 10}, nil)
+	suite.dbRepo.On("GetSurveyByID", ctx, tx, int64(1)).Return(survey, nil)
+	suite.dbRepo.On("GetCompletedSurveys", ctx, tx, userGUID).Return(nil, nil)
+	tx.On("Rollback").Return(nil)
+
+	suite.telegramRepo.On("SendMessage", ctx, responses.ReportNotFound).Return(nil)
+
+	err := suite.svc.HandleReportCommand(ctx, 1)
+	suite.ErrorIs(err, service.ErrNotFound)
+}
+
+func (suite *ServiceTestSuite) TestGetCompletedSurvey() {
+	ctx := stdcontext.Background()
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	startedAt := time.Date(2024, 1, 1, 12, 0, 0, 123456000, time.UTC)
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(entity.User{GUID: userGUID, UserID: 10}, nil)
+	// the attempt finished later is the first in the list
+	suite.dbRepo.On("GetCompletedSurveys", ctx, tx, userGUID).Return([]entity.SurveyStateReport{
+		{SurveyGUID: surveyGUID, StartedAt: startedAt.Add(time.Hour), Results: &entity.Results{Text: "second"}},
+		{SurveyGUID: surveyGUID, StartedAt: startedAt, Results: &entity.Results{Text: "first"}},
+	}, nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.GetCompletedSurvey(ctx, 10, surveyGUID, startedAt.In(time.FixedZone("UTC+3", 3*60*60)))
+	suite.NoError(err)
+	suite.Equal("first", got.Results.Text)
+}
+
+func (suite *ServiceTestSuite) TestGetSurveyAttempts() {
+	ctx := stdcontext.Background()
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(entity.User{GUID: userGUID, UserID: 10}, nil)
+	suite.dbRepo.On("GetUserSurveyAttempts", ctx, tx, userGUID, surveyGUID).Return([]entity.SurveyStateReport{
+		{SurveyGUID: surveyGUID, Results: &entity.Results{Scales: []entity.ResultsScale{{Key: "s1", Name: "Эмоциональное истощение", Score: 28}}}},
+		{SurveyGUID: surveyGUID, Results: &entity.Results{Scales: []entity.ResultsScale{{Key: "s1", Name: "Эмоциональное истощение", Score: 19}}}},
+	}, nil)
+	suite.dbRepo.On("GetSurveyNorms", ctx, tx, surveyGUID).Return(nil, nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.GetSurveyAttempts(ctx, 10, surveyGUID)
+	suite.NoError(err)
+	suite.Len(got, 2)
+	suite.Empty(got[0].Results.Changes)
+	suite.Equal([]entity.ScaleChange{
+		{Key: "s1", Name: "Эмоциональное истощение", Previous: 28, Current: 19, Delta: -9, Trend: entity.TrendDown, Attempts: 2},
+	}, got[1].Results.Changes)
+}
+
+func (suite *ServiceTestSuite) TestHandleAnswer_Alert() {
+	ctx := newTestContext(stdcontext.Background(), 10, 33, []string{"5"})
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	alertGUID := uuid.MustParse("2F4F2F55-0B5C-4F47-9E0B-C7E1B5C2F4A6")
+
+	service.UUIDProvider = func() uuid.UUID {
+		return alertGUID
+	}
+
+	user := entity.User{GUID: userGUID, UserID: 10, ChatID: 33, Nickname: "nickname", CurrentSurvey: &surveyGUID}
+	survey := entity.Survey{
+		GUID:             surveyGUID,
+		ID:               1,
+		Name:             "Survey",
+		CalculationsType: "test_1",
+		Questions: []entity.Question{
+			{Text: "Question 1", AnswerType: entity.AnswerTypeSegment, PossibleAnswers: []int{1, 5}},
+		},
+	}
+	state := entity.SurveyState{State: entity.ActiveState, UserGUID: userGUID, SurveyGUID: surveyGUID}
+	results := entity.Results{
+		Text: "results",
+		Scales: []entity.ResultsScale{
+			{Key: "depression", Name: "Депрессия", Score: 5, Level: "тяжелая"},
+			{Key: "anxiety", Name: "Тревога", Score: 1, Level: "норма"},
+		},
+	}
+
+	svc := service.New(suite.telegramRepo, suite.dbRepo, suite.resultsProc, suite.logger, service.WithAlerts([]entity.AlertRule{
+		{CalculationsType: "test_1", Scale: "depression", Levels: []string{"тяжелая"}},
+		{CalculationsType: "test_1", Scale: "anxiety", Levels: []string{"тяжелая"}},
+	}, -100))
+
+	alert := entity.Alert{
+		GUID:       alertGUID,
+		UserGUID:   userGUID,
+		SurveyGUID: surveyGUID,
+		Scale:      "depression",
+		ScaleName:  "Депрессия",
+		Score:      5,
+		Level:      "тяжелая",
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, userGUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, userGUID, surveyGUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, surveyGUID).Return(survey, nil)
+	suite.resultsProc.On("GetResults", survey, mock.Anything).Return(results, nil)
+	suite.dbRepo.On("GetSurveyNorms", ctx, tx, surveyGUID).Return(nil, nil)
+	suite.dbRepo.On("GetUserSurveyAttempts", ctx, tx, userGUID, surveyGUID).Return(nil, nil)
+	suite.dbRepo.On("CreateAlert", ctx, tx, alert).Return(nil)
+	suite.dbRepo.On("SetUserCurrentSurveyToNil", ctx, tx, userGUID).Return(nil)
+	suite.telegramRepo.On("SendResults", ctx, survey, mock.MatchedBy(func(r entity.Results) bool {
+		return r.Text == "results\n\n"+responses.AlertHelp
+	})).Return(nil)
+	suite.telegramRepo.On("SendAlert", ctx, int64(-100), alert, user, "Survey").Return(nil)
+	// help depends on the current alert rules, so it is not stored in the text
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, mock.MatchedBy(func(s entity.SurveyState) bool {
+		return s.Results.Text == "results"
+	})).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	err := svc.HandleAnswer(ctx, "5")
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestHandleAnswer_AlertNotSentIfNotCommitted() {
+	ctx := newTestContext(stdcontext.Background(), 10, 33, []string{"5"})
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+
+	service.UUIDProvider = func() uuid.UUID {
+		return uuid.MustParse("2F4F2F55-0B5C-4F47-9E0B-C7E1B5C2F4A6")
+	}
+
+	user := entity.User{GUID: userGUID, UserID: 10, ChatID: 33, Nickname: "nickname", CurrentSurvey: &surveyGUID}
+	survey := entity.Survey{
+		GUID:             surveyGUID,
+		ID:               1,
+		Name:             "Survey",
+		CalculationsType: "test_1",
+		Questions: []entity.Question{
+			{Text: "Question 1", AnswerType: entity.AnswerTypeSegment, PossibleAnswers: []int{1, 5}},
+		},
+	}
+	state := entity.SurveyState{State: entity.ActiveState, UserGUID: userGUID, SurveyGUID: surveyGUID}
+
+	svc := service.New(suite.telegramRepo, suite.dbRepo, suite.resultsProc, suite.logger, service.WithAlerts([]entity.AlertRule{
+		{CalculationsType: "test_1", Scale: "depression", Levels: []string{"тяжелая"}},
+	}, -100))
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, userGUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, userGUID, surveyGUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, surveyGUID).Return(survey, nil)
+	suite.resultsProc.On("GetResults", survey, mock.Anything).Return(entity.Results{
+		Text:   "results",
+		Scales: []entity.ResultsScale{{Key: "depression", Name: "Депрессия", Score: 5, Level: "тяжелая"}},
+	}, nil)
+	suite.dbRepo.On("GetSurveyNorms", ctx, tx, surveyGUID).Return(nil, nil)
+	suite.dbRepo.On("GetUserSurveyAttempts", ctx, tx, userGUID, surveyGUID).Return(nil, nil)
+	suite.dbRepo.On("CreateAlert", ctx, tx, mock.AnythingOfType("entity.Alert")).Return(nil)
+	suite.dbRepo.On("SetUserCurrentSurveyToNil", ctx, tx, userGUID).Return(nil)
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, mock.AnythingOfType("entity.SurveyState")).Return(nil)
+	tx.On("Commit").Return(errors.New("connection reset"))
+
+	// neither results nor the alert are sent, as they are not stored
+	err := svc.HandleAnswer(ctx, "5")
+	suite.Error(err)
+}
+
+func (suite *ServiceTestSuite) TestHandleAlertAcknowledge() {
+	ctx := newTestContext(stdcontext.Background(), 10, 33, []string{"alert_ack"})
+	alertGUID := uuid.MustParse("2F4F2F55-0B5C-4F47-9E0B-C7E1B5C2F4A6")
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("AcknowledgeAlert", ctx, tx, alertGUID, int64(10)).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	suite.logger.On("Infof", ctx, "alert %s acknowledged", alertGUID).Return()
+	suite.telegramRepo.On("SendMessage", ctx, responses.AlertAcknowledged).Return(nil)
+
+	err := suite.svc.HandleAlertAcknowledge(ctx, alertGUID)
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestHandleAlertAcknowledge_AlreadyAcknowledged() {
+	ctx := newTestContext(stdcontext.Background(), 10, 33, []string{"alert_ack"})
+	alertGUID := uuid.MustParse("2F4F2F55-0B5C-4F47-9E0B-C7E1B5C2F4A6")
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("AcknowledgeAlert", ctx, tx, alertGUID, int64(10)).Return(service.ErrNotFound)
+	tx.On("Rollback").Return(nil)
+
+	suite.telegramRepo.On("SendMessage", ctx, responses.AlertAlreadyAcknowledged).Return(nil)
+
+	err := suite.svc.HandleAlertAcknowledge(ctx, alertGUID)
+	suite.ErrorIs(err, service.ErrNotFound)
+}
+
+func (suite *ServiceTestSuite) TestGetSurveys() {
+	ctx := stdcontext.Background()
+	surveys := suite.generateTestSurveyList()
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurveysList", ctx, tx).Return([]entity.Survey{surveys[2], surveys[0], surveys[1]}, nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.GetSurveys(ctx)
+	suite.NoError(err)
+	suite.Equal(surveys, got)
+}
+
+func (suite *ServiceTestSuite) TestUpdateSurvey_DifferentNumberOfQuestions() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+
+	updated := survey
+	updated.Name = "Updated"
+	updated.Questions = survey.Questions[:1]
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.resultsProc.On("Validate", updated).Return(nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	tx.On("Rollback").Return(nil)
+
+	err := suite.svc.UpdateSurvey(ctx, updated)
+	suite.ErrorIs(err, service.ErrInvalidSurvey)
+}
+
+func (suite *ServiceTestSuite) TestDeleteSurvey() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("CountStatesOfSurvey", ctx, tx, survey.GUID, []entity.State{entity.ActiveState}).Return(0, nil)
+	suite.dbRepo.On("DeleteSurvey", ctx, tx, survey.GUID).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	err := suite.svc.DeleteSurvey(ctx, survey.GUID, false)
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestDeleteSurvey_InUse() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("CountStatesOfSurvey", ctx, tx, survey.GUID, []entity.State{entity.ActiveState}).Return(2, nil)
+	tx.On("Rollback").Return(nil)
+
+	err := suite.svc.DeleteSurvey(ctx, survey.GUID, false)
+	suite.ErrorIs(err, service.ErrSurveyInUse)
+}
+
+func (suite *ServiceTestSuite) TestDeleteSurvey_Force() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("DeleteSurvey", ctx, tx, survey.GUID).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	err := suite.svc.DeleteSurvey(ctx, survey.GUID, true)
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestGetSurveySummaries() {
+	ctx := stdcontext.Background()
+	surveys := suite.generateTestSurveyList()
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurveysList", ctx, tx).Return([]entity.Survey{surveys[1], surveys[0]}, nil)
+	suite.dbRepo.On("CountSurveyStates", ctx, tx, []entity.State{entity.ActiveState}).Return(map[uuid.UUID]int{surveys[0].GUID: 2}, nil)
+	suite.dbRepo.On("CountSurveyStates", ctx, tx, []entity.State{entity.FinishedState}).Return(map[uuid.UUID]int{surveys[0].GUID: 3, surveys[1].GUID: 1}, nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.GetSurveySummaries(ctx)
+	suite.NoError(err)
+	suite.Equal([]service.SurveySummary{
+		{Survey: surveys[0], Active: 2, Finished: 3},
+		{Survey: surveys[1], Active: 0, Finished: 1},
+	}, got)
+}
+
+func (suite *ServiceTestSuite) TestDeleteSurvey_NotFound() {
+	ctx := stdcontext.Background()
+	surveyGUID := uuid.MustParse("6E3B5A2E-4C1D-4F0B-9A47-2D8C1B7E5F30")
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, surveyGUID).Return(entity.Survey{}, service.ErrNotFound)
+	tx.On("Rollback").Return(nil)
+
+	err := suite.svc.DeleteSurvey(ctx, surveyGUID, false)
+	suite.ErrorIs(err, service.ErrNotFound)
+}
+
+func (suite *ServiceTestSuite) TestGetResults() {
+	ctx := stdcontext.Background()
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	f := service.ResultsFilter{SurveyGUID: &surveyGUID, Scale: "s1", Level: "high"}
+
+	results := []entity.SurveyStateReport{
+		{SurveyGUID: surveyGUID, UserGUID: userGUID.String(), FinishedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{SurveyGUID: surveyGUID, UserGUID: userGUID.String(), FinishedAt: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{SurveyGUID: surveyGUID, UserGUID: userGUID.String(), FinishedAt: time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetFinishedSurveysAfter", ctx, tx, f, (*service.ResultsCursor)(nil), 3).Return(results, nil).Once()
+	suite.dbRepo.On("GetFinishedSurveysAfter", ctx, tx, f, &service.ResultsCursor{
+		FinishedAt: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+		UserGUID:   userGUID,
+		SurveyGUID: surveyGUID,
+	}, 3).Return(results[2:], nil).Once()
+	tx.On("Commit").Return(nil)
+
+	page, err := suite.svc.GetResults(ctx, f, "", 2)
+	suite.NoError(err)
+	suite.Equal(results[:2], page.Results)
+	suite.NotEmpty(page.NextCursor)
+
+	page, err = suite.svc.GetResults(ctx, f, page.NextCursor, 2)
+	suite.NoError(err)
+	suite.Equal(results[2:], page.Results)
+	suite.Empty(page.NextCursor)
+}
+
+func (suite *ServiceTestSuite) TestGetResults_InvalidCursor() {
+	_, err := suite.svc.GetResults(stdcontext.Background(), service.ResultsFilter{}, "not a cursor", 10)
+	suite.ErrorIs(err, service.ErrInvalidCursor)
+}
+
+func (suite *ServiceTestSuite) TestGetUserDetails() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	deletedSurveyGUID := uuid.MustParse("6E3B5A2E-4C1D-4F0B-9A47-2D8C1B7E5F30")
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 33}
+
+	reports := []entity.SurveyStateReport{
+		{
+			SurveyGUID: survey.GUID,
+			State:      entity.ActiveState,
+			Answers: []entity.Answer{
+				{Type: entity.AnswerTypeSelect, Data: []int{2}},
+				{Type: entity.AnswerTypeSegment, Data: []int{4}},
+			},
+		},
+		{
+			SurveyGUID: deletedSurveyGUID,
+			State:      entity.FinishedState,
+			Answers: []entity.Answer{
+				{Type: entity.AnswerTypeMultiSelect, Data: []int{1, 3}},
+			},
+		},
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByGUID", ctx, tx, user.GUID).Return(user, nil)
+	suite.dbRepo.On("GetUserSurveyReports", ctx, tx, user.GUID).Return(reports, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, deletedSurveyGUID).Return(entity.Survey{}, service.ErrNotFound)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.GetUserDetails(ctx, user.GUID)
+	suite.NoError(err)
+	suite.Equal(service.UserDetails{
+		User: user,
+		Surveys: []service.UserSurveyDetails{
+			{
+				Report: reports[0],
+				Answers: []service.QuestionAnswer{
+					{Question: "Question 1", Answer: "variant 2"},
+					{Question: "Question 2", Answer: "4"},
+				},
+				Questions: 3,
+			},
+			{
+				Report: reports[1],
+				Answers: []service.QuestionAnswer{
+					{Answer: "1, 3"},
+				},
+			},
+		},
+	}, got)
+}
+
+func (suite *ServiceTestSuite) TestDeleteUser() {
+	ctx := stdcontext.Background()
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 33}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByGUID", ctx, tx, user.GUID).Return(user, nil)
+	suite.dbRepo.On("DeleteUser", ctx, tx, user.GUID).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	err := suite.svc.DeleteUser(ctx, user.GUID)
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestDeleteUser_NotFound() {
+	ctx := stdcontext.Background()
+	userGUID := uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C")
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByGUID", ctx, tx, userGUID).Return(entity.User{}, service.ErrNotFound)
+	tx.On("Rollback").Return(nil)
+
+	err := suite.svc.DeleteUser(ctx, userGUID)
+	suite.ErrorIs(err, service.ErrNotFound)
+}
+
+func (suite *ServiceTestSuite) TestFindUser() {
+	ctx := stdcontext.Background()
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 10, Nickname: "Ivan Ivanov (ivan)"}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByGUID", ctx, tx, user.GUID).Return(user, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("GetUsersByNickname", ctx, tx, "ivan").Return([]entity.User{user}, nil)
+	suite.dbRepo.On("GetUsersByNickname", ctx, tx, "Ivan Ivanov (ivan)").Return([]entity.User{user}, nil)
+	tx.On("Commit").Return(nil)
+
+	for _, query := range []string{user.GUID.String(), "10", "@ivan", "ivan", "Ivan Ivanov (ivan)"} {
+		got, err := suite.svc.FindUser(ctx, query)
+		suite.NoError(err)
+		suite.Equal(user, got)
+	}
+}
+
+func (suite *ServiceTestSuite) TestFindUser_ByNickname() {
+	ctx := stdcontext.Background()
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUsersByNickname", ctx, tx, "unknown").Return([]entity.User{
+		{GUID: uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214"), Nickname: "unknown"},
+		{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), Nickname: "unknown"},
+	}, nil)
+	suite.dbRepo.On("GetUsersByNickname", ctx, tx, "iva").Return([]entity.User{}, nil)
+	tx.On("Rollback").Return(nil)
+
+	_, err := suite.svc.FindUser(ctx, "@unknown")
+	suite.ErrorIs(err, service.ErrAmbiguousUser)
+
+	_, err = suite.svc.FindUser(ctx, "iva")
+	suite.ErrorIs(err, service.ErrNotFound)
+
+	_, err = suite.svc.FindUser(ctx, "@")
+	suite.ErrorIs(err, service.ErrNotFound)
+}
+
+func (suite *ServiceTestSuite) TestAnonymizeUser() {
+	ctx := stdcontext.Background()
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	user := entity.User{
+		GUID:          uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"),
+		UserID:        10,
+		ChatID:        33,
+		Nickname:      "ivan",
+		CurrentSurvey: &surveyGUID,
+		Cohort:        "spring",
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByGUID", ctx, tx, user.GUID).Return(user, nil)
+	suite.dbRepo.On("AnonymizeUser", ctx, tx, mock.MatchedBy(func(u entity.User) bool {
+		return u.GUID == user.GUID &&
+			u.UserID < 0 && u.ChatID == u.UserID &&
+			u.Nickname == "anonymous" &&
+			u.CurrentSurvey == nil &&
+			u.Cohort == "spring"
+	})).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	err := suite.svc.AnonymizeUser(ctx, user.GUID)
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestResetUserSurvey() {
+	ctx := stdcontext.Background()
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, CurrentSurvey: &surveyGUID}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByGUID", ctx, tx, user.GUID).Return(user, nil)
+	suite.dbRepo.On("DeleteUserSurveyStates", ctx, tx, user.GUID, surveyGUID, []entity.State{entity.ActiveState}).Return(1, nil)
+	suite.dbRepo.On("SetUserCurrentSurveyToNil", ctx, tx, user.GUID).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	deleted, err := suite.svc.ResetUserSurvey(ctx, user.GUID, surveyGUID, false)
+	suite.NoError(err)
+	suite.Equal(1, deleted)
+}
+
+func (suite *ServiceTestSuite) TestResetUserSurvey_NotFound() {
+	ctx := stdcontext.Background()
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByGUID", ctx, tx, user.GUID).Return(user, nil)
+	suite.dbRepo.On("DeleteUserSurveyStates", ctx, tx, user.GUID, surveyGUID, []entity.State{entity.ActiveState, entity.FinishedState}).Return(0, nil)
+	tx.On("Rollback").Return(nil)
+
+	_, err := suite.svc.ResetUserSurvey(ctx, user.GUID, surveyGUID, true)
+	suite.ErrorIs(err, service.ErrNotFound)
+}
+
+func (suite *ServiceTestSuite) TestStartSurvey_UserCreatedIfNotFound() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	userGUID := uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C")
+
+	service.UUIDProvider = func() uuid.UUID {
+		return userGUID
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(entity.User{}, service.ErrNotFound)
+	suite.dbRepo.On("CreateUser", ctx, tx, entity.User{GUID: userGUID, UserID: 10, ChatID: 10, Nickname: "nickname"}).Return(nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, userGUID, survey.GUID, []entity.State{entity.ActiveState}).Return(entity.SurveyState{}, service.ErrNotFound)
+	suite.dbRepo.On("CreateUserSurveyState", ctx, tx, entity.SurveyState{UserGUID: userGUID, SurveyGUID: survey.GUID, State: entity.ActiveState}).Return(nil)
+	suite.dbRepo.On("UpdateUserCurrentSurvey", ctx, tx, userGUID, survey.GUID).Return(nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, userGUID).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.StartSurvey(ctx, service.UserIdentity{UserID: 10, ChatID: 10, Nickname: "nickname"}, survey.GUID)
+	suite.NoError(err)
+	suite.Equal(entity.ActiveState, got.State.State)
+	suite.Equal(&survey.Questions[0], got.Question)
+}
+
+func (suite *ServiceTestSuite) TestAnswerSurvey_SurveyBecomesCurrent() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 10}
+	state := entity.SurveyState{
+		State:      entity.ActiveState,
+		UserGUID:   user.GUID,
+		SurveyGUID: survey.GUID,
+		Answers:    []entity.Answer{{Type: entity.AnswerTypeSelect, Data: []int{1}}},
+	}
+
+	updated := state
+	updated.Answers = []entity.Answer{
+		{Type: entity.AnswerTypeSelect, Data: []int{1}},
+		{Type: entity.AnswerTypeSegment, Data: []int{3}},
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, user.GUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("UpdateUserCurrentSurvey", ctx, tx, user.GUID, survey.GUID).Return(nil)
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, updated).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.AnswerSurvey(ctx, 10, survey.GUID, "3")
+	suite.NoError(err)
+	suite.Equal(updated, got.State)
+	suite.Equal(&survey.Questions[2], got.Question)
+}
+
+func (suite *ServiceTestSuite) TestAnswerSurvey_Finished() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 10, CurrentSurvey: &survey.GUID}
+	state := entity.SurveyState{
+		State:      entity.ActiveState,
+		UserGUID:   user.GUID,
+		SurveyGUID: survey.GUID,
+		Answers: []entity.Answer{
+			{Type: entity.AnswerTypeSelect, Data: []int{1}},
+			{Type: entity.AnswerTypeSegment, Data: []int{3}},
+		},
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, user.GUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.resultsProc.On("GetResults", survey, mock.Anything).Return(entity.Results{Text: "results"}, nil)
+	suite.dbRepo.On("SetUserCurrentSurveyToNil", ctx, tx, user.GUID).Return(nil)
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, mock.MatchedBy(func(s entity.SurveyState) bool {
+		return s.State == entity.FinishedState && len(s.Answers) == 3
+	})).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.AnswerSurvey(ctx, 10, survey.GUID, "4")
+	suite.NoError(err)
+	suite.Equal(entity.FinishedState, got.State.State)
+	suite.Nil(got.Question)
+	suite.Equal("results", got.State.Results.Text)
+}
+
+func (suite *ServiceTestSuite) TestAnswerSurvey_Alert() {
+	ctx := stdcontext.Background()
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	alertGUID := uuid.MustParse("2F4F2F55-0B5C-4F47-9E0B-C7E1B5C2F4A6")
+
+	service.UUIDProvider = func() uuid.UUID {
+		return alertGUID
+	}
+
+	user := entity.User{GUID: userGUID, UserID: 10, ChatID: 10, Nickname: "nickname"}
+	survey := entity.Survey{
+		GUID:             surveyGUID,
+		ID:               1,
+		Name:             "Survey",
+		CalculationsType: "test_1",
+		Questions: []entity.Question{
+			{Text: "Question 1", AnswerType: entity.AnswerTypeSegment, PossibleAnswers: []int{1, 5}},
+		},
+	}
+	state := entity.SurveyState{State: entity.ActiveState, UserGUID: userGUID, SurveyGUID: surveyGUID}
+	alert := entity.Alert{
+		GUID:       alertGUID,
+		UserGUID:   userGUID,
+		SurveyGUID: surveyGUID,
+		Scale:      "depression",
+		ScaleName:  "Депрессия",
+		Score:      5,
+		Level:      "тяжелая",
+	}
+
+	svc := service.New(suite.telegramRepo, suite.dbRepo, suite.resultsProc, suite.logger, service.WithAlerts([]entity.AlertRule{
+		{CalculationsType: "test_1", Scale: "depression", Levels: []string{"тяжелая"}},
+	}, -100))
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, userGUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, userGUID, surveyGUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, surveyGUID).Return(survey, nil)
+	suite.resultsProc.On("GetResults", survey, mock.Anything).Return(entity.Results{
+		Text:   "results",
+		Scales: []entity.ResultsScale{{Key: "depression", Name: "Депрессия", Score: 5, Level: "тяжелая"}},
+	}, nil)
+	suite.dbRepo.On("GetSurveyNorms", ctx, tx, surveyGUID).Return(nil, nil)
+	suite.dbRepo.On("GetUserSurveyAttempts", ctx, tx, userGUID, surveyGUID).Return(nil, nil)
+	suite.dbRepo.On("CreateAlert", ctx, tx, alert).Return(nil)
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, mock.AnythingOfType("entity.SurveyState")).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	// admins are notified about results finished in the mini app as well as in the bot
+	suite.telegramRepo.On("SendAlert", ctx, int64(-100), alert, user, "Survey").Return(nil).Once()
+
+	got, err := svc.AnswerSurvey(ctx, 10, surveyGUID, "5")
+	suite.NoError(err)
+	suite.Equal(entity.FinishedState, got.State.State)
+}
+
+func (suite *ServiceTestSuite) TestAnswerSurvey_NormsTextNotStored() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 10, CurrentSurvey: &survey.GUID}
+	state := entity.SurveyState{
+		State:      entity.ActiveState,
+		UserGUID:   user.GUID,
+		SurveyGUID: survey.GUID,
+		Answers: []entity.Answer{
+			{Type: entity.AnswerTypeSelect, Data: []int{1}},
+			{Type: entity.AnswerTypeSegment, Data: []int{3}},
+		},
+	}
+	norm := entity.NewPopulationNorm(survey.GUID, "s", "", []entity.NormPoint{{Score: 1, Count: 20}, {Score: 9, Count: 20}})
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, user.GUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.resultsProc.On("GetResults", survey, mock.Anything).Return(entity.Results{
+		Text:   "results",
+		Scales: []entity.ResultsScale{{Key: "s", Name: "Scale", Score: 5}},
+	}, nil)
+	suite.dbRepo.On("GetSurveyNorms", ctx, tx, survey.GUID).Return([]entity.Norm{norm}, nil)
+	suite.dbRepo.On("GetUserSurveyAttempts", ctx, tx, user.GUID, survey.GUID).Return(nil, nil)
+	suite.dbRepo.On("SetUserCurrentSurveyToNil", ctx, tx, user.GUID).Return(nil)
+	// ranks depend on the current norms, so they are not stored in the text
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, mock.MatchedBy(func(s entity.SurveyState) bool {
+		return s.State == entity.FinishedState && s.Results.Text == "results"
+	})).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.AnswerSurvey(ctx, 10, survey.GUID, "4")
+	suite.NoError(err)
+	suite.Equal("results\n\n"+responses.NormsTitle+"\nScale: выше, чем у 50% респондентов", got.State.Results.Text)
+}
+
+func (suite *ServiceTestSuite) TestAnswerSurvey_ResultsTextOrder() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 10, CurrentSurvey: &survey.GUID}
+	state := entity.SurveyState{
+		State:      entity.ActiveState,
+		UserGUID:   user.GUID,
+		SurveyGUID: survey.GUID,
+		Answers: []entity.Answer{
+			{Type: entity.AnswerTypeSelect, Data: []int{1}},
+			{Type: entity.AnswerTypeSegment, Data: []int{3}},
+		},
+	}
+	norm := entity.NewPopulationNorm(survey.GUID, "s", "", []entity.NormPoint{{Score: 1, Count: 20}, {Score: 9, Count: 20}})
+
+	service.UUIDProvider = func() uuid.UUID {
+		return uuid.MustParse("2F4F2F55-0B5C-4F47-9E0B-C7E1B5C2F4A6")
+	}
+
+	svc := service.New(suite.telegramRepo, suite.dbRepo, suite.resultsProc, suite.logger, service.WithAlerts([]entity.AlertRule{
+		{CalculationsType: survey.CalculationsType, Scale: "s", Levels: []string{"тяжелая"}, Help: "help"},
+	}, 0))
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, user.GUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.resultsProc.On("GetResults", survey, mock.Anything).Return(entity.Results{
+		Text:   "results",
+		Scales: []entity.ResultsScale{{Key: "s", Name: "Scale", Score: 5, Level: "тяжелая"}},
+	}, nil)
+	suite.dbRepo.On("GetSurveyNorms", ctx, tx, survey.GUID).Return([]entity.Norm{norm}, nil)
+	suite.dbRepo.On("GetUserSurveyAttempts", ctx, tx, user.GUID, survey.GUID).Return([]entity.SurveyStateReport{
+		{SurveyGUID: survey.GUID, Results: &entity.Results{Scales: []entity.ResultsScale{{Key: "s", Name: "Scale", Score: 3}}}},
+	}, nil)
+	suite.dbRepo.On("CreateAlert", ctx, tx, mock.AnythingOfType("entity.Alert")).Return(nil)
+	suite.dbRepo.On("SetUserCurrentSurveyToNil", ctx, tx, user.GUID).Return(nil)
+	// only the structured changes are stored
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, mock.MatchedBy(func(s entity.SurveyState) bool {
+		return s.Results.Text == "results" && len(s.Results.Changes) == 1
+	})).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := svc.AnswerSurvey(ctx, 10, survey.GUID, "4")
+	suite.NoError(err)
+
+	// help for the high-risk result is the last in the message
+	suite.Equal(
+		"results"+
+			"\n\n"+responses.NormsTitle+"\nScale: выше, чем у 50% респондентов"+
+			"\n\n"+responses.ChangesTitle+"\n"+fmt.Sprintf(responses.ChangesScale, "Scale", "3", "5", responses.TrendUp)+
+			"\n\nhelp",
+		got.State.Results.Text,
+	)
+}
+
+func (suite *ServiceTestSuite) TestAnswerSurvey_InvalidAnswer() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 10, CurrentSurvey: &survey.GUID}
+	state := entity.SurveyState{State: entity.ActiveState, UserGUID: user.GUID, SurveyGUID: survey.GUID}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, user.GUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	tx.On("Rollback").Return(nil)
+
+	_, err := suite.svc.AnswerSurvey(ctx, 10, survey.GUID, "7")
+	suite.ErrorIs(err, service.ErrInvalidAnswer)
+	suite.Equal(responses.AnswerNotFound, service.AnswerErrorText(err))
+}
+
+func (suite *ServiceTestSuite) TestGetSurveyAnalytics() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	median := 20 * time.Minute
+
+	stats := service.SurveyStatesStats{
+		Started:        4,
+		Finished:       1,
+		Abandoned:      1,
+		MedianDuration: &median,
+		Funnel:         []service.FunnelStep{{Answered: 2, Count: 3}},
+		Answers: []service.AnswerCount{
+			{Question: 0, Value: 2, Count: 4},
+			{Question: 1, Value: 3, Count: 2},
+			{Question: 1, Value: 7, Count: 1},
+			{Question: 5, Value: 1, Count: 1},
+		},
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("GetSurveyStatesStats", ctx, tx, survey.GUID, 24*time.Hour).Return(stats, nil)
+	tx.On("Commit").Return(nil)
+
+	got, err := suite.svc.GetSurveyAnalytics(ctx, survey.GUID, 24*time.Hour)
+	suite.NoError(err)
+	suite.Equal(0.25, got.CompletionRate)
+	suite.Equal([]service.FunnelStep{{Answered: 0}, {Answered: 1}, {Answered: 2, Count: 3}}, got.Funnel)
+	suite.Len(got.Questions, 3)
+	suite.Equal([]service.AnswerStats{
+		{Value: 1, Text: "variant 1"},
+		{Value: 2, Text: "variant 2", Count: 4},
+		{Value: 3, Text: "variant 3"},
+		{Value: 4, Text: "variant 4"},
+	}, got.Questions[0].Answers)
+	suite.Equal([]service.AnswerStats{
+		{Value: 1, Text: "1"},
+		{Value: 2, Text: "2"},
+		{Value: 3, Text: "3", Count: 2},
+		{Value: 4, Text: "4"},
+		{Value: 5, Text: "5"},
+		{Value: 7, Text: "7", Count: 1},
+	}, got.Questions[1].Answers)
+}
+
+func (suite *ServiceTestSuite) TestCreateAndAuthenticateAPIKey() {
+	ctx := stdcontext.Background()
+	keyGUID := uuid.MustParse("3C1B9B6E-6B7A-4A0E-9C57-4E2F1D8A7B10")
+
+	service.UUIDProvider = func() uuid.UUID {
+		return keyGUID
+	}
+
+	key := entity.APIKey{
+		GUID:   keyGUID,
+		Name:   "bi",
+		Scopes: []entity.APIKeyScope{entity.APIKeyScopeResultsRead},
+	}
+
+	var keyHash string
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("CreateAPIKey", ctx, tx, key, mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		keyHash = args.String(3)
+	}).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	got, secret, err := suite.svc.CreateAPIKey(ctx, "bi", []entity.APIKeyScope{entity.APIKeyScopeResultsRead})
+	suite.NoError(err)
+	suite.Equal(key, got)
+	suite.True(strings.HasPrefix(secret, "sbk_"))
+	suite.NotEqual(secret, keyHash)
+	suite.Len(keyHash, 64)
+
+	suite.dbRepo.On("GetAPIKeyByHash", ctx, tx, keyHash).Return(key, nil)
+	suite.dbRepo.On("UpdateAPIKeyLastUsed", ctx, tx, keyGUID).Return(nil)
+	suite.logger.On("Infof", ctx, "api key %s (%s) is used", keyGUID, "bi").Return()
+
+	got, err = suite.svc.AuthenticateAPIKey(ctx, secret)
+	suite.NoError(err)
+	suite.Equal(key, got)
+}
+
+func (suite *ServiceTestSuite) TestAuthenticateAPIKey_NotFound() {
+	ctx := stdcontext.Background()
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetAPIKeyByHash", ctx, tx, mock.AnythingOfType("string")).Return(entity.APIKey{}, service.ErrNotFound)
+	tx.On("Rollback").Return(nil)
+
+	_, err := suite.svc.AuthenticateAPIKey(ctx, "sbk_unknown")
+	suite.ErrorIs(err, service.ErrNotFound)
+}
+
+func (suite *ServiceTestSuite) TestAnswerSurvey_AnswerEventsThrottled() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 10, Nickname: "nickname", CurrentSurvey: &survey.GUID}
+	state := entity.SurveyState{
+		State:      entity.ActiveState,
+		UserGUID:   user.GUID,
+		SurveyGUID: survey.GUID,
+		Answers:    []entity.Answer{{Type: entity.AnswerTypeSelect, Data: []int{1}}},
+	}
+
+	svc := service.New(suite.telegramRepo, suite.dbRepo, suite.resultsProc, suite.logger, service.WithEvents(service.NewEventBus(), time.Hour))
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, user.GUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, mock.Anything).Return(nil)
+	suite.dbRepo.On("NotifyEvent", ctx, tx, mock.MatchedBy(func(e entity.Event) bool {
+		return e.Type == entity.EventSurveyAnswered &&
+			e.UserGUID == user.GUID &&
+			e.Nickname == "nickname" &&
+			*e.SurveyGUID == survey.GUID &&
+			e.Answered == 2 &&
+			e.Questions == len(survey.Questions)
+	})).Return(nil).Once()
+	tx.On("Commit").Return(nil)
+
+	// the second answer within the throttle interval is not published
+	for i := 0; i < 2; i++ {
+		_, err := svc.AnswerSurvey(ctx, 10, survey.GUID, "3")
+		suite.NoError(err)
+	}
+}
+
+func (suite *ServiceTestSuite) TestAnswerSurvey_AnswerEventsNotThrottledByRollback() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	user := entity.User{GUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C"), UserID: 10, ChatID: 10, Nickname: "nickname", CurrentSurvey: &survey.GUID}
+	state := entity.SurveyState{
+		State:      entity.ActiveState,
+		UserGUID:   user.GUID,
+		SurveyGUID: survey.GUID,
+		Answers:    []entity.Answer{{Type: entity.AnswerTypeSelect, Data: []int{1}}},
+	}
+
+	svc := service.New(suite.telegramRepo, suite.dbRepo, suite.resultsProc, suite.logger, service.WithEvents(service.NewEventBus(), time.Hour))
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(user, nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, user.GUID).Return(nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, user.GUID, survey.GUID, []entity.State{entity.ActiveState}).Return(state, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("UpdateActiveUserSurveyState", ctx, tx, mock.Anything).Return(nil)
+	suite.dbRepo.On("NotifyEvent", ctx, tx, mock.AnythingOfType("entity.Event")).Return(nil).Twice()
+	tx.On("Commit").Return(errors.New("connection reset")).Once()
+	tx.On("Commit").Return(nil).Once()
+
+	// the event of the answer which is not committed doesn't suppress the next one
+	_, err := svc.AnswerSurvey(ctx, 10, survey.GUID, "3")
+	suite.Error(err)
+
+	_, err = svc.AnswerSurvey(ctx, 10, survey.GUID, "3")
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestStartSurvey_Events() {
+	ctx := stdcontext.Background()
+	survey := suite.generateTestSurveyList()[0]
+	userGUID := uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C")
+
+	service.UUIDProvider = func() uuid.UUID {
+		return userGUID
+	}
+
+	svc := service.New(suite.telegramRepo, suite.dbRepo, suite.resultsProc, suite.logger, service.WithEvents(service.NewEventBus(), time.Hour))
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetUserByID", ctx, tx, int64(10)).Return(entity.User{}, service.ErrNotFound)
+	suite.dbRepo.On("CreateUser", ctx, tx, mock.Anything).Return(nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, survey.GUID).Return(survey, nil)
+	suite.dbRepo.On("GetUserSurveyState", ctx, tx, userGUID, survey.GUID, []entity.State{entity.ActiveState}).Return(entity.SurveyState{}, service.ErrNotFound)
+	suite.dbRepo.On("CreateUserSurveyState", ctx, tx, mock.Anything).Return(nil)
+	suite.dbRepo.On("UpdateUserCurrentSurvey", ctx, tx, userGUID, survey.GUID).Return(nil)
+	suite.dbRepo.On("UpdateUserLastActivity", ctx, tx, userGUID).Return(nil)
+	suite.dbRepo.On("NotifyEvent", ctx, tx, mock.MatchedBy(func(e entity.Event) bool {
+		return e.Type == entity.EventUserRegistered && e.UserGUID == userGUID && e.SurveyGUID == nil && !e.CreatedAt.IsZero()
+	})).Return(nil).Once()
+	suite.dbRepo.On("NotifyEvent", ctx, tx, mock.MatchedBy(func(e entity.Event) bool {
+		return e.Type == entity.EventSurveyStarted && e.UserGUID == userGUID && *e.SurveyGUID == survey.GUID && e.SurveyName == survey.Name
+	})).Return(nil).Once()
+	tx.On("Commit").Return(nil)
+
+	_, err := svc.StartSurvey(ctx, service.UserIdentity{UserID: 10, ChatID: 10, Nickname: "nickname"}, survey.GUID)
+	suite.NoError(err)
+}
+
+func (suite *ServiceTestSuite) TestEventBus() {
+	bus := service.NewEventBus()
+	svc := service.New(suite.telegramRepo, suite.dbRepo, suite.resultsProc, suite.logger, service.WithEvents(bus, time.Hour))
+
+	events, unsubscribe := svc.SubscribeEvents(stdcontext.Background())
+
+	event := entity.Event{Type: entity.EventUserRegistered, UserGUID: uuid.MustParse("AE2B602C-F255-47E5-B8C6-ABCD1F3D2A4C")}
+	bus.Publish(event)
+	suite.Equal(event, <-events)
+
+	unsubscribe()
+	_, ok := <-events
+	suite.False(ok)
+
+	// publishing to the unsubscribed channel doesn't panic
+	bus.Publish(event)
+
+	// events are not enabled
+	events, _ = suite.svc.SubscribeEvents(stdcontext.Background())
+	suite.Nil(events)
+}
+
+func (suite *ServiceTestSuite) TestWriteSurveyFile() {
+	files, err := filepath.Glob("../../surveytests/*.json")
+	suite.NoError(err)
+	suite.NotEmpty(files)
+
+	// exported surveys are the same as the files they are created from
+	for _, file := range files {
+		survey, err := service.ReadSurveyFromFile(file)
+		suite.NoError(err)
+
+		var buf bytes.Buffer
+		suite.NoError(service.WriteSurveyFile(&buf, survey))
+
+		expected, err := os.ReadFile(file)
+		suite.NoError(err)
+		suite.JSONEq(string(expected), buf.String(), file)
+	}
+}
+
+func (suite *ServiceTestSuite) TestCheckSurveyFile() {
+	data := []byte(`{
+    "name": "Survey",
+    "calculations_type": "test_1",
+    "questions": [
+        {
+            "text": "Question?",
+            "answer_type": "select",
+            "possible_answers": [1, 2],
+            "answers_text": ["Yes ", "No"],
+            "answer_txt": []
+        }
+    ]
+}
+`)
+
+	suite.resultsProc.On("ValidationErrors", mock.Anything).Return([]entity.ValidationError{
+		{Path: "description", Message: "empty survey description"},
+		{Path: "questions[0].possible_answers[1]", Message: "possible answer is out of range"},
+	})
+
+	suite.Equal([]service.SurveyFileError{
+		// missing fields are reported at the line of the parent
+		{ValidationError: entity.ValidationError{Path: "description", Message: "empty survey description"}, Line: 1},
+		{ValidationError: entity.ValidationError{Path: "questions[0].possible_answers[1]", Message: "possible answer is out of range"}, Line: 8},
+		{ValidationError: entity.ValidationError{Path: "questions[0].answers_text[0]", Message: "leading or trailing whitespace", Lint: true}, Line: 9},
+		{ValidationError: entity.ValidationError{Path: "questions[0].answer_txt", Message: "unknown field"}, Line: 10},
+	}, service.CheckSurveyFile(data, suite.resultsProc))
+}
+
+func (suite *ServiceTestSuite) TestCheckSurveyFile_InvalidJSON() {
+	suite.Equal([]service.SurveyFileError{
+		{ValidationError: entity.ValidationError{Message: "invalid JSON: invalid character '}' looking for beginning of object key string"}, Line: 3},
+	}, service.CheckSurveyFile([]byte("{\n    \"name\": \"Survey\",\n}"), suite.resultsProc))
+
+	suite.Equal([]service.SurveyFileError{
+		{ValidationError: entity.ValidationError{Path: "questions[0].possible_answers[0]", Message: "expected int, got string"}, Line: 2},
+	}, service.CheckSurveyFile([]byte("{\"questions\": [\n{\"possible_answers\": [\"1\"]}]}"), suite.resultsProc))
+}
+
+func (suite *ServiceTestSuite) generateSyncSurveys() ([]entity.Survey, []entity.Survey) {
+	stored := suite.generateTestSurveyList()
+	stored[0].Slug, stored[0].Name = "first", "First"
+	stored[1].Name = "Second"
+	stored[2].Slug, stored[2].Name = "third", "Third"
+
+	first := stored[0]
+	first.Questions = slices.Clone(first.Questions)
+	first.Questions[0].Text = "Question 1 changed"
+
+	// created before slugs, it is matched by name
+	second := stored[1]
+	second.Slug = "second"
+
+	created := stored[0]
+	created.GUID, created.ID, created.Slug, created.Name = uuid.Nil, 0, "new", "New"
+
+	return stored, []entity.Survey{second, created, first}
+}
+
+func (suite *ServiceTestSuite) TestPlanSurveySync() {
+	ctx := stdcontext.Background()
+	stored, files := suite.generateSyncSurveys()
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.resultsProc.On("Validate", mock.Anything).Return(nil)
+	suite.dbRepo.On("GetSurveysList", ctx, tx).Return(slices.Clone(stored), nil)
+	suite.dbRepo.On("CountSurveyStates", ctx, tx, []entity.State{entity.ActiveState}).Return(map[uuid.UUID]int{stored[0].GUID: 2}, nil)
+	tx.On("Commit").Return(nil)
+
+	plan, err := suite.svc.PlanSurveySync(ctx, files)
+	suite.NoError(err)
+	suite.Equal(service.SurveySyncPlan{
+		// only texts are changed, so active states are not broken
+		{Action: service.SurveySyncUpdate, Slug: "first", Survey: files[2], Current: &stored[0], Changes: []string{"questions[0]"}, Active: 2},
+		{Action: service.SurveySyncCreate, Slug: "new", Survey: files[1]},
+		{Action: service.SurveySyncUpdate, Slug: "second", Survey: files[0], Current: &stored[1], Adopted: true, Changes: []string{"slug"}},
+		{Action: service.SurveySyncOrphaned, Slug: "third", Survey: stored[2], Current: &stored[2]},
+	}, plan)
+}
+
+func (suite *ServiceTestSuite) TestPlanSurveySync_Refused() {
+	ctx := stdcontext.Background()
+	stored := suite.generateTestSurveyList()[:1]
+	stored[0].Slug = "first"
+
+	answers := stored[0]
+	answers.Questions = slices.Clone(answers.Questions)
+	answers.Questions[0].PossibleAnswers = []int{1, 2, 3}
+	answers.Questions[0].AnswersText = []string{"variant 1", "variant 2", "variant 3"}
+
+	questions := stored[0]
+	questions.Questions = questions.Questions[:2]
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.resultsProc.On("Validate", mock.Anything).Return(nil)
+	suite.dbRepo.On("GetSurveysList", ctx, tx).Return(stored, nil)
+	suite.dbRepo.On("CountSurveyStates", ctx, tx, []entity.State{entity.ActiveState}).Return(map[uuid.UUID]int{stored[0].GUID: 1}, nil)
+	tx.On("Commit").Return(nil)
+
+	plan, err := suite.svc.PlanSurveySync(ctx, []entity.Survey{answers})
+	suite.NoError(err)
+	suite.Len(plan, 1)
+	suite.ErrorIs(plan[0].Refused, service.ErrSurveyInUse)
+
+	plan, err = suite.svc.PlanSurveySync(ctx, []entity.Survey{questions})
+	suite.NoError(err)
+	suite.Len(plan, 1)
+	suite.ErrorIs(plan[0].Refused, service.ErrInvalidSurvey)
+
+	// the survey without slug can't be synced
+	tx.On("Rollback").Return(nil)
+	questions.Slug = ""
+	_, err = suite.svc.PlanSurveySync(ctx, []entity.Survey{questions})
+	suite.ErrorIs(err, service.ErrInvalidSurvey)
+}
+
+func (suite *ServiceTestSuite) TestSyncSurveys() {
+	ctx := stdcontext.Background()
+	stored, files := suite.generateSyncSurveys()
+
+	service.UUIDProvider = func() uuid.UUID {
+		return uuid.MustParse("2F8A6B42-3C43-4C4B-9C45-1D6C0B7E2D11")
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.resultsProc.On("Validate", mock.Anything).Return(nil)
+	suite.dbRepo.On("GetSurveysList", ctx, tx).Return(func(stdcontext.Context, service.DBTransaction) []entity.Survey {
+		return slices.Clone(stored)
+	}, nil)
+	suite.dbRepo.On("CountSurveyStates", ctx, tx, []entity.State{entity.ActiveState}).Return(map[uuid.UUID]int{}, nil)
+
+	created := files[1]
+	created.GUID = uuid.MustParse("2F8A6B42-3C43-4C4B-9C45-1D6C0B7E2D11")
+	created.ID = 4
+	suite.dbRepo.On("CreateSurvey", ctx, tx, created).Return(nil)
+	suite.dbRepo.On("UpdateSurvey", ctx, tx, files[2]).Return(nil)
+	suite.dbRepo.On("UpdateSurvey", ctx, tx, files[0]).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	plan, err := suite.svc.PlanSurveySync(ctx, files)
+	suite.NoError(err)
+	suite.NoError(suite.svc.SyncSurveys(ctx, plan))
+}
+
+func (suite *ServiceTestSuite) TestSyncSurveys_Outdated() {
+	ctx := stdcontext.Background()
+	stored, files := suite.generateSyncSurveys()
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.resultsProc.On("Validate", mock.Anything).Return(nil)
+	suite.dbRepo.On("GetSurveysList", ctx, tx).Return(slices.Clone(stored), nil).Once()
+	suite.dbRepo.On("CountSurveyStates", ctx, tx, []entity.State{entity.ActiveState}).Return(map[uuid.UUID]int{}, nil)
+	tx.On("Commit").Return(nil)
+
+	plan, err := suite.svc.PlanSurveySync(ctx, files)
+	suite.NoError(err)
+
+	// the first survey is deleted after the plan is made
+	suite.dbRepo.On("GetSurveysList", ctx, tx).Return(slices.Clone(stored[1:]), nil).Once()
+	tx.On("Rollback").Return(nil)
+
+	suite.ErrorIs(suite.svc.SyncSurveys(ctx, plan), service.ErrSyncPlanOutdated)
+}
+
+func (suite *ServiceTestSuite) TestDiffSurveys() {
+	old := entity.Survey{
+		Name:             "Survey",
+		CalculationsType: "test_1",
+		Questions: []entity.Question{
+			{
+				Text:            "Question 1?",
+				AnswerType:      entity.AnswerTypeSelect,
+				PossibleAnswers: []int{1, 2, 3},
+				AnswersText:     []string{"Never", "Sometimes", "Often"},
+			},
+			{
+				Text:            "Question 2?",
+				AnswerType:      entity.AnswerTypeSelect,
+				PossibleAnswers: []int{1, 2},
+				AnswersText:     []string{"Yes", "No"},
+			},
+			{
+				Text:            "Question 3?",
+				AnswerType:      entity.AnswerTypeSegment,
+				PossibleAnswers: []int{1, 5},
+			},
+		},
+	}
+
+	suite.Empty(service.DiffSurveys(old, old))
+
+	new := old
+	new.Name = "New survey"
+	new.Questions = []entity.Question{
+		{
+			// re-numbered possible answers
+			Text:            "Question 1?",
+			AnswerType:      entity.AnswerTypeSelect,
+			PossibleAnswers: []int{1, 2, 3},
+			AnswersText:     []string{"Often", "Sometimes", "Never"},
+		},
+		{
+			Text:            "Question 2 changed?",
+			AnswerType:      entity.AnswerTypeSelect,
+			PossibleAnswers: []int{1, 3},
+			AnswersText:     []string{"Yes!", "Maybe"},
+		},
+		{
+			Text:            "Question 3?",
+			AnswerType:      entity.AnswerTypeSegment,
+			PossibleAnswers: []int{1, 10},
+		},
+	}
+
+	suite.Equal([]service.SurveyChange{
+		{Question: -1, Field: "name", Old: "Survey", New: "New survey"},
+		{Question: 0, Field: "answer 1", Old: "Never", New: "Often", Breaking: `stored answers 1 meant "Never", it was the label of answer 3`},
+		{Question: 0, Field: "answer 3", Old: "Often", New: "Never", Breaking: `stored answers 3 meant "Often", it was the label of answer 1`},
+		{Question: 1, Field: "text", Old: "Question 2?", New: "Question 2 changed?"},
+		{Question: 1, Field: "possible_answers", Old: "[1, 2]", New: "[1, 3]"},
+		{Question: 1, Field: "answer 1", Old: "Yes", New: "Yes!"},
+		{Question: 1, Field: "answer 2", Old: "No", Breaking: "stored answers with it are not possible anymore"},
+		{Question: 1, Field: "answer 3", New: "Maybe"},
+		{Question: 2, Field: "possible_answers", Old: "[1, 5]", New: "[1, 10]", Breaking: "stored answers are from another range"},
+	}, service.DiffSurveys(old, new))
+
+	new = old
+	new.CalculationsType = "test_2"
+	new.Questions = []entity.Question{old.Questions[0], old.Questions[1]}
+	new.Questions[0].AnswerType = entity.AnswerTypeMultiSelect
+
+	suite.Equal([]service.SurveyChange{
+		{Question: -1, Field: "calculations_type", Old: "test_1", New: "test_2", Breaking: "stored answers are calculated differently"},
+		{Question: 0, Field: "answer_type", Old: "select", New: "multiselect", Breaking: "stored answers are select answers"},
+		{Question: 2, Field: "question", Old: "Question 3?", Breaking: "stored answers of the question are lost"},
+	}, service.DiffSurveys(old, new))
+
+	suite.Equal(`answer 2: "No" -> none`, service.SurveyChange{Field: "answer 2", Old: "No"}.String())
+	suite.Equal(`possible_answers: [1, 2] -> [1, 3]`, service.SurveyChange{Field: "possible_answers", Old: "[1, 2]", New: "[1, 3]"}.String())
+}
+
+func (suite *ServiceTestSuite) TestDiffSurvey() {
+	ctx := stdcontext.Background()
+	old := suite.generateTestSurveyList()[0]
+
+	new := old
+	new.Questions = slices.Clone(old.Questions)
+	new.Questions[1].PossibleAnswers = []int{1, 10}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetSurvey", ctx, tx, old.GUID).Return(old, nil)
+	suite.resultsProc.On("Validate", new).Return(nil)
+	tx.On("Commit").Return(nil)
+
+	diff, err := suite.svc.DiffSurvey(ctx, new)
+	suite.NoError(err)
+	suite.NoError(diff.Refused)
+	suite.Equal([]service.SurveyChange{
+		{Question: 1, Field: "possible_answers", Old: "[1, 5]", New: "[1, 10]", Breaking: "stored answers are from another range"},
+	}, diff.Breaking())
+
+	// the number of questions can't be changed by the update
+	new.Questions = new.Questions[:2]
+	suite.resultsProc.On("Validate", new).Return(nil)
+
+	diff, err = suite.svc.DiffSurvey(ctx, new)
+	suite.NoError(err)
+	suite.ErrorIs(diff.Refused, service.ErrInvalidSurvey)
+	suite.Len(diff.Changes, 2)
+}
+
+func (suite *ServiceTestSuite) TestExportResults_JSONL() {
+	ctx := stdcontext.Background()
+	results := suite.generateResultsReports()
+	f := service.ResultsFilter{SurveyGUID: &results[0].SurveyGUID}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginSnapshotTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetFinishedSurveys", ctx, tx, f, 100, 0).Return(results, nil)
+	suite.dbRepo.On("GetFinishedSurveys", ctx, tx, f, 100, 100).Return(nil, nil)
+	tx.On("Commit").Return(nil)
+
+	var progress []int
+	var buf bytes.Buffer
+	total, err := suite.svc.ExportResults(ctx, &buf, service.ResultsExport{
+		Filter:   f,
+		Format:   service.ResultsFormatJSONL,
+		Progress: func(exported int) { progress = append(progress, exported) },
+	})
+	suite.NoError(err)
+	suite.Equal(2, total)
+	suite.Equal([]int{2}, progress)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	suite.Len(lines, 2)
+	suite.JSONEq(`{
+		"survey_guid": "91def2ea-829d-443e-bcbf-fa2ef8283214",
+		"survey_name": "Survey 1",
+		"description": "",
+		"user_guid": "eddf980a-73e9-458b-926d-13b79bc2e947",
+		"user_id": 1,
+		"text": "good",
+		"metadata": {"s1": 10},
+		"started_at": "2021-01-01T00:00:00Z",
+		"finished_at": "2021-01-02T00:00:00Z",
+		"answers": [{"type": "select", "data": [1]}, {"type": "multiselect", "data": [1, 2]}]
+	}`, lines[0])
+}
+
+func (suite *ServiceTestSuite) TestExportResults_CSV() {
+	ctx := stdcontext.Background()
+	results := suite.generateResultsReports()
+	results[0].Description = "Description 1"
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginSnapshotTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetFinishedSurveys", ctx, tx, service.ResultsFilter{}, 100, 0).Return(results, nil)
+	suite.dbRepo.On("GetFinishedSurveys", ctx, tx, service.ResultsFilter{}, 100, 100).Return(nil, nil)
+	tx.On("Commit").Return(nil)
+
+	var buf bytes.Buffer
+	total, err := suite.svc.ExportResults(ctx, &buf, service.ResultsExport{Format: service.ResultsFormatCSV})
+	suite.NoError(err)
+	suite.Equal(2, total)
+
+	reader := csv.NewReader(&buf)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	suite.NoError(err)
+	suite.Len(rows, 3)
+
+	// every column of the header names the same column of the rows, answers take the last one and the rest
+	header := rows[0]
+	for i, row := range rows[1:] {
+		suite.Len(row, len(header)-1+len(results[i].Answers))
+
+		columns := make(map[string]string)
+		for j, name := range header {
+			columns[name] = row[j]
+		}
+		suite.Equal(results[i].Description, columns["description"])
+		suite.Equal("1", columns["user_id"])
+		suite.Equal(results[i].Results.Text, columns["text"])
+	}
+}
+
+func (suite *ServiceTestSuite) TestExportResults_XLSX() {
+	ctx := stdcontext.Background()
+	results := suite.generateResultsReports()
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginSnapshotTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetFinishedSurveys", ctx, tx, service.ResultsFilter{}, 100, 0).Return(results, nil)
+	suite.dbRepo.On("GetFinishedSurveys", ctx, tx, service.ResultsFilter{}, 100, 100).Return(nil, nil)
+	tx.On("Commit").Return(nil)
+
+	var buf bytes.Buffer
+	total, err := suite.svc.ExportResults(ctx, &buf, service.ResultsExport{Format: service.ResultsFormatXLSX})
+	suite.NoError(err)
+	suite.Equal(2, total)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	suite.NoError(err)
+
+	var names []string
+	var sheet []byte
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			r, err := file.Open()
+			suite.NoError(err)
+			sheet, err = io.ReadAll(r)
+			suite.NoError(err)
+		}
+	}
+	suite.ElementsMatch([]string{
+		"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml",
+	}, names)
+
+	// the sheet is well-formed XML
+	suite.NoError(xml.Unmarshal(sheet, new(struct{})))
+	suite.Contains(string(sheet), `<c r="A1" t="inlineStr"><is><t xml:space="preserve">survey_guid</t></is></c>`)
+	suite.Contains(string(sheet), `<c r="E2"><v>1</v></c>`)
+	suite.Contains(string(sheet), `<c r="J2"><v>1</v></c><c r="K2" t="inlineStr"><is><t xml:space="preserve">1 2</t></is></c>`)
+	suite.Contains(string(sheet), `<t xml:space="preserve">&lt;b&gt;bad&lt;/b&gt; &amp; worse</t>`)
+}
+
+func (suite *ServiceTestSuite) TestExportResults_UnknownFormat() {
+	_, err := suite.svc.ExportResults(stdcontext.Background(), io.Discard, service.ResultsExport{Format: "pdf"})
+	suite.Error(err)
+}
+
+func (suite *ServiceTestSuite) TestBackupRestore() {
+	ctx := stdcontext.Background()
+	surveys, users, states := generateBackupRecords()
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginSnapshotTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("GetBackupSurveys", ctx, tx, 1000, 0).Return(surveys, nil)
+	suite.dbRepo.On("GetBackupUsers", ctx, tx, 1000, 0).Return(users, nil)
+	suite.dbRepo.On("GetBackupSurveyStates", ctx, tx, 1000, 0).Return(states, nil)
+	tx.On("Commit").Return(nil)
+
+	var buf bytes.Buffer
+	manifest, err := suite.svc.Backup(ctx, &buf, 12)
+	suite.NoError(err)
+	suite.Equal(service.BackupVersion, manifest.Version)
+	suite.Equal(uint(12), manifest.SchemaVersion)
+	suite.Len(manifest.Files, 3)
+	suite.Equal("surveys.jsonl", manifest.Files[0].Name)
+	suite.Equal(1, manifest.Files[0].Records)
+	suite.Equal("users.jsonl", manifest.Files[1].Name)
+	suite.Equal(1, manifest.Files[1].Records)
+	suite.Equal("survey_states.jsonl", manifest.Files[2].Name)
+	suite.Equal(2, manifest.Files[2].Records)
+
+	r := bytes.NewReader(buf.Bytes())
+
+	checked, err := service.CheckBackup(r, r.Size())
+	suite.NoError(err)
+	suite.Equal(manifest.Files, checked.Files)
+
+	restoreTx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(restoreTx, nil)
+	suite.dbRepo.On("RestoreSurvey", ctx, restoreTx, surveys[0], service.RestoreSkip).Return(surveys[0].GUID, true, nil)
+	suite.dbRepo.On("RestoreUser", ctx, restoreTx, users[0], service.RestoreSkip).Return(users[0].GUID, false, nil)
+	suite.dbRepo.On("RestoreSurveyState", ctx, restoreTx, states[0], service.RestoreSkip).Return(true, nil)
+	suite.dbRepo.On("RestoreSurveyState", ctx, restoreTx, states[1], service.RestoreSkip).Return(true, nil)
+	restoreTx.On("Commit").Return(nil)
+
+	_, stats, err := suite.svc.Restore(ctx, r, r.Size(), service.RestoreSkip)
+	suite.NoError(err)
+	suite.Equal(service.RestoreStats{
+		Surveys:      service.RestoreCount{Restored: 1},
+		Users:        service.RestoreCount{Skipped: 1},
+		SurveyStates: service.RestoreCount{Restored: 2},
+	}, stats)
+}
+
+func (suite *ServiceTestSuite) TestRestore_AlreadyExists() {
+	ctx := stdcontext.Background()
+	surveys, _, _ := generateBackupRecords()
+
+	archive := writeTestBackup(suite.T(), service.BackupVersion, map[string]string{
+		"surveys.jsonl":       `{"guid":"` + surveys[0].GUID.String() + `","id":1,"questions":[]}` + "\n",
+		"users.jsonl":         "",
+		"survey_states.jsonl": "",
+	})
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("RestoreSurvey", ctx, tx, mock.Anything, service.RestoreFail).Return(uuid.Nil, false, service.ErrAlreadyExists)
+	tx.On("Rollback").Return(nil)
+
+	_, _, err := suite.svc.Restore(ctx, archive, archive.Size(), service.RestoreFail)
+	suite.ErrorIs(err, service.ErrAlreadyExists)
+}
+
+func (suite *ServiceTestSuite) TestRestore_SkippedRecordsStoredUnderOtherGUIDs() {
+	ctx := stdcontext.Background()
+	surveys, users, states := generateBackupRecords()
+	storedSurveyGUID := uuid.MustParse("A1DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	storedUserGUID := uuid.MustParse("ADDF980A-73E9-458B-926D-13B79BC2E947")
+
+	var buf bytes.Buffer
+	backupTx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginSnapshotTx", ctx).Return(backupTx, nil)
+	suite.dbRepo.On("GetBackupSurveys", ctx, backupTx, 1000, 0).Return(surveys, nil)
+	suite.dbRepo.On("GetBackupUsers", ctx, backupTx, 1000, 0).Return(users, nil)
+	suite.dbRepo.On("GetBackupSurveyStates", ctx, backupTx, 1000, 0).Return(states, nil)
+	backupTx.On("Commit").Return(nil)
+
+	_, err := suite.svc.Backup(ctx, &buf, 12)
+	suite.NoError(err)
+
+	// the survey and the user are stored under other guids with the same slug and user_id
+	restoredUser := users[0]
+	restoredUser.CurrentSurvey = &storedSurveyGUID
+	restoredStates := slices.Clone(states)
+	for i := range restoredStates {
+		restoredStates[i].UserGUID = storedUserGUID
+		restoredStates[i].SurveyGUID = storedSurveyGUID
+	}
+
+	tx := mocks.NewDBTransaction(suite.T())
+	suite.dbRepo.On("BeginTx", ctx).Return(tx, nil)
+	suite.dbRepo.On("RestoreSurvey", ctx, tx, surveys[0], service.RestoreSkip).Return(storedSurveyGUID, false, nil)
+	suite.dbRepo.On("RestoreUser", ctx, tx, restoredUser, service.RestoreSkip).Return(storedUserGUID, false, nil)
+	suite.dbRepo.On("RestoreSurveyState", ctx, tx, restoredStates[0], service.RestoreSkip).Return(true, nil)
+	suite.dbRepo.On("RestoreSurveyState", ctx, tx, restoredStates[1], service.RestoreSkip).Return(false, nil)
+	tx.On("Commit").Return(nil)
+
+	r := bytes.NewReader(buf.Bytes())
+	_, stats, err := suite.svc.Restore(ctx, r, r.Size(), service.RestoreSkip)
+	suite.NoError(err)
+	suite.Equal(service.RestoreStats{
+		Surveys:      service.RestoreCount{Skipped: 1},
+		Users:        service.RestoreCount{Skipped: 1},
+		SurveyStates: service.RestoreCount{Restored: 1, Skipped: 1},
+	}, stats)
+}
+
+func (suite *ServiceTestSuite) TestParseResultsFilter() {
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	got, err := service.ParseResultsFilter(service.ResultsFilterParams{
+		From:   "2024-01-01",
+		To:     "2024-02-01T12:00:00Z",
+		Survey: surveyGUID.String(),
+		Scale:  "depression",
+		Level:  "high",
+	})
+	suite.NoError(err)
+	suite.Equal(service.ResultsFilter{From: &from, To: &to, SurveyGUID: &surveyGUID, Scale: "depression", Level: "high"}, got)
+
+	_, err = service.ParseResultsFilter(service.ResultsFilterParams{From: "01.01.2024"})
+	suite.ErrorContains(err, "invalid from")
+	_, err = service.ParseResultsFilter(service.ResultsFilterParams{User: "user"})
+	suite.ErrorContains(err, "invalid user")
+	_, err = service.ParseResultsFilter(service.ResultsFilterParams{Level: "high"})
+	suite.EqualError(err, "level requires scale")
+}
+
+func (suite *ServiceTestSuite) TestCheckBackup_Invalid() {
+	files := map[string]string{
+		"surveys.jsonl":       "{}\n",
+		"users.jsonl":         "",
+		"survey_states.jsonl": "",
+	}
+
+	// unsupported version
+	archive := writeTestBackup(suite.T(), service.BackupVersion+1, files)
+	_, err := service.CheckBackup(archive, archive.Size())
+	suite.ErrorIs(err, service.ErrInvalidBackup)
+
+	// file changed after the manifest is written
+	archive = writeTestBackup(suite.T(), service.BackupVersion, files, func(name string, data string) string {
+		if name == "surveys.jsonl" {
+			return data + "{}\n"
+		}
+		return data
+	})
+	_, err = service.CheckBackup(archive, archive.Size())
+	suite.ErrorIs(err, service.ErrInvalidBackup)
+
+	// missing file
+	delete(files, "users.jsonl")
+	archive = writeTestBackup(suite.T(), service.BackupVersion, files)
+	_, err = service.CheckBackup(archive, archive.Size())
+	suite.ErrorIs(err, service.ErrInvalidBackup)
+
+	// not an archive
+	_, err = service.CheckBackup(strings.NewReader("surveys"), 7)
+	suite.ErrorIs(err, service.ErrInvalidBackup)
+
+	_, _, err = suite.svc.Restore(stdcontext.Background(), strings.NewReader("surveys"), 7, service.RestoreSkip)
+	suite.ErrorIs(err, service.ErrInvalidBackup)
+}
+
+// writeTestBackup returns the archive of files with the manifest, files could be changed by corrupt after
+// the manifest is made
+func writeTestBackup(t *testing.T, version int, files map[string]string, corrupt ...func(name, data string) string) *bytes.Reader {
+	manifest := service.BackupManifest{Version: version, SchemaVersion: 1}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	for _, name := range []string{"surveys.jsonl", "users.jsonl", "survey_states.jsonl"} {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+
+		checksum := sha256.Sum256([]byte(data))
+		manifest.Files = append(manifest.Files, service.BackupFile{
+			Name:    name,
+			Records: strings.Count(data, "\n"),
+			SHA256:  hex.EncodeToString(checksum[:]),
+		})
+
+		for _, f := range corrupt {
+			data = f(name, data)
+		}
+
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+
+	w, err := archive.Create("manifest.json")
+	require.NoError(t, err)
+	require.NoError(t, json.NewEncoder(w).Encode(manifest))
+	require.NoError(t, archive.Close())
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+func generateBackupRecords() ([]service.BackupSurvey, []service.BackupUser, []service.BackupSurveyState) {
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	userGUID := uuid.MustParse("EDDF980A-73E9-458B-926D-13B79BC2E947")
+	slug := "survey-1"
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	surveys := []service.BackupSurvey{
+		{
+			GUID:             surveyGUID,
+			ID:               1,
+			Slug:             &slug,
+			Name:             "Survey 1",
+			CalculationsType: "test_1",
+			Questions:        json.RawMessage(`[{"text":"Question 1","answer_type":"select","possible_answers":[1,2]}]`),
+			CreatedAt:        createdAt,
+			UpdatedAt:        createdAt,
+		},
+	}
+
+	users := []service.BackupUser{
+		{
+			GUID:          userGUID,
+			UserID:        1,
+			ChatID:        1,
+			Nickname:      "nickname",
+			CurrentSurvey: &surveyGUID,
+			Cohort:        "default",
+			LastActivity:  createdAt,
+			CreatedAt:     createdAt,
+			UpdatedAt:     createdAt,
+		},
+	}
+
+	states := []service.BackupSurveyState{
+		{
+			UserGUID:   userGUID,
+			SurveyGUID: surveyGUID,
+			State:      entity.FinishedState,
+			Answers:    json.RawMessage(`[{"type":"select","data":[1]}]`),
+			Results:    json.RawMessage(`{"text":"good"}`),
+			CreatedAt:  createdAt,
+			UpdatedAt:  createdAt,
+		},
+		{
+			UserGUID:   userGUID,
+			SurveyGUID: surveyGUID,
+			State:      entity.ActiveState,
+			Answers:    json.RawMessage(`[]`),
+			CreatedAt:  createdAt.Add(time.Hour),
+			UpdatedAt:  createdAt.Add(time.Hour),
+		},
+	}
+
+	return surveys, users, states
+}
+
+func (suite *ServiceTestSuite) generateResultsReports() []entity.SurveyStateReport {
+	surveyGUID := uuid.MustParse("91DEF2EA-829D-443E-BCBF-FA2EF8283214")
+	userGUID := "eddf980a-73e9-458b-926d-13b79bc2e947"
+
+	return []entity.SurveyStateReport{
+		{
+			SurveyGUID: surveyGUID,
+			SurveyName: "Survey 1",
+			StartedAt:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			FinishedAt: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+			UserGUID:   userGUID,
+			UserID:     1,
+			Answers: []entity.Answer{
+				{Type: entity.AnswerTypeSelect, Data: []int{1}},
+				{Type: entity.AnswerTypeMultiSelect, Data: []int{1, 2}},
+			},
+			Results: &entity.Results{
+				Text:     "good",
+				Metadata: entity.ResultsMetadata{Raw: map[string]interface{}{"s1": 10}},
+			},
+		},
+		{
+			SurveyGUID: surveyGUID,
+			SurveyName: "Survey 1",
+			StartedAt:  time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+			FinishedAt: time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC),
+			UserGUID:   userGUID,
+			UserID:     1,
+			Answers: []entity.Answer{
+				{Type: entity.AnswerTypeSelect, Data: []int{2}},
+				{Type: entity.AnswerTypeMultiSelect, Data: []int{3}},
+			},
+			Results: &entity.Results{Text: "<b>bad</b> & worse"},
+		},
+	}
+}
+
 func (suite *ServiceTestSuite) generateSurveyStates() []entity.SurveyState {
 	surveys := suite.generateTestSurveyList()
 	return []entity.SurveyState{
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+// surveyFilePath matches paths of all fields of the survey file
+var surveyFilePath = regexp.MustCompile(
+	`^(slug|name|calculations_type|description|questions(\[\d+\](\.(text|answer_type|(possible_answers|answers_text)(\[\d+\])?))?)?)?$`,
+)
+
+// SurveyFileError is a problem of the survey file at the line, Path is empty for syntax errors
+type SurveyFileError struct {
+	entity.ValidationError
+	Line int
+}
+
+// CheckSurveyFile returns all problems of the survey file sorted by lines: syntax errors, unknown fields,
+// problems of the survey found by rsltProc and lint warnings. The file is valid if there are only warnings.
+func CheckSurveyFile(data []byte, rsltProc entity.ResultsProcessor) []SurveyFileError {
+	// unmarshalling reports syntax errors more precisely than the decoder of tokens
+	if err := json.Unmarshal(data, new(any)); err != nil {
+		return []SurveyFileError{syntaxError(data, err)}
+	}
+
+	lines, err := surveyFileLines(data)
+	if err != nil {
+		return []SurveyFileError{syntaxError(data, err)}
+	}
+
+	var s surveyFile
+	if err := json.Unmarshal(data, &s); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return []SurveyFileError{{ValidationError: entity.ValidationError{Message: err.Error()}, Line: 1}}
+		}
+
+		return []SurveyFileError{{
+			ValidationError: entity.ValidationError{
+				Path:    fieldPath(typeErr.Field),
+				Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+			},
+			Line: lineAt(data, typeErr.Offset),
+		}}
+	}
+
+	var errs []SurveyFileError
+
+	for path, line := range lines {
+		if !surveyFilePath.MatchString(path) {
+			errs = append(errs, SurveyFileError{
+				ValidationError: entity.ValidationError{Path: path, Message: "unknown field"},
+				Line:            line,
+			})
+		}
+	}
+
+	survey := entity.Survey{
+		Name:             s.Name,
+		Questions:        s.Questions,
+		CalculationsType: s.CalculationsType,
+		Description:      s.Description,
+		Slug:             s.Slug,
+	}
+
+	for _, validationErr := range append(rsltProc.ValidationErrors(survey), survey.Lint()...) {
+		errs = append(errs, SurveyFileError{
+			ValidationError: validationErr,
+			Line:            pathLine(lines, validationErr.Path),
+		})
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+
+		return errs[i].Path < errs[j].Path
+	})
+
+	return errs
+}
+
+func syntaxError(data []byte, err error) SurveyFileError {
+	offset := int64(len(data))
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+
+	return SurveyFileError{
+		ValidationError: entity.ValidationError{Message: fmt.Sprintf("invalid JSON: %v", err)},
+		Line:            lineAt(data, offset),
+	}
+}
+
+// surveyFileLines returns lines of all values of the JSON file by their paths, e.g. questions[2].text,
+// the root object has the empty path
+func surveyFileLines(data []byte) (map[string]int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	lines := make(map[string]int)
+
+	if err := walkJSON(decoder, data, "", lines); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, &json.SyntaxError{Offset: decoder.InputOffset()}
+	}
+
+	return lines, nil
+}
+
+func walkJSON(decoder *json.Decoder, data []byte, path string, lines map[string]int) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	// keys of objects are already added with their lines
+	if _, ok := lines[path]; !ok {
+		lines[path] = lineAt(data, decoder.InputOffset())
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+
+			child := fmt.Sprint(key)
+			if path != "" {
+				child = path + "." + child
+			}
+
+			lines[child] = lineAt(data, decoder.InputOffset())
+			if err := walkJSON(decoder, data, child, lines); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; decoder.More(); i++ {
+			if err := walkJSON(decoder, data, fmt.Sprintf("%s[%d]", path, i), lines); err != nil {
+				return err
+			}
+		}
+	}
+
+	// closing delimiter
+	_, err = decoder.Token()
+
+	return err
+}
+
+// fieldPath converts field of json errors to the path, e.g. questions.2.text to questions[2].text
+func fieldPath(field string) string {
+	var path string
+	for _, part := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			path += "[" + part + "]"
+			continue
+		}
+
+		if path != "" {
+			path += "."
+		}
+		path += part
+	}
+
+	return path
+}
+
+// pathLine returns line of the path or of its closest parent if the field is missing in the file
+func pathLine(lines map[string]int, path string) int {
+	for {
+		if line, ok := lines[path]; ok {
+			return line
+		}
+
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return lines[""]
+		}
+
+		path = path[:i]
+	}
+}
+
+func lineAt(data []byte, offset int64) int {
+	offset = min(max(offset, 0), int64(len(data)))
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	stdcontext "context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+func (s *service) GetSurveys(ctx stdcontext.Context) ([]entity.Survey, error) {
+	var surveys []entity.Survey
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		surveys, err = s.getSurveyList(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to get surveys list: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	sort.Slice(surveys, func(i, j int) bool {
+		return surveys[i].ID < surveys[j].ID
+	})
+
+	return surveys, nil
+}
+
+func (s *service) GetSurvey(ctx stdcontext.Context, surveyGUID uuid.UUID) (entity.Survey, error) {
+	var survey entity.Survey
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		survey, err = s.dbRepo.GetSurvey(ctx, tx, surveyGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get survey: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return entity.Survey{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return survey, nil
+}
+
+func (s *service) GetSurveySummaries(ctx stdcontext.Context) ([]SurveySummary, error) {
+	var summaries []SurveySummary
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		surveys, err := s.getSurveyList(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to get surveys list: %w", err)
+		}
+
+		active, err := s.dbRepo.CountSurveyStates(ctx, tx, []entity.State{entity.ActiveState})
+		if err != nil {
+			return fmt.Errorf("failed to count active states: %w", err)
+		}
+
+		finished, err := s.dbRepo.CountSurveyStates(ctx, tx, []entity.State{entity.FinishedState})
+		if err != nil {
+			return fmt.Errorf("failed to count finished states: %w", err)
+		}
+
+		for _, survey := range surveys {
+			summaries = append(summaries, SurveySummary{
+				Survey:   survey,
+				Active:   active[survey.GUID],
+				Finished: finished[survey.GUID],
+			})
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Survey.ID < summaries[j].Survey.ID
+	})
+
+	return summaries, nil
+}
+
+func (s *service) DeleteSurvey(ctx stdcontext.Context, surveyGUID uuid.UUID, force bool) error {
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		// deleted surveys are not found as well
+		if _, err := s.dbRepo.GetSurvey(ctx, tx, surveyGUID); err != nil {
+			return fmt.Errorf("failed to get survey: %w", err)
+		}
+
+		if !force {
+			active, err := s.dbRepo.CountStatesOfSurvey(ctx, tx, surveyGUID, []entity.State{entity.ActiveState})
+			if err != nil {
+				return fmt.Errorf("failed to count active states: %w", err)
+			}
+
+			if active > 0 {
+				return fmt.Errorf("%w: %d users have active states", ErrSurveyInUse, active)
+			}
+		}
+
+		if err := s.dbRepo.DeleteSurvey(ctx, tx, surveyGUID); err != nil {
+			return fmt.Errorf("failed to delete survey: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) GetSurveyAnalytics(ctx stdcontext.Context, surveyGUID uuid.UUID, abandonedAfter time.Duration) (SurveyAnalytics, error) {
+	var analytics SurveyAnalytics
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		survey, err := s.dbRepo.GetSurvey(ctx, tx, surveyGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get survey: %w", err)
+		}
+
+		stats, err := s.dbRepo.GetSurveyStatesStats(ctx, tx, surveyGUID, abandonedAfter)
+		if err != nil {
+			return fmt.Errorf("failed to get survey states stats: %w", err)
+		}
+
+		analytics = SurveyAnalytics{
+			Survey:            survey,
+			SurveyStatesStats: stats,
+			Questions:         questionsAnalytics(survey.Questions, stats.Answers),
+		}
+
+		// funnel has a step for each question, even if nobody stopped on it
+		analytics.Funnel = make([]FunnelStep, len(survey.Questions))
+		for i := range analytics.Funnel {
+			analytics.Funnel[i].Answered = i
+		}
+		for _, step := range stats.Funnel {
+			if step.Answered >= 0 && step.Answered < len(analytics.Funnel) {
+				analytics.Funnel[step.Answered].Count += step.Count
+			}
+		}
+
+		if stats.Started > 0 {
+			analytics.CompletionRate = float64(stats.Finished) / float64(stats.Started)
+		}
+
+		return nil
+	}); err != nil {
+		return SurveyAnalytics{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return analytics, nil
+}
+
+// questionsAnalytics returns counts of all possible answers to each question,
+// values which are not possible anymore (e.g. the survey is updated) are added to the end
+func questionsAnalytics(questions []entity.Question, counts []AnswerCount) []QuestionAnalytics {
+	byQuestion := make([]map[int]int, len(questions))
+	for i := range byQuestion {
+		byQuestion[i] = make(map[int]int)
+	}
+
+	for _, count := range counts {
+		if count.Question < 0 || count.Question >= len(questions) {
+			continue
+		}
+
+		byQuestion[count.Question][count.Value] += count.Count
+	}
+
+	var result []QuestionAnalytics
+	for i, question := range questions {
+		var values []int
+		switch question.AnswerType {
+		case entity.AnswerTypeSegment:
+			for value := question.PossibleAnswers[0]; value <= question.PossibleAnswers[1]; value++ {
+				values = append(values, value)
+			}
+		default:
+			values = append(values, question.PossibleAnswers...)
+		}
+
+		var rest []int
+		for value := range byQuestion[i] {
+			if !slices.Contains(values, value) {
+				rest = append(rest, value)
+			}
+		}
+		sort.Ints(rest)
+
+		item := QuestionAnalytics{Question: question}
+		for _, value := range append(values, rest...) {
+			item.Answers = append(item.Answers, AnswerStats{
+				Value: value,
+				Text:  question.AnswerText(entity.Answer{Type: question.AnswerType, Data: []int{value}}),
+				Count: byQuestion[i][value],
+			})
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	stdcontext "context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+const (
+	SurveySyncCreate    SurveySyncAction = "create"
+	SurveySyncUpdate    SurveySyncAction = "update"
+	SurveySyncUnchanged SurveySyncAction = "unchanged"
+	// SurveySyncOrphaned is the stored survey missing in the files, it is not deleted by the sync
+	SurveySyncOrphaned SurveySyncAction = "orphaned"
+)
+
+func (s *service) PlanSurveySync(ctx stdcontext.Context, surveys []entity.Survey) (SurveySyncPlan, error) {
+	var plan SurveySyncPlan
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		plan, err = s.planSurveySync(ctx, tx, surveys)
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return plan, nil
+}
+
+func (s *service) SyncSurveys(ctx stdcontext.Context, plan SurveySyncPlan) error {
+	var surveys []entity.Survey
+	for _, item := range plan {
+		if item.Action != SurveySyncOrphaned {
+			surveys = append(surveys, item.Survey)
+		}
+	}
+
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		// surveys could be changed since the plan is confirmed
+		current, err := s.planSurveySync(ctx, tx, surveys)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range current {
+			if item.Refused != nil {
+				return fmt.Errorf("survey %s: %w", item.Slug, item.Refused)
+			}
+		}
+
+		if !samePlan(plan, current) {
+			return ErrSyncPlanOutdated
+		}
+
+		for _, item := range current {
+			switch item.Action {
+			case SurveySyncCreate:
+				if _, err := s.createSurvey(ctx, tx, item.Survey); err != nil {
+					return fmt.Errorf("failed to create survey %s: %w", item.Slug, err)
+				}
+			case SurveySyncUpdate:
+				updated := *item.Current
+				updated.Slug = item.Survey.Slug
+				updated.Name = item.Survey.Name
+				updated.Questions = item.Survey.Questions
+				updated.CalculationsType = item.Survey.CalculationsType
+				updated.Description = item.Survey.Description
+
+				if err := s.dbRepo.UpdateSurvey(ctx, tx, updated); err != nil {
+					return fmt.Errorf("failed to update survey %s: %w", item.Slug, err)
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) planSurveySync(ctx stdcontext.Context, tx DBTransaction, surveys []entity.Survey) (SurveySyncPlan, error) {
+	slugs := make(map[string]struct{}, len(surveys))
+	for _, survey := range surveys {
+		if survey.Slug == "" {
+			return nil, fmt.Errorf("%w: empty slug of survey %q", ErrInvalidSurvey, survey.Name)
+		}
+
+		if _, ok := slugs[survey.Slug]; ok {
+			return nil, fmt.Errorf("%w: duplicate slug %s", ErrInvalidSurvey, survey.Slug)
+		}
+		slugs[survey.Slug] = struct{}{}
+
+		if err := s.rsltProc.Validate(survey); err != nil {
+			return nil, fmt.Errorf("failed to validate survey %s: %w: %w", survey.Slug, ErrInvalidSurvey, err)
+		}
+	}
+
+	stored, err := s.getSurveyList(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get surveys list: %w", err)
+	}
+
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].ID < stored[j].ID
+	})
+
+	active, err := s.dbRepo.CountSurveyStates(ctx, tx, []entity.State{entity.ActiveState})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active states: %w", err)
+	}
+
+	bySlug := make(map[string]entity.Survey)
+	byName := make(map[string][]entity.Survey)
+	for _, survey := range stored {
+		if survey.Slug != "" {
+			bySlug[survey.Slug] = survey
+		} else {
+			byName[survey.Name] = append(byName[survey.Name], survey)
+		}
+	}
+
+	surveys = slices.Clone(surveys)
+	sort.Slice(surveys, func(i, j int) bool {
+		return surveys[i].Slug < surveys[j].Slug
+	})
+
+	var plan SurveySyncPlan
+	matched := make(map[uuid.UUID]struct{})
+
+	for _, survey := range surveys {
+		current, ok := bySlug[survey.Slug]
+
+		// surveys created before slugs are matched by name if it is not ambiguous
+		adopted := false
+		if candidates := byName[survey.Name]; !ok && len(candidates) == 1 {
+			if _, taken := matched[candidates[0].GUID]; !taken {
+				current, ok, adopted = candidates[0], true, true
+			}
+		}
+
+		if !ok {
+			plan = append(plan, SurveySyncItem{Action: SurveySyncCreate, Slug: survey.Slug, Survey: survey})
+			continue
+		}
+
+		matched[current.GUID] = struct{}{}
+
+		item := SurveySyncItem{
+			Action:  SurveySyncUnchanged,
+			Slug:    survey.Slug,
+			Survey:  survey,
+			Current: &current,
+			Adopted: adopted,
+			Changes: surveyChanges(current, survey),
+			Active:  active[current.GUID],
+		}
+
+		if len(item.Changes) > 0 {
+			item.Action = SurveySyncUpdate
+			item.Refused = refuseSurveyUpdate(current, survey, item.Active)
+		}
+
+		plan = append(plan, item)
+	}
+
+	for _, survey := range stored {
+		if _, ok := matched[survey.GUID]; ok {
+			continue
+		}
+
+		plan = append(plan, SurveySyncItem{
+			Action:  SurveySyncOrphaned,
+			Slug:    survey.Slug,
+			Survey:  survey,
+			Current: &survey,
+			Active:  active[survey.GUID],
+		})
+	}
+
+	return plan, nil
+}
+
+// surveyChanges returns fields of the stored survey changed by the new one
+func surveyChanges(current, new entity.Survey) []string {
+	var changes []string
+
+	if current.Slug != new.Slug {
+		changes = append(changes, "slug")
+	}
+	if current.Name != new.Name {
+		changes = append(changes, "name")
+	}
+	if current.Description != new.Description {
+		changes = append(changes, "description")
+	}
+	if current.CalculationsType != new.CalculationsType {
+		changes = append(changes, "calculations_type")
+	}
+
+	if len(current.Questions) != len(new.Questions) {
+		return append(changes, "questions")
+	}
+
+	for i := range current.Questions {
+		if !sameQuestion(current.Questions[i], new.Questions[i]) {
+			changes = append(changes, fmt.Sprintf("questions[%d]", i))
+		}
+	}
+
+	return changes
+}
+
+// refuseSurveyUpdate returns why the update can't be applied. Answers of active states are kept by the number of
+// the question, so only texts could be changed while users are passing the survey.
+func refuseSurveyUpdate(current, new entity.Survey, active int) error {
+	if len(current.Questions) != len(new.Questions) {
+		return fmt.Errorf("%w: cannot update survey with different number of questions", ErrInvalidSurvey)
+	}
+
+	if active == 0 {
+		return nil
+	}
+
+	if current.CalculationsType != new.CalculationsType {
+		return fmt.Errorf("%w: %d users have active states, calculations type can't be changed", ErrSurveyInUse, active)
+	}
+
+	for i := range current.Questions {
+		old, changed := current.Questions[i], new.Questions[i]
+		if old.AnswerType != changed.AnswerType || !slices.Equal(old.PossibleAnswers, changed.PossibleAnswers) {
+			return fmt.Errorf("%w: %d users have active states, answers of question %d can't be changed", ErrSurveyInUse, active, i)
+		}
+	}
+
+	return nil
+}
+
+func sameQuestion(a, b entity.Question) bool {
+	return a.Text == b.Text &&
+		a.AnswerType == b.AnswerType &&
+		slices.Equal(a.PossibleAnswers, b.PossibleAnswers) &&
+		slices.Equal(a.AnswersText, b.AnswersText)
+}
+
+// samePlan checks that the same changes are made to the same stored surveys
+func samePlan(a, b SurveySyncPlan) bool {
+	return slices.EqualFunc(a, b, func(x, y SurveySyncItem) bool {
+		var xGUID, yGUID uuid.UUID
+		if x.Current != nil {
+			xGUID = x.Current.GUID
+		}
+		if y.Current != nil {
+			yGUID = y.Current.GUID
+		}
+
+		return x.Action == y.Action && x.Slug == y.Slug && xGUID == yGUID
+	})
+}
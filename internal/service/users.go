@@ -0,0 +1,209 @@
+package service
+
+import (
+	stdcontext "context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+const anonymousNickname = "anonymous"
+
+func (s *service) GetUserDetails(ctx stdcontext.Context, userGUID uuid.UUID) (UserDetails, error) {
+	var details UserDetails
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		details.User, err = s.dbRepo.GetUserByGUID(ctx, tx, userGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		reports, err := s.dbRepo.GetUserSurveyReports(ctx, tx, userGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get user survey reports: %w", err)
+		}
+
+		questions := make(map[uuid.UUID][]entity.Question)
+		for _, report := range reports {
+			if _, ok := questions[report.SurveyGUID]; !ok {
+				survey, err := s.dbRepo.GetSurvey(ctx, tx, report.SurveyGUID)
+				switch {
+				case errors.Is(err, ErrNotFound):
+					// survey is deleted, answers are shown without questions
+				case err != nil:
+					return fmt.Errorf("failed to get survey: %w", err)
+				}
+
+				questions[report.SurveyGUID] = survey.Questions
+			}
+
+			details.Surveys = append(details.Surveys, UserSurveyDetails{
+				Report:    report,
+				Answers:   questionAnswers(questions[report.SurveyGUID], report.Answers),
+				Questions: len(questions[report.SurveyGUID]),
+			})
+		}
+
+		return nil
+	}); err != nil {
+		return UserDetails{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return details, nil
+}
+
+func (s *service) DeleteUser(ctx stdcontext.Context, userGUID uuid.UUID) error {
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		if _, err := s.dbRepo.GetUserByGUID(ctx, tx, userGUID); err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		if err := s.dbRepo.DeleteUser(ctx, tx, userGUID); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) FindUser(ctx stdcontext.Context, query string) (entity.User, error) {
+	// nicknames are stored without @
+	query = strings.TrimPrefix(strings.TrimSpace(query), "@")
+
+	var user entity.User
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		var err error
+		if guid, parseErr := uuid.Parse(query); parseErr == nil {
+			user, err = s.dbRepo.GetUserByGUID(ctx, tx, guid)
+		} else if userID, parseErr := strconv.ParseInt(query, 10, 64); parseErr == nil {
+			user, err = s.dbRepo.GetUserByID(ctx, tx, userID)
+		} else {
+			user, err = s.findUserByNickname(ctx, tx, query)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return entity.User{}, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return user, nil
+}
+
+// findUserByNickname returns the only user with the telegram username or the whole nickname, case is ignored.
+// Partial matches are not used as the found user could be reset or forgotten.
+func (s *service) findUserByNickname(ctx stdcontext.Context, tx DBTransaction, nickname string) (entity.User, error) {
+	if nickname == "" {
+		return entity.User{}, ErrNotFound
+	}
+
+	matched, err := s.dbRepo.GetUsersByNickname(ctx, tx, nickname)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to get users by nickname: %w", err)
+	}
+
+	switch len(matched) {
+	case 0:
+		return entity.User{}, ErrNotFound
+	case 1:
+		return matched[0], nil
+	default:
+		return entity.User{}, fmt.Errorf("%w: %d users have nickname %s", ErrAmbiguousUser, len(matched), nickname)
+	}
+}
+
+func (s *service) AnonymizeUser(ctx stdcontext.Context, userGUID uuid.UUID) error {
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByGUID(ctx, tx, userGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		// telegram ids are positive, so the user is never found by the id of the real one
+		user.UserID = anonymousUserID(userGUID)
+		user.ChatID = user.UserID
+		user.Nickname = anonymousNickname
+		user.CurrentSurvey = nil
+
+		if err := s.dbRepo.AnonymizeUser(ctx, tx, user); err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) ResetUserSurvey(ctx stdcontext.Context, userGUID, surveyGUID uuid.UUID, withFinished bool) (int, error) {
+	states := []entity.State{entity.ActiveState}
+	if withFinished {
+		states = append(states, entity.FinishedState)
+	}
+
+	var deleted int
+	if err := s.Transact(ctx, func(tx DBTransaction) error {
+		user, err := s.dbRepo.GetUserByGUID(ctx, tx, userGUID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		deleted, err = s.dbRepo.DeleteUserSurveyStates(ctx, tx, userGUID, surveyGUID, states)
+		if err != nil {
+			return fmt.Errorf("failed to delete user survey states: %w", err)
+		}
+
+		if deleted == 0 {
+			return fmt.Errorf("failed to find user survey states: %w", ErrNotFound)
+		}
+
+		if user.CurrentSurvey != nil && *user.CurrentSurvey == surveyGUID {
+			if err := s.dbRepo.SetUserCurrentSurveyToNil(ctx, tx, userGUID); err != nil {
+				return fmt.Errorf("failed to update user: %w", err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("failed to transact: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// anonymousUserID returns negative id made of the guid, so ids of anonymized users are unique
+func anonymousUserID(userGUID uuid.UUID) int64 {
+	return -int64(binary.BigEndian.Uint64(userGUID[:8])>>1) - 1
+}
+
+func questionAnswers(questions []entity.Question, answers []entity.Answer) []QuestionAnswer {
+	var result []QuestionAnswer
+	for i, answer := range answers {
+		var qa QuestionAnswer
+		if i < len(questions) {
+			qa.Question = questions[i].Text
+			qa.Answer = questions[i].AnswerText(answer)
+		} else {
+			qa.Answer = entity.Question{}.AnswerText(answer)
+		}
+
+		result = append(result, qa)
+	}
+
+	return result
+}
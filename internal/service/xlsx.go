@@ -0,0 +1,155 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+)
+
+// userIDColumn is the index of user_id in resultsColumns, answers start from the last column
+const userIDColumn = 4
+
+// xlsxParts are parts of the workbook besides the sheet, it is the minimal set opened by spreadsheet editors
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{
+		name: "[Content_Types].xml",
+		content: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`,
+	},
+	{
+		name: "_rels/.rels",
+		content: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`,
+	},
+	{
+		name: "xl/workbook.xml",
+		content: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="results" sheetId="1" r:id="rId1"/></sheets>` +
+			`</workbook>`,
+	},
+	{
+		name: "xl/_rels/workbook.xml.rels",
+		content: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+	},
+}
+
+// xlsxResultsWriter streams results to the only sheet of the workbook, so the whole export is not kept in memory
+type xlsxResultsWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	row     int
+}
+
+func newXLSXResultsWriter(w io.Writer) (*xlsxResultsWriter, error) {
+	archive := zip.NewWriter(w)
+
+	for _, part := range xlsxParts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", part.name, err)
+		}
+
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", part.name, err)
+		}
+	}
+
+	// the sheet is the last part, as parts of zip are written one by one
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sheet: %w", err)
+	}
+
+	if _, err := io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, fmt.Errorf("failed to write sheet: %w", err)
+	}
+
+	x := &xlsxResultsWriter{archive: archive, sheet: sheet}
+	if err := x.writeRow(resultsColumns, false); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	return x, nil
+}
+
+func (x *xlsxResultsWriter) Write(report entity.SurveyStateReport) error {
+	columns, err := report.ToCSV()
+	if err != nil {
+		return fmt.Errorf("failed to convert survey state to row: %w", err)
+	}
+
+	return x.writeRow(columns, true)
+}
+
+func (x *xlsxResultsWriter) Flush() error {
+	return x.archive.Flush()
+}
+
+func (x *xlsxResultsWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return fmt.Errorf("failed to write sheet: %w", err)
+	}
+
+	return x.archive.Close()
+}
+
+// writeRow writes cells of the row, user id and single answers are numbers if numeric is set
+func (x *xlsxResultsWriter) writeRow(columns []string, numeric bool) error {
+	x.row++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.row)
+
+	for i, value := range columns {
+		ref := xlsxColumn(i) + strconv.Itoa(x.row)
+
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil && numeric && (i == userIDColumn || i >= len(resultsColumns)-1) {
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+			continue
+		}
+
+		fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		if err := xml.EscapeText(&b, []byte(value)); err != nil {
+			return fmt.Errorf("failed to escape cell: %w", err)
+		}
+		b.WriteString(`</t></is></c>`)
+	}
+
+	b.WriteString(`</row>`)
+
+	if _, err := io.WriteString(x.sheet, b.String()); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
+	}
+
+	return nil
+}
+
+// xlsxColumn returns the name of the column by its index, e.g. 0 is A, 26 is AA
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+
+	return name
+}
@@ -0,0 +1,225 @@
+package simulator
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxErrorMessages limits distinct messages of errors in the report, the rest are counted as other
+const maxErrorMessages = 20
+
+// conflictCodes are errors of postgres caused by concurrent transactions, e.g. the same user created twice
+var conflictCodes = []pq.ErrorCode{
+	"40001", // serialization_failure
+	"40P01", // deadlock_detected
+	"23505", // unique_violation
+	"55P03", // lock_not_available
+}
+
+type (
+	// Report is written as JSON to compare releases, latencies are in milliseconds
+	Report struct {
+		Release   string    `json:"release,omitempty"`
+		StartedAt time.Time `json:"started_at"`
+		Duration  float64   `json:"duration_seconds"`
+
+		// Users is a number of concurrent virtual users, UsersStarted counts the ones replaced during soak
+		Users           int `json:"users"`
+		UsersStarted    int `json:"users_started"`
+		SurveysFinished int `json:"surveys_finished"`
+
+		Total      OperationStats            `json:"total"`
+		Operations map[string]OperationStats `json:"operations"`
+
+		// Errors are counted by message
+		Errors map[string]int `json:"errors,omitempty"`
+		// Messages are counted by method of TelegramRepo
+		Messages map[string]int `json:"messages"`
+	}
+
+	OperationStats struct {
+		Count     int `json:"count"`
+		Errors    int `json:"errors"`
+		Conflicts int `json:"conflicts"`
+		// Throughput is a number of operations per second
+		Throughput float64      `json:"throughput"`
+		Latency    LatencyStats `json:"latency_ms"`
+	}
+
+	LatencyStats struct {
+		Mean float64 `json:"mean"`
+		P50  float64 `json:"p50"`
+		P90  float64 `json:"p90"`
+		P95  float64 `json:"p95"`
+		P99  float64 `json:"p99"`
+		Max  float64 `json:"max"`
+	}
+
+	// collector records operations of all virtual users
+	collector struct {
+		mu sync.Mutex
+
+		latencies map[string][]time.Duration
+		errors    map[string]int
+		conflicts map[string]int
+		messages  map[string]int
+
+		operations      int
+		failed          int
+		usersStarted    int
+		surveysFinished int
+	}
+)
+
+func newCollector() *collector {
+	return &collector{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+		conflicts: make(map[string]int),
+		messages:  make(map[string]int),
+	}
+}
+
+func (c *collector) record(op string, latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.latencies[op] = append(c.latencies[op], latency)
+	c.operations++
+
+	if err == nil {
+		return
+	}
+
+	c.failed++
+	c.errors[op]++
+	if isConflict(err) {
+		c.conflicts[op]++
+	}
+
+	msg := err.Error()
+	if _, ok := c.messages[msg]; !ok && len(c.messages) >= maxErrorMessages {
+		msg = "other"
+	}
+	c.messages[msg]++
+}
+
+func (c *collector) userStarted() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.usersStarted++
+}
+
+func (c *collector) surveyFinished() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.surveysFinished++
+}
+
+// progress returns numbers of operations and errors recorded so far
+func (c *collector) progress() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.operations, c.failed
+}
+
+func (c *collector) report(startedAt time.Time, duration time.Duration) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := Report{
+		StartedAt:       startedAt,
+		Duration:        duration.Seconds(),
+		UsersStarted:    c.usersStarted,
+		SurveysFinished: c.surveysFinished,
+		Operations:      make(map[string]OperationStats, len(c.latencies)),
+		Errors:          make(map[string]int, len(c.messages)),
+	}
+
+	var all []time.Duration
+	for op, latencies := range c.latencies {
+		report.Operations[op] = operationStats(latencies, c.errors[op], c.conflicts[op], duration)
+		all = append(all, latencies...)
+	}
+	report.Total = operationStats(all, c.failed, sumCounts(c.conflicts), duration)
+
+	for msg, count := range c.messages {
+		report.Errors[msg] = count
+	}
+
+	return report
+}
+
+func operationStats(latencies []time.Duration, failed, conflicts int, duration time.Duration) OperationStats {
+	stats := OperationStats{
+		Count:     len(latencies),
+		Errors:    failed,
+		Conflicts: conflicts,
+		Latency:   latencyStats(latencies),
+	}
+
+	if duration > 0 {
+		stats.Throughput = float64(len(latencies)) / duration.Seconds()
+	}
+
+	return stats
+}
+
+func latencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+
+	var sum time.Duration
+	for _, latency := range sorted {
+		sum += latency
+	}
+
+	return LatencyStats{
+		Mean: milliseconds(sum / time.Duration(len(sorted))),
+		P50:  milliseconds(percentile(sorted, 0.5)),
+		P90:  milliseconds(percentile(sorted, 0.9)),
+		P95:  milliseconds(percentile(sorted, 0.95)),
+		P99:  milliseconds(percentile(sorted, 0.99)),
+		Max:  milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+
+	return total
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func isConflict(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && slices.Contains(conflictCodes, pqErr.Code)
+}
@@ -0,0 +1,250 @@
+// Package simulator drives virtual users through the service as the bot does, to measure how many concurrent
+// respondents one instance handles.
+package simulator
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+// Operations of the report, one for each handler of the bot
+const (
+	OpStart         = "start"
+	OpList          = "list"
+	OpSurvey        = "survey"
+	OpAnswer        = "answer"
+	OpInvalidAnswer = "invalid_answer"
+)
+
+// invalidAnswer is rejected by questions of all answer types
+const invalidAnswer = "invalid"
+
+// errInvalidAnswerAccepted is recorded if the invalid answer is not rejected
+var errInvalidAnswerAccepted = errors.New("invalid answer is accepted")
+
+type (
+	Config struct {
+		// Users is a number of concurrent virtual users
+		Users int
+		// Duration of the soak test, users who passed all surveys are replaced with new ones until it elapses.
+		// Each user passes all surveys once if it is zero.
+		Duration time.Duration
+		// Think is an average pause of users between operations
+		Think time.Duration
+		// InvalidAnswers is a share of questions answered with the invalid answer first
+		InvalidAnswers float64
+		// FirstUserID is telegram id of the first virtual user, ids of the next ones are increased by one
+		FirstUserID int64
+		Seed        int64
+
+		// Progress is called with numbers of operations and errors every ProgressInterval
+		Progress         func(operations, errors int)
+		ProgressInterval time.Duration
+	}
+
+	virtualUser struct {
+		svc service.Service
+		cfg Config
+		c   *collector
+		rnd *rand.Rand
+		ctx *userContext
+	}
+)
+
+// Run drives virtual users through start, list, survey and answer handlers of the service until they pass all
+// surveys or the duration elapses. Operations started before ctx is canceled are finished, so they are not counted
+// as errors.
+func Run(ctx stdcontext.Context, svc service.Service, tg *Telegram, surveys []entity.Survey, cfg Config) (Report, error) {
+	switch {
+	case cfg.Users <= 0:
+		return Report{}, fmt.Errorf("invalid number of users: %d", cfg.Users)
+	case cfg.InvalidAnswers < 0 || cfg.InvalidAnswers > 1:
+		return Report{}, fmt.Errorf("invalid share of invalid answers: %v", cfg.InvalidAnswers)
+	case len(surveys) == 0:
+		return Report{}, errors.New("no surveys")
+	}
+
+	stop := ctx
+	if cfg.Duration > 0 {
+		var cancel stdcontext.CancelFunc
+		stop, cancel = stdcontext.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	c := newCollector()
+	startedAt := time.Now()
+
+	if cfg.Progress != nil && cfg.ProgressInterval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			ticker := time.NewTicker(cfg.ProgressInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					cfg.Progress(c.progress())
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+	)
+	for i := 0; i < cfg.Users; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			rnd := rand.New(rand.NewSource(cfg.Seed + int64(worker)))
+			for stop.Err() == nil {
+				u := &virtualUser{
+					svc: svc,
+					cfg: cfg,
+					c:   c,
+					rnd: rnd,
+					ctx: &userContext{
+						Context: stdcontext.WithoutCancel(ctx),
+						userID:  cfg.FirstUserID + next.Add(1) - 1,
+						tg:      tg,
+					},
+				}
+
+				c.userStarted()
+				u.run(stop, surveys)
+
+				if cfg.Duration == 0 {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	report := c.report(startedAt, time.Since(startedAt))
+	report.Users = cfg.Users
+	report.Messages = tg.Messages()
+
+	return report, nil
+}
+
+// run passes surveys in random order, the survey is left on the first error
+func (u *virtualUser) run(stop stdcontext.Context, surveys []entity.Survey) {
+	if !u.do(stop, OpStart, func() error { return u.svc.HandleStartCommand(u.ctx, "") }) {
+		return
+	}
+
+	if !u.do(stop, OpList, func() error { return u.svc.HandleListCommand(u.ctx) }) {
+		return
+	}
+
+	for _, i := range u.rnd.Perm(len(surveys)) {
+		survey := surveys[i]
+
+		if !u.do(stop, OpSurvey, func() error { return u.svc.HandleSurveyCommand(u.ctx, survey.ID) }) {
+			if stop.Err() != nil {
+				return
+			}
+
+			continue
+		}
+
+		if u.answer(stop, survey) {
+			u.c.surveyFinished()
+		}
+		if stop.Err() != nil {
+			return
+		}
+
+		if !u.do(stop, OpList, func() error { return u.svc.HandleListCommand(u.ctx) }) && stop.Err() != nil {
+			return
+		}
+	}
+}
+
+// answer answers all questions of the started survey, true is returned if it is finished
+func (u *virtualUser) answer(stop stdcontext.Context, survey entity.Survey) bool {
+	for _, question := range survey.Questions {
+		if u.rnd.Float64() < u.cfg.InvalidAnswers {
+			if !u.do(stop, OpInvalidAnswer, func() error {
+				err := u.svc.HandleAnswer(u.ctx, invalidAnswer)
+				switch {
+				case errors.Is(err, service.ErrInvalidAnswer):
+					return nil
+				case err == nil:
+					return errInvalidAnswerAccepted
+				default:
+					return err
+				}
+			}) {
+				return false
+			}
+		}
+
+		msg := u.validAnswer(question)
+		if !u.do(stop, OpAnswer, func() error { return u.svc.HandleAnswer(u.ctx, msg) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// do waits for think time and records the operation, false is returned if it fails or the run is stopped
+func (u *virtualUser) do(stop stdcontext.Context, op string, f func() error) bool {
+	if u.cfg.Think > 0 {
+		// think time is between a half and one and a half of the average
+		think := u.cfg.Think/2 + time.Duration(u.rnd.Int63n(int64(u.cfg.Think)+1))
+
+		timer := time.NewTimer(think)
+		select {
+		case <-timer.C:
+		case <-stop.Done():
+			timer.Stop()
+		}
+	}
+
+	if stop.Err() != nil {
+		return false
+	}
+
+	started := time.Now()
+	err := f()
+	u.c.record(op, time.Since(started), err)
+
+	return err == nil
+}
+
+// validAnswer returns random answer to the question in the form users send it to the bot
+func (u *virtualUser) validAnswer(q entity.Question) string {
+	switch q.AnswerType {
+	case entity.AnswerTypeSegment:
+		from, to := q.PossibleAnswers[0], q.PossibleAnswers[1]
+		return strconv.Itoa(from + u.rnd.Intn(to-from+1))
+	case entity.AnswerTypeMultiSelect:
+		var answers []string
+		for _, i := range u.rnd.Perm(len(q.PossibleAnswers))[:1+u.rnd.Intn(len(q.PossibleAnswers))] {
+			answers = append(answers, strconv.Itoa(q.PossibleAnswers[i]))
+		}
+
+		return strings.Join(answers, ",")
+	default:
+		return strconv.Itoa(q.PossibleAnswers[u.rnd.Intn(len(q.PossibleAnswers))])
+	}
+}
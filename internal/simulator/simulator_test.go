@@ -0,0 +1,139 @@
+package simulator
+
+import (
+	stdcontext "context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service/mocks"
+)
+
+var testSurveys = []entity.Survey{
+	{
+		ID: 1,
+		Questions: []entity.Question{
+			{AnswerType: entity.AnswerTypeSelect, PossibleAnswers: []int{1, 2, 3}, AnswersText: []string{"a", "b", "c"}},
+			{AnswerType: entity.AnswerTypeMultiSelect, PossibleAnswers: []int{1, 2, 3}, AnswersText: []string{"a", "b", "c"}},
+			{AnswerType: entity.AnswerTypeSegment, PossibleAnswers: []int{1, 10}},
+		},
+	},
+	{
+		ID:        2,
+		Questions: []entity.Question{{AnswerType: entity.AnswerTypeSegment, PossibleAnswers: []int{1, 10}}},
+	},
+}
+
+func TestRun(t *testing.T) {
+	svc := mocks.NewService(t)
+
+	var (
+		mu       sync.Mutex
+		answered = make(map[int64]int)
+		invalid  int
+	)
+
+	svc.On("HandleStartCommand", mock.Anything, "").Return(nil)
+	svc.On("HandleListCommand", mock.Anything).Return(nil)
+	svc.On("HandleSurveyCommand", mock.Anything, int64(1)).Return(nil)
+	svc.On("HandleSurveyCommand", mock.Anything, int64(2)).
+		Return(fmt.Errorf("failed to transact: %w", &pq.Error{Code: "40P01", Message: "deadlock detected"}))
+	svc.On("HandleAnswer", mock.Anything, mock.Anything).Return(func(ctx context.Context, msg string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if msg == invalidAnswer {
+			invalid++
+			return fmt.Errorf("failed to answer survey: %w", service.ErrInvalidAnswer)
+		}
+
+		// answers are sent in order of questions of the survey
+		question := testSurveys[0].Questions[answered[ctx.UserID()]]
+		if _, err := question.GetAnswer(msg); err != nil {
+			return err
+		}
+		answered[ctx.UserID()]++
+
+		return nil
+	})
+
+	tg := NewTelegram()
+	report, err := Run(stdcontext.Background(), svc, tg, testSurveys, Config{
+		Users:          3,
+		InvalidAnswers: 0.5,
+		FirstUserID:    100,
+		Seed:           1,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[int64]int{100: 3, 101: 3, 102: 3}, answered)
+	require.Equal(t, 3, report.Users)
+	require.Equal(t, 3, report.UsersStarted)
+	require.Equal(t, 3, report.SurveysFinished)
+
+	require.Equal(t, 3, report.Operations[OpStart].Count)
+	require.Equal(t, 6, report.Operations[OpList].Count)
+	require.Equal(t, 9, report.Operations[OpAnswer].Count)
+	require.Zero(t, report.Operations[OpAnswer].Errors)
+	require.Equal(t, invalid, report.Operations[OpInvalidAnswer].Count)
+	require.Zero(t, report.Operations[OpInvalidAnswer].Errors)
+
+	require.Equal(t, OperationStats{Count: 6, Errors: 3, Conflicts: 3}, withoutTimings(report.Operations[OpSurvey]))
+	require.Equal(t, OperationStats{Count: 24 + invalid, Errors: 3, Conflicts: 3}, withoutTimings(report.Total))
+	require.Equal(t, map[string]int{"failed to transact: pq: deadlock detected": 3}, report.Errors)
+	require.Equal(t, map[string]int{}, report.Messages)
+}
+
+func TestRun_Soak(t *testing.T) {
+	svc := mocks.NewService(t)
+	svc.On("HandleStartCommand", mock.Anything, "").Return(nil)
+	svc.On("HandleListCommand", mock.Anything).Return(nil)
+	svc.On("HandleSurveyCommand", mock.Anything, mock.Anything).Return(nil)
+	svc.On("HandleAnswer", mock.Anything, mock.Anything).Return(nil)
+
+	report, err := Run(stdcontext.Background(), svc, NewTelegram(), testSurveys, Config{
+		Users:    2,
+		Duration: 50 * time.Millisecond,
+		Think:    time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// users passed all surveys are replaced with new ones
+	require.Greater(t, report.UsersStarted, 2)
+	require.Zero(t, report.Total.Errors)
+	require.GreaterOrEqual(t, report.Duration, 0.05)
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	_, err := Run(stdcontext.Background(), nil, NewTelegram(), testSurveys, Config{})
+	require.Error(t, err)
+
+	_, err = Run(stdcontext.Background(), nil, NewTelegram(), nil, Config{Users: 1})
+	require.Error(t, err)
+}
+
+func TestLatencyStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	require.Equal(t, LatencyStats{Mean: 50.5, P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}, latencyStats(latencies))
+	require.Equal(t, LatencyStats{Mean: 7, P50: 7, P90: 7, P95: 7, P99: 7, Max: 7}, latencyStats([]time.Duration{7 * time.Millisecond}))
+	require.Equal(t, LatencyStats{}, latencyStats(nil))
+}
+
+func withoutTimings(stats OperationStats) OperationStats {
+	stats.Throughput = 0
+	stats.Latency = LatencyStats{}
+
+	return stats
+}
@@ -0,0 +1,108 @@
+package simulator
+
+import (
+	stdcontext "context"
+	"fmt"
+	"maps"
+	"sync"
+
+	"git.ykonkov.com/ykonkov/survey-bot/internal/context"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/entity"
+	"git.ykonkov.com/ykonkov/survey-bot/internal/service"
+)
+
+type (
+	// Telegram is TelegramRepo counting messages instead of sending them
+	Telegram struct {
+		mu       sync.Mutex
+		messages map[string]int
+	}
+
+	// userContext is the chat of the virtual user, messages sent to it are counted
+	userContext struct {
+		stdcontext.Context
+
+		userID int64
+		tg     *Telegram
+	}
+)
+
+var (
+	_ service.TelegramRepo = (*Telegram)(nil)
+	_ context.Context      = (*userContext)(nil)
+)
+
+func NewTelegram() *Telegram {
+	return &Telegram{messages: make(map[string]int)}
+}
+
+// Messages returns numbers of messages by method
+func (t *Telegram) Messages() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return maps.Clone(t.messages)
+}
+
+func (t *Telegram) record(method string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages[method]++
+}
+
+func (t *Telegram) SendSurveyList(ctx context.Context, states []service.UserSurveyState) error {
+	t.record("SendSurveyList")
+	return nil
+}
+
+func (t *Telegram) SendSurveyQuestion(ctx context.Context, question entity.Question) error {
+	t.record("SendSurveyQuestion")
+	return nil
+}
+
+func (t *Telegram) SendMessage(ctx context.Context, msg string) error {
+	t.record("SendMessage")
+	return nil
+}
+
+func (t *Telegram) SendResults(ctx context.Context, survey entity.Survey, results entity.Results) error {
+	t.record("SendResults")
+	return nil
+}
+
+func (t *Telegram) SendFile(ctx context.Context, path string) error {
+	t.record("SendFile")
+	return nil
+}
+
+func (t *Telegram) SendDocument(ctx context.Context, fileName string, data []byte) error {
+	t.record("SendDocument")
+	return nil
+}
+
+func (t *Telegram) SendAlert(ctx stdcontext.Context, chatID int64, alert entity.Alert, user entity.User, surveyName string) error {
+	t.record("SendAlert")
+	return nil
+}
+
+func (c *userContext) Send(msg interface{}, options ...interface{}) error {
+	c.tg.record("Send")
+	return nil
+}
+
+func (c *userContext) UserID() int64 {
+	return c.userID
+}
+
+func (c *userContext) ChatID() int64 {
+	return c.userID
+}
+
+func (c *userContext) Nickname() string {
+	return fmt.Sprintf("simulator %d", c.userID)
+}
+
+func (c *userContext) SetStdContext(ctx stdcontext.Context) {
+	c.Context = ctx
+}